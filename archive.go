@@ -0,0 +1,176 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Default limits applied while extracting plugin archives, so that a
+// hostile or corrupted archive cannot fill the disk
+const (
+	DefaultMaxExtractedSize  = 512 << 20 // Total bytes written
+	DefaultMaxArchiveEntries = 10000     // Files and directories created
+)
+
+// contentSniffingSize is the number of bytes http.DetectContentType considers
+const contentSniffingSize = 512
+
+// ExtractionLimits bound what extracting a plugin archive may write. Zero
+// fields take the defaults.
+type ExtractionLimits struct {
+	MaxSize    int64 // Total bytes written, DefaultMaxExtractedSize when zero
+	MaxEntries int   // Files and directories created, DefaultMaxArchiveEntries when zero
+}
+
+// WithExtractionLimits sets the limits archives are extracted within by
+// Install, Upgrade and Stage. Archives exceeding them fail with
+// *ErrMalformedArchive.
+func (m *Manager) WithExtractionLimits(limits ExtractionLimits) *Manager {
+	m.extractionLimits = limits
+	return m
+}
+
+type extractionLimitsKey struct{}
+
+// extractionContext carries the Manager's extraction limits to the
+// extractors
+func (m *Manager) extractionContext(ctx context.Context) context.Context {
+	if m.extractionLimits == (ExtractionLimits{}) {
+		return ctx
+	}
+
+	return context.WithValue(ctx, extractionLimitsKey{}, m.extractionLimits)
+}
+
+// ErrMalformedArchive is returned when plugin content cannot be extracted:
+// the archive is corrupted or truncated, holds paths outside the plugin
+// directory or exceeds the extraction limits. Nothing is left installed.
+type ErrMalformedArchive struct {
+	Plugin string
+	Format string // Detected content type
+	Err    error
+}
+
+func (e *ErrMalformedArchive) Error() string {
+	return fmt.Sprintf("malformed %s archive for plugin %s: %v", e.Format, e.Plugin, e.Err)
+}
+
+func (e *ErrMalformedArchive) Unwrap() error {
+	return e.Err
+}
+
+// UserMessage implements UserMessager
+func (e *ErrMalformedArchive) UserMessage() Message {
+	return Message{Key: MsgMalformedArchive, Params: map[string]string{"plugin": e.Plugin, "format": e.Format}}
+}
+
+// errArchiveLimit is returned when an archive exceeds the extraction limits
+var errArchiveLimit = errors.New("archive exceeds extraction limits")
+
+// extractArchive runs the processors of an archive format. Panics in the
+// decompressors and archive readers are turned into errors, so that a
+// single bad archive cannot crash the host process.
+func extractArchive(ctx context.Context, fsys FS, r io.Reader, plugin, format, destDir string, processors []fileProcessor) (reader io.Reader, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			reader, err = nil, &ErrMalformedArchive{Plugin: plugin, Format: format, Err: fmt.Errorf("extractor panicked: %v", p)}
+		}
+	}()
+
+	reader, err = processFile(ctx, fsys, r, destDir, processors...)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, err
+		}
+
+		return nil, &ErrMalformedArchive{Plugin: plugin, Format: format, Err: err}
+	}
+
+	return reader, nil
+}
+
+// safeJoin returns the path of an archive entry under destDir, refusing
+// absolute names and names escaping destDir
+func safeJoin(destDir, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(filepath.ToSlash(name), "/") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("invalid archive path: %s", name)
+	}
+
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive path: %s", name)
+	}
+
+	return target, nil
+}
+
+// safeFileJoin is safeJoin for an entry extracted as a file, which cannot
+// replace destDir itself
+func safeFileJoin(destDir, name string) (string, error) {
+	target, err := safeJoin(destDir, name)
+	if err != nil {
+		return "", err
+	}
+
+	if target == filepath.Clean(destDir) {
+		return "", fmt.Errorf("invalid archive path: %q", name)
+	}
+
+	return target, nil
+}
+
+// extractionBudget tracks what an archive has written against the
+// extraction limits
+type extractionBudget struct {
+	size    int64
+	entries int
+}
+
+// newExtractionBudget returns the budget of the limits carried by ctx
+func newExtractionBudget(ctx context.Context) *extractionBudget {
+	limits, _ := ctx.Value(extractionLimitsKey{}).(ExtractionLimits)
+
+	budget := &extractionBudget{size: limits.MaxSize, entries: limits.MaxEntries}
+
+	if budget.size <= 0 {
+		budget.size = DefaultMaxExtractedSize
+	}
+
+	if budget.entries <= 0 {
+		budget.entries = DefaultMaxArchiveEntries
+	}
+
+	return budget
+}
+
+// entry accounts for a file or directory about to be created
+func (b *extractionBudget) entry() error {
+	if b.entries == 0 {
+		return errArchiveLimit
+	}
+
+	b.entries--
+
+	return nil
+}
+
+// copy writes src to dst within the remaining size
+func (b *extractionBudget) copy(dst io.Writer, src io.Reader) error {
+	n, err := io.CopyN(dst, src, b.size+1)
+	b.size -= n
+
+	switch {
+	case b.size < 0:
+		return errArchiveLimit
+	case err == io.EOF:
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,432 @@
+package extension
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-logr/logr"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/ulikunitz/xz"
+)
+
+const fuzzDestDir = "/plugins/fuzz"
+
+// fuzzContext keeps fuzzed archives small enough to explore quickly
+func fuzzContext() context.Context {
+	return context.WithValue(context.Background(), extractionLimitsKey{}, ExtractionLimits{MaxSize: 1 << 20, MaxEntries: 100})
+}
+
+type archiveEntry struct {
+	name string
+	body string
+	dir  bool
+}
+
+func tarArchive(t testing.TB, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0755, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.dir {
+			header.Typeflag, header.Size = tar.TypeDir, 0
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("failed to write tar entry: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipArchive(t testing.TB, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, e := range entries {
+		name := e.name
+		if e.dir {
+			name += "/"
+		}
+
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatalf("failed to write zip entry: %v", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func gzipData(t testing.TB, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatalf("failed to gzip: %v", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// compressData compresses data in the format of a content type of
+// fileProcessorMap. bzip2 has no encoder in the standard library; its seeds
+// are in testdata/fuzz.
+func compressData(t testing.TB, contentType string, data []byte) []byte {
+	t.Helper()
+
+	if contentType == "application/gzip" {
+		return gzipData(t, data)
+	}
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+
+	switch contentType {
+	case "application/x-xz":
+		w, err = xz.NewWriter(&buf)
+	case "application/x-lz4":
+		w = lz4.NewWriter(&buf)
+	case "application/x-brotli":
+		w = brotli.NewWriter(&buf)
+	case "application/zstd":
+		w, err = zstd.NewWriter(&buf)
+	default:
+		t.Fatalf("no encoder for %s", contentType)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to create %s writer: %v", contentType, err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("failed to compress %s: %v", contentType, err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close %s writer: %v", contentType, err)
+	}
+
+	return buf.Bytes()
+}
+
+// seedArchives are well-formed and hostile archives shared by the fuzz
+// targets, including complete and truncated compressed tarballs of every
+// format with an encoder
+func seedArchives(t testing.TB) [][]byte {
+	tarball := tarArchive(t,
+		archiveEntry{name: "bin", dir: true},
+		archiveEntry{name: "bin/plugin", body: "#!/bin/sh\necho hello\n"},
+	)
+
+	var compressed [][]byte
+
+	for _, contentType := range []string{"application/gzip", "application/x-xz", "application/x-lz4", "application/x-brotli", "application/zstd"} {
+		data := compressData(t, contentType, tarball)
+		compressed = append(compressed, data, data[:len(data)/2])
+	}
+
+	return append(compressed, [][]byte{
+		tarball,
+		tarArchive(t, archiveEntry{name: "../escape", body: "x"}),
+		tarArchive(t, archiveEntry{name: "/etc/escape", body: "x"}),
+		zipArchive(t,
+			archiveEntry{name: "bin", dir: true},
+			archiveEntry{name: "bin/plugin", body: "#!/bin/sh\necho hello\n"},
+		),
+		zipArchive(t, archiveEntry{name: "../../escape", body: "x"}),
+		tarball[:len(tarball)/2],
+		{0x1f, 0x8b, 0x08},
+		[]byte("PK\x03\x04"),
+		[]byte("#!/bin/sh\necho hello\n"),
+		nil,
+	}...)
+}
+
+// checkContained fails when extraction wrote a file outside destDir
+func checkContained(t *testing.T, fsys FS, destDir string) {
+	t.Helper()
+
+	err := walkDir(fsys, "/", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && !strings.HasPrefix(path, destDir+string(filepath.Separator)) {
+			t.Errorf("extraction wrote %s outside %s", path, destDir)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk file system: %v", err)
+	}
+}
+
+// checkExtractionError fails on errors other than the ones hostile archives
+// are expected to produce
+func checkExtractionError(t *testing.T, err error) {
+	t.Helper()
+
+	var malformed *ErrMalformedArchive
+	if err != nil && !errors.As(err, &malformed) {
+		t.Errorf("extraction failed with %T, want *ErrMalformedArchive: %v", err, err)
+	}
+}
+
+func FuzzDetectContentType(f *testing.F) {
+	for _, seed := range seedArchives(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, content := range []interface{}{data, io.MultiReader(bytes.NewReader(data))} {
+			fsys := NewMemFS()
+			info := &Info{Name: "plugin", FileName: "plugin", Content: content}
+
+			err := writePluginFiles(fuzzContext(), fsys, logr.Discard(), fuzzDestDir, info)
+			checkExtractionError(t, err)
+			checkContained(t, fsys, fuzzDestDir)
+		}
+	})
+}
+
+func fuzzExtractor(f *testing.F, format string, processors ...fileProcessor) {
+	for _, seed := range seedArchives(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fsys := NewMemFS()
+
+		_, err := extractArchive(fuzzContext(), fsys, bytes.NewReader(data), "plugin", format, fuzzDestDir, processors)
+		checkExtractionError(t, err)
+		checkContained(t, fsys, fuzzDestDir)
+	})
+}
+
+func FuzzExtractTar(f *testing.F) {
+	fuzzExtractor(f, "application/x-tar", extractTar)
+}
+
+func FuzzExtractZip(f *testing.F) {
+	fuzzExtractor(f, "application/zip", extractZip)
+}
+
+func FuzzExtractGzip(f *testing.F) {
+	fuzzExtractor(f, "application/gzip", extractGz)
+}
+
+// fuzzedContentTypes are the entries of fileProcessorMap with a fuzz target
+var fuzzedContentTypes = map[string]string{
+	"application/gzip":     "FuzzExtractTarGzip",
+	"application/x-gzip":   "FuzzExtractTarGzip", // Same processors as application/gzip
+	"application/zip":      "FuzzExtractZip",
+	"application/x-xz":     "FuzzExtractTarXz",
+	"application/x-bzip2":  "FuzzExtractTarBzip2",
+	"application/x-lz4":    "FuzzExtractTarLz4",
+	"application/x-brotli": "FuzzExtractTarBrotli",
+	"application/zstd":     "FuzzExtractTarZstd",
+}
+
+func FuzzExtractTarGzip(f *testing.F) {
+	fuzzExtractor(f, "application/gzip", fileProcessorMap["application/gzip"]...)
+}
+
+func FuzzExtractTarXz(f *testing.F) {
+	fuzzExtractor(f, "application/x-xz", fileProcessorMap["application/x-xz"]...)
+}
+
+func FuzzExtractTarBzip2(f *testing.F) {
+	fuzzExtractor(f, "application/x-bzip2", fileProcessorMap["application/x-bzip2"]...)
+}
+
+func FuzzExtractTarLz4(f *testing.F) {
+	fuzzExtractor(f, "application/x-lz4", fileProcessorMap["application/x-lz4"]...)
+}
+
+func FuzzExtractTarBrotli(f *testing.F) {
+	fuzzExtractor(f, "application/x-brotli", fileProcessorMap["application/x-brotli"]...)
+}
+
+func FuzzExtractTarZstd(f *testing.F) {
+	fuzzExtractor(f, "application/zstd", fileProcessorMap["application/zstd"]...)
+}
+
+func TestExtractCompressedTarballs(t *testing.T) {
+	tarball := tarArchive(t, archiveEntry{name: "bin/plugin", body: "#!/bin/sh\necho hello\n"})
+
+	for _, contentType := range []string{"application/gzip", "application/x-xz", "application/x-lz4", "application/x-brotli", "application/zstd"} {
+		t.Run(contentType, func(t *testing.T) {
+			fsys := NewMemFS()
+			data := compressData(t, contentType, tarball)
+
+			if _, err := extractArchive(context.Background(), fsys, bytes.NewReader(data), "plugin", contentType, fuzzDestDir, fileProcessorMap[contentType]); err != nil {
+				t.Fatalf("extractArchive() error = %v", err)
+			}
+
+			if got, err := fsys.ReadFile(filepath.Join(fuzzDestDir, "bin", "plugin")); err != nil || string(got) != "#!/bin/sh\necho hello\n" {
+				t.Errorf("extracted plugin = %q, %v", got, err)
+			}
+
+			_, err := extractArchive(context.Background(), NewMemFS(), bytes.NewReader(data[:len(data)/2]), "plugin", contentType, fuzzDestDir, fileProcessorMap[contentType])
+
+			var malformed *ErrMalformedArchive
+			if !errors.As(err, &malformed) {
+				t.Errorf("truncated archive error = %v, want *ErrMalformedArchive", err)
+			}
+		})
+	}
+}
+
+func TestFuzzTargetsCoverFileProcessors(t *testing.T) {
+	for contentType := range fileProcessorMap {
+		if _, ok := fuzzedContentTypes[contentType]; !ok {
+			t.Errorf("%s has no fuzz target, add one to fuzzedContentTypes", contentType)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // Empty when the name is refused
+	}{
+		{name: "plugin", want: "/dest/plugin"},
+		{name: "bin/plugin", want: "/dest/bin/plugin"},
+		{name: "./bin/../plugin", want: "/dest/plugin"},
+		{name: "./", want: "/dest"},
+		{name: "..", want: ""},
+		{name: "../escape", want: ""},
+		{name: "bin/../../escape", want: ""},
+		{name: "/etc/passwd", want: ""},
+		{name: "...", want: "/dest/..."},
+		{name: "..plugin", want: "/dest/..plugin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeJoin("/dest", tt.name)
+
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("safeJoin(%q) = %q, want error", tt.name, got)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("safeJoin(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeFileJoin(t *testing.T) {
+	for _, name := range []string{"", ".", "./", "bin/.."} {
+		if got, err := safeFileJoin("/dest", name); err == nil {
+			t.Errorf("safeFileJoin(%q) = %q, want error", name, got)
+		}
+	}
+
+	if got, err := safeFileJoin("/dest", "bin/plugin"); err != nil || got != "/dest/bin/plugin" {
+		t.Errorf("safeFileJoin(bin/plugin) = %q, %v, want /dest/bin/plugin", got, err)
+	}
+}
+
+func TestExtractionBudget(t *testing.T) {
+	ctx := context.WithValue(context.Background(), extractionLimitsKey{}, ExtractionLimits{MaxSize: 10, MaxEntries: 2})
+
+	t.Run("entries", func(t *testing.T) {
+		budget := newExtractionBudget(ctx)
+
+		for i := 0; i < 2; i++ {
+			if err := budget.entry(); err != nil {
+				t.Fatalf("entry %d: %v", i, err)
+			}
+		}
+
+		if err := budget.entry(); !errors.Is(err, errArchiveLimit) {
+			t.Errorf("third entry error = %v, want errArchiveLimit", err)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		budget := newExtractionBudget(ctx)
+
+		var buf bytes.Buffer
+		if err := budget.copy(&buf, strings.NewReader("0123456789")); err != nil {
+			t.Fatalf("copy within the limit: %v", err)
+		}
+
+		if err := budget.copy(&buf, strings.NewReader("x")); !errors.Is(err, errArchiveLimit) {
+			t.Errorf("copy over the limit error = %v, want errArchiveLimit", err)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		budget := newExtractionBudget(context.Background())
+
+		if budget.size != DefaultMaxExtractedSize || budget.entries != DefaultMaxArchiveEntries {
+			t.Errorf("budget = %+v, want the default limits", budget)
+		}
+	})
+
+	t.Run("archive over the limit", func(t *testing.T) {
+		data := tarArchive(t,
+			archiveEntry{name: "a", body: "0123456789"},
+			archiveEntry{name: "b", body: "0123456789"},
+		)
+
+		_, err := extractArchive(ctx, NewMemFS(), bytes.NewReader(data), "plugin", "application/x-tar", fuzzDestDir, []fileProcessor{extractTar})
+
+		var malformed *ErrMalformedArchive
+		if !errors.As(err, &malformed) || !errors.Is(err, errArchiveLimit) {
+			t.Errorf("extractArchive() error = %v, want *ErrMalformedArchive wrapping errArchiveLimit", err)
+		}
+	})
+}
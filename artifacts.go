@@ -0,0 +1,100 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// ArtifactRemover is implemented by executors that create backend artifacts
+// for a plugin, such as images, containers, volumes or VM disks
+type ArtifactRemover interface {
+	// RemoveArtifacts reclaims everything the executor created for the
+	// plugin. Volumes, disks and other data the plugin wrote are kept when
+	// keepData is set. Artifacts that no longer exist are ignored.
+	RemoveArtifacts(ctx context.Context, pluginName string, info Info, keepData bool) error
+}
+
+type keepDataKey struct{}
+
+// ContextWithKeepData returns a context instructing Runtime.Uninstall to
+// keep the data a plugin wrote
+func ContextWithKeepData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keepDataKey{}, true)
+}
+
+// KeepDataFromContext reports whether the data of an uninstalled plugin is
+// to be kept
+func KeepDataFromContext(ctx context.Context) bool {
+	keep, _ := ctx.Value(keepDataKey{}).(bool)
+	return keep
+}
+
+// ImageNotFound reports whether the output of a failed image removal means
+// that the image did not exist
+func ImageNotFound(output []byte) bool {
+	output = bytes.ToLower(output)
+	return bytes.Contains(output, []byte("no such image")) || bytes.Contains(output, []byte("image not known")) ||
+		bytes.Contains(output, []byte("not found"))
+}
+
+// removeArtifacts reclaims what the plugin's executor and runtime created
+// for it. Backends that are not configured are skipped, as are plugins with
+// unreadable metadata, so that broken plugins can still be uninstalled.
+func (m *Manager) removeArtifacts(ctx context.Context, name, pluginDir string, opts UninstallOptions) error {
+	info, err := readMetadata(m.fs, filepath.Join(pluginDir, "metadata.json"))
+	if err != nil {
+		m.logger.Error(err, "cannot read metadata, leaving runtime artifacts behind", "plugin", name)
+		return nil
+	}
+
+	m.mu.RLock()
+	executor := m.executors[info.Runtime]
+	m.mu.RUnlock()
+
+	switch e := executor.(type) {
+	case ArtifactRemover:
+		if err := e.RemoveArtifacts(ctx, name, *info, opts.KeepData); err != nil {
+			return fmt.Errorf("failed to remove artifacts of plugin %s: %w", name, err)
+		}
+	case VolumePurger:
+		if !opts.KeepData && len(info.Volumes) > 0 {
+			if err := e.RemoveVolumes(ctx, name, info.Volumes); err != nil {
+				return fmt.Errorf("failed to purge volumes of plugin %s: %w", name, err)
+			}
+		}
+	default:
+		if info.Image != "" || (!opts.KeepData && len(info.Volumes) > 0) {
+			m.logger.Info("no executor removes the plugin's artifacts, leaving them behind", "plugin", name, "runtime", info.Runtime)
+		}
+	}
+
+	if m.registry == nil {
+		return nil
+	}
+
+	runtime, ok := m.registry.GetRuntime(info.Runtime)
+	if !ok {
+		return nil
+	}
+
+	if opts.KeepData {
+		ctx = ContextWithKeepData(ctx)
+	}
+
+	plugin := &Plugin{
+		Info:     *info,
+		Path:     pluginDir,
+		FileName: info.FileName,
+		Runtime:  runtime,
+	}
+
+	if err := runtime.Uninstall(ctx, plugin); err != nil {
+		return fmt.Errorf("runtime %s failed to uninstall plugin %s: %w", info.Runtime, name, err)
+	}
+
+	m.logger.V(1).Info("removed plugin artifacts", "plugin", name, "runtime", info.Runtime, "keepData", opts.KeepData)
+
+	return nil
+}
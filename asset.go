@@ -1,14 +1,72 @@
 package extension
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// AssetPreference decides between release assets that match the platform
+// equally well
+type AssetPreference string
+
+const (
+	// PreferSmallest picks the smallest asset, by the size the store reports
+	PreferSmallest AssetPreference = "smallest"
+
+	// PreferDocumented picks archives over bare binaries, since only they
+	// can ship shell completions and man pages next to the plugin
+	PreferDocumented AssetPreference = "documented"
+
+	// PreferStatic picks statically linked builds, published with a -static
+	// suffix such as name-v1.0.0-linux-amd64-static.tar.gz
+	PreferStatic AssetPreference = "static"
 )
 
+// AssetCandidate is a release asset offered to SelectAsset
+type AssetCandidate struct {
+	Name string
+	Size int64 // Zero when unknown
+}
+
+type assetPreferenceKey struct{}
+
+// ContextWithAssetPreference returns a context instructing stores to apply
+// the given preferences, in order, when several assets match
+func ContextWithAssetPreference(ctx context.Context, preferences ...AssetPreference) context.Context {
+	return context.WithValue(ctx, assetPreferenceKey{}, preferences)
+}
+
+// AssetPreferenceFromContext returns the asset preferences carried by ctx
+func AssetPreferenceFromContext(ctx context.Context) []AssetPreference {
+	preferences, _ := ctx.Value(assetPreferenceKey{}).([]AssetPreference)
+	return preferences
+}
+
+// WithAssetPreference sets how stores choose between assets that match the
+// host platform equally well. Preferences apply in order, each breaking the
+// ties of the previous ones. Without any, the first match in naming
+// convention order is used.
+func (m *Manager) WithAssetPreference(preferences ...AssetPreference) *Manager {
+	m.assetPreferences = preferences
+	return m
+}
+
+// fetchContext carries the manager's asset preferences to the store
+func (m *Manager) fetchContext(ctx context.Context) context.Context {
+	if len(m.assetPreferences) == 0 {
+		return ctx
+	}
+
+	return ContextWithAssetPreference(ctx, m.assetPreferences...)
+}
+
 // FindAsset finds and filters assets based on naming conventions and returns the best match
 func FindAsset(
+	logger logr.Logger,
 	prefix string,
 	name string,
 	version string,
@@ -16,113 +74,311 @@ func FindAsset(
 	arch string,
 	getAssets func() []string,
 ) (assetName string, runtime string, err error) {
-	log.Printf("[FindAsset] prefix: %s, name: %s, version: %s, goos: %s, arch: %s", prefix, name, version, goos, arch)
+	names := getAssets()
 
-	// Filter valid assets
-	var validAssets []string
+	candidates := make([]AssetCandidate, len(names))
+	for i, asset := range names {
+		candidates[i] = AssetCandidate{Name: asset}
+	}
 
-	for _, asset := range getAssets() {
-		log.Printf("[FindAsset] asset: %s", asset)
+	return SelectAsset(logger, prefix, name, version, goos, arch, nil, candidates)
+}
 
-		// Skip if wrong prefix or has unwanted suffix
-		if !strings.HasPrefix(asset, name) ||
-			strings.HasSuffix(asset, ".sha256") ||
-			strings.HasSuffix(asset, ".asc") ||
-			strings.HasSuffix(asset, ".sig") {
+// SelectAsset is FindAsset applying preferences among the assets that match
+// the most specific naming convention
+func SelectAsset(
+	logger logr.Logger,
+	prefix string,
+	name string,
+	version string,
+	goos string,
+	arch string,
+	preferences []AssetPreference,
+	assets []AssetCandidate,
+) (assetName string, runtime string, err error) {
+	logger = logger.WithValues("prefix", prefix, "name", name, "version", version, "goos", goos, "arch", arch)
+
+	// Group the plugin's assets by how specific their name is: built for
+	// the platform, for the version only, or named after the plugin only
+	var groups [3][]assetMatch
+
+	for _, asset := range assets {
+		parsed, ok := ParseAssetName(asset.Name, version, assetBases(prefix, name)...)
+		if !ok {
 			continue
 		}
 
-		validAssets = append(validAssets, asset)
-	}
+		specificity := 2
+
+		switch {
+		case parsed.OS != "":
+			if !parsed.Matches(goos, arch) {
+				continue
+			}
 
-	log.Printf("[FindAsset] validAssets: %v", validAssets)
+			specificity = 0
+		case parsed.Version != "":
+			specificity = 1
+		}
 
-	if len(validAssets) == 0 {
-		return "", "", fmt.Errorf("no valid assets found for %s-%s", prefix, name)
+		groups[specificity] = append(groups[specificity], assetMatch{AssetCandidate: asset, AssetName: parsed})
 	}
 
-	// Try to find assets in order of specificity
-	patterns := []string{
-		// Most specific (with platform)
-		fmt.Sprintf("%s-%s-%s-%s", name, version, goos, arch),
-		// Version only
-		fmt.Sprintf("%s-%s", name, version),
-		// Least specific
-		name,
+	for _, matches := range groups {
+		if len(matches) == 0 {
+			continue
+		}
+
+		// Static builds and less common extensions come last unless preferred
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].Static != matches[j].Static {
+				return !matches[i].Static
+			}
+
+			return extensionRank(matches[i].Ext) < extensionRank(matches[j].Ext)
+		})
+
+		best := preferAsset(matches, preferences)
+
+		logger.V(1).Info("matched asset", "asset", best.Name, "runtime", best.Runtime(), "candidates", len(matches))
+
+		return best.Name, best.Runtime(), nil
 	}
 
-	// Add extensions to each pattern
-	extensions := []string{"", ".exe", ".zip", ".tar.gz", ".tgz", ".wasm"}
-	for _, pattern := range patterns {
-		for _, ext := range extensions {
-			for _, asset := range validAssets {
-				log.Printf("pattern: %s, ext: %s, asset: %s", pattern, ext, asset)
+	logger.V(1).Info("no asset matched", "assets", len(assets))
 
-				if matched, _ := filepath.Match(pattern+ext, asset); matched {
-					runtime := "exec"
-					if strings.Contains(ext, "wasm") {
-						runtime = "wasm"
-					}
+	return "", "", fmt.Errorf("no matching assets found for %s", name)
+}
 
-					return asset, runtime, nil
+// assetMatch is an asset matching the naming convention
+type assetMatch struct {
+	AssetCandidate
+	AssetName
+}
+
+// preferAsset returns the match the preferences rank first. Matches they do
+// not tell apart keep their naming convention order.
+func preferAsset(matches []assetMatch, preferences []AssetPreference) assetMatch {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+
+		for _, preference := range preferences {
+			switch preference {
+			case PreferSmallest:
+				if a.Size != b.Size {
+					// Unknown sizes sort last
+					switch {
+					case a.Size == 0:
+						return false
+					case b.Size == 0:
+						return true
+					default:
+						return a.Size < b.Size
+					}
+				}
+			case PreferDocumented:
+				if a.Archive() != b.Archive() {
+					return a.Archive()
+				}
+			case PreferStatic:
+				if a.Static != b.Static {
+					return a.Static
 				}
 			}
 		}
-	}
 
-	return "", "", fmt.Errorf("no matching assets found")
+		return false
+	})
+
+	return matches[0]
 }
 
-// Filter returns a filtered list of matching plugin artifact names
+// Filter returns a filtered list of matching plugin artifact names, for any
+// platform. It follows the same naming convention as FindAsset.
 func Filter(prefix, name, version string, getAssetNames func() []string) []string {
-	// Define supported platforms and extensions
-	platforms := struct {
-		oses   []string
-		arches []string
-	}{
-		oses:   []string{"linux", "windows", "macos"},
-		arches: []string{"amd64", "386", "arm", "arm64"},
+	assetNames := getAssetNames()
+
+	result := make([]string, 0, len(assetNames))
+
+	for _, asset := range assetNames {
+		if _, ok := ParseAssetName(asset, version, assetBases(prefix, name)...); ok {
+			result = append(result, asset)
+		}
+	}
+
+	return result
+}
+
+// AssetRuntime returns the runtime of a plugin asset
+func AssetRuntime(asset string) string {
+	if strings.HasPrefix(assetExtension(asset), ".wasm") {
+		return "wasm"
+	}
+
+	return "exec"
+}
+
+// assetExtensions are the extensions of plugin assets in order of
+// preference. Compound extensions are matched before their suffixes.
+var assetExtensions = []string{"", ".exe", ".zip", ".tar.gz", ".tgz", ".wasm", ".wasm.zip", ".wasm.tar.gz", ".wasm.tgz"}
+
+// assetOS and assetArch map the spellings found in asset names to GOOS and
+// GOARCH values
+var (
+	assetOS = map[string]string{
+		"linux": "linux", "windows": "windows", "win": "windows",
+		"darwin": "darwin", "macos": "darwin", "osx": "darwin",
+		"freebsd": "freebsd", "openbsd": "openbsd", "netbsd": "netbsd",
+	}
+
+	assetArch = map[string]string{
+		"amd64": "amd64", "x86_64": "amd64", "x64": "amd64",
+		"386": "386", "i386": "386", "i686": "386", "x86": "386",
+		"arm64": "arm64", "aarch64": "arm64",
+		"arm": "arm", "armv6": "arm", "armv7": "arm",
+		"riscv64": "riscv64", "ppc64le": "ppc64le", "s390x": "s390x",
+	}
+)
+
+// AssetName is a release asset name split according to the naming
+// convention <name>[-<version>[-<os>-<arch>]][-static][<ext>]
+type AssetName struct {
+	Version string // Empty when the name carries no version
+	OS      string // GOOS value, empty when the asset is not platform specific
+	Arch    string // GOARCH value
+	Static  bool
+	Ext     string
+}
+
+// ParseAssetName parses asset as an artifact of the plugin published under
+// one of bases at version. The version may appear with or without its v
+// prefix and the platform under its common spellings, such as macos for
+// darwin or x86_64 for amd64. Assets of other plugins or versions, and
+// checksums and signatures, are not matched.
+func ParseAssetName(asset, version string, bases ...string) (AssetName, bool) {
+	ext := assetExtension(asset)
+	stem := strings.TrimSuffix(asset, ext)
+
+	var parsed AssetName
+
+	parsed.Ext = ext
+	stem, parsed.Static = strings.CutSuffix(stem, "-static")
+
+	for _, base := range bases {
+		if base == "" {
+			continue
+		}
+
+		if stem == base {
+			return parsed, true
+		}
+
+		rest, ok := strings.CutPrefix(stem, base+"-")
+		if !ok {
+			continue
+		}
+
+		if parsed.Version, rest, ok = cutVersion(rest, version); !ok {
+			continue
+		}
+
+		if rest == "" {
+			return parsed, true
+		}
+
+		goos, arch, ok := strings.Cut(rest, "-")
+		if !ok {
+			continue
+		}
+
+		if parsed.OS, ok = assetOS[strings.ToLower(goos)]; !ok {
+			continue
+		}
+
+		if parsed.Arch, ok = assetArch[strings.ToLower(arch)]; !ok {
+			continue
+		}
+
+		return parsed, true
 	}
 
-	extensions := map[string][]string{
-		"native":  {"", ".exe"},
-		"archive": {".zip", ".tar.gz", ".tgz"},
-		"wasm":    {".wasm", ".wasm.zip", ".wasm.tar.gz", ".wasm.tgz"},
+	return AssetName{}, false
+}
+
+// Matches reports whether the asset is built for goos and arch, or for any
+// platform
+func (n AssetName) Matches(goos, arch string) bool {
+	if n.OS == "" {
+		return true
 	}
 
-	// Generate base patterns
-	patterns := []string{
-		fmt.Sprintf("%s-%s", prefix, name),
-		fmt.Sprintf("%s-%s-v%s", prefix, name, version),
+	return n.OS == assetOS[strings.ToLower(goos)] && n.Arch == assetArch[strings.ToLower(arch)]
+}
+
+// Archive reports whether the asset is an archive rather than a bare file
+func (n AssetName) Archive() bool {
+	return strings.HasSuffix(n.Ext, ".zip") || strings.HasSuffix(n.Ext, ".gz") || strings.HasSuffix(n.Ext, ".tgz")
+}
+
+// Runtime returns the runtime of the asset
+func (n AssetName) Runtime() string {
+	if strings.HasPrefix(n.Ext, ".wasm") {
+		return "wasm"
 	}
 
-	// Add platform-specific patterns
-	for _, os := range platforms.oses {
-		for _, arch := range platforms.arches {
-			patterns = append(patterns, fmt.Sprintf("%s-%s-v%s-%s-%s", prefix, name, version, os, arch))
+	return "exec"
+}
+
+// assetBases returns the names a plugin's assets may start with
+func assetBases(prefix, name string) []string {
+	if prefix == "" || strings.HasPrefix(name, prefix) {
+		return []string{name}
+	}
+
+	return []string{name, prefix + "-" + name}
+}
+
+// assetExtension returns the longest known plugin asset extension of asset
+func assetExtension(asset string) string {
+	var ext string
+
+	for _, candidate := range assetExtensions {
+		if len(candidate) > len(ext) && strings.HasSuffix(asset, candidate) {
+			ext = candidate
 		}
 	}
 
-	// Create a set of valid asset names for efficient matching
-	validAssets := make(map[string]bool)
+	return ext
+}
 
-	for _, pattern := range patterns {
-		for _, extList := range extensions {
-			for _, ext := range extList {
-				validAssets[pattern+ext] = true
-			}
+// extensionRank orders extensions as listed in assetExtensions
+func extensionRank(ext string) int {
+	for i, candidate := range assetExtensions {
+		if candidate == ext {
+			return i
 		}
 	}
 
-	assetNames := getAssetNames()
+	return len(assetExtensions)
+}
 
-	result := make([]string, 0, len(assetNames))
+// cutVersion removes version, with or without its v prefix, from the start
+// of s
+func cutVersion(s, version string) (string, string, bool) {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return "", s, false
+	}
 
-	for _, asset := range assetNames {
-		if validAssets[asset] {
-			result = append(result, asset)
+	for _, candidate := range []string{"v" + version, version} {
+		if s == candidate {
+			return candidate, "", true
+		}
+
+		if rest, ok := strings.CutPrefix(s, candidate+"-"); ok {
+			return candidate, rest, true
 		}
 	}
 
-	return result
+	return "", s, false
 }
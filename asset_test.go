@@ -0,0 +1,193 @@
+package extension_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+func TestFindAsset(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		goos, arch  string
+		assets      []string
+		want        string
+		wantRuntime string
+	}{
+		{
+			name:    "v-prefixed asset, bare version",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-v1.0.0-linux-amd64"},
+			want:   "hello-v1.0.0-linux-amd64",
+		},
+		{
+			name:    "bare asset, v-prefixed version",
+			version: "v1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-1.0.0-linux-amd64.tar.gz"},
+			want:   "hello-1.0.0-linux-amd64.tar.gz",
+		},
+		{
+			name:    "macos and aarch64 aliases",
+			version: "1.0.0", goos: "darwin", arch: "arm64",
+			assets: []string{"hello-1.0.0-linux-arm64", "hello-1.0.0-macos-aarch64.zip"},
+			want:   "hello-1.0.0-macos-aarch64.zip",
+		},
+		{
+			name:    "x86_64 alias in any case",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-1.0.0-linux-arm64", "hello-1.0.0-Linux-x86_64.tar.gz"},
+			want:   "hello-1.0.0-Linux-x86_64.tar.gz",
+		},
+		{
+			name:    "darwin spelled out",
+			version: "1.0.0", goos: "darwin", arch: "amd64",
+			assets: []string{"hello-1.0.0-darwin-amd64"},
+			want:   "hello-1.0.0-darwin-amd64",
+		},
+		{
+			name:    "prefixed name",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"pk-hello-1.0.0-linux-amd64"},
+			want:   "pk-hello-1.0.0-linux-amd64",
+		},
+		{
+			name:    "platform build over version only",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello", "hello-1.0.0", "hello-1.0.0-linux-amd64"},
+			want:   "hello-1.0.0-linux-amd64",
+		},
+		{
+			name:    "binary over archive",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-1.0.0-linux-amd64.tar.gz", "hello-1.0.0-linux-amd64"},
+			want:   "hello-1.0.0-linux-amd64",
+		},
+		{
+			name:    "dynamic over static",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-1.0.0-linux-amd64-static", "hello-1.0.0-linux-amd64.zip"},
+			want:   "hello-1.0.0-linux-amd64.zip",
+		},
+		{
+			name:    "checksums and other versions ignored",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets: []string{"hello-1.0.0-linux-amd64.sha256", "hello-1.1.0-linux-amd64", "hello-1.0.0-linux-amd64.exe"},
+			want:   "hello-1.0.0-linux-amd64.exe",
+		},
+		{
+			name:    "wasm",
+			version: "1.0.0", goos: "linux", arch: "amd64",
+			assets:      []string{"hello-1.0.0.wasm"},
+			want:        "hello-1.0.0.wasm",
+			wantRuntime: "wasm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, runtime, err := extension.FindAsset(logr.Discard(), "pk", "hello", tt.version, tt.goos, tt.arch, func() []string { return tt.assets })
+			if err != nil {
+				t.Fatalf("FindAsset() error = %v", err)
+			}
+
+			wantRuntime := tt.wantRuntime
+			if wantRuntime == "" {
+				wantRuntime = "exec"
+			}
+
+			if got != tt.want || runtime != wantRuntime {
+				t.Errorf("FindAsset() = %s, %s, want %s, %s", got, runtime, tt.want, wantRuntime)
+			}
+		})
+	}
+}
+
+func TestFindAssetNoMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []string
+	}{
+		{name: "no assets"},
+		{name: "other platforms", assets: []string{"hello-1.0.0-linux-arm64", "hello-1.0.0-windows-amd64.exe"}},
+		{name: "other plugin", assets: []string{"world-1.0.0-linux-amd64", "hello-world-1.0.0-linux-amd64"}},
+		{name: "other version", assets: []string{"hello-1.0.1-linux-amd64", "hello-v1.0.0-rc.1-linux-amd64"}},
+		{name: "unknown platform", assets: []string{"hello-1.0.0-plan9-amd64", "hello-1.0.0-linux-mips"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := extension.FindAsset(logr.Discard(), "pk", "hello", "1.0.0", "linux", "amd64", func() []string { return tt.assets })
+			if err == nil {
+				t.Errorf("FindAsset() = %s, want an error", got)
+			}
+		})
+	}
+}
+
+func TestSelectAssetPreferences(t *testing.T) {
+	assets := []extension.AssetCandidate{
+		{Name: "hello-1.0.0-linux-amd64", Size: 500},
+		{Name: "hello-1.0.0-linux-amd64.tar.gz", Size: 200},
+		{Name: "hello-1.0.0-linux-amd64.zip"},
+		{Name: "hello-1.0.0-linux-amd64-static.tar.gz", Size: 100},
+	}
+
+	tests := []struct {
+		name        string
+		preferences []extension.AssetPreference
+		want        string
+	}{
+		{name: "naming convention order", want: "hello-1.0.0-linux-amd64"},
+		{name: "documented", preferences: []extension.AssetPreference{extension.PreferDocumented}, want: "hello-1.0.0-linux-amd64.zip"},
+		{name: "smallest", preferences: []extension.AssetPreference{extension.PreferSmallest}, want: "hello-1.0.0-linux-amd64-static.tar.gz"},
+		{name: "static", preferences: []extension.AssetPreference{extension.PreferStatic}, want: "hello-1.0.0-linux-amd64-static.tar.gz"},
+		{
+			name:        "documented then smallest",
+			preferences: []extension.AssetPreference{extension.PreferDocumented, extension.PreferSmallest},
+			want:        "hello-1.0.0-linux-amd64-static.tar.gz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := extension.SelectAsset(logr.Discard(), "", "hello", "v1.0.0", "linux", "amd64", tt.preferences, slices.Clone(assets))
+			if err != nil || got != tt.want {
+				t.Errorf("SelectAsset() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	assets := []string{
+		"hello-v1.0.0-linux-amd64.tar.gz",
+		"hello-v1.0.0-macos-x86_64.zip",
+		"hello-1.0.0-windows-amd64.exe",
+		"pk-hello-1.0.0-darwin-arm64",
+		"hello-v1.0.0-linux-amd64.tar.gz.sha256",
+		"hello-v1.1.0-linux-amd64.tar.gz",
+		"world-v1.0.0-linux-amd64.tar.gz",
+		"checksums.txt",
+	}
+
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{"1.0.0", assets[:4]},
+		{"v1.0.0", assets[:4]},
+		{"1.1.0", assets[5:6]},
+		{"2.0.0", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := extension.Filter("pk", "hello", tt.version, func() []string { return assets })
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Filter(%s) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package extension
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// Attestation kinds
+const (
+	AttestationSBOM       = "sbom"
+	AttestationProvenance = "provenance"
+)
+
+// attestationsDir holds the attestations of an installed plugin
+const attestationsDir = ".attestations"
+
+// slsaPredicatePrefix identifies SLSA provenance predicates in in-toto statements
+const slsaPredicatePrefix = "https://slsa.dev/provenance/"
+
+// Attestation is an SBOM or provenance document published with a plugin
+type Attestation struct {
+	Name    string `json:"name"`   // File name as published by the store
+	Kind    string `json:"kind"`   // AttestationSBOM or AttestationProvenance
+	Format  string `json:"format"` // spdx, cyclonedx or slsa
+	Content []byte `json:"-"`
+}
+
+// ClassifyAttestation reports whether a release file name is an SBOM or a
+// provenance attestation, and in which format
+func ClassifyAttestation(name string) (kind, format string, ok bool) {
+	lower := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lower, ".spdx"), strings.HasSuffix(lower, ".spdx.json"):
+		return AttestationSBOM, "spdx", true
+	case strings.HasSuffix(lower, ".cdx.json"), strings.HasSuffix(lower, ".cdx.xml"),
+		strings.HasSuffix(lower, ".cyclonedx.json"), strings.HasSuffix(lower, ".cyclonedx.xml"),
+		lower == "bom.json", lower == "bom.xml":
+		return AttestationSBOM, "cyclonedx", true
+	case strings.HasSuffix(lower, ".intoto.jsonl"), strings.HasSuffix(lower, ".intoto.json"),
+		strings.HasSuffix(lower, ".sigstore"), strings.HasSuffix(lower, ".sigstore.json"),
+		strings.Contains(lower, "provenance") && strings.HasSuffix(lower, ".json"):
+		return AttestationProvenance, "slsa", true
+	default:
+		return "", "", false
+	}
+}
+
+// ProvenancePolicy requires plugins to ship SLSA provenance for their
+// artifact before they are installed. Any provenance whose subject matches
+// the artifact digest counts as level 1; higher levels are granted by the
+// builder that produced it. Signatures on the attestations are not verified.
+type ProvenancePolicy struct {
+	MinLevel int            // Minimum SLSA build level required
+	Builders map[string]int // Builder ID prefixes to the SLSA level they attain
+}
+
+// ProvenanceError is returned when a plugin does not meet the provenance policy
+type ProvenanceError struct {
+	Plugin   string
+	Level    int
+	MinLevel int
+}
+
+func (e *ProvenanceError) Error() string {
+	if e.Level == 0 {
+		return fmt.Sprintf("plugin %s has no provenance for its artifact, SLSA level %d is required", e.Plugin, e.MinLevel)
+	}
+
+	return fmt.Sprintf("plugin %s has SLSA level %d provenance, level %d is required", e.Plugin, e.Level, e.MinLevel)
+}
+
+// UserMessage implements UserMessager
+func (e *ProvenanceError) UserMessage() Message {
+	return Message{Key: MsgProvenance, Params: map[string]string{
+		"plugin": e.Plugin, "level": strconv.Itoa(e.Level), "minLevel": strconv.Itoa(e.MinLevel),
+	}}
+}
+
+// WithProvenancePolicy refuses to install or upgrade plugins whose
+// provenance does not satisfy the policy
+func (m *Manager) WithProvenancePolicy(policy ProvenancePolicy) *Manager {
+	m.provenance = &policy
+	return m
+}
+
+// SBOM returns the software bills of materials stored with an installed plugin
+func (m *Manager) SBOM(ctx context.Context, name string) ([]Attestation, error) {
+	return m.attestations(ctx, name, AttestationSBOM)
+}
+
+// Provenance returns the provenance attestations stored with an installed plugin
+func (m *Manager) Provenance(ctx context.Context, name string) ([]Attestation, error) {
+	return m.attestations(ctx, name, AttestationProvenance)
+}
+
+func (m *Manager) attestations(ctx context.Context, name, kind string) ([]Attestation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before reading attestations: %w", err)
+	}
+
+	defer m.plugins.rlock(name)()
+
+	pluginDir := filepath.Join(m.pluginDir, name)
+
+	info, err := readMetadata(m.fs, filepath.Join(pluginDir, "metadata.json"))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s is not installed: %w", name, err)
+	}
+
+	var result []Attestation
+
+	for _, a := range info.Attestations {
+		if a.Kind != kind {
+			continue
+		}
+
+		a.Content, err = m.fs.ReadFile(filepath.Join(pluginDir, attestationsDir, filepath.Base(a.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attestation %s: %w", a.Name, err)
+		}
+
+		result = append(result, a)
+	}
+
+	return result, nil
+}
+
+// checkProvenance enforces the provenance policy and returns the SLSA level
+// the plugin attained
+func (m *Manager) checkProvenance(operation string, info *Info) (int, error) {
+	if m.provenance == nil {
+		return 0, nil
+	}
+
+	level := m.provenance.level(info)
+	if level < m.provenance.MinLevel {
+		m.metrics.Failed(operation, metrics.ReasonProvenance)
+		return level, &ProvenanceError{Plugin: info.Name, Level: level, MinLevel: m.provenance.MinLevel}
+	}
+
+	return level, nil
+}
+
+// writeAttestations stores the attestations of a plugin in its directory
+func writeAttestations(fsys FS, dir string, info *Info) error {
+	if len(info.Attestations) == 0 {
+		return nil
+	}
+
+	attDir := filepath.Join(dir, attestationsDir)
+	if err := fsys.MkdirAll(attDir, 0755); err != nil {
+		return fmt.Errorf("failed to create attestations directory: %w", err)
+	}
+
+	for _, a := range info.Attestations {
+		if err := writeFileSync(fsys, filepath.Join(attDir, filepath.Base(a.Name)), a.Content); err != nil {
+			return fmt.Errorf("failed to write attestation %s: %w", a.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// level returns the highest SLSA level attained by the plugin's provenance
+// for its artifact, or 0 when there is none
+func (p *ProvenancePolicy) level(info *Info) int {
+	best := 0
+
+	for _, st := range coveringProvenance(info) {
+		level := 1
+
+		builder := st.builderID()
+		for prefix, l := range p.Builders {
+			if builder != "" && strings.HasPrefix(builder, prefix) && l > level {
+				level = l
+			}
+		}
+
+		if level > best {
+			best = level
+		}
+	}
+
+	return best
+}
+
+// coveringProvenance returns the SLSA provenance statements that cover the
+// plugin's artifact
+func coveringProvenance(info *Info) []inTotoStatement {
+	digest := strings.TrimPrefix(contentDigest(info.Content), "sha256:")
+	if digest == "" {
+		return nil
+	}
+
+	var statements []inTotoStatement
+
+	for _, a := range info.Attestations {
+		if a.Kind != AttestationProvenance {
+			continue
+		}
+
+		for _, st := range parseStatements(a.Content) {
+			if strings.HasPrefix(st.PredicateType, slsaPredicatePrefix) && st.covers(digest) {
+				statements = append(statements, st)
+			}
+		}
+	}
+
+	return statements
+}
+
+// signatureIdentities returns the certificate identities that signed
+// provenance covering the plugin's artifact, and the builders it names.
+// Like provenance levels, they are taken from the attestations as
+// published; their signatures are not verified.
+func signatureIdentities(info *Info) []string {
+	var identities []string
+
+	for _, st := range coveringProvenance(info) {
+		for _, id := range slices.Concat(st.signers, []string{st.builderID()}) {
+			if id != "" && !slices.Contains(identities, id) {
+				identities = append(identities, id)
+			}
+		}
+	}
+
+	return identities
+}
+
+// inTotoStatement is the subset of an in-toto statement needed to match
+// provenance to an artifact
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"` // SLSA v0.2
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"` // SLSA v1
+	} `json:"predicate"`
+
+	signers []string // Identities of the certificate of a Sigstore bundle
+}
+
+func (s *inTotoStatement) covers(digest string) bool {
+	for _, subject := range s.Subject {
+		if strings.EqualFold(subject.Digest["sha256"], digest) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *inTotoStatement) builderID() string {
+	if id := s.Predicate.RunDetails.Builder.ID; id != "" {
+		return id
+	}
+
+	return s.Predicate.Builder.ID
+}
+
+// parseStatements extracts in-toto statements from plain statements, DSSE
+// envelopes or Sigstore bundles, one document per line or a single document
+func parseStatements(content []byte) []inTotoStatement {
+	var statements []inTotoStatement
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if st, ok := parseStatement(line); ok {
+			statements = append(statements, st)
+		}
+	}
+
+	// Pretty printed documents span several lines
+	if len(statements) == 0 {
+		if st, ok := parseStatement(content); ok {
+			statements = append(statements, st)
+		}
+	}
+
+	return statements
+}
+
+func parseStatement(data []byte) (inTotoStatement, bool) {
+	var doc struct {
+		inTotoStatement
+		Payload      string `json:"payload"`
+		DSSEEnvelope *struct {
+			Payload string `json:"payload"`
+		} `json:"dsseEnvelope"`
+		VerificationMaterial *verificationMaterial `json:"verificationMaterial"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return inTotoStatement{}, false
+	}
+
+	payload := doc.Payload
+	if doc.DSSEEnvelope != nil {
+		payload = doc.DSSEEnvelope.Payload
+	}
+
+	if payload == "" {
+		return doc.inTotoStatement, doc.PredicateType != ""
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return inTotoStatement{}, false
+	}
+
+	var st inTotoStatement
+	if err := json.Unmarshal(decoded, &st); err != nil {
+		return inTotoStatement{}, false
+	}
+
+	st.signers = doc.VerificationMaterial.identities()
+
+	return st, st.PredicateType != ""
+}
+
+// verificationMaterial holds the signing certificate of a Sigstore bundle,
+// as a chain in bundles up to v0.2 and as a single certificate since v0.3
+type verificationMaterial struct {
+	Certificate *struct {
+		RawBytes []byte `json:"rawBytes"`
+	} `json:"certificate"`
+	X509CertificateChain *struct {
+		Certificates []struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificates"`
+	} `json:"x509CertificateChain"`
+}
+
+// identities returns the URIs and email addresses the signing certificate
+// was issued to, such as the workflow that signed a release
+func (v *verificationMaterial) identities() []string {
+	if v == nil {
+		return nil
+	}
+
+	var raw []byte
+
+	switch {
+	case v.Certificate != nil:
+		raw = v.Certificate.RawBytes
+	case v.X509CertificateChain != nil && len(v.X509CertificateChain.Certificates) > 0:
+		raw = v.X509CertificateChain.Certificates[0].RawBytes
+	}
+
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil
+	}
+
+	identities := append([]string(nil), cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	return identities
+}
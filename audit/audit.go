@@ -0,0 +1,340 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/redact"
+)
+
+// Record is a single audit log entry. Each record carries the hash of the
+// previous one so that edits, deletions and reordering are detectable.
+// Anyone able to write the log can also recompute an unkeyed chain, so logs
+// that must resist that are opened with OpenWithKey or have their Head
+// anchored outside the log.
+type Record struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor"`
+	Action   string            `json:"action"`
+	Plugin   string            `json:"plugin"`
+	Version  string            `json:"version,omitempty"`
+	Digest   string            `json:"digest,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+// ChainError reports where the hash chain of a log is broken
+type ChainError struct {
+	Seq    uint64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at record %d: %s", e.Seq, e.Reason)
+}
+
+// Filter selects records in Query. Zero fields match everything.
+type Filter struct {
+	Actor  string
+	Action string
+	Plugin string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f Filter) matches(r Record) bool {
+	return (f.Actor == "" || f.Actor == r.Actor) &&
+		(f.Action == "" || f.Action == r.Action) &&
+		(f.Plugin == "" || f.Plugin == r.Plugin) &&
+		(f.Since.IsZero() || !r.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || r.Time.Before(f.Until))
+}
+
+// Log is an append-only JSONL audit log
+type Log struct {
+	path     string
+	mu       sync.Mutex
+	seq      uint64
+	lastHash string
+	redactor *redact.Redactor
+	key      []byte
+}
+
+// Open opens the audit log at path, creating it if needed. The existing
+// chain is verified before new records can be appended.
+func Open(path string) (*Log, error) {
+	return OpenWithKey(path, nil)
+}
+
+// OpenWithKey opens the audit log at path like Open, chaining records with
+// HMAC-SHA256 under key instead of plain SHA-256 so that the chain cannot be
+// recomputed without the key. An empty key is the same as Open.
+func OpenWithKey(path string, key []byte) (*Log, error) {
+	l := &Log{path: path, key: key}
+
+	records, err := l.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verify(records, key); err != nil {
+		return nil, err
+	}
+
+	if n := len(records); n > 0 {
+		l.seq = records[n-1].Seq
+		l.lastHash = records[n-1].Hash
+	}
+
+	return l, nil
+}
+
+// WithRedactor sets the redactor applied to record details before they are
+// written. By default the redact.Default patterns are used.
+func (l *Log) WithRedactor(r *redact.Redactor) *Log {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.redactor = r
+
+	return l
+}
+
+// Append adds a record to the log. Seq, PrevHash and Hash are assigned by
+// the log; Time and Actor default to now and the current OS user.
+func (l *Log) Append(r Record) (*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+
+	if r.Actor == "" {
+		r.Actor = currentUser()
+	}
+
+	r.Details = l.redactor.Map(r.Details)
+	r.Seq = l.seq + 1
+	r.PrevHash = l.lastHash
+
+	hash, err := hashRecord(r, l.key)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Hash = hash
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.seq = r.Seq
+	l.lastHash = r.Hash
+
+	return &r, nil
+}
+
+// Query returns the records matching the filter in log order
+func (l *Log) Query(filter Filter) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Record
+
+	for _, r := range records {
+		if filter.matches(r) {
+			result = append(result, r)
+		}
+	}
+
+	return result, nil
+}
+
+// Verify checks the whole hash chain and returns a *ChainError if it has
+// been tampered with
+func (l *Log) Verify() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.readAll()
+	if err != nil {
+		return err
+	}
+
+	if err := verify(records, l.key); err != nil {
+		return err
+	}
+
+	// Records truncated from the end of the file are only detectable
+	// against the state this process has seen
+	if n := len(records); (n == 0 && l.seq != 0) || (n > 0 && records[n-1].Seq < l.seq) {
+		return &ChainError{Seq: l.seq, Reason: "log truncated"}
+	}
+
+	return nil
+}
+
+// Head returns the sequence number and hash of the last record, or zero and
+// an empty hash for an empty log. Kept outside the log, they let VerifyHead
+// detect later truncation or rewriting by another process.
+func (l *Log) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.seq, l.lastHash
+}
+
+// VerifyHead checks the whole hash chain like Verify and that it still
+// holds record seq with the given hash, as previously returned by Head
+func (l *Log) VerifyHead(seq uint64, hash string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.readAll()
+	if err != nil {
+		return err
+	}
+
+	if err := verify(records, l.key); err != nil {
+		return err
+	}
+
+	if seq == 0 {
+		return nil
+	}
+
+	if seq > uint64(len(records)) {
+		return &ChainError{Seq: seq, Reason: "log truncated"}
+	}
+
+	if records[seq-1].Hash != hash {
+		return &ChainError{Seq: seq, Reason: "head hash mismatch"}
+	}
+
+	return nil
+}
+
+func (l *Log) readAll() ([]Record, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, &ChainError{Seq: uint64(len(records) + 1), Reason: "malformed record"}
+		}
+
+		records = append(records, r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return records, nil
+}
+
+func verify(records []Record, key []byte) error {
+	var prev string
+
+	for i, r := range records {
+		if r.Seq != uint64(i+1) {
+			return &ChainError{Seq: r.Seq, Reason: fmt.Sprintf("expected sequence %d", i+1)}
+		}
+
+		if r.PrevHash != prev {
+			return &ChainError{Seq: r.Seq, Reason: "previous hash mismatch"}
+		}
+
+		hash, err := hashRecord(r, key)
+		if err != nil {
+			return err
+		}
+
+		if hash != r.Hash {
+			return &ChainError{Seq: r.Seq, Reason: "record hash mismatch"}
+		}
+
+		prev = r.Hash
+	}
+
+	return nil
+}
+
+// hashRecord computes the SHA-256 of the record's JSON form without its own
+// hash, or its HMAC-SHA256 when key is set
+func hashRecord(r Record, key []byte) (string, error) {
+	r.Hash = ""
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return "unknown"
+}
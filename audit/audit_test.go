@@ -0,0 +1,180 @@
+package audit_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/edsonmichaque/pluginkit/audit"
+)
+
+// newLog appends three records to a new log and returns its path
+func newLog(t *testing.T, key []byte) (*audit.Log, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	l, err := audit.OpenWithKey(path, key)
+	if err != nil {
+		t.Fatalf("OpenWithKey() error = %v", err)
+	}
+
+	for _, plugin := range []string{"hello", "world", "tools"} {
+		if _, err := l.Append(audit.Record{Actor: "test", Action: "install", Plugin: plugin}); err != nil {
+			t.Fatalf("Append(%s) error = %v", plugin, err)
+		}
+	}
+
+	return l, path
+}
+
+// rewrite applies edit to every record of the log at path
+func rewrite(t *testing.T, path string, edit func(r *audit.Record)) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []byte
+
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var r audit.Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatal(err)
+		}
+
+		edit(&r)
+
+		line, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out = append(append(out, line...), '\n')
+	}
+
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rehash recomputes an unkeyed chain over the edited records, as anyone
+// able to write the log could
+func rehash() func(r *audit.Record) {
+	var prev string
+
+	return func(r *audit.Record) {
+		r.PrevHash, r.Hash = prev, ""
+
+		data, _ := json.Marshal(r)
+		sum := sha256.Sum256(data)
+
+		r.Hash = hex.EncodeToString(sum[:])
+		prev = r.Hash
+	}
+}
+
+func assertChainError(t *testing.T, err error, seq uint64) {
+	t.Helper()
+
+	var chainErr *audit.ChainError
+	if !errors.As(err, &chainErr) || chainErr.Seq != seq {
+		t.Errorf("error = %v, want a *ChainError at record %d", err, seq)
+	}
+}
+
+func TestVerifyDetectsEditedRecord(t *testing.T) {
+	l, path := newLog(t, nil)
+
+	rewrite(t, path, func(r *audit.Record) {
+		if r.Seq == 2 {
+			r.Plugin = "evil"
+		}
+	})
+
+	assertChainError(t, l.Verify(), 2)
+
+	_, err := audit.Open(path)
+	assertChainError(t, err, 2)
+}
+
+func TestVerifyDetectsRehashedLog(t *testing.T) {
+	edit := func(next func(r *audit.Record)) func(r *audit.Record) {
+		return func(r *audit.Record) {
+			if r.Seq == 2 {
+				r.Plugin = "evil"
+			}
+
+			next(r)
+		}
+	}
+
+	t.Run("anchored head", func(t *testing.T) {
+		l, path := newLog(t, nil)
+		seq, hash := l.Head()
+
+		rewrite(t, path, edit(rehash()))
+
+		if err := l.Verify(); err != nil {
+			t.Fatalf("Verify() of the re-hashed unkeyed chain error = %v, want it to pass", err)
+		}
+
+		assertChainError(t, l.VerifyHead(seq, hash), 3)
+	})
+
+	t.Run("keyed", func(t *testing.T) {
+		key := []byte("secret")
+		l, path := newLog(t, key)
+
+		rewrite(t, path, edit(rehash()))
+
+		assertChainError(t, l.Verify(), 1)
+
+		_, err := audit.OpenWithKey(path, key)
+		assertChainError(t, err, 1)
+
+		if _, err := audit.OpenWithKey(path, []byte("other")); err == nil {
+			t.Error("OpenWithKey() with another key succeeded")
+		}
+	})
+}
+
+func TestVerifyDetectsTruncatedTail(t *testing.T) {
+	l, path := newLog(t, nil)
+	seq, hash := l.Head()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if err := os.WriteFile(path, bytes.Join(lines[:2], nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	assertChainError(t, l.Verify(), 3)
+
+	// A process opening the truncated log only sees a valid, shorter chain
+	reopened, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if err := reopened.Verify(); err != nil {
+		t.Errorf("Verify() of the reopened log error = %v", err)
+	}
+
+	assertChainError(t, reopened.VerifyHead(seq, hash), 3)
+
+	if err := reopened.VerifyHead(reopened.Head()); err != nil {
+		t.Errorf("VerifyHead() of the current head error = %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"github.com/edsonmichaque/pluginkit/events"
+)
+
+// Subscribe records every lifecycle event published on bus as actor. Errors
+// writing the log are passed to onError, which may be nil.
+func Subscribe(bus *events.Bus, l *Log, actor string, onError func(error)) func() {
+	return bus.Subscribe(func(e events.Event) {
+		details := make(map[string]string, len(e.Metadata)+2)
+		for k, v := range e.Metadata {
+			details[k] = v
+		}
+
+		if e.Store != "" {
+			details["store"] = e.Store
+		}
+
+		if e.Runtime != "" {
+			details["runtime"] = e.Runtime
+		}
+
+		if e.Err != nil {
+			details["error"] = e.Err.Error()
+		}
+
+		_, err := l.Append(Record{
+			Time:    e.Time.UTC(),
+			Actor:   actor,
+			Action:  string(e.Type),
+			Plugin:  e.Plugin,
+			Version: e.Version,
+			Digest:  e.Metadata["digest"],
+			Details: details,
+		})
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
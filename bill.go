@@ -0,0 +1,175 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// InstalledFile is an entry of a plugin's bill of files, recorded for every
+// file written when the plugin was installed or upgraded
+type InstalledFile struct {
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+// UnmarshalJSON also accepts the bare digests recorded before sizes and
+// modes were
+func (f *InstalledFile) UnmarshalJSON(data []byte) error {
+	var digest string
+	if err := json.Unmarshal(data, &digest); err == nil {
+		*f = InstalledFile{SHA256: digest}
+		return nil
+	}
+
+	type plain InstalledFile
+
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// FileDiff lists how two bills of files differ. Paths are relative to the
+// plugin directory and sorted.
+type FileDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"` // Content or mode differs
+}
+
+// Empty reports whether the bills list the same files
+func (d FileDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffFiles compares two bills of files. Modes are only compared when the
+// earlier bill recorded them.
+func DiffFiles(before, after map[string]InstalledFile) FileDiff {
+	var diff FileDiff
+
+	for path, old := range before {
+		current, ok := after[path]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, path)
+		case current.SHA256 != old.SHA256, old.Mode != 0 && current.Mode != old.Mode:
+			diff.Changed = append(diff.Changed, path)
+		}
+	}
+
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			diff.Added = append(diff.Added, path)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+// ErrUnrecordedFiles is returned by a strict uninstall when the plugin
+// directory holds files that were not installed with the plugin
+type ErrUnrecordedFiles struct {
+	Plugin string
+	Files  []string
+}
+
+func (e *ErrUnrecordedFiles) Error() string {
+	return fmt.Sprintf("plugin %s directory holds files that were not installed with it: %s", e.Plugin, strings.Join(e.Files, ", "))
+}
+
+// UserMessage implements UserMessager
+func (e *ErrUnrecordedFiles) UserMessage() Message {
+	return Message{Key: MsgUnrecordedFiles, Params: map[string]string{
+		"plugin": e.Plugin, "files": strings.Join(e.Files, ", "),
+	}}
+}
+
+// recordFiles builds the bill of files under dir, keyed by slash separated
+// path relative to dir. The files the Manager keeps next to the plugin are
+// left out.
+func recordFiles(fsys FS, dir string) (map[string]InstalledFile, error) {
+	files := make(map[string]InstalledFile)
+
+	err := walkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if isManagerFile(rel) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		sum, err := fileDigest(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		files[rel] = InstalledFile{Size: fi.Size(), Mode: fi.Mode().Perm(), SHA256: sum}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// isManagerFile reports whether a path relative to a plugin directory is
+// one of the files the Manager keeps there: metadata, run history, cached
+// help and the plugin's data and config directories
+func isManagerFile(rel string) bool {
+	return isUserDataPath(rel) ||
+		rel == "metadata.json" ||
+		rel == "metadata.json"+metadataBackupSuffix ||
+		rel == "metadata.json"+metadataSignatureSuffix ||
+		strings.HasPrefix(rel, ".metadata.json.tmp-") ||
+		rel == historyFile ||
+		rel == helpFile
+}
+
+// checkUnrecordedFiles compares a plugin directory about to be removed with
+// the plugin's bill of files. Unrecorded files are refused when strict and
+// logged otherwise. Plugins installed before bills were recorded pass.
+func (m *Manager) checkUnrecordedFiles(name, dir string, strict bool) error {
+	info, err := readMetadata(m.fs, filepath.Join(dir, "metadata.json"))
+	if err != nil || info.Files == nil {
+		return nil
+	}
+
+	current, err := recordFiles(m.fs, dir)
+	if err != nil {
+		return fmt.Errorf("failed to list plugin files: %w", err)
+	}
+
+	added := DiffFiles(info.Files, current).Added
+	if len(added) == 0 {
+		return nil
+	}
+
+	if strict {
+		return &ErrUnrecordedFiles{Plugin: name, Files: added}
+	}
+
+	m.logger.Info("removing files that were not installed with the plugin", "plugin", name, "files", added)
+
+	return nil
+}
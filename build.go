@@ -0,0 +1,82 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ImageBuilder is implemented by container executors that can build a
+// plugin's image from a Dockerfile shipped with the plugin
+type ImageBuilder interface {
+	// BuildImage builds the image described by dockerfile with contextDir as
+	// the build context, tags it and returns its image ID
+	BuildImage(ctx context.Context, contextDir, dockerfile, tag string) (string, error)
+}
+
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_.-]`)
+
+// ImageTag returns the tag of the image built for a plugin version. It is
+// derived only from the name and version so that rebuilding the same version
+// replaces the tag.
+func ImageTag(name, version string) string {
+	repo := invalidTagChars.ReplaceAllString(strings.ToLower(name), "-")
+	tag := invalidTagChars.ReplaceAllString(strings.TrimPrefix(version, "v"), "-")
+
+	if tag == "" {
+		tag = "latest"
+	}
+
+	return "pluginkit/" + repo + ":" + tag
+}
+
+// buildImage builds the container image of a plugin that ships a Dockerfile
+// with the executor of its runtime and pins the plugin to the image ID
+func (m *Manager) buildImage(ctx context.Context, dir string, info *Info) error {
+	contextDir := filepath.Join(dir, info.Name)
+	dockerfile := filepath.Join(contextDir, "Dockerfile")
+
+	if _, err := m.fs.Stat(dockerfile); err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	executor := m.executors[info.Runtime]
+	m.mu.RUnlock()
+
+	builder, ok := executor.(ImageBuilder)
+	if !ok {
+		m.logger.Info("plugin ships a Dockerfile but no executor can build it", "plugin", info.Name, "runtime", info.Runtime)
+		return nil
+	}
+
+	tag := ImageTag(info.Name, info.Version)
+
+	m.logger.V(1).Info("building plugin image", "plugin", info.Name, "tag", tag)
+
+	id, err := builder.BuildImage(ctx, contextDir, dockerfile, tag)
+	if err != nil {
+		return err
+	}
+
+	info.Image = id
+	info.Metadata["image_tag"] = tag
+
+	return nil
+}
+
+// ImageID normalizes an image ID written by a build tool's --iidfile
+func ImageID(raw string) (string, error) {
+	id := strings.TrimSpace(raw)
+	if id == "" {
+		return "", fmt.Errorf("build did not report an image ID")
+	}
+
+	if !strings.Contains(id, ":") {
+		id = "sha256:" + id
+	}
+
+	return id, nil
+}
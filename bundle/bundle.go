@@ -0,0 +1,436 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+const (
+	apiVersion    = "pluginkit.bundle/v1"
+	indexFile     = "bundle.json"
+	signatureFile = "bundle.sig"
+	pluginsPrefix = "plugins/"
+)
+
+// Index describes the content of a bundle
+type Index struct {
+	APIVersion string    `json:"apiVersion"`
+	Created    time.Time `json:"created"`
+	Plugins    []Entry   `json:"plugins"`
+}
+
+// Entry describes one plugin in a bundle
+type Entry struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Files   map[string]string `json:"files"` // Path relative to the plugin directory to SHA-256
+}
+
+// CreateOptions configures bundle creation
+type CreateOptions struct {
+	SigningKey ed25519.PrivateKey // Signs the bundle index when set
+}
+
+// InstallOptions configures bundle installation
+type InstallOptions struct {
+	PublicKey        ed25519.PublicKey // Verifies the bundle signature when set
+	RequireSignature bool              // Refuse unsigned bundles
+	Overwrite        bool              // Replace plugins that are already installed
+}
+
+// Create packages installed plugins from pluginDir into a gzipped tar
+// archive written to w. Each plugin's files, metadata and checksums are
+// included so that the bundle can be installed without store access.
+func Create(ctx context.Context, w io.Writer, pluginDir string, names []string, opts CreateOptions) error {
+	index := Index{
+		APIVersion: apiVersion,
+		Created:    time.Now().UTC(),
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("bundle creation cancelled: %w", err)
+		}
+
+		entry, err := describe(pluginDir, name)
+		if err != nil {
+			return err
+		}
+
+		index.Plugins = append(index.Plugins, *entry)
+	}
+
+	indexBytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle index: %w", err)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := writeTarFile(tw, indexFile, indexBytes, 0644); err != nil {
+		return err
+	}
+
+	if opts.SigningKey != nil {
+		sig := ed25519.Sign(opts.SigningKey, indexBytes)
+		if err := writeTarFile(tw, signatureFile, []byte(base64.StdEncoding.EncodeToString(sig)), 0644); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range index.Plugins {
+		for _, rel := range sortedKeys(entry.Files) {
+			src := filepath.Join(pluginDir, entry.Name, filepath.FromSlash(rel))
+
+			if err := copyToTar(tw, src, pluginsPrefix+entry.Name+"/"+rel); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+
+	return nil
+}
+
+// Install verifies a bundle read from r and installs its plugins into
+// pluginDir. It returns the names of the installed plugins.
+func Install(ctx context.Context, r io.Reader, pluginDir string, opts InstallOptions) ([]string, error) {
+	staging, err := os.MkdirTemp("", "plugin-bundle-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	indexBytes, signature, err := unpack(ctx, r, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(indexBytes, signature, opts); err != nil {
+		return nil, err
+	}
+
+	var index Index
+	if err := json.Unmarshal(indexBytes, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle index: %w", err)
+	}
+
+	if index.APIVersion != apiVersion {
+		return nil, fmt.Errorf("unsupported bundle version %q", index.APIVersion)
+	}
+
+	// Verify every plugin before touching the plugin directory
+	for _, entry := range index.Plugins {
+		if err := verifyEntry(staging, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	var installed []string
+
+	for _, entry := range index.Plugins {
+		target := filepath.Join(pluginDir, entry.Name)
+
+		if _, err := os.Stat(target); err == nil {
+			if !opts.Overwrite {
+				return installed, fmt.Errorf("plugin %s is already installed", entry.Name)
+			}
+
+			if err := os.RemoveAll(target); err != nil {
+				return installed, fmt.Errorf("failed to replace plugin %s: %w", entry.Name, err)
+			}
+		}
+
+		if err := os.Rename(filepath.Join(staging, entry.Name), target); err != nil {
+			return installed, fmt.Errorf("failed to install plugin %s: %w", entry.Name, err)
+		}
+
+		installed = append(installed, entry.Name)
+	}
+
+	return installed, nil
+}
+
+// describe builds the bundle entry of an installed plugin
+func describe(pluginDir, name string) (*Entry, error) {
+	dir := filepath.Join(pluginDir, name)
+
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s is not installed: %w", name, err)
+	}
+
+	var info extension.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata for plugin %s: %w", name, err)
+	}
+
+	entry := &Entry{
+		Name:    name,
+		Version: info.Version,
+		Files:   make(map[string]string),
+	}
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("unsupported file type in plugin %s: %s", name, p)
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		sum, err := fileDigest(p)
+		if err != nil {
+			return err
+		}
+
+		entry.Files[filepath.ToSlash(rel)] = sum
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin %s: %w", name, err)
+	}
+
+	return entry, nil
+}
+
+// unpack extracts a bundle into dir and returns its index and signature
+func unpack(ctx context.Context, r io.Reader, dir string) ([]byte, []byte, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	var indexBytes, signature []byte
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("bundle installation cancelled: %w", err)
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch name := path.Clean(header.Name); {
+		case name == indexFile:
+			if indexBytes, err = io.ReadAll(tr); err != nil {
+				return nil, nil, fmt.Errorf("failed to read bundle index: %w", err)
+			}
+		case name == signatureFile:
+			if signature, err = io.ReadAll(tr); err != nil {
+				return nil, nil, fmt.Errorf("failed to read bundle signature: %w", err)
+			}
+		case strings.HasPrefix(name, pluginsPrefix):
+			target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, pluginsPrefix)))
+			if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+				return nil, nil, fmt.Errorf("invalid bundle path: %s", header.Name)
+			}
+
+			if err := writeFile(target, tr, os.FileMode(header.Mode)&0755); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if indexBytes == nil {
+		return nil, nil, fmt.Errorf("bundle index not found")
+	}
+
+	return indexBytes, signature, nil
+}
+
+func verifySignature(indexBytes, signature []byte, opts InstallOptions) error {
+	if signature == nil {
+		if opts.RequireSignature {
+			return fmt.Errorf("bundle is not signed")
+		}
+
+		return nil
+	}
+
+	if opts.PublicKey == nil {
+		if opts.RequireSignature {
+			return fmt.Errorf("no public key configured to verify the bundle signature")
+		}
+
+		return nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid bundle signature encoding: %w", err)
+	}
+
+	if !ed25519.Verify(opts.PublicKey, indexBytes, sig) {
+		return fmt.Errorf("bundle signature verification failed")
+	}
+
+	return nil
+}
+
+// verifyEntry checks that the staged files of a plugin match the index
+func verifyEntry(staging string, entry Entry) error {
+	if entry.Name == "" || strings.ContainsAny(entry.Name, `\`) || strings.Contains(entry.Name, "..") {
+		return fmt.Errorf("invalid plugin name in bundle: %q", entry.Name)
+	}
+
+	dir := filepath.Join(staging, entry.Name)
+
+	for rel, expected := range entry.Files {
+		actual, err := fileDigest(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return fmt.Errorf("plugin %s is missing %s: %w", entry.Name, rel, err)
+		}
+
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %s in plugin %s", rel, entry.Name)
+		}
+	}
+
+	if _, ok := entry.Files["metadata.json"]; !ok {
+		return fmt.Errorf("plugin %s has no metadata", entry.Name)
+	}
+
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func copyToTar(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(stat.Mode().Perm()),
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return f.Close()
+}
+
+func fileDigest(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,153 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// infoCache keeps parsed plugin metadata in memory. Entries are keyed by
+// the modification time and size of the metadata file, so changes made by
+// other processes are picked up on the next read, while changes made by the
+// Manager invalidate entries explicitly.
+type infoCache struct {
+	fs      FS
+	mu      sync.Mutex
+	entries map[string]cachedInfo
+
+	dirModTime time.Time // Modification time of the plugin directory when names was read
+	names      []string  // Plugin directory entries, nil when not cached
+	generation uint64    // Incremented whenever cached data is replaced or dropped
+}
+
+type cachedInfo struct {
+	modTime time.Time
+	size    int64
+	info    *Info
+}
+
+func newInfoCache(fsys FS) *infoCache {
+	return &infoCache{fs: fsys, entries: make(map[string]cachedInfo)}
+}
+
+// read returns the metadata at path, parsing it only when the file changed
+// since it was cached
+func (c *infoCache) read(path string) (*Info, error) {
+	stat, err := c.fs.Stat(path)
+	if err != nil {
+		// A missing file may still be recovered from its backup
+		return readMetadata(c.fs, path)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+
+	if ok && entry.modTime.Equal(stat.ModTime()) && entry.size == stat.Size() {
+		return cloneInfo(entry.info), nil
+	}
+
+	info, err := readMetadata(c.fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[path] = cachedInfo{modTime: stat.ModTime(), size: stat.Size(), info: info}
+	c.generation++
+	c.mu.Unlock()
+
+	return cloneInfo(info), nil
+}
+
+// pluginNames returns the subdirectories of dir, reusing the previous
+// listing while the directory is unchanged
+func (c *infoCache) pluginNames(dir string) ([]string, error) {
+	stat, err := c.fs.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.names != nil && c.dirModTime.Equal(stat.ModTime()) {
+		names := c.names
+		c.mu.Unlock()
+
+		return names, nil
+	}
+	c.mu.Unlock()
+
+	entries, err := c.fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && isPluginDirName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	c.mu.Lock()
+	c.dirModTime = stat.ModTime()
+	c.names = names
+	c.generation++
+	c.mu.Unlock()
+
+	return names, nil
+}
+
+// invalidate drops the cached metadata of one plugin directory and the
+// cached directory listing
+func (c *infoCache) invalidate(pluginDir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, filepath.Join(pluginDir, "metadata.json"))
+	c.names = nil
+	c.generation++
+}
+
+// reset drops every cached entry
+func (c *infoCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]cachedInfo)
+	c.names = nil
+	c.generation++
+}
+
+// currentGeneration identifies the cached data, so that values derived from
+// it can tell when they are stale
+func (c *infoCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// cloneInfo copies the cached value so callers cannot modify the cache
+func cloneInfo(info *Info) *Info {
+	clone := *info
+	clone.Metadata = maps.Clone(info.Metadata)
+
+	return &clone
+}
+
+// Refresh discards the in-memory metadata cache so the next List, Fetch or
+// Execute reads every plugin from disk. File watchers should call it when
+// the plugin directory changes outside the Manager.
+func (m *Manager) Refresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before refreshing plugins: %w", err)
+	}
+
+	m.cache.reset()
+
+	return nil
+}
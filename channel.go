@@ -0,0 +1,114 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Channel is a release track a plugin can follow. Each channel includes
+// the versions of the more stable ones: beta also offers stable releases
+// and nightly offers everything.
+type Channel string
+
+const (
+	ChannelStable  Channel = "stable"
+	ChannelBeta    Channel = "beta"
+	ChannelNightly Channel = "nightly"
+)
+
+// ChannelStore is implemented by stores that publish the channel of each
+// version themselves, e.g. as a pre-release flag, instead of leaving the
+// Manager to derive it from the version
+type ChannelStore interface {
+	// Channels returns the available versions of a plugin and their channel
+	Channels(ctx context.Context, name string) (map[string]Channel, error)
+}
+
+// ParseChannel returns the channel selected by a version of the form
+// @channel, as in Install(ctx, name, InstallOptions{Version: "@beta"})
+func ParseChannel(version string) (Channel, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(version), "@")
+	if !ok {
+		return "", false
+	}
+
+	switch channel := Channel(strings.ToLower(name)); channel {
+	case ChannelStable, ChannelBeta, ChannelNightly:
+		return channel, true
+	default:
+		return "", false
+	}
+}
+
+// VersionChannel derives the channel of a version from its pre-release tag:
+// releases are stable, alpha, dev, nightly and snapshot builds are nightly
+// and other pre-releases such as beta or rc are beta
+func VersionChannel(version string) Channel {
+	_, pre := splitVersion(version)
+	if pre == "" {
+		return ChannelStable
+	}
+
+	pre = strings.ToLower(pre)
+
+	for _, prefix := range []string{"alpha", "dev", "nightly", "snapshot"} {
+		if strings.HasPrefix(pre, prefix) {
+			return ChannelNightly
+		}
+	}
+
+	return ChannelBeta
+}
+
+// Includes reports whether the channel offers versions of other
+func (c Channel) Includes(other Channel) bool {
+	return channelRank(other) <= channelRank(c)
+}
+
+func channelRank(c Channel) int {
+	switch c {
+	case ChannelNightly:
+		return 2
+	case ChannelBeta:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// resolveChannel returns the highest version of a plugin the channel offers
+func resolveChannel(ctx context.Context, store Store, name string, channel Channel) (string, error) {
+	var channels map[string]Channel
+
+	if cs, ok := store.(ChannelStore); ok {
+		var err error
+		if channels, err = cs.Channels(ctx, name); err != nil {
+			return "", fmt.Errorf("failed to list channels of plugin %s: %w", name, err)
+		}
+	} else {
+		versions, err := AdaptStore(store).Versions(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to list versions of plugin %s: %w", name, err)
+		}
+
+		channels = make(map[string]Channel, len(versions))
+		for _, v := range versions {
+			channels[v] = VersionChannel(v)
+		}
+	}
+
+	var best string
+
+	for v, c := range channels {
+		if channel.Includes(c) && (best == "" || CompareVersions(v, best) > 0) {
+			best = v
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("plugin %s has no %s version", name, channel)
+	}
+
+	return best, nil
+}
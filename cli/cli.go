@@ -0,0 +1,787 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+// Options wires the command tree to a plugin manager and executor
+type Options struct {
+	Manager  *extension.Manager
+	Executor extension.Executor // Used by the run command, may be nil
+	Use      string             // Name of the parent command, defaults to "extension"
+}
+
+// NewCommand returns the complete plugin management command tree, ready to
+// be mounted on an application's root command
+func NewCommand(opts Options) *cobra.Command {
+	use := opts.Use
+	if use == "" {
+		use = "extension"
+	}
+
+	cmd := &cobra.Command{
+		Use:     use,
+		Aliases: []string{"ext", "plugin"},
+		Short:   "Manage plugins",
+	}
+
+	cmd.PersistentFlags().String("profile", extension.DefaultProfile, "Plugin profile to operate on")
+
+	cmd.AddCommand(
+		newInstallCommand(opts),
+		newUninstallCommand(opts),
+		newUpgradeCommand(opts),
+		newPinCommand(opts),
+		newUnpinCommand(opts),
+		newListCommand(opts),
+		newSearchCommand(opts),
+		newRunCommand(opts),
+		newInfoCommand(opts),
+		newCommandsCommand(opts),
+		newCompleteCommand(opts),
+		newManCommand(opts),
+		newVerifyCommand(opts),
+		newEnableCommand(opts),
+		newDisableCommand(opts),
+	)
+
+	return cmd
+}
+
+func newInstallCommand(opts Options) *cobra.Command {
+	var (
+		installOpts extension.InstallOptions
+		platform    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "install NAME",
+		Short: "Install a plugin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			if platform != "" {
+				if installOpts.Platform, err = extension.ParsePlatform(platform); err != nil {
+					return err
+				}
+			}
+
+			if err := mgr.Install(cmd.Context(), args[0], installOpts); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed %s\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&installOpts.Version, "version", "latest", "Version, version constraint or @channel (stable, beta, nightly) to install")
+	cmd.Flags().StringVar(&installOpts.Store, "store", "", "Store to install from")
+	cmd.Flags().StringVar(&installOpts.Runtime, "runtime", "", "Runtime to use instead of the one reported by the store")
+	cmd.Flags().StringVar(&installOpts.Alias, "alias", "", "Name to install the plugin under")
+	cmd.Flags().StringVar(&platform, "platform", "", "Platform to install for, as os/arch")
+	cmd.Flags().BoolVar(&installOpts.Force, "force", false, "Replace an existing installation")
+	cmd.Flags().BoolVar(&installOpts.SkipVerify, "skip-verify", false, "Skip scanners and provenance checks")
+	cmd.Flags().BoolVar(&installOpts.AllowYanked, "allow-yanked", false, "Install a version its store withdrew")
+
+	return cmd
+}
+
+func newUninstallCommand(opts Options) *cobra.Command {
+	var keepData bool
+
+	cmd := &cobra.Command{
+		Use:     "uninstall NAME",
+		Aliases: []string{"remove", "rm"},
+		Short:   "Uninstall a plugin",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := mgr.UninstallWithOptions(cmd.Context(), args[0], extension.UninstallOptions{KeepData: keepData}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Uninstalled %s\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&keepData, "keep-data", false, "Keep the plugin's volumes, caches and VM disks")
+
+	return cmd
+}
+
+func newUpgradeCommand(opts Options) *cobra.Command {
+	var (
+		version        string
+		allowDowngrade bool
+		all            bool
+		concurrency    int
+		output         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade [NAME]",
+		Short: "Upgrade a plugin, or every plugin that is not pinned",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all != (len(args) == 0) {
+				return fmt.Errorf("specify either a plugin name or --all")
+			}
+
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			if all {
+				report, err := mgr.UpgradeAll(cmd.Context(), extension.UpgradeAllOptions{Concurrency: concurrency})
+				if err != nil {
+					return err
+				}
+
+				if err := printUpgradeReport(cmd.OutOrStdout(), output, report); err != nil {
+					return err
+				}
+
+				if n := report.Count(extension.UpgradeFailed); n > 0 {
+					return fmt.Errorf("%d plugins failed to upgrade", n)
+				}
+
+				return nil
+			}
+
+			err = mgr.UpgradeWithOptions(cmd.Context(), args[0], extension.UpgradeOptions{
+				Version:        version,
+				AllowDowngrade: allowDowngrade,
+				OnDowngrade: func(plugin, from, to string) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: downgrading %s from %s to %s\n", plugin, from, to)
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			// The requested version may be a constraint, a channel or latest,
+			// so report the version that was actually installed
+			info, err := mgr.Fetch(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Upgraded %s to %s\n", args[0], info.Version)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "latest", "Version or @channel to upgrade to")
+	cmd.Flags().BoolVar(&allowDowngrade, "allow-downgrade", false, "Allow installing a version older than the installed one")
+	cmd.Flags().BoolVar(&all, "all", false, "Upgrade every installed plugin that is not pinned")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 0, "Plugins to upgrade at once with --all")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format of --all (table, json)")
+
+	return cmd
+}
+
+func newPinCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pin NAME...",
+		Short: "Keep plugins at their installed version when upgrading all",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range args {
+				if err := mgr.Pin(cmd.Context(), name); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func newUnpinCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unpin NAME...",
+		Short: "Let upgrading all upgrade pinned plugins again",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range args {
+				if err := mgr.Unpin(cmd.Context(), name); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func printUpgradeReport(w io.Writer, format string, report *extension.UpgradeReport) error {
+	switch format {
+	case "json":
+		return printJSON(w, report)
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PLUGIN\tOUTCOME\tFROM\tTO\tREASON")
+
+		for _, r := range report.Results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.Outcome, r.From, r.To, r.Reason)
+		}
+
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+func newListCommand(opts Options) *cobra.Command {
+	var (
+		output string
+		labels []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List installed plugins",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			plugins, err := mgr.ListWithOptions(cmd.Context(), extension.ListOptions{Labels: labels})
+			if err != nil {
+				return err
+			}
+
+			return printInfos(cmd.OutOrStdout(), output, plugins)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "Only list plugins with this label (repeatable)")
+
+	return cmd
+}
+
+func newSearchCommand(opts Options) *cobra.Command {
+	var (
+		output  string
+		filters []string
+		order   string
+		limit   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search [QUERY]",
+		Short: "Search available plugins",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			criteria := extension.SearchOptions{}
+			if len(args) == 1 {
+				criteria[extension.SearchQuery] = args[0]
+			}
+
+			for _, filter := range filters {
+				key, value, ok := strings.Cut(filter, "=")
+				if !ok {
+					return fmt.Errorf("invalid filter %q, expected key=value", filter)
+				}
+
+				criteria[key] = value
+			}
+
+			if order != "" {
+				criteria[extension.SearchSort] = order
+			}
+
+			if limit > 0 {
+				criteria[extension.SearchLimit] = strconv.Itoa(limit)
+			}
+
+			plugins, err := mgr.Search(cmd.Context(), criteria)
+			if err != nil {
+				return err
+			}
+
+			return printInfos(cmd.OutOrStdout(), output, plugins)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringArrayVar(&filters, "filter", nil, "Search filter as key=value, e.g. owner, language, min-stars or updated-since (repeatable)")
+	cmd.Flags().StringVar(&order, "sort", "", "Order results by name, downloads, stars, updated or rating")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of results per store")
+
+	return cmd
+}
+
+func newRunCommand(opts Options) *cobra.Command {
+	var (
+		env         []string
+		workDir     string
+		force       bool
+		copyOnWrite bool
+		explain     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run NAME [ARGS...]",
+		Short: "Run an installed plugin",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			if opts.Executor == nil {
+				return fmt.Errorf("no executor configured")
+			}
+
+			environment := make(map[string]string, len(env))
+			for _, e := range env {
+				key, value, _ := strings.Cut(e, "=")
+				environment[key] = value
+			}
+
+			execOpts := extension.ExecuteOptions{
+				Args:         args[1:],
+				Environment:  environment,
+				WorkingDir:   workDir,
+				IgnoreStatus: force,
+				CopyOnWrite:  copyOnWrite,
+			}
+
+			if explain {
+				plan, err := mgr.ExplainWith(cmd.Context(), opts.Executor, args[0], execOpts)
+				if err != nil {
+					return err
+				}
+
+				return printJSON(cmd.OutOrStdout(), plan)
+			}
+
+			result, err := mgr.ExecuteWith(cmd.Context(), opts.Executor, args[0], execOpts)
+			if err != nil {
+				return err
+			}
+
+			writeOutput(cmd.OutOrStdout(), result.Stdout)
+			writeOutput(cmd.ErrOrStderr(), result.Stderr)
+
+			if !result.Success {
+				return fmt.Errorf("plugin %s exited with code %d", args[0], result.ExitCode)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().StringArrayVarP(&env, "env", "e", nil, "Environment variable as KEY=VALUE (repeatable)")
+	cmd.Flags().StringVarP(&workDir, "workdir", "w", "", "Working directory for the plugin")
+	cmd.Flags().BoolVar(&force, "force", false, "Run the plugin even if it is disabled or quarantined")
+	cmd.Flags().BoolVar(&copyOnWrite, "copy-on-write", false, "Run against a copy of the working directory and apply changes only on success")
+	cmd.Flags().BoolVar(&explain, "explain", false, "Print how the plugin would be run without running it")
+
+	return cmd
+}
+
+func newInfoCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "info NAME",
+		Short: "Show details of an installed plugin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			info, err := mgr.Fetch(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("plugin %s is not installed: %w", args[0], err)
+			}
+
+			return printJSON(cmd.OutOrStdout(), info)
+		},
+	}
+}
+
+func newCommandsCommand(opts Options) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "commands",
+		Short: "List the commands contributed by enabled plugins",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			commands, err := mgr.Commands(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				return printJSON(cmd.OutOrStdout(), commands)
+			case "table", "":
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "COMMAND\tPLUGIN\tSUMMARY")
+
+				for _, c := range commands {
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Plugin, c.Summary)
+				}
+
+				return tw.Flush()
+			default:
+				return fmt.Errorf("unsupported output format %q", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+func newCompleteCommand(opts Options) *cobra.Command {
+	// Called by the host's completion functions with the words typed after
+	// the host program, the last one being completed
+	cmd := &cobra.Command{
+		Use:    "complete SHELL [WORDS...]",
+		Short:  "Print completion entries for plugin commands (bash, zsh, fish)",
+		Args:   cobra.MinimumNArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			var completions []extension.Completion
+			if opts.Executor != nil {
+				completions, err = mgr.CompleteWith(cmd.Context(), opts.Executor, args[1:])
+			} else {
+				completions, err = mgr.Complete(cmd.Context(), args[1:])
+			}
+
+			if err != nil {
+				return err
+			}
+
+			return extension.WriteCompletions(cmd.OutOrStdout(), extension.Shell(args[0]), completions)
+		},
+	}
+
+	// Words being completed may look like flags
+	cmd.Flags().SetInterspersed(false)
+
+	return cmd
+}
+
+func newVerifyCommand(opts Options) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "verify NAME...",
+		Short: "Check installed plugins against their recorded and published checksums",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			var (
+				reports []*extension.VerifyReport
+				failed  []string
+			)
+
+			for _, name := range args {
+				report, err := mgr.Verify(cmd.Context(), name)
+				if err != nil {
+					return err
+				}
+
+				reports = append(reports, report)
+
+				if !report.OK() {
+					failed = append(failed, name)
+				}
+			}
+
+			switch output {
+			case "json":
+				if err := printJSON(cmd.OutOrStdout(), reports); err != nil {
+					return err
+				}
+			case "table", "":
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "PLUGIN\tSTATUS\tFILE")
+
+				for _, r := range reports {
+					printVerifyReport(tw, r)
+				}
+
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unsupported output format %q", output)
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("verification failed for %s", strings.Join(failed, ", "))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+func printVerifyReport(w io.Writer, r *extension.VerifyReport) {
+	if r.OK() {
+		fmt.Fprintf(w, "%s\tok\t\n", r.Plugin)
+		return
+	}
+
+	if r.Unrecorded {
+		fmt.Fprintf(w, "%s\tunrecorded\t\n", r.Plugin)
+	}
+
+	for _, f := range r.Modified {
+		fmt.Fprintf(w, "%s\tmodified\t%s\n", r.Plugin, f)
+	}
+
+	for _, f := range r.Missing {
+		fmt.Fprintf(w, "%s\tmissing\t%s\n", r.Plugin, f)
+	}
+
+	for _, f := range r.Extra {
+		fmt.Fprintf(w, "%s\textra\t%s\n", r.Plugin, f)
+	}
+
+	if r.PublishedDigest != "" && !strings.EqualFold(r.PublishedDigest, r.ArtifactDigest) {
+		fmt.Fprintf(w, "%s\tchecksum mismatch\tpublished %s, installed %s\n", r.Plugin, r.PublishedDigest, r.ArtifactDigest)
+	}
+}
+
+func newManCommand(opts Options) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "man [NAME]",
+		Short: "Show the documentation of a plugin, or of every installed plugin",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			var manual []extension.Help
+
+			if len(args) == 1 {
+				var help *extension.Help
+				if opts.Executor != nil {
+					help, err = mgr.HelpWith(cmd.Context(), opts.Executor, args[0])
+				} else {
+					help, err = mgr.Help(cmd.Context(), args[0])
+				}
+
+				if err != nil {
+					return err
+				}
+
+				manual = append(manual, *help)
+			} else {
+				if opts.Executor != nil {
+					manual, err = mgr.ManualWith(cmd.Context(), opts.Executor)
+				} else {
+					manual, err = mgr.Manual(cmd.Context())
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			switch output {
+			case "json":
+				return printJSON(cmd.OutOrStdout(), manual)
+			case "text", "":
+				for i, help := range manual {
+					if i > 0 {
+						fmt.Fprintln(cmd.OutOrStdout())
+					}
+
+					printHelp(cmd.OutOrStdout(), help)
+				}
+
+				return nil
+			default:
+				return fmt.Errorf("unsupported output format %q", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format (text, json)")
+
+	return cmd
+}
+
+// printHelp writes a plugin's documentation, falling back to its manifest
+// when it has no help text
+func printHelp(w io.Writer, help extension.Help) {
+	fmt.Fprintf(w, "%s %s\n", help.Plugin, help.Version)
+
+	if help.Description != "" {
+		fmt.Fprintf(w, "  %s\n", help.Description)
+	}
+
+	if help.Text != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(help.Text, "\n"))
+		return
+	}
+
+	if len(help.Commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+
+		for _, c := range help.Commands {
+			fmt.Fprintf(w, "  %-20s %s\n", strings.TrimSpace(c.Name+" "+c.Usage), c.Summary)
+		}
+	}
+}
+
+func newEnableCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable NAME...",
+		Short: "Enable plugins",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			return mgr.SetStatus(cmd.Context(), args, extension.StatusEnabled)
+		},
+	}
+}
+
+func newDisableCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable NAME...",
+		Short: "Disable plugins",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mgr, err := opts.manager(cmd)
+			if err != nil {
+				return err
+			}
+
+			return mgr.SetStatus(cmd.Context(), args, extension.StatusDisabled)
+		},
+	}
+}
+
+// manager returns the Manager scoped to the profile selected on the command line
+func (o Options) manager(cmd *cobra.Command) (*extension.Manager, error) {
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" || profile == extension.DefaultProfile {
+		return o.Manager, nil
+	}
+
+	return o.Manager.WithProfile(profile)
+}
+
+func printInfos(w io.Writer, format string, plugins []extension.Info) error {
+	switch format {
+	case "json":
+		return printJSON(w, plugins)
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVERSION\tSTATUS\tRUNTIME\tDESCRIPTION")
+
+		for _, p := range plugins {
+			status := string(p.Status)
+			if p.Deprecation.Active() {
+				status += " (deprecated)"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Version, status, p.Runtime, p.Description)
+		}
+
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// writeOutput writes captured plugin output, which executors return as raw bytes
+func writeOutput(w io.Writer, output interface{}) {
+	switch v := output.(type) {
+	case []byte:
+		w.Write(v)
+	case string:
+		io.WriteString(w, v)
+	case nil:
+	default:
+		fmt.Fprint(w, v)
+	}
+}
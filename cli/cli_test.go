@@ -0,0 +1,50 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/cli"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestUpgradePrintsResolvedVersion(t *testing.T) {
+	content := []byte("#!/bin/sh\necho hello\n")
+	store := storetest.New().Add(extension.Info{Name: "hello", Version: "1.0.0", Content: content})
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).WithFS(extension.NewMemFS())
+	if err := manager.Install(context.Background(), "hello", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	store.Add(extension.Info{Name: "hello", Version: "1.1.0", Content: content}).
+		Add(extension.Info{Name: "hello", Version: "1.2.0", Content: content}).
+		Add(extension.Info{Name: "hello", Version: "2.0.0", Content: content})
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"upgrade", "hello", "--version", "~1.1"}, "Upgraded hello to 1.1.0\n"},
+		{[]string{"upgrade", "hello"}, "Upgraded hello to 2.0.0\n"},
+	} {
+		var out bytes.Buffer
+
+		cmd := cli.NewCommand(cli.Options{Manager: manager})
+		cmd.SetArgs(tt.args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+
+		if err := cmd.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("%v: error = %v", tt.args, err)
+		}
+
+		if out.String() != tt.want {
+			t.Errorf("%v: output = %q, want %q", tt.args, out.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Command is a subcommand a plugin contributes to the host CLI, declared in
+// its manifest so hosts can list it in their help and completion without
+// running the plugin
+type Command struct {
+	Name    string          `json:"name"`
+	Summary string          `json:"summary,omitempty"`
+	Usage   string          `json:"usage,omitempty"`   // Argument synopsis, e.g. "[flags] NAME"
+	Aliases []string        `json:"aliases,omitempty"` // Alternative names
+	Args    json.RawMessage `json:"args,omitempty"`    // JSON schema of the command's arguments and flags
+
+	// Complete declares that the plugin answers completion requests for the
+	// command, see CompleteCommand
+	Complete bool `json:"complete,omitempty"`
+}
+
+// UnmarshalJSON also accepts a bare command name, as written by stores that
+// only know the names of the commands
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = Command{Name: name}
+		return nil
+	}
+
+	type command Command
+
+	return json.Unmarshal(data, (*command)(c))
+}
+
+var commandNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_:.-]*$`)
+
+// Validate checks that the command has a usable name and a well-formed
+// arguments schema
+func (c Command) Validate() error {
+	if !commandNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("invalid command name %q", c.Name)
+	}
+
+	for _, alias := range c.Aliases {
+		if !commandNamePattern.MatchString(alias) {
+			return fmt.Errorf("command %s has an invalid alias %q", c.Name, alias)
+		}
+	}
+
+	if len(c.Args) > 0 && !json.Valid(c.Args) {
+		return fmt.Errorf("command %s has an invalid arguments schema", c.Name)
+	}
+
+	return nil
+}
+
+// PluginCommand is a command together with the plugin that provides it
+type PluginCommand struct {
+	Plugin string `json:"plugin"`
+	Command
+}
+
+// Commands enumerates the commands contributed by enabled plugins, sorted
+// by name. A plugin that declares no commands contributes one named after
+// itself. Commands with invalid declarations are skipped and logged; when
+// several plugins declare the same name, all are returned and the host
+// decides which one wins.
+func (m *Manager) Commands(ctx context.Context) ([]PluginCommand, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []PluginCommand
+
+	for _, info := range plugins {
+		if checkStatus(info.Name, info.Status) != nil {
+			continue
+		}
+
+		declared := info.Commands
+		if len(declared) == 0 {
+			declared = []Command{{Name: info.Name, Summary: info.Description}}
+		}
+
+		for _, command := range declared {
+			if err := command.Validate(); err != nil {
+				m.logger.Error(err, "ignoring invalid command", "plugin", info.Name)
+				continue
+			}
+
+			commands = append(commands, PluginCommand{Plugin: info.Name, Command: command})
+		}
+	}
+
+	sort.SliceStable(commands, func(i, j int) bool {
+		if commands[i].Name != commands[j].Name {
+			return commands[i].Name < commands[j].Name
+		}
+
+		return commands[i].Plugin < commands[j].Plugin
+	})
+
+	return commands, nil
+}
@@ -0,0 +1,160 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Requirements are the constraints a plugin places on the environment it is
+// installed into
+type Requirements struct {
+	MinHostVersion string   `json:"minHostVersion,omitempty" yaml:"minHostVersion,omitempty"` // Minimum host application version
+	Platforms      []string `json:"platforms,omitempty" yaml:"platforms,omitempty"`           // Supported os/arch pairs, e.g. linux/amd64
+	Runtime        string   `json:"runtime,omitempty" yaml:"runtime,omitempty"`               // Runtime that must be available to execute the plugin
+	Interpreter    string   `json:"interpreter,omitempty" yaml:"interpreter,omitempty"`       // Version constraint on the interpreter of script plugins, e.g. >=3.10
+}
+
+// CompatibilityMode controls how Install reacts to unmet requirements
+type CompatibilityMode int
+
+const (
+	// CompatibilityEnforce refuses to install incompatible plugins
+	CompatibilityEnforce CompatibilityMode = iota
+	// CompatibilityWarn logs unmet requirements and installs anyway
+	CompatibilityWarn
+)
+
+// MetadataFetcher is implemented by stores that can describe a plugin
+// without downloading its content, which lets compatibility be checked
+// before anything is fetched
+type MetadataFetcher interface {
+	FetchMetadata(ctx context.Context, name string, version string) (*Info, error)
+}
+
+// IncompatibleError lists the requirements a plugin does not meet
+type IncompatibleError struct {
+	Plugin  string
+	Reasons []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("plugin %s is not compatible: %s", e.Plugin, strings.Join(e.Reasons, "; "))
+}
+
+// UserMessage implements UserMessager
+func (e *IncompatibleError) UserMessage() Message {
+	return Message{Key: MsgIncompatible, Params: map[string]string{"plugin": e.Plugin, "reasons": strings.Join(e.Reasons, "; ")}}
+}
+
+// WithHostVersion sets the host application version checked against the
+// plugins' minimum host version
+func (m *Manager) WithHostVersion(version string) *Manager {
+	m.hostVersion = version
+	return m
+}
+
+// WithCompatibilityMode sets whether incompatible plugins are refused or
+// only reported
+func (m *Manager) WithCompatibilityMode(mode CompatibilityMode) *Manager {
+	m.compatMode = mode
+	return m
+}
+
+// CheckCompatibility verifies a plugin's requirements against the local
+// environment and returns an *IncompatibleError describing any unmet ones
+func (m *Manager) CheckCompatibility(info *Info) error {
+	return m.CheckCompatibilityFor(info, CurrentPlatform())
+}
+
+// CheckCompatibilityFor is CheckCompatibility for a plugin installed for
+// another platform, as with InstallOptions.Platform
+func (m *Manager) CheckCompatibilityFor(info *Info, platform Platform) error {
+	var reasons []string
+
+	req := info.Requirements
+	if req == nil {
+		req = &Requirements{}
+	}
+
+	if req.MinHostVersion != "" && m.hostVersion != "" && CompareVersions(m.hostVersion, req.MinHostVersion) < 0 {
+		reasons = append(reasons, fmt.Sprintf("requires host version %s or later, have %s", req.MinHostVersion, m.hostVersion))
+	}
+
+	if len(req.Platforms) > 0 {
+		supported := false
+
+		for _, p := range req.Platforms {
+			if p == platform.String() || p == platform.OS+"/*" || p == "*/"+platform.Arch {
+				supported = true
+				break
+			}
+		}
+
+		if !supported {
+			reasons = append(reasons, fmt.Sprintf("does not support %s", platform))
+		}
+	}
+
+	rt := req.Runtime
+	if rt == "" {
+		rt = info.Runtime
+	}
+
+	if rt != "" {
+		if available, checked := m.runtimeAvailable(rt); checked && !available {
+			reasons = append(reasons, fmt.Sprintf("requires runtime %s which is not available", rt))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	return &IncompatibleError{Plugin: info.Name, Reasons: reasons}
+}
+
+// runtimeAvailable reports whether a runtime can be executed: an executor
+// is registered for it with WithExecutor or in the registry, or the
+// registry knows the runtime. Nothing is checked while no executor is
+// registered at all, as when a host wires its executors after installing.
+func (m *Manager) runtimeAvailable(rt string) (available, checked bool) {
+	m.mu.RLock()
+	_, ok := m.executors[rt]
+	checked = len(m.executors) > 0
+	m.mu.RUnlock()
+
+	if ok {
+		return true, true
+	}
+
+	if m.registry != nil {
+		if _, ok := m.registry.GetExecutor(rt); ok {
+			return true, true
+		}
+
+		if _, ok := m.registry.GetRuntime(rt); ok {
+			return true, true
+		}
+
+		checked = checked || len(m.registry.executorNames()) > 0
+	}
+
+	return false, checked
+}
+
+// checkCompatibility applies the compatibility mode to the result of
+// CheckCompatibilityFor the platform carried by ctx
+func (m *Manager) checkCompatibility(ctx context.Context, info *Info) error {
+	err := m.CheckCompatibilityFor(info, PlatformFromContext(ctx))
+	if err == nil {
+		return nil
+	}
+
+	if m.compatMode == CompatibilityWarn {
+		m.logger.Info("installing incompatible plugin", "plugin", info.Name, "reason", err.Error())
+		return nil
+	}
+
+	return err
+}
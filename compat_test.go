@@ -0,0 +1,75 @@
+package extension_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/runtime/runtimetest"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func newRegistryManager(t *testing.T, store *storetest.Fake) *extension.Manager {
+	t.Helper()
+
+	registry := extension.NewRegistry()
+	registry.RegisterStore("fake", store)
+
+	return extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithRegistry(registry).
+		WithExecutor("native", runtimetest.New())
+}
+
+func TestInstallThroughRegistryEnforcesCompatibility(t *testing.T) {
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	tests := []struct {
+		name         string
+		info         extension.Info
+		opts         extension.InstallOptions
+		incompatible bool
+	}{
+		{
+			name: "registered runtime",
+			info: extension.Info{Name: "hello", Version: "1.0.0", Content: content},
+		},
+		{
+			name:         "unregistered runtime",
+			info:         extension.Info{Name: "hello", Version: "1.0.0", Runtime: "wasm", Content: content},
+			incompatible: true,
+		},
+		{
+			name: "platform of the install options",
+			info: extension.Info{Name: "hello", Version: "1.0.0", Content: content,
+				Requirements: &extension.Requirements{Platforms: []string{"plan9/arm"}}},
+			opts: extension.InstallOptions{Platform: extension.Platform{OS: "plan9", Arch: "arm"}},
+		},
+		{
+			name: "unsupported platform",
+			info: extension.Info{Name: "hello", Version: "1.0.0", Content: content,
+				Requirements: &extension.Requirements{Platforms: []string{"plan9/arm"}}},
+			incompatible: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newRegistryManager(t, storetest.New().Add(tt.info))
+
+			err := manager.Install(context.Background(), "hello", tt.opts)
+
+			var incompatible *extension.IncompatibleError
+			if got := errors.As(err, &incompatible); got != tt.incompatible {
+				t.Fatalf("Install() error = %v, want incompatible %v", err, tt.incompatible)
+			}
+
+			if !tt.incompatible && err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+		})
+	}
+}
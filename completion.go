@@ -0,0 +1,241 @@
+package extension
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CompleteCommand is the argument plugins that declare Command.Complete are
+// run with to complete their arguments. The plugin receives the command
+// name, unless it is the plugin's own name, followed by the words typed so
+// far, the last one being the word to complete, possibly empty:
+//
+//	plugin __complete deploy --env pro
+//
+// It prints one candidate per line, optionally followed by a tab and a
+// description. Lines starting with a colon are ignored, so plugins built
+// with cobra answer the protocol as is.
+const CompleteCommand = "__complete"
+
+// Shell is a shell completion entries are formatted for
+type Shell string
+
+const (
+	ShellBash Shell = "bash"
+	ShellZsh  Shell = "zsh"
+	ShellFish Shell = "fish"
+)
+
+// Completion is a candidate for the word being completed
+type Completion struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Complete returns the candidates for the last of args, the words typed
+// after the host program, the first being a plugin command. Command names
+// and aliases come from the manifests of enabled plugins, flags from the
+// arguments schema of the command. Plugins that declare Command.Complete
+// are asked for the candidates of their arguments with the executor
+// registered for their runtime.
+func (m *Manager) Complete(ctx context.Context, args []string) ([]Completion, error) {
+	return m.complete(ctx, args, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.Execute(ctx, name, opts)
+	})
+}
+
+// CompleteWith is Complete asking plugins for candidates with the given
+// executor
+func (m *Manager) CompleteWith(ctx context.Context, executor Executor, args []string) ([]Completion, error) {
+	return m.complete(ctx, args, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.ExecuteWith(ctx, executor, name, opts)
+	})
+}
+
+func (m *Manager) complete(ctx context.Context, args []string, run func(string, ExecuteOptions) (*ExecuteResult, error)) ([]Completion, error) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
+	commands, err := m.Commands(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	word := args[len(args)-1]
+
+	if len(args) == 1 {
+		var completions []Completion
+		for _, c := range commands {
+			for _, name := range append([]string{c.Name}, c.Aliases...) {
+				completions = append(completions, Completion{Value: name, Description: c.Summary})
+			}
+		}
+
+		return filterCompletions(completions, word), nil
+	}
+
+	command, ok := findCommand(commands, args[0])
+	if !ok {
+		return nil, nil
+	}
+
+	var completions []Completion
+
+	if command.Complete {
+		completions, err = m.completeDynamic(command, args[1:], run)
+		if err != nil {
+			// Fall back to what the manifest declares
+			m.logger.Error(err, "plugin failed to complete arguments", "plugin", command.Plugin, "command", command.Name)
+		}
+	}
+
+	if strings.HasPrefix(word, "-") {
+		completions = append(completions, schemaFlags(command.Args)...)
+	}
+
+	return filterCompletions(completions, word), nil
+}
+
+// completeDynamic runs the plugin providing command with CompleteCommand
+func (m *Manager) completeDynamic(command PluginCommand, words []string, run func(string, ExecuteOptions) (*ExecuteResult, error)) ([]Completion, error) {
+	args := []string{CompleteCommand}
+	if command.Name != command.Plugin {
+		args = append(args, command.Name)
+	}
+
+	result, err := run(command.Plugin, ExecuteOptions{Args: append(args, words...)})
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		return nil, fmt.Errorf("completion exited with code %d", result.ExitCode)
+	}
+
+	return parseCompletions(truncateOutput(result.Stdout, 0)), nil
+}
+
+// findCommand returns the command called name, by its name or an alias. The
+// first plugin declaring it wins, as in the order of Commands.
+func findCommand(commands []PluginCommand, name string) (PluginCommand, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	for _, c := range commands {
+		for _, alias := range c.Aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+
+	return PluginCommand{}, false
+}
+
+// schemaFlags returns a flag for each property of an arguments schema
+func schemaFlags(schema json.RawMessage) []Completion {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	var s struct {
+		Properties map[string]struct {
+			Description string `json:"description"`
+		} `json:"properties"`
+	}
+
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil
+	}
+
+	flags := make([]Completion, 0, len(s.Properties))
+	for name, property := range s.Properties {
+		flags = append(flags, Completion{Value: "--" + name, Description: property.Description})
+	}
+
+	return flags
+}
+
+// parseCompletions reads the candidates printed by a plugin
+func parseCompletions(output string) []Completion {
+	var completions []Completion
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		value, description, _ := strings.Cut(line, "\t")
+		completions = append(completions, Completion{Value: value, Description: strings.TrimSpace(description)})
+	}
+
+	return completions
+}
+
+// filterCompletions keeps the candidates starting with word, sorted and
+// without duplicates
+func filterCompletions(completions []Completion, word string) []Completion {
+	seen := make(map[string]bool, len(completions))
+
+	var filtered []Completion
+	for _, c := range completions {
+		if strings.HasPrefix(c.Value, word) && !seen[c.Value] {
+			seen[c.Value] = true
+			filtered = append(filtered, c)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Value < filtered[j].Value })
+
+	return filtered
+}
+
+// WriteCompletions writes completion entries in the format the shell's
+// completion functions consume: bare values for bash, value:description
+// pairs for zsh's _describe and tab-separated pairs for fish
+func WriteCompletions(w io.Writer, shell Shell, completions []Completion) error {
+	var line func(Completion) string
+
+	switch shell {
+	case ShellBash:
+		line = func(c Completion) string { return c.Value }
+	case ShellZsh:
+		line = func(c Completion) string {
+			value := strings.ReplaceAll(c.Value, ":", `\:`)
+			if c.Description == "" {
+				return value
+			}
+
+			return value + ":" + c.Description
+		}
+	case ShellFish:
+		line = func(c Completion) string {
+			if c.Description == "" {
+				return c.Value
+			}
+
+			return c.Value + "\t" + c.Description
+		}
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	for _, c := range completions {
+		if _, err := io.WriteString(w, line(c)+"\n"); err != nil {
+			return fmt.Errorf("failed to write completions: %w", err)
+		}
+	}
+
+	return nil
+}
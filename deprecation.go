@@ -0,0 +1,254 @@
+package extension
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// Deprecation is how a store signals that a plugin, or one of its versions,
+// should no longer be used
+type Deprecation struct {
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	Yanked      bool   `json:"yanked,omitempty"`      // The version was withdrawn and is refused by default
+	Message     string `json:"message,omitempty"`     // Why, and what to do instead
+	Replacement string `json:"replacement,omitempty"` // Plugin to use instead
+	EndOfLife   string `json:"endOfLife,omitempty"`   // Date support ends, YYYY-MM-DD
+}
+
+// Active reports whether the deprecation flags anything
+func (d *Deprecation) Active() bool {
+	return d != nil && (d.Deprecated || d.Yanked)
+}
+
+func (d *Deprecation) String() string {
+	if !d.Active() {
+		return ""
+	}
+
+	s := "deprecated"
+	if d.Yanked {
+		s = "yanked"
+	}
+
+	if d.Message != "" {
+		s += ": " + d.Message
+	}
+
+	if d.Replacement != "" {
+		s += " (use " + d.Replacement + " instead)"
+	}
+
+	if d.EndOfLife != "" {
+		s += ", end of life " + d.EndOfLife
+	}
+
+	return s
+}
+
+// ErrVersionYanked is returned when installing or upgrading to a version
+// its store withdrew
+type ErrVersionYanked struct {
+	Plugin  string
+	Version string
+	Message string
+}
+
+func (e *ErrVersionYanked) Error() string {
+	msg := fmt.Sprintf("plugin %s version %s was yanked", e.Plugin, e.Version)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	return msg
+}
+
+// UserMessage implements UserMessager
+func (e *ErrVersionYanked) UserMessage() Message {
+	return Message{Key: MsgVersionYanked, Params: map[string]string{
+		"plugin": e.Plugin, "version": e.Version, "message": e.Message,
+	}}
+}
+
+// ParseDeprecation reads deprecation trailers from release notes, one per
+// line, as stores that publish releases signal them:
+//
+//	Deprecated: superseded by the v2 API
+//	Yanked: corrupts the cache on upgrade
+//	Replacement: acme-cli
+//	End-Of-Life: 2025-06-30
+//
+// It returns nil when the notes carry no Deprecated or Yanked trailer.
+func ParseDeprecation(notes string) *Deprecation {
+	var d Deprecation
+
+	scanner := bufio.NewScanner(strings.NewReader(notes))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "deprecated":
+			d.Deprecated = true
+			if d.Message == "" {
+				d.Message = value
+			}
+		case "yanked":
+			d.Yanked = true
+			d.Message = value
+		case "replacement":
+			d.Replacement = value
+		case "end-of-life":
+			d.EndOfLife = value
+		}
+	}
+
+	if !d.Active() {
+		return nil
+	}
+
+	return &d
+}
+
+// checkDeprecation refuses yanked versions unless allowed and warns about
+// deprecated ones
+func (m *Manager) checkDeprecation(operation string, info *Info, version string, allowYanked bool) error {
+	d := info.Deprecation
+	if !d.Active() {
+		return nil
+	}
+
+	if version == "" {
+		version = info.Version
+	}
+
+	if d.Yanked && !allowYanked {
+		m.metrics.Failed(operation, metrics.ReasonFetch)
+		return &ErrVersionYanked{Plugin: info.Name, Version: version, Message: d.Message}
+	}
+
+	m.logger.Info("plugin is "+d.String(), "plugin", info.Name, "version", version, "operation", operation)
+
+	return nil
+}
+
+// UpdateStatus reports how an installed plugin compares to its store
+type UpdateStatus struct {
+	Name            string       `json:"name"`
+	Installed       string       `json:"installed"`
+	Latest          string       `json:"latest,omitempty"`
+	Channel         Channel      `json:"channel,omitempty"` // Channel the plugin follows, Latest is the newest version it offers
+	UpdateAvailable bool         `json:"updateAvailable"`
+	Deprecation     *Deprecation `json:"deprecation,omitempty"` // Deprecation of the installed version
+	Err             error        `json:"-"`
+}
+
+// CheckUpdates asks the store of every installed plugin for its latest
+// version, on the channel it was installed from if any, and for the current
+// deprecation of the installed one, which is recorded so that List reports
+// it. The deprecation of read-only system plugins is only reported. Every
+// plugin is checked; failures are reported in UpdateStatus.Err.
+func (m *Manager) CheckUpdates(ctx context.Context) ([]UpdateStatus, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]UpdateStatus, 0, len(plugins))
+
+	for _, info := range plugins {
+		if err := ctx.Err(); err != nil {
+			return statuses, fmt.Errorf("update check cancelled: %w", err)
+		}
+
+		statuses = append(statuses, m.checkUpdate(ctx, info))
+	}
+
+	return statuses, nil
+}
+
+func (m *Manager) checkUpdate(ctx context.Context, info Info) UpdateStatus {
+	status := UpdateStatus{
+		Name:        info.Name,
+		Installed:   info.Version,
+		Channel:     Channel(info.Metadata["channel"]),
+		Deprecation: info.Deprecation,
+	}
+
+	latest, err := m.latestVersion(ctx, info)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	status.Latest = latest
+	status.UpdateAvailable = CompareVersions(status.Latest, info.Version) > 0
+
+	store, err := m.storeFor(info.Metadata["source"])
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	current, err := AdaptStore(store).Describe(ctx, upstreamOf(info), info.Version)
+	if err != nil {
+		status.Err = fmt.Errorf("failed to describe installed version: %w", err)
+		return status
+	}
+
+	status.Deprecation = current.Deprecation
+
+	// System plugins are read-only, their deprecation is reported only
+	if info.Layer != LayerSystem && current.Deprecation.String() != info.Deprecation.String() {
+		status.Err = m.recordDeprecation(info.Name, current.Deprecation)
+	}
+
+	return status
+}
+
+// latestVersion resolves the latest version of an installed plugin in the
+// store it was installed from, on the channel it follows if any
+func (m *Manager) latestVersion(ctx context.Context, info Info) (string, error) {
+	store, err := m.storeFor(info.Metadata["source"])
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	if channel := Channel(info.Metadata["channel"]); channel != "" {
+		latest, err = resolveChannel(ctx, store, upstreamOf(info), channel)
+	} else {
+		latest, err = AdaptStore(store).Resolve(ctx, upstreamOf(info), "latest")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve latest version: %w", err)
+	}
+
+	return latest, nil
+}
+
+// upstreamOf returns the name of an installed plugin in its store
+func upstreamOf(info Info) string {
+	if upstream := info.Metadata["upstream"]; upstream != "" {
+		return upstream
+	}
+
+	return info.Name
+}
+
+func (m *Manager) recordDeprecation(name string, d *Deprecation) error {
+	defer m.plugins.lock(name)()
+
+	return m.updateMetadata(name, func(info *Info) error {
+		info.Deprecation = d
+		return nil
+	})
+}
@@ -0,0 +1,55 @@
+package extension_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestCheckUpdatesReportsSystemDeprecation(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	store := storetest.New().
+		Add(extension.Info{Name: "system", Version: "1.0.0", Content: content})
+
+	fsys := extension.NewMemFS()
+
+	system := extension.NewManager("/system", store, logr.Discard()).WithFS(fsys)
+	if err := system.Install(ctx, "system", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() in the system directory error = %v", err)
+	}
+
+	deprecation := &extension.Deprecation{Deprecated: true, Message: "use other"}
+	store.Add(extension.Info{Name: "system", Version: "1.0.0", Content: content, Deprecation: deprecation})
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(fsys).
+		WithSystemDir("/system")
+
+	statuses, err := manager.CheckUpdates(ctx)
+	if err != nil {
+		t.Fatalf("CheckUpdates() error = %v", err)
+	}
+
+	if len(statuses) != 1 {
+		t.Fatalf("CheckUpdates() = %+v, want the system plugin", statuses)
+	}
+
+	if status := statuses[0]; status.Err != nil || status.Deprecation.String() != deprecation.String() {
+		t.Errorf("status = %+v, want deprecation %s and no error", status, deprecation)
+	}
+
+	info, err := manager.Fetch(ctx, "system")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if info.Deprecation != nil {
+		t.Errorf("deprecation %s was recorded in the read-only system plugin", info.Deprecation)
+	}
+}
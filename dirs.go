@@ -0,0 +1,133 @@
+package extension
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DefaultAppName names the directories NewManager defaults to
+const DefaultAppName = "pluginkit"
+
+// Dirs are the directories an application keeps plugins, caches and
+// configuration in
+type Dirs struct {
+	Plugins string
+	Cache   string
+	Config  string
+}
+
+// DefaultDirs returns the conventional directories of an application on the
+// current platform:
+//
+//	Linux, BSD  $XDG_DATA_HOME/<app>/plugins, $XDG_CACHE_HOME/<app>, $XDG_CONFIG_HOME/<app>
+//	            defaulting to ~/.local/share, ~/.cache and ~/.config
+//	macOS       ~/Library/Application Support/<app>/plugins, ~/Library/Caches/<app>,
+//	            ~/Library/Application Support/<app>
+//	Windows     %LOCALAPPDATA%\<app>\plugins, %LOCALAPPDATA%\<app>\cache, %APPDATA%\<app>
+func DefaultDirs(app string) (Dirs, error) {
+	if app == "" {
+		return Dirs{}, errors.New("application name is required")
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		local, err := windowsDir("LOCALAPPDATA")
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		roaming, err := windowsDir("APPDATA")
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		return Dirs{
+			Plugins: filepath.Join(local, app, "plugins"),
+			Cache:   filepath.Join(local, app, "cache"),
+			Config:  filepath.Join(roaming, app),
+		}, nil
+	case "darwin", "ios":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Dirs{}, fmt.Errorf("failed to find home directory: %w", err)
+		}
+
+		support := filepath.Join(home, "Library", "Application Support", app)
+
+		return Dirs{
+			Plugins: filepath.Join(support, "plugins"),
+			Cache:   filepath.Join(home, "Library", "Caches", app),
+			Config:  support,
+		}, nil
+	default:
+		data, err := xdgDir("XDG_DATA_HOME", ".local", "share")
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		cache, err := xdgDir("XDG_CACHE_HOME", ".cache")
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		config, err := xdgDir("XDG_CONFIG_HOME", ".config")
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		return Dirs{
+			Plugins: filepath.Join(data, app, "plugins"),
+			Cache:   filepath.Join(cache, app),
+			Config:  filepath.Join(config, app),
+		}, nil
+	}
+}
+
+// DefaultPluginDir returns the conventional plugin directory of an
+// application, see DefaultDirs
+func DefaultPluginDir(app string) (string, error) {
+	dirs, err := DefaultDirs(app)
+	return dirs.Plugins, err
+}
+
+// DefaultCacheDir returns the conventional cache directory of an
+// application, see DefaultDirs
+func DefaultCacheDir(app string) (string, error) {
+	dirs, err := DefaultDirs(app)
+	return dirs.Cache, err
+}
+
+// DefaultConfigDir returns the conventional configuration directory of an
+// application, see DefaultDirs
+func DefaultConfigDir(app string) (string, error) {
+	dirs, err := DefaultDirs(app)
+	return dirs.Config, err
+}
+
+// xdgDir returns the directory named by an XDG variable, falling back to
+// the given path under the home directory. Relative values are ignored, as
+// the specification requires.
+func xdgDir(env string, fallback ...string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	return filepath.Join(append([]string{home}, fallback...)...), nil
+}
+
+func windowsDir(env string) (string, error) {
+	dir := os.Getenv(env)
+	if dir == "" {
+		return "", fmt.Errorf("%%%s%% is not defined", env)
+	}
+
+	return dir, nil
+}
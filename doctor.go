@@ -0,0 +1,177 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FixAction is a machine-actionable remedy for a health problem
+type FixAction string
+
+const (
+	FixReinstall      FixAction = "reinstall"
+	FixUninstall      FixAction = "uninstall"
+	FixChmod          FixAction = "chmod"
+	FixInstallRuntime FixAction = "install-runtime"
+	FixRemovePath     FixAction = "remove-path"
+)
+
+// HealthProblem describes one failed check
+type HealthProblem struct {
+	Check   string    `json:"check"`
+	Message string    `json:"message"`
+	Fix     FixAction `json:"fix"`
+	Path    string    `json:"path,omitempty"` // File or directory the fix applies to
+}
+
+// PluginHealth is the result of checking one installed plugin
+type PluginHealth struct {
+	Name     string          `json:"name"`
+	Version  string          `json:"version,omitempty"`
+	Problems []HealthProblem `json:"problems,omitempty"`
+}
+
+// Healthy reports whether all checks passed
+func (h PluginHealth) Healthy() bool {
+	return len(h.Problems) == 0
+}
+
+// DoctorReport is the outcome of Manager.Doctor
+type DoctorReport struct {
+	Plugins  []PluginHealth  `json:"plugins"`
+	Dangling []HealthProblem `json:"dangling,omitempty"` // Leftovers not belonging to an installed plugin
+}
+
+// Healthy reports whether every plugin passed and nothing is left dangling
+func (r *DoctorReport) Healthy() bool {
+	if len(r.Dangling) > 0 {
+		return false
+	}
+
+	for _, p := range r.Plugins {
+		if !p.Healthy() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Doctor validates every installed plugin: metadata is parseable, the plugin
+// file exists and is executable, its checksum still matches the one recorded
+// at install time and its runtime is available. Interrupted upgrades are
+// reported as dangling directories.
+func (m *Manager) Doctor(ctx context.Context) (*DoctorReport, error) {
+	m.dirMu.RLock()
+	defer m.dirMu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before health check: %w", err)
+	}
+
+	report := &DoctorReport{}
+
+	entries, err := m.fs.ReadDir(m.pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(m.pluginDir, entry.Name())
+
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if strings.HasSuffix(entry.Name(), ".upgrade") || strings.HasSuffix(entry.Name(), ".backup") {
+			report.Dangling = append(report.Dangling, HealthProblem{
+				Check:   "dangling",
+				Message: fmt.Sprintf("leftover directory from an interrupted upgrade: %s", entry.Name()),
+				Fix:     FixRemovePath,
+				Path:    path,
+			})
+
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("health check cancelled: %w", err)
+		}
+
+		report.Plugins = append(report.Plugins, m.checkPlugin(entry.Name(), path))
+	}
+
+	return report, nil
+}
+
+// checkPlugin runs the health checks for a single plugin directory
+func (m *Manager) checkPlugin(name, dir string) PluginHealth {
+	health := PluginHealth{Name: name}
+
+	metadataPath := filepath.Join(dir, "metadata.json")
+
+	info, err := readMetadata(m.fs, metadataPath)
+	if err != nil {
+		health.Problems = append(health.Problems, HealthProblem{
+			Check:   "metadata",
+			Message: fmt.Sprintf("metadata is missing or unreadable: %v", err),
+			Fix:     FixReinstall,
+			Path:    metadataPath,
+		})
+
+		return health
+	}
+
+	health.Version = info.Version
+
+	binPath := binaryPath(dir, info)
+
+	stat, err := m.fs.Stat(binPath)
+
+	switch {
+	case err != nil:
+		health.Problems = append(health.Problems, HealthProblem{
+			Check:   "binary",
+			Message: "plugin file is missing",
+			Fix:     FixReinstall,
+			Path:    binPath,
+		})
+	case info.Runtime == "exec" && stat.Mode()&0111 == 0:
+		health.Problems = append(health.Problems, HealthProblem{
+			Check:   "binary",
+			Message: "plugin file is not executable",
+			Fix:     FixChmod,
+			Path:    binPath,
+		})
+	}
+
+	if expected := info.Metadata["binary_sha256"]; expected != "" && err == nil {
+		if actual, err := fileDigest(m.fs, binPath); err != nil || actual != expected {
+			health.Problems = append(health.Problems, HealthProblem{
+				Check:   "checksum",
+				Message: "plugin file does not match the checksum recorded at install time",
+				Fix:     FixReinstall,
+				Path:    binPath,
+			})
+		}
+	}
+
+	if info.Runtime != "" && m.registry != nil {
+		if _, ok := m.registry.GetRuntime(info.Runtime); !ok {
+			health.Problems = append(health.Problems, HealthProblem{
+				Check:   "runtime",
+				Message: fmt.Sprintf("runtime %s is not available", info.Runtime),
+				Fix:     FixInstallRuntime,
+			})
+		}
+	}
+
+	return health
+}
@@ -0,0 +1,47 @@
+package extension
+
+import (
+	"fmt"
+
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// ErrDowngrade is returned by Upgrade when the requested version is older
+// than the installed one and downgrades were not allowed
+type ErrDowngrade struct {
+	Plugin    string
+	Installed string
+	Requested string
+}
+
+func (e *ErrDowngrade) Error() string {
+	return fmt.Sprintf("plugin %s is at version %s, newer than %s; allow downgrades to install it", e.Plugin, e.Installed, e.Requested)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrDowngrade) UserMessage() Message {
+	return Message{Key: MsgDowngrade, Params: map[string]string{
+		"plugin": e.Plugin, "installed": e.Installed, "requested": e.Requested,
+	}}
+}
+
+// checkDowngrade refuses moving a plugin to an older version unless allowed,
+// and warns through opts.OnDowngrade when it is
+func (m *Manager) checkDowngrade(name, installed, requested string, opts UpgradeOptions) error {
+	if CompareVersions(requested, installed) >= 0 {
+		return nil
+	}
+
+	if !opts.AllowDowngrade {
+		m.metrics.Failed("upgrade", metrics.ReasonConflict)
+		return &ErrDowngrade{Plugin: name, Installed: installed, Requested: requested}
+	}
+
+	m.logger.Info("downgrading plugin", "plugin", name, "from", installed, "to", requested)
+
+	if opts.OnDowngrade != nil {
+		opts.OnDowngrade(name, installed, requested)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/redact"
+)
+
+// Type identifies the kind of lifecycle event
+type Type string
+
+const (
+	PluginInstalled    Type = "plugin.installed"
+	PluginUpgraded     Type = "plugin.upgraded"
+	PluginRemoved      Type = "plugin.removed"
+	ExecutionStarted   Type = "execution.started"
+	ExecutionFinished  Type = "execution.finished"
+	VerificationFailed Type = "verification.failed"
+	InstallProgress    Type = "install.progress"
+	UpdateAvailable    Type = "plugin.update_available"
+	PluginUnhealthy    Type = "plugin.unhealthy"
+	MaintenanceRun     Type = "maintenance.run"
+)
+
+// Event describes something that happened to a plugin
+type Event struct {
+	Type     Type              `json:"type"`
+	Time     time.Time         `json:"time"`
+	Plugin   string            `json:"plugin"`
+	Version  string            `json:"version,omitempty"`
+	Store    string            `json:"store,omitempty"`
+	Runtime  string            `json:"runtime,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"` // Event specific details
+	Err      error             `json:"-"`                  // Failure cause, if any
+}
+
+// Handler receives published events
+type Handler func(Event)
+
+// Bus dispatches events to subscribers
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[int]subscriber
+	nextID      int
+	redactor    *redact.Redactor
+}
+
+type subscriber struct {
+	handler Handler
+	types   map[Type]bool // nil means all types
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]subscriber),
+	}
+}
+
+// WithRedactor sets the redactor applied to event metadata before delivery.
+// By default the redact.Default patterns are used.
+func (b *Bus) WithRedactor(r *redact.Redactor) *Bus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.redactor = r
+
+	return b
+}
+
+// Subscribe registers a handler for the given event types, or for every
+// event if no type is given. Handlers run synchronously on the publishing
+// goroutine and must not block. The returned function removes the handler.
+func (b *Bus) Subscribe(handler Handler, types ...Type) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var filter map[Type]bool
+	if len(types) > 0 {
+		filter = make(map[Type]bool, len(types))
+		for _, t := range types {
+			filter[t] = true
+		}
+	}
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = subscriber{handler: handler, types: filter}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Channel subscribes a buffered channel to the given event types. Events are
+// dropped when the channel is full so that slow consumers never block the
+// publisher. The returned function unsubscribes and closes the channel.
+func (b *Bus) Channel(buffer int, types ...Type) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	var mu sync.Mutex
+
+	closed := false
+
+	unsubscribe := b.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		select {
+		case ch <- e:
+		default:
+		}
+	}, types...)
+
+	return ch, func() {
+		unsubscribe()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers an event to every matching subscriber. Publishing on a
+// nil bus is a no-op.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	e.Metadata = b.redactor.Map(e.Metadata)
+	handlers := make([]Handler, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		if s.types == nil || s.types[e.Type] {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,337 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/events"
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// ErrPluginDisabled is returned when executing a plugin whose status does
+// not allow it to run
+type ErrPluginDisabled struct {
+	Plugin string
+	Status Status
+}
+
+func (e *ErrPluginDisabled) Error() string {
+	return fmt.Sprintf("plugin %s is %s and cannot be executed", e.Plugin, e.Status)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrPluginDisabled) UserMessage() Message {
+	return Message{Key: MsgPluginDisabled, Params: map[string]string{"plugin": e.Plugin, "status": string(e.Status)}}
+}
+
+// CheckStatus returns *ErrPluginDisabled when the installed plugin in
+// pluginDir is disabled or quarantined. Plugins without metadata are not
+// managed and pass the check.
+func CheckStatus(pluginDir, name string) error {
+	info, err := readMetadata(OSFS{}, filepath.Join(pluginDir, name, "metadata.json"))
+	if err != nil {
+		return nil
+	}
+
+	return checkStatus(name, info.Status)
+}
+
+func checkStatus(name string, status Status) error {
+	switch status {
+	case StatusDisabled, StatusQuarantined:
+		return &ErrPluginDisabled{Plugin: name, Status: status}
+	default:
+		return nil
+	}
+}
+
+// WithExecutor registers the executor used to run plugins of the given runtime
+func (m *Manager) WithExecutor(runtime string, executor Executor) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.executors == nil {
+		m.executors = make(map[string]Executor)
+	}
+
+	m.executors[runtime] = executor
+	delete(m.executorFactories, runtime)
+
+	return m
+}
+
+// ExecutorFactory creates an executor running the plugins installed in
+// pluginDir
+type ExecutorFactory func(pluginDir string) Executor
+
+// WithExecutorFactory registers the executor for the given runtime through a
+// factory, so profiles get an executor rooted at their own plugin directory
+func (m *Manager) WithExecutorFactory(runtime string, factory ExecutorFactory) *Manager {
+	m.WithExecutor(runtime, factory(m.pluginDir))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.executorFactories == nil {
+		m.executorFactories = make(map[string]ExecutorFactory)
+	}
+
+	m.executorFactories[runtime] = factory
+
+	return m
+}
+
+// Execute runs an installed plugin with the executor registered for its runtime
+func (m *Manager) Execute(ctx context.Context, name string, opts ExecuteOptions) (*ExecuteResult, error) {
+	info, err := m.readInfo(name)
+	if err != nil {
+		return nil, wrapOp(OpExecute, name, "", fmt.Errorf("plugin %s is not installed: %w", name, err))
+	}
+
+	executor, ok := m.executorFor(info)
+	if !ok {
+		return nil, wrapOp(OpExecute, name, info.Metadata["source"], fmt.Errorf("no executor registered for runtime %s", info.Runtime))
+	}
+
+	return m.executeOp(ctx, executor, info, name, opts)
+}
+
+// ExecuteWith runs an installed plugin with the given executor
+func (m *Manager) ExecuteWith(ctx context.Context, executor Executor, name string, opts ExecuteOptions) (*ExecuteResult, error) {
+	info, err := m.readInfo(name)
+	if err != nil {
+		return nil, wrapOp(OpExecute, name, "", fmt.Errorf("plugin %s is not installed: %w", name, err))
+	}
+
+	return m.executeOp(ctx, executor, info, name, opts)
+}
+
+// Explain returns the plan the executor registered for the plugin's runtime
+// would follow to run it, without running anything. The executor must
+// implement Explainer.
+func (m *Manager) Explain(ctx context.Context, name string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	info, err := m.readInfo(name)
+	if err != nil {
+		return nil, wrapOp(OpExplain, name, "", fmt.Errorf("plugin %s is not installed: %w", name, err))
+	}
+
+	executor, ok := m.executorFor(info)
+	if !ok {
+		return nil, wrapOp(OpExplain, name, info.Metadata["source"], fmt.Errorf("no executor registered for runtime %s", info.Runtime))
+	}
+
+	return m.explainOp(ctx, executor, info, name, opts)
+}
+
+// ExplainWith returns the plan the given executor would follow to run an
+// installed plugin, without running anything
+func (m *Manager) ExplainWith(ctx context.Context, executor Executor, name string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	info, err := m.readInfo(name)
+	if err != nil {
+		return nil, wrapOp(OpExplain, name, "", fmt.Errorf("plugin %s is not installed: %w", name, err))
+	}
+
+	return m.explainOp(ctx, executor, info, name, opts)
+}
+
+func (m *Manager) explain(ctx context.Context, executor Executor, info *Info, name string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	explainer, ok := executor.(Explainer)
+	if !ok {
+		return nil, fmt.Errorf("executor for runtime %s cannot explain executions", info.Runtime)
+	}
+
+	if !opts.IgnoreStatus {
+		if err := checkStatus(name, info.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	// Explain with the same permissions and volumes Execute would use
+	if opts.Permissions == nil {
+		opts.Permissions = info.Permissions
+	}
+
+	if opts.Volumes == nil {
+		opts.Volumes = info.Volumes
+	}
+
+	if opts.Image == "" {
+		opts.Image = info.Image
+	}
+
+	plan, err := explainer.Explain(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.Runtime == "" {
+		plan.Runtime = info.Runtime
+	}
+
+	return plan, nil
+}
+
+func (m *Manager) execute(ctx context.Context, executor Executor, info *Info, name string, opts ExecuteOptions) (*ExecuteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before execution: %w", err)
+	}
+
+	if err := m.beginExecution(); err != nil {
+		return nil, err
+	}
+	defer m.inflight.Done()
+
+	if !opts.IgnoreStatus {
+		if err := checkStatus(name, info.Status); err != nil {
+			return nil, err
+		}
+
+		// System plugins are signed by whoever installed them, not this
+		// Manager's key
+		if info.Layer != LayerSystem {
+			if err := m.checkTampering(ctx, name); err != nil {
+				return nil, err
+			}
+		}
+	} else if info.Status != StatusEnabled {
+		m.logger.Info("executing plugin despite its status", "plugin", name, "status", info.Status)
+	}
+
+	// Enforce the permissions recorded at install time unless the caller
+	// narrowed them further, within what the plugin's trust level allows
+	if opts.Permissions == nil {
+		opts.Permissions = info.Permissions
+	}
+
+	admission := executePolicyInput(name, info)
+
+	if err := m.enforceTrust(&admission, &opts); err != nil {
+		return nil, err
+	}
+
+	if err := m.admit(ctx, admission); err != nil {
+		return nil, err
+	}
+
+	if info.Layer != LayerSystem {
+		if err := m.approveRun(ctx, name, info); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.prepareUserDirs(name, &opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Volumes == nil {
+		opts.Volumes = info.Volumes
+	}
+
+	if opts.Image == "" {
+		opts.Image = info.Image
+	}
+
+	release, err := m.scheduler.Acquire(ctx, info.Runtime, name, opts.Priority)
+	if err != nil {
+		m.metrics.Failed("execute", metrics.ReasonCancelled)
+		return nil, err
+	}
+	defer release()
+
+	// Run against a private copy of the working directory if requested
+	var sandbox *WorkDirSandbox
+
+	if opts.CopyOnWrite && opts.WorkingDir != "" {
+		if !opts.Permissions.AllowsPath(opts.WorkingDir) {
+			return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+		}
+
+		if sandbox, err = NewWorkDirSandbox(opts.WorkingDir); err != nil {
+			return nil, err
+		}
+
+		opts.WorkingDir = sandbox.Copy
+		opts.Permissions = opts.Permissions.withPath(sandbox.Copy)
+	}
+
+	m.events.Publish(events.Event{
+		Type:    events.ExecutionStarted,
+		Plugin:  name,
+		Version: info.Version,
+		Runtime: info.Runtime,
+	})
+
+	finish := m.metrics.StartExecution(info.Runtime)
+	start := time.Now()
+
+	result, err := executor.Execute(ctx, name, opts)
+
+	if sandbox != nil {
+		if err == nil && result != nil && result.Success {
+			if commitErr := sandbox.Commit(); commitErr != nil {
+				err = fmt.Errorf("failed to apply working directory changes, kept in %s: %w", sandbox.Copy, commitErr)
+			}
+		} else {
+			sandbox.Discard()
+		}
+
+		if result != nil {
+			result.WorkingDir = sandbox.Original
+		}
+	}
+
+	finish(err == nil && result != nil && result.Success)
+
+	finished := events.Event{
+		Type:     events.ExecutionFinished,
+		Plugin:   name,
+		Version:  info.Version,
+		Runtime:  info.Runtime,
+		Metadata: map[string]string{"duration": time.Since(start).String()},
+		Err:      err,
+	}
+
+	if result != nil {
+		finished.Metadata["exit_code"] = fmt.Sprintf("%d", result.ExitCode)
+	}
+
+	m.events.Publish(finished)
+
+	if err := m.recordExecution(info, name, opts, start, result, err); err != nil {
+		m.logger.Error(err, "failed to record execution history", "plugin", name)
+	}
+
+	if err != nil {
+		m.metrics.Failed("execute", metrics.ReasonExecute)
+		return nil, err
+	}
+
+	if err := m.ReportExecution(ctx, name, result); err != nil {
+		m.logger.Error(err, "failed to record execution outcome", "plugin", name)
+	}
+
+	return result, nil
+}
+
+// readInfo reads the metadata of an installed plugin under its shared lock
+func (m *Manager) readInfo(name string) (*Info, error) {
+	defer m.plugins.rlock(name)()
+
+	return m.readLayered(name)
+}
+
+// executeOp runs execute with the failure wrapped in an *OpError
+func (m *Manager) executeOp(ctx context.Context, executor Executor, info *Info, name string, opts ExecuteOptions) (*ExecuteResult, error) {
+	result, err := m.execute(ctx, executor, info, name, opts)
+
+	return result, wrapOp(OpExecute, name, info.Metadata["source"], err)
+}
+
+// explainOp runs explain with the failure wrapped in an *OpError
+func (m *Manager) explainOp(ctx context.Context, executor Executor, info *Info, name string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	result, err := m.explain(ctx, executor, info, name, opts)
+
+	return result, wrapOp(OpExplain, name, info.Metadata["source"], err)
+}
@@ -3,6 +3,8 @@ package extension
 import (
 	"context"
 	"time"
+
+	"github.com/edsonmichaque/pluginkit/redact"
 )
 
 // ExecuteOptions contains parameters for plugin execution
@@ -10,6 +12,19 @@ type ExecuteOptions struct {
 	Args        []string          // Command line arguments
 	Environment map[string]string // Environment variables
 	WorkingDir  string            // Working directory for the plugin
+	Permissions *Permissions      // Capabilities granted to the plugin, nil for unrestricted
+	Redactor    *redact.Redactor  // Hides secrets in the result, nil for the default patterns
+	Priority    int               // Scheduling priority when executions are queued, higher runs first
+	Volumes     []Volume          // Named volumes to mount, defaults to the ones the plugin declares
+	Image       string            // Container image to run, defaults to the one built at install time or the plugin name
+
+	// CopyOnWrite runs the plugin against a temporary copy of WorkingDir
+	// and only applies its changes to WorkingDir when it succeeds
+	CopyOnWrite bool
+
+	// IgnoreStatus runs disabled or quarantined plugins. It is meant for
+	// administrative use such as diagnosing a quarantined plugin.
+	IgnoreStatus bool
 }
 
 // ExecuteResult contains the output of plugin execution
@@ -20,11 +35,96 @@ type ExecuteResult struct {
 	StartTime   time.Time         // Time when the plugin execution started
 	EndTime     time.Time         // Time when the plugin execution ended
 	Duration    time.Duration     // Total execution duration
-	CommandLine string            // Full command line that was executed
+	CommandLine string            // Human readable command line, not safe to re-parse
+	Command     []string          // Program and arguments that were executed
+	Image       string            // Container image, VM disk or module that was run, if any
+	Mounts      []Mount           // Host paths exposed to the plugin
+	Network     string            // Network mode the plugin ran with, if the runtime isolates it
 	WorkingDir  string            // Working directory used for execution
 	Environment map[string]string // Environment variables used
 	PID         int               // Process ID of the executed plugin
 	Success     bool              // Whether the execution was successful (ExitCode == 0)
+	Structured  map[string]any    // Machine-readable result emitted by the plugin, if any
+	Timings     *Timings          // Breakdown of Duration, for runtimes that measure it
+}
+
+// Timings breaks down where an execution spent its time. Phases a runtime
+// does not measure are zero.
+type Timings struct {
+	Compile     time.Duration // Compiling the plugin, e.g. a WASM module
+	Instantiate time.Duration // Instantiating the compiled plugin
+	Run         time.Duration // Wall-clock time spent in the plugin's entrypoint
+	CPU         time.Duration // CPU time spent in the plugin's entrypoint, if measurable
+}
+
+// Mount describes a host path made available to a plugin
+type Mount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// ExecutionPlan describes how an executor would run a plugin
+type ExecutionPlan struct {
+	Runtime     string            `json:"runtime,omitempty"`
+	Command     []string          `json:"command"`               // Program and arguments that would be run
+	CommandLine string            `json:"commandLine"`           // Human readable form of Command
+	Image       string            `json:"image,omitempty"`       // Container image, VM disk or module
+	Mounts      []Mount           `json:"mounts,omitempty"`      // Host paths exposed to the plugin
+	Network     string            `json:"network,omitempty"`     // Network mode, "none" when access is denied
+	Security    []string          `json:"security,omitempty"`    // Isolation and resource limit flags
+	Environment map[string]string `json:"environment,omitempty"` // Environment after permission filtering
+	WorkingDir  string            `json:"workingDir,omitempty"`
+}
+
+// Explainer is implemented by executors that can describe an execution
+// without running anything
+type Explainer interface {
+	// Explain returns the plan Execute would follow for the same arguments
+	Explain(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error)
+}
+
+// Pinger is implemented by executors that can check their backend is
+// reachable, such as a running container daemon or a responsive remote host
+type Pinger interface {
+	// Ping returns an error when the executor could not run a plugin now
+	Ping(ctx context.Context) error
+}
+
+// Closer is implemented by executors that hold resources beyond a single
+// execution, such as a shared runtime or loaded libraries
+type Closer interface {
+	// Close waits for in-flight executions until ctx is done, then releases
+	// the executor's resources. Executions started after Close fail.
+	Close(ctx context.Context) error
+}
+
+// RedactPlan hides the values of sensitive environment variables in the
+// plan's Command, CommandLine and Environment fields
+func RedactPlan(r *redact.Redactor, plan *ExecutionPlan) *ExecutionPlan {
+	if plan == nil {
+		return nil
+	}
+
+	plan.Command = redactCommand(r, plan.Command, plan.Environment)
+	plan.CommandLine = r.String(plan.CommandLine, plan.Environment)
+	plan.Environment = r.Map(plan.Environment)
+
+	return plan
+}
+
+// RedactResult hides the values of sensitive environment variables in the
+// result's Environment, Command and CommandLine fields
+func RedactResult(r *redact.Redactor, result *ExecuteResult) *ExecuteResult {
+	if result == nil {
+		return nil
+	}
+
+	result.Command = redactCommand(r, result.Command, result.Environment)
+	result.CommandLine = r.String(result.CommandLine, result.Environment)
+	result.Environment = r.Map(result.Environment)
+
+	return result
 }
 
 // Executor defines the interface for plugin execution
@@ -35,3 +135,18 @@ type Executor interface {
 	// Execute runs a plugin with the given options
 	Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error)
 }
+
+// redactCommand returns a copy of command with sensitive values from env
+// hidden in every argument
+func redactCommand(r *redact.Redactor, command []string, env map[string]string) []string {
+	if command == nil {
+		return nil
+	}
+
+	redacted := make([]string, len(command))
+	for i, arg := range command {
+		redacted[i] = r.String(arg, env)
+	}
+
+	return redacted
+}
@@ -0,0 +1,176 @@
+// Package extensiontest runs complete plugin flows, from install to
+// execution, hermetically. A Harness installs plugins from an in-memory
+// store into a temporary plugin directory and runs them with a scriptable
+// executor, so tests neither reach the network, start processes nor touch
+// the user's plugins.
+//
+// Releases are added with AddRelease or loaded from fixtures. Fixture files
+// are the content of a release and are laid out by plugin and version:
+//
+//	testdata/plugins/hello/1.0.0/hello
+//	testdata/plugins/acme/tools/2.1.0/tools.tar.gz
+//
+// The plugin name is the path up to the version directory, so names of the
+// form owner/name are nested one level deeper.
+package extensiontest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/runtime/runtimetest"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+// DefaultRuntime is the runtime of releases that do not set one, run by the
+// harness's Executor
+const DefaultRuntime = "native"
+
+// Options controls a Harness
+type Options struct {
+	Runtimes map[string]extension.Executor // Executors of runtimes other than DefaultRuntime
+}
+
+// Harness is an in-memory store, a temporary plugin directory and a Manager
+// installing from one into the other
+type Harness struct {
+	PluginDir string
+	Store     *storetest.Fake
+	Executor  *runtimetest.Fake // Runs plugins of DefaultRuntime
+	Manager   *extension.Manager
+
+	t testing.TB
+}
+
+// New creates a harness serving the releases in fixtures, which may be nil
+// when every release is added with AddRelease. Everything is torn down when
+// the test ends.
+func New(t testing.TB, fixtures fs.FS, opts Options) *Harness {
+	t.Helper()
+
+	logger := testr.NewWithInterface(t, testr.Options{})
+
+	h := &Harness{
+		PluginDir: t.TempDir(),
+		Store:     storetest.New(),
+		Executor:  runtimetest.New(),
+		t:         t,
+	}
+
+	if fixtures != nil {
+		if err := h.loadFixtures(fixtures); err != nil {
+			t.Fatalf("failed to load fixtures: %v", err)
+		}
+	}
+
+	h.Manager = extension.NewManager(h.PluginDir, h.Store, logger).
+		WithExecutor(DefaultRuntime, h.Executor)
+
+	for runtime, executor := range opts.Runtimes {
+		h.Manager.WithExecutor(runtime, executor)
+	}
+
+	t.Cleanup(func() {
+		if err := h.Manager.Close(context.Background()); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	})
+
+	return h
+}
+
+// AddRelease makes a release available for install and upgrade. The
+// release runs on DefaultRuntime unless it sets another runtime.
+func (h *Harness) AddRelease(info extension.Info) {
+	h.t.Helper()
+
+	if info.Name == "" || info.Version == "" {
+		h.t.Fatalf("release needs a name and a version: %+v", info)
+	}
+
+	if info.Runtime == "" {
+		info.Runtime = DefaultRuntime
+	}
+
+	h.Store.Add(info)
+}
+
+// Install installs a plugin, failing the test on error
+func (h *Harness) Install(name string, opts extension.InstallOptions) {
+	h.t.Helper()
+
+	if err := h.Manager.Install(context.Background(), name, opts); err != nil {
+		h.t.Fatalf("failed to install %s: %v", name, err)
+	}
+}
+
+// Upgrade upgrades an installed plugin to version, failing the test on
+// error
+func (h *Harness) Upgrade(name, version string) {
+	h.t.Helper()
+
+	if err := h.Manager.Upgrade(context.Background(), name, version); err != nil {
+		h.t.Fatalf("failed to upgrade %s: %v", name, err)
+	}
+}
+
+// Execute runs an installed plugin, failing the test on error
+func (h *Harness) Execute(name string, opts extension.ExecuteOptions) *extension.ExecuteResult {
+	h.t.Helper()
+
+	result, err := h.Manager.Execute(context.Background(), name, opts)
+	if err != nil {
+		h.t.Fatalf("failed to execute %s: %v", name, err)
+	}
+
+	return result
+}
+
+// Installed returns the metadata of an installed plugin, failing the test
+// when it is not installed
+func (h *Harness) Installed(name string) *extension.Info {
+	h.t.Helper()
+
+	info, err := h.Manager.Fetch(context.Background(), name)
+	if err != nil {
+		h.t.Fatalf("failed to read installed plugin %s: %v", name, err)
+	}
+
+	return info
+}
+
+// loadFixtures adds a release for every file of fixtures
+func (h *Harness) loadFixtures(fixtures fs.FS) error {
+	return fs.WalkDir(fixtures, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		dir := path.Dir(p)
+		name, version := path.Dir(dir), path.Base(dir)
+
+		if name == "." || dir == "." {
+			return fmt.Errorf("fixture %s is not in a plugin and version directory", p)
+		}
+
+		content, err := fs.ReadFile(fixtures, p)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture %s: %w", p, err)
+		}
+
+		h.AddRelease(extension.Info{
+			Name:     name,
+			Version:  version,
+			FileName: path.Base(p),
+			Content:  content,
+		})
+
+		return nil
+	})
+}
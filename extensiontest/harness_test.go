@@ -0,0 +1,58 @@
+package extensiontest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/extensiontest"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestInstallExecuteUpgrade(t *testing.T) {
+	h := extensiontest.New(t, os.DirFS("testdata/plugins"), extensiontest.Options{})
+
+	h.Executor.Respond("hello", extension.ExecuteResult{Stdout: "hello\n"})
+
+	h.Install("hello", extension.InstallOptions{})
+
+	if info := h.Installed("hello"); info.Version != "1.0.0" {
+		t.Errorf("installed version %s, want 1.0.0 from the fixtures", info.Version)
+	}
+
+	if result := h.Execute("hello", extension.ExecuteOptions{Args: []string{"world"}}); result.Stdout != "hello\n" {
+		t.Errorf("Execute() stdout = %q, want hello", result.Stdout)
+	}
+
+	h.AddRelease(extension.Info{Name: "hello", Version: "1.1.0", FileName: "hello", Content: []byte("#!/bin/sh\necho hello 1.1.0\n")})
+	h.Upgrade("hello", "latest")
+
+	if info := h.Installed("hello"); info.Version != "1.1.0" || info.Metadata["upgraded_from"] != "1.0.0" {
+		t.Errorf("upgraded to %s from %s, want 1.1.0 from 1.0.0", info.Version, info.Metadata["upgraded_from"])
+	}
+
+	data, err := os.ReadFile(filepath.Join(h.PluginDir, "hello", "hello", "hello"))
+	if err != nil || string(data) != "#!/bin/sh\necho hello 1.1.0\n" {
+		t.Errorf("installed plugin file = %q, %v, want the 1.1.0 release", data, err)
+	}
+
+	h.Execute("hello", extension.ExecuteOptions{})
+
+	calls := h.Executor.Calls()
+	if len(calls) != 2 || calls[0].Plugin != "hello" || len(calls[0].Opts.Args) != 1 || calls[0].Opts.Args[0] != "world" {
+		t.Errorf("executor calls = %+v, want two executions of hello", calls)
+	}
+
+	fetched := 0
+
+	for _, call := range h.Store.Calls() {
+		if call.Op == storetest.OpFetch {
+			fetched++
+		}
+	}
+
+	if fetched != 2 {
+		t.Errorf("store fetched %d releases, want 2", fetched)
+	}
+}
@@ -0,0 +1,94 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ErrRunNotApproved is returned when the host declines to run a plugin for
+// the first time, or after its file changed
+var ErrRunNotApproved = errors.New("plugin run was not approved")
+
+// RunApproval is what the host is asked to confirm before a plugin runs
+// for the first time
+type RunApproval struct {
+	Plugin      string
+	Version     string
+	Source      string // Configured store the plugin was installed from, empty for the default store
+	Store       string // Type of that store
+	Fingerprint string // SHA-256 of the plugin file, or the image ID of container plugins
+	Permissions *Permissions
+
+	// Previous is the fingerprint approved before, set when the plugin
+	// changed since without being upgraded
+	Previous string
+}
+
+// RunApprovalFunc asks the host whether a plugin may run. Returning false
+// refuses the execution.
+type RunApprovalFunc func(ctx context.Context, approval RunApproval) (bool, error)
+
+// WithRunApproval asks approve before the first execution of every
+// installed plugin and pins the approved fingerprint. The plugin runs
+// without asking while its fingerprint is unchanged; a change made outside
+// of Upgrade asks again. Upgrading an approved plugin pins the new version.
+func (m *Manager) WithRunApproval(approve RunApprovalFunc) *Manager {
+	m.runApproval = approve
+	return m
+}
+
+// approveRun asks for approval when the plugin's fingerprint is not pinned
+func (m *Manager) approveRun(ctx context.Context, name string, info *Info) error {
+	if m.runApproval == nil {
+		return nil
+	}
+
+	fingerprint := runFingerprint(filepath.Join(m.pluginDir, name), info)
+	if fingerprint == "" {
+		return fmt.Errorf("cannot fingerprint plugin %s for approval", name)
+	}
+
+	pinned := info.Metadata["approved_fingerprint"]
+	if pinned == fingerprint {
+		return nil
+	}
+
+	ok, err := m.runApproval(ctx, RunApproval{
+		Plugin:      name,
+		Version:     info.Version,
+		Source:      info.Metadata["source"],
+		Store:       info.Store,
+		Fingerprint: fingerprint,
+		Permissions: info.Permissions,
+		Previous:    pinned,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to approve run: %w", err)
+	}
+
+	if !ok {
+		return ErrRunNotApproved
+	}
+
+	defer m.plugins.lock(name)()
+
+	return m.updateMetadata(name, func(info *Info) error {
+		info.Metadata["approved_fingerprint"] = fingerprint
+		info.Metadata["approved"] = time.Now().Format(time.RFC3339)
+
+		return nil
+	})
+}
+
+// runFingerprint identifies what runs: the plugin file or, for container
+// plugins without one, the image built at install time
+func runFingerprint(dir string, info *Info) string {
+	if sum, err := fileDigest(OSFS{}, binaryPath(dir, info)); err == nil {
+		return sum
+	}
+
+	return info.Image
+}
@@ -0,0 +1,137 @@
+package extension
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the filesystem the Manager keeps installed plugins in. Paths are
+// native paths as passed to NewManager, not the slash-separated paths of
+// io/fs. OSFS is the default; MemFS keeps everything in memory for tests.
+//
+// Installs, upgrades, uninstalls, archive extraction, metadata and the
+// bill of files go through FS. Executors run plugins from real paths, so
+// running plugins still requires OSFS. Errors must wrap the fs.Err values
+// the os package returns, and ReadDir sorts entries by name.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Chmod(name string, mode fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+}
+
+// File is an open file of an FS
+type File interface {
+	fs.File
+	io.Writer
+	io.ReaderAt
+	Name() string
+	Sync() error
+}
+
+// OSFS is the FS of the operating system
+type OSFS struct{}
+
+func (OSFS) Open(name string) (File, error) { return osFile(os.Open(name)) }
+
+func (OSFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return osFile(os.OpenFile(name, flag, perm))
+}
+
+func (OSFS) CreateTemp(dir, pattern string) (File, error) {
+	return osFile(os.CreateTemp(dir, pattern))
+}
+
+func (OSFS) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) }
+
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (OSFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+
+func (OSFS) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+func (OSFS) Remove(name string) error { return os.Remove(name) }
+
+func (OSFS) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// osFile keeps a failed open from returning a non-nil File holding a nil
+// *os.File
+func osFile(f *os.File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// WithFS sets the filesystem plugins are installed in, OSFS by default.
+// It must be called before the Manager is used, as it drops the metadata
+// cache.
+func (m *Manager) WithFS(fsys FS) *Manager {
+	m.fs = fsys
+	m.cache = newInfoCache(fsys)
+
+	return m
+}
+
+// walkDir walks the tree rooted at root like filepath.WalkDir, in lexical
+// order, without following symbolic links
+func walkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	stat, err := fsys.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	err = walk(fsys, root, fs.FileInfoToDirEntry(stat), fn)
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+
+	return err
+}
+
+func walk(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walk(fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
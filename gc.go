@@ -0,0 +1,96 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// leftoverSuffixes mark the directories installs, upgrades and uninstalls
+// create next to a plugin directory and remove once they complete
+var leftoverSuffixes = []string{".upgrade", ".backup", ".removing"}
+
+// CollectGarbage removes the directories interrupted installs, upgrades and
+// uninstalls left in the plugin directory and returns their paths. With
+// dryRun they are only listed. Directories of operations still in progress
+// are left alone.
+func (m *Manager) CollectGarbage(ctx context.Context, dryRun bool) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before collecting garbage: %w", err)
+	}
+
+	entries, err := m.fs.ReadDir(m.pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	var removed []string
+
+	for _, entry := range entries {
+		name, ok := leftoverOf(entry.Name())
+		if !ok || !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(m.pluginDir, entry.Name())
+
+		if dryRun {
+			removed = append(removed, path)
+			continue
+		}
+
+		ok, err := m.removeLeftover(name, path)
+		if err != nil {
+			return removed, err
+		}
+
+		if ok {
+			removed = append(removed, path)
+		}
+	}
+
+	return removed, nil
+}
+
+// removeLeftover removes a leftover directory once no operation on its
+// plugin is running. It reports false when the operation finished and
+// removed the directory meanwhile.
+func (m *Manager) removeLeftover(name, path string) (bool, error) {
+	defer m.plugins.lock(name)()
+
+	if _, err := m.fs.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	m.dirMu.Lock()
+	defer m.dirMu.Unlock()
+
+	if err := m.fs.RemoveAll(path); err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+
+	m.logger.Info("removed leftover directory", "plugin", name, "path", path)
+
+	return true, nil
+}
+
+// leftoverOf returns the plugin a leftover directory belongs to
+func leftoverOf(dirName string) (string, bool) {
+	for _, suffix := range leftoverSuffixes {
+		if name, ok := strings.CutSuffix(dirName, suffix); ok {
+			if suffix == ".removing" {
+				name, ok = strings.CutPrefix(name, ".")
+			}
+
+			return name, ok && name != ""
+		}
+	}
+
+	return "", false
+}
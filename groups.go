@@ -0,0 +1,395 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// groupsDir holds the definitions of plugin groups
+const groupsDir = ".groups"
+
+// Group is a named set of plugins, such as kubernetes-tools, that is
+// installed, upgraded, enabled or removed as a unit. Installed members
+// record the groups they belong to in Info.Groups.
+type Group struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Plugins     []string `json:"plugins"` // Plugin references, [store/]name[@version]
+}
+
+var groupNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// Validate checks that the group has a usable name and no duplicate members
+func (g Group) Validate() error {
+	if !groupNamePattern.MatchString(g.Name) {
+		return fmt.Errorf("invalid group name %q", g.Name)
+	}
+
+	seen := make(map[string]bool, len(g.Plugins))
+	for _, ref := range g.Plugins {
+		name := ParseReference(ref, nil).Name
+		if name == "" {
+			return fmt.Errorf("group %s has an empty plugin reference", g.Name)
+		}
+
+		if seen[ref] {
+			return fmt.Errorf("group %s lists %s more than once", g.Name, ref)
+		}
+
+		seen[ref] = true
+	}
+
+	return nil
+}
+
+// DefineGroup creates or replaces a group and updates the membership
+// recorded by installed plugins to match it
+func (m *Manager) DefineGroup(ctx context.Context, group Group) error {
+	return wrapGroupOp(OpDefineGroup, group.Name, m.defineGroup(ctx, group))
+}
+
+func (m *Manager) defineGroup(ctx context.Context, group Group) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before defining group: %w", err)
+	}
+
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(group, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal group: %w", err)
+	}
+
+	dir := filepath.Join(m.pluginDir, groupsDir)
+	if err := m.fs.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create groups directory: %w", err)
+	}
+
+	if err := writeFileSync(m.fs, m.groupPath(group.Name), data); err != nil {
+		return fmt.Errorf("failed to save group: %w", err)
+	}
+
+	if err := syncDir(m.fs, dir); err != nil {
+		return err
+	}
+
+	return m.syncMembership(ctx, group.Name, m.groupMembers(&group))
+}
+
+// GetGroup returns the definition of a group
+func (m *Manager) GetGroup(ctx context.Context, name string) (*Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before reading group: %w", err)
+	}
+
+	return m.readGroup(name)
+}
+
+// Groups returns every defined group, sorted by name
+func (m *Manager) Groups(ctx context.Context) ([]Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before listing groups: %w", err)
+	}
+
+	entries, err := m.fs.ReadDir(filepath.Join(m.pluginDir, groupsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read groups directory: %w", err)
+	}
+
+	var groups []Group
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		group, err := m.readGroup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, *group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups, nil
+}
+
+// DeleteGroup removes the definition of a group. Its members stay
+// installed and no longer record the group.
+func (m *Manager) DeleteGroup(ctx context.Context, name string) error {
+	return wrapGroupOp(OpDeleteGroup, name, m.deleteGroup(ctx, name))
+}
+
+func (m *Manager) deleteGroup(ctx context.Context, name string) error {
+	if _, err := m.GetGroup(ctx, name); err != nil {
+		return err
+	}
+
+	if err := m.syncMembership(ctx, name, nil); err != nil {
+		return err
+	}
+
+	if err := m.fs.Remove(m.groupPath(name)); err != nil {
+		return fmt.Errorf("failed to remove group: %w", err)
+	}
+
+	return nil
+}
+
+// InstallGroup installs the members of a group that are not installed yet,
+// at the version their reference pins, and records their membership. Every
+// member is attempted; the errors of those that failed are joined.
+func (m *Manager) InstallGroup(ctx context.Context, name string, opts InstallOptions) error {
+	return wrapGroupOp(OpInstallGroup, name, m.installGroup(ctx, name, opts))
+}
+
+func (m *Manager) installGroup(ctx context.Context, name string, opts InstallOptions) error {
+	group, err := m.GetGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	installed, err := m.installedNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, ref := range group.Plugins {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("group install cancelled: %w", err)
+		}
+
+		if installed[m.parseReference(ref).Name] {
+			continue
+		}
+
+		if err := m.Install(ctx, ref, opts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to install %s: %w", ref, err))
+		}
+	}
+
+	if err := m.syncMembership(ctx, name, m.groupMembers(group)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// UpgradeGroup upgrades the installed members of a group to the version
+// their reference pins, or to the latest version. Members that are already
+// up to date are skipped.
+func (m *Manager) UpgradeGroup(ctx context.Context, name string) error {
+	return wrapGroupOp(OpUpgradeGroup, name, m.upgradeGroup(ctx, name))
+}
+
+func (m *Manager) upgradeGroup(ctx context.Context, name string) error {
+	group, err := m.GetGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	installed, err := m.installedNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, ref := range group.Plugins {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("group upgrade cancelled: %w", err)
+		}
+
+		r := m.parseReference(ref)
+		if !installed[r.Name] {
+			continue
+		}
+
+		version := r.Version
+		if version == "" {
+			version = "latest"
+		}
+
+		if err := m.Upgrade(ctx, r.Name, version); err != nil && !errors.Is(err, ErrUpToDate) {
+			errs = append(errs, fmt.Errorf("failed to upgrade %s: %w", r.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetGroupStatus enables or disables the installed members of a group
+func (m *Manager) SetGroupStatus(ctx context.Context, name string, status Status) error {
+	return wrapGroupOp(OpSetGroupStatus, name, m.setGroupStatus(ctx, name, status))
+}
+
+func (m *Manager) setGroupStatus(ctx context.Context, name string, status Status) error {
+	group, err := m.GetGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	installed, err := m.installedNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for member := range m.groupMembers(group) {
+		if installed[member] {
+			names = append(names, member)
+		}
+	}
+
+	sort.Strings(names)
+
+	return m.SetStatus(ctx, names, status)
+}
+
+// UninstallGroup uninstalls the members of a group. Members that also
+// belong to another group stay installed and only leave this one. The
+// group itself stays defined and can be installed again.
+func (m *Manager) UninstallGroup(ctx context.Context, name string, opts UninstallOptions) error {
+	return wrapGroupOp(OpUninstallGroup, name, m.uninstallGroup(ctx, name, opts))
+}
+
+func (m *Manager) uninstallGroup(ctx context.Context, name string, opts UninstallOptions) error {
+	if _, err := m.GetGroup(ctx, name); err != nil {
+		return err
+	}
+
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, info := range plugins {
+		if !slices.Contains(info.Groups, name) {
+			continue
+		}
+
+		if len(info.Groups) > 1 {
+			if err := m.setMembership(info.Name, name, false); err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		if err := m.UninstallWithOptions(ctx, info.Name, opts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to uninstall %s: %w", info.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncMembership makes installed plugins record the group exactly when they
+// are among members
+func (m *Manager) syncMembership(ctx context.Context, group string, members map[string]bool) error {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, info := range plugins {
+		if slices.Contains(info.Groups, group) == members[info.Name] {
+			continue
+		}
+
+		if err := m.setMembership(info.Name, group, members[info.Name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setMembership adds a plugin to or removes it from a group in its metadata
+func (m *Manager) setMembership(name, group string, member bool) error {
+	defer m.plugins.lock(name)()
+
+	return m.updateMetadata(name, func(info *Info) error {
+		info.Groups = slices.DeleteFunc(info.Groups, func(g string) bool { return g == group })
+		if member {
+			info.Groups = append(info.Groups, group)
+			sort.Strings(info.Groups)
+		}
+
+		return nil
+	})
+}
+
+// groupMembers returns the local names of the plugins of a group
+func (m *Manager) groupMembers(group *Group) map[string]bool {
+	members := make(map[string]bool, len(group.Plugins))
+	for _, ref := range group.Plugins {
+		members[m.parseReference(ref).Name] = true
+	}
+
+	return members
+}
+
+func (m *Manager) installedNames(ctx context.Context) (map[string]bool, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(plugins))
+	for _, info := range plugins {
+		names[info.Name] = true
+	}
+
+	return names, nil
+}
+
+func (m *Manager) readGroup(name string) (*Group, error) {
+	if !groupNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid group name %q", name)
+	}
+
+	data, err := m.fs.ReadFile(m.groupPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("group %s is not defined", name)
+		}
+
+		return nil, fmt.Errorf("failed to read group: %w", err)
+	}
+
+	var group Group
+	if err := json.Unmarshal(data, &group); err != nil {
+		return nil, fmt.Errorf("failed to parse group %s: %w", name, err)
+	}
+
+	return &group, nil
+}
+
+// groupPath returns where the definition of a group is stored
+func (m *Manager) groupPath(name string) string {
+	return filepath.Join(m.pluginDir, groupsDir, name+".json")
+}
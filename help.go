@@ -0,0 +1,197 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HelpFlag is the argument plugins without a man page are run with to
+// capture their help
+const HelpFlag = "--help"
+
+// helpFile caches the help a plugin printed, so that it only runs once per
+// installed version
+const helpFile = "help.json"
+
+// HelpSource tells where the text of a plugin's help comes from
+type HelpSource string
+
+const (
+	HelpManifest HelpSource = "manifest" // Only the description and commands of the manifest
+	HelpManPage  HelpSource = "man"      // A man page shipped with the plugin
+	HelpOutput   HelpSource = "output"   // The output of the plugin run with HelpFlag
+)
+
+// Help is the documentation of an installed plugin
+type Help struct {
+	Plugin      string     `json:"plugin"`
+	Version     string     `json:"version,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Commands    []Command  `json:"commands,omitempty"`
+	Text        string     `json:"text,omitempty"` // Man page source or captured help, empty for HelpManifest
+	Source      HelpSource `json:"source"`
+}
+
+type cachedHelp struct {
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+// Help returns the documentation of an installed plugin: the man page it
+// ships as man/NAME.N or NAME.N in its directory, or else what it prints
+// when run with HelpFlag by the executor registered for its runtime. The
+// captured output is cached until the plugin changes version. Plugins that
+// are disabled, or fail to print their help, are described by their
+// manifest alone.
+func (m *Manager) Help(ctx context.Context, name string) (*Help, error) {
+	return m.help(ctx, name, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.Execute(ctx, name, opts)
+	})
+}
+
+// HelpWith is Help capturing the help of plugins with the given executor
+func (m *Manager) HelpWith(ctx context.Context, executor Executor, name string) (*Help, error) {
+	return m.help(ctx, name, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.ExecuteWith(ctx, executor, name, opts)
+	})
+}
+
+// Manual returns the documentation of every installed plugin, sorted by
+// name, for hosts rendering the help of all their plugins at once
+func (m *Manager) Manual(ctx context.Context) ([]Help, error) {
+	return m.manual(ctx, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.Execute(ctx, name, opts)
+	})
+}
+
+// ManualWith is Manual capturing the help of plugins with the given
+// executor
+func (m *Manager) ManualWith(ctx context.Context, executor Executor) ([]Help, error) {
+	return m.manual(ctx, func(name string, opts ExecuteOptions) (*ExecuteResult, error) {
+		return m.ExecuteWith(ctx, executor, name, opts)
+	})
+}
+
+func (m *Manager) manual(ctx context.Context, run func(string, ExecuteOptions) (*ExecuteResult, error)) ([]Help, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manual := make([]Help, 0, len(plugins))
+
+	for _, info := range plugins {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		help, err := m.help(ctx, info.Name, run)
+		if err != nil {
+			return nil, err
+		}
+
+		manual = append(manual, *help)
+	}
+
+	sort.Slice(manual, func(i, j int) bool { return manual[i].Plugin < manual[j].Plugin })
+
+	return manual, nil
+}
+
+func (m *Manager) help(ctx context.Context, name string, run func(string, ExecuteOptions) (*ExecuteResult, error)) (*Help, error) {
+	info, err := m.readInfo(name)
+	if err != nil {
+		return nil, wrapOp(OpHelp, name, "", fmt.Errorf("plugin %s is not installed: %w", name, err))
+	}
+
+	help := &Help{
+		Plugin:      info.Name,
+		Version:     info.Version,
+		Description: info.Description,
+		Commands:    info.Commands,
+		Source:      HelpManifest,
+	}
+
+	dir, _ := m.locate(name)
+
+	if text, ok := readManPage(m.fs, dir, info); ok {
+		help.Text, help.Source = text, HelpManPage
+		return help, nil
+	}
+
+	if text, ok := readCachedHelp(m.fs, dir, info.Version); ok {
+		help.Text, help.Source = text, HelpOutput
+		return help, nil
+	}
+
+	if checkStatus(name, info.Status) != nil {
+		return help, nil
+	}
+
+	result, err := run(name, ExecuteOptions{Args: []string{HelpFlag}})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, wrapOp(OpHelp, name, info.Metadata["source"], ctxErr)
+		}
+
+		m.logger.Error(err, "failed to capture plugin help", "plugin", name)
+
+		return help, nil
+	}
+
+	text := truncateOutput(result.Stdout, 0)
+	if !result.Success || strings.TrimSpace(text) == "" {
+		m.logger.V(1).Info("plugin printed no help", "plugin", name, "exitCode", result.ExitCode)
+		return help, nil
+	}
+
+	help.Text, help.Source = text, HelpOutput
+
+	if err := writeCachedHelp(m.fs, dir, info.Version, text); err != nil {
+		m.logger.Error(err, "failed to cache plugin help", "plugin", name)
+	}
+
+	return help, nil
+}
+
+// readManPage returns the first man page found for the plugin, in any
+// section
+func readManPage(fsys FS, dir string, info *Info) (string, bool) {
+	for _, base := range []string{filepath.Join(dir, "man", info.Name), filepath.Join(dir, info.Name)} {
+		for section := 1; section <= 9; section++ {
+			data, err := fsys.ReadFile(fmt.Sprintf("%s.%d", base, section))
+			if err == nil {
+				return string(data), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func readCachedHelp(fsys FS, dir, version string) (string, bool) {
+	data, err := fsys.ReadFile(filepath.Join(dir, helpFile))
+	if err != nil {
+		return "", false
+	}
+
+	var cached cachedHelp
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Version != version {
+		return "", false
+	}
+
+	return cached.Text, true
+}
+
+func writeCachedHelp(fsys FS, dir, version, text string) error {
+	data, err := json.Marshal(cachedHelp{Version: version, Text: text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal help: %w", err)
+	}
+
+	return writeFileSync(fsys, filepath.Join(dir, helpFile), data)
+}
@@ -0,0 +1,235 @@
+package extension
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// historyFile holds the execution history of a plugin, one JSON record per line
+const historyFile = "history.jsonl"
+
+const (
+	defaultHistoryRecords = 100
+	defaultHistoryOutput  = 4096
+)
+
+// ExecutionRecord summarizes one execution of a plugin
+type ExecutionRecord struct {
+	Plugin     string         `json:"plugin"`
+	Version    string         `json:"version,omitempty"`
+	Runtime    string         `json:"runtime,omitempty"`
+	StartTime  time.Time      `json:"start_time"`
+	Duration   time.Duration  `json:"duration"`
+	ExitCode   int            `json:"exit_code"`
+	Success    bool           `json:"success"`
+	Error      string         `json:"error,omitempty"` // Set when the executor itself failed
+	Args       []string       `json:"args,omitempty"`
+	Stdout     string         `json:"stdout,omitempty"` // Truncated
+	Stderr     string         `json:"stderr,omitempty"` // Truncated
+	Structured map[string]any `json:"structured,omitempty"`
+}
+
+// HistoryOptions filters ExecutionHistory results
+type HistoryOptions struct {
+	Since      time.Time // Only executions started at or after Since
+	Until      time.Time // Only executions started before Until
+	FailedOnly bool      // Only unsuccessful executions
+	Limit      int       // Maximum number of records, newest first; 0 for all
+}
+
+// WithExecutionHistory sets how many execution records are kept per plugin
+// and how many bytes of each output stream are stored. A records limit of
+// zero or less disables history.
+func (m *Manager) WithExecutionHistory(records, outputBytes int) *Manager {
+	m.historyRecords = records
+	m.historyOutput = outputBytes
+	return m
+}
+
+// ExecutionHistory returns the recorded executions of a plugin, newest first
+func (m *Manager) ExecutionHistory(ctx context.Context, name string, opts HistoryOptions) ([]ExecutionRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before reading history: %w", err)
+	}
+
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	records, err := m.readHistory(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ExecutionRecord
+
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+
+		if !opts.Since.IsZero() && r.StartTime.Before(opts.Since) {
+			continue
+		}
+
+		if !opts.Until.IsZero() && !r.StartTime.Before(opts.Until) {
+			continue
+		}
+
+		if opts.FailedOnly && r.Success {
+			continue
+		}
+
+		result = append(result, r)
+
+		if opts.Limit > 0 && len(result) == opts.Limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// recordExecution appends an execution to the plugin's history and prunes
+// records beyond the retention limit
+func (m *Manager) recordExecution(info *Info, name string, opts ExecuteOptions, start time.Time, result *ExecuteResult, execErr error) error {
+	if m.historyRecords <= 0 {
+		return nil
+	}
+
+	record := ExecutionRecord{
+		Plugin:    name,
+		Version:   info.Version,
+		Runtime:   info.Runtime,
+		StartTime: start,
+		Duration:  time.Since(start),
+		Args:      opts.Args,
+	}
+
+	if execErr != nil {
+		record.ExitCode = -1
+		record.Error = execErr.Error()
+	}
+
+	if result != nil {
+		record.StartTime = result.StartTime
+		record.Duration = result.Duration
+		record.ExitCode = result.ExitCode
+		record.Success = result.Success
+		record.Stdout = truncateOutput(result.Stdout, m.historyOutput)
+		record.Stderr = truncateOutput(result.Stderr, m.historyOutput)
+		record.Structured = result.Structured
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution record: %w", err)
+	}
+
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	path := filepath.Join(m.pluginDir, name, historyFile)
+
+	f, err := m.fs.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open execution history: %w", err)
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write execution history: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write execution history: %w", err)
+	}
+
+	return m.pruneHistory(name)
+}
+
+// pruneHistory rewrites the history once it holds twice the retention
+// limit, so that appends stay cheap
+func (m *Manager) pruneHistory(name string) error {
+	records, err := m.readHistory(name)
+	if err != nil || len(records) < 2*m.historyRecords {
+		return err
+	}
+
+	records = records[len(records)-m.historyRecords:]
+
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal execution record: %w", err)
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := writeFileSync(m.fs, filepath.Join(m.pluginDir, name, historyFile), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to prune execution history: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) readHistory(name string) ([]ExecutionRecord, error) {
+	f, err := m.fs.Open(filepath.Join(m.pluginDir, name, historyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open execution history: %w", err)
+	}
+	defer f.Close()
+
+	var records []ExecutionRecord
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		var r ExecutionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// Skip a partially written line rather than losing the history
+			continue
+		}
+
+		records = append(records, r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read execution history: %w", err)
+	}
+
+	return records, nil
+}
+
+// truncateOutput converts captured output to a string of at most n bytes
+func truncateOutput(output interface{}, n int) string {
+	var s string
+
+	switch v := output.(type) {
+	case nil:
+		return ""
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if n > 0 && len(s) > n {
+		s = s[:n] + "...(truncated)"
+	}
+
+	return s
+}
@@ -0,0 +1,121 @@
+// Package hostctx defines the context document a host passes to the plugins
+// it runs. The host serializes a Context into the PLUGINKIT_HOST_CONTEXT
+// environment variable, or writes it to the plugin's stdin for runtimes that
+// cannot forward environment variables; plugins parse it with FromEnv or Read.
+package hostctx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvVar is the environment variable carrying the serialized context
+const EnvVar = "PLUGINKIT_HOST_CONTEXT"
+
+// APIVersion identifies the version of the context document
+const APIVersion = "pluginkit.host/v1"
+
+// Context describes the host a plugin is running under
+type Context struct {
+	APIVersion  string            `json:"apiVersion"`
+	HostName    string            `json:"hostName"`              // Name of the host application
+	HostVersion string            `json:"hostVersion"`           // Version of the host application
+	Plugin      string            `json:"plugin,omitempty"`      // Name the plugin was invoked as
+	ConfigDir   string            `json:"configDir,omitempty"`   // Directory for plugin configuration
+	DataDir     string            `json:"dataDir,omitempty"`     // Directory for persistent plugin data
+	AuthScopes  []string          `json:"authScopes,omitempty"`  // Scopes granted to the plugin by the host
+	Locale      string            `json:"locale,omitempty"`      // Preferred locale, e.g. en_US
+	Interactive bool              `json:"interactive,omitempty"` // Whether a user is attached to the terminal
+	Extra       map[string]string `json:"extra,omitempty"`       // Host specific values
+}
+
+// New creates a context for the given host, with the locale taken from the
+// environment
+func New(hostName, hostVersion string) *Context {
+	return &Context{
+		APIVersion:  APIVersion,
+		HostName:    hostName,
+		HostVersion: hostVersion,
+		Locale:      localeFromEnv(),
+	}
+}
+
+// Encode serializes the context
+func (c *Context) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal host context: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Environment returns a copy of env with the context added under EnvVar
+func (c *Context) Environment(env map[string]string) (map[string]string, error) {
+	encoded, err := c.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(env)+1)
+	for k, v := range env {
+		result[k] = v
+	}
+
+	result[EnvVar] = encoded
+
+	return result, nil
+}
+
+// FromEnv parses the context a host passed through EnvVar. It returns
+// ok=false when the plugin was not started by a pluginkit host.
+func FromEnv() (ctx *Context, ok bool, err error) {
+	value, ok := os.LookupEnv(EnvVar)
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, err = Read(strings.NewReader(value))
+
+	return ctx, true, err
+}
+
+// Read parses a context document, for example from stdin
+func Read(r io.Reader) (*Context, error) {
+	var c Context
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse host context: %w", err)
+	}
+
+	if c.APIVersion != APIVersion {
+		return nil, fmt.Errorf("unsupported host context version %q", c.APIVersion)
+	}
+
+	return &c, nil
+}
+
+// HasScope reports whether the host granted the given auth scope
+func (c *Context) HasScope(scope string) bool {
+	for _, s := range c.AuthScopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func localeFromEnv() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			// Strip the encoding, e.g. en_US.UTF-8
+			locale, _, _ := strings.Cut(value, ".")
+			return locale
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when requests to a host are rejected because its
+// circuit breaker has tripped
+type ErrCircuitOpen struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// CircuitBreaker trips per host after a number of consecutive failures and
+// rejects requests until a cooldown has elapsed. After the cooldown a single
+// trial request is let through; its outcome closes or re-opens the circuit.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures and stays open for cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a request to host may proceed
+func (b *CircuitBreaker) Allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok || c.failures < b.threshold {
+		return nil
+	}
+
+	if time.Now().Before(c.openUntil) || c.trial {
+		return &ErrCircuitOpen{Host: host, Until: c.openUntil}
+	}
+
+	// Half-open: let one trial request through
+	c.trial = true
+
+	return nil
+}
+
+// Success records a successful request to host and closes its circuit
+func (b *CircuitBreaker) Success(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.hosts, host)
+}
+
+// Release ends a request to host without recording an outcome, so a
+// half-open circuit lets another trial request through
+func (b *CircuitBreaker) Release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.hosts[host]; ok {
+		c.trial = false
+	}
+}
+
+// Failure records a failed request to host, opening the circuit once the
+// threshold is reached
+func (b *CircuitBreaker) Failure(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+
+	c.failures++
+	c.trial = false
+
+	if c.failures >= b.threshold {
+		c.openUntil = time.Now().Add(b.cooldown)
+	}
+}
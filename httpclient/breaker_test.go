@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func assertOpen(t *testing.T, b *CircuitBreaker, host string) {
+	t.Helper()
+
+	var open *ErrCircuitOpen
+	if err := b.Allow(host); !errors.As(err, &open) || open.Host != host {
+		t.Errorf("Allow(%s) = %v, want *ErrCircuitOpen", host, err)
+	}
+}
+
+func assertAllowed(t *testing.T, b *CircuitBreaker, host string) {
+	t.Helper()
+
+	if err := b.Allow(host); err != nil {
+		t.Errorf("Allow(%s) = %v, want the request let through", host, err)
+	}
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		b.Failure("a")
+		assertAllowed(t, b, "a")
+	}
+
+	b.Failure("a")
+	assertOpen(t, b, "a")
+	assertAllowed(t, b, "b")
+
+	// Failures only open the circuit when they are consecutive
+	b.Success("b")
+	b.Failure("b")
+	b.Failure("b")
+	b.Success("b")
+	b.Failure("b")
+	assertAllowed(t, b, "b")
+}
+
+func TestCircuitBreakerHalfOpenTrial(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond)
+
+	b.Failure("a")
+	assertOpen(t, b, "a")
+
+	time.Sleep(15 * time.Millisecond)
+
+	assertAllowed(t, b, "a")
+	assertOpen(t, b, "a")
+
+	// A trial abandoned without an outcome lets another one through
+	b.Release("a")
+	assertAllowed(t, b, "a")
+
+	// A failed trial re-opens the circuit for another cooldown
+	b.Failure("a")
+	assertOpen(t, b, "a")
+
+	time.Sleep(15 * time.Millisecond)
+
+	assertAllowed(t, b, "a")
+}
+
+func TestCircuitBreakerRecovers(t *testing.T) {
+	b := NewCircuitBreaker(2, 10*time.Millisecond)
+
+	b.Failure("a")
+	b.Failure("a")
+	assertOpen(t, b, "a")
+
+	time.Sleep(15 * time.Millisecond)
+
+	assertAllowed(t, b, "a")
+	b.Success("a")
+
+	for i := 0; i < 3; i++ {
+		assertAllowed(t, b, "a")
+	}
+
+	// The failure count starts over after recovering
+	b.Failure("a")
+	assertAllowed(t, b, "a")
+}
+
+func TestOpenCircuitDoesNotSpendRateLimit(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+
+	c := &Client{
+		BaseURL:        srv.URL,
+		RateLimiter:    NewRateLimiter(0.001, 1),
+		CircuitBreaker: NewCircuitBreaker(1, time.Minute),
+	}
+
+	c.CircuitBreaker.Failure(u.Host)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		var open *ErrCircuitOpen
+		if _, err := c.Get(ctx, "/items", nil); !errors.As(err, &open) {
+			t.Fatalf("Get() error = %v, want *ErrCircuitOpen without waiting", err)
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("server received %d requests through an open circuit", requests)
+	}
+
+	if delay := c.RateLimiter.reserve(u.Host); delay != 0 {
+		t.Errorf("rejected requests spent the rate limit, next request delayed by %s", delay)
+	}
+}
@@ -8,11 +8,18 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type Client struct {
 	BaseURL   string
 	AuthToken string
+
+	RateLimiter    *RateLimiter    // Optional per-host rate limiting
+	CircuitBreaker *CircuitBreaker // Optional per-host circuit breaker
+
+	transport  TransportOptions
+	httpClient *http.Client
 }
 
 // RequestOptions holds optional parameters for requests
@@ -26,6 +33,7 @@ type Response struct {
 	StatusCode int
 	Body       []byte
 	Headers    http.Header
+	URL        *url.URL // URL of the request, after redirects
 }
 
 // APIError represents an error response from the API
@@ -46,8 +54,35 @@ func New(baseURL, authToken string) *Client {
 	}
 }
 
+// WithTLS configures client certificates and custom CA bundles
+func (c *Client) WithTLS(opts TLSOptions) (*Client, error) {
+	c.transport.TLS = opts
+	return c, c.rebuildTransport()
+}
+
+// WithProxy routes requests through the given proxy
+func (c *Client) WithProxy(opts ProxyOptions) (*Client, error) {
+	c.transport.Proxy = opts
+	return c, c.rebuildTransport()
+}
+
+func (c *Client) rebuildTransport() error {
+	transport, err := NewTransport(c.transport)
+	if err != nil {
+		return err
+	}
+
+	c.httpClient = &http.Client{Transport: transport}
+
+	return nil
+}
+
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, body interface{}, opts *RequestOptions) (*Response, error) {
-	fullURL := fmt.Sprintf("%s%s", c.BaseURL, endpoint)
+	// Absolute endpoints, such as pagination links, are requested as they are
+	fullURL := endpoint
+	if u, err := url.Parse(endpoint); err != nil || !u.IsAbs() {
+		fullURL = fmt.Sprintf("%s%s", c.BaseURL, endpoint)
+	}
 
 	// Add query parameters to the URL if options are provided
 	if opts != nil && opts.QueryParams != nil {
@@ -56,7 +91,12 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 			q.Add(key, value)
 		}
 
-		fullURL = fmt.Sprintf("%s?%s", fullURL, q.Encode())
+		separator := "?"
+		if strings.Contains(fullURL, "?") {
+			separator = "&"
+		}
+
+		fullURL = fmt.Sprintf("%s%s%s", fullURL, separator, q.Encode())
 	}
 
 	var jsonBody []byte
@@ -74,8 +114,12 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Set default headers
-	req.Header.Set("Authorization", c.AuthToken)
+	// Set default headers. The token is only sent to the API itself, never
+	// to other hosts an absolute endpoint may point to.
+	if c.sameOrigin(req.URL) {
+		req.Header.Set("Authorization", c.AuthToken)
+	}
+
 	req.Header.Set("Content-Type", "application/json")
 
 	// Set optional headers
@@ -85,10 +129,34 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 		}
 	}
 
-	client := &http.Client{}
+	host := req.URL.Host
+
+	// Check the breaker first so requests to a host with an open circuit fail
+	// fast instead of waiting for, and spending, rate limiter tokens
+	if c.CircuitBreaker != nil {
+		if err := c.CircuitBreaker.Allow(host); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(ctx, host); err != nil {
+			if c.CircuitBreaker != nil {
+				c.CircuitBreaker.Release(host)
+			}
+
+			return nil, err
+		}
+	}
+
+	client := c.httpClient
+	if client == nil {
+		client = &http.Client{}
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
+		c.recordFailure(ctx, host)
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
@@ -96,6 +164,7 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 
 	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		c.recordFailure(ctx, host)
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
@@ -103,6 +172,13 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 		StatusCode: resp.StatusCode,
 		Body:       responseBody,
 		Headers:    resp.Header,
+		URL:        resp.Request.URL,
+	}
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		c.recordFailure(ctx, host)
+	} else if c.CircuitBreaker != nil {
+		c.CircuitBreaker.Success(host)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -116,6 +192,32 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 	return response, nil
 }
 
+// sameOrigin reports whether u has the scheme and host of BaseURL
+func (c *Client) sameOrigin(u *url.URL) bool {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Scheme, base.Scheme) && strings.EqualFold(u.Host, base.Host)
+}
+
+// recordFailure reports a failed request to the circuit breaker, if any. A
+// request abandoned because the caller's context ended says nothing about the
+// host, so it only releases the breaker's trial slot.
+func (c *Client) recordFailure(ctx context.Context, host string) {
+	if c.CircuitBreaker == nil {
+		return
+	}
+
+	if ctx.Err() != nil {
+		c.CircuitBreaker.Release(host)
+		return
+	}
+
+	c.CircuitBreaker.Failure(host)
+}
+
 func (c *Client) Get(ctx context.Context, endpoint string, opts *RequestOptions) (*Response, error) {
 	return c.doRequest(ctx, http.MethodGet, endpoint, nil, opts)
 }
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetJSON performs a GET request and decodes the JSON response body into T
+func GetJSON[T any](ctx context.Context, c *Client, endpoint string, opts *RequestOptions) (T, error) {
+	resp, err := c.Get(ctx, endpoint, opts)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return decodeJSON[T](resp)
+}
+
+// PostJSON performs a POST request and decodes the JSON response body into T
+func PostJSON[T any](ctx context.Context, c *Client, endpoint string, body interface{}, opts *RequestOptions) (T, error) {
+	resp, err := c.Post(ctx, endpoint, body, opts)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return decodeJSON[T](resp)
+}
+
+// PutJSON performs a PUT request and decodes the JSON response body into T
+func PutJSON[T any](ctx context.Context, c *Client, endpoint string, body interface{}, opts *RequestOptions) (T, error) {
+	resp, err := c.Put(ctx, endpoint, body, opts)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return decodeJSON[T](resp)
+}
+
+// PatchJSON performs a PATCH request and decodes the JSON response body into T
+func PatchJSON[T any](ctx context.Context, c *Client, endpoint string, body interface{}, opts *RequestOptions) (T, error) {
+	resp, err := c.Patch(ctx, endpoint, body, opts)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return decodeJSON[T](resp)
+}
+
+func decodeJSON[T any](resp *Response) (T, error) {
+	var result T
+
+	if len(resp.Body) == 0 {
+		return result, nil
+	}
+
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return result, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"strings"
+)
+
+// Page identifies a single page request of a paginated endpoint
+type Page struct {
+	Endpoint    string
+	QueryParams map[string]string
+}
+
+// NextPageFunc inspects a response and returns the next page to request.
+// It returns false when there are no more pages.
+type NextPageFunc func(resp *Response, current Page) (Page, bool)
+
+// Paginator iterates over the items of a paginated endpoint
+type Paginator[T any] struct {
+	Client   *Client
+	Endpoint string
+	Options  *RequestOptions
+
+	// Items extracts the page items from a response body. Defaults to
+	// decoding the body as a JSON array.
+	Items func(body []byte) ([]T, error)
+
+	// Next resolves the following page. Defaults to LinkHeaderNext.
+	Next NextPageFunc
+}
+
+// NewPaginator creates a paginator that follows Link headers
+func NewPaginator[T any](c *Client, endpoint string, opts *RequestOptions) *Paginator[T] {
+	return &Paginator[T]{
+		Client:   c,
+		Endpoint: endpoint,
+		Options:  opts,
+	}
+}
+
+// All returns an iterator over every item of every page. Iteration stops at
+// the first error, which is yielded together with the zero value of T. A next
+// page on another scheme or host than the client's BaseURL is an error.
+func (p *Paginator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		items := p.Items
+		if items == nil {
+			items = jsonArrayItems[T]
+		}
+
+		next := p.Next
+		if next == nil {
+			next = LinkHeaderNext(p.Client)
+		}
+
+		page := Page{Endpoint: p.Endpoint}
+
+		var headers map[string]string
+		if p.Options != nil {
+			page.QueryParams = p.Options.QueryParams
+			headers = p.Options.Headers
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, fmt.Errorf("pagination cancelled: %w", err))
+				return
+			}
+
+			resp, err := p.Client.Get(ctx, page.Endpoint, &RequestOptions{
+				Headers:     headers,
+				QueryParams: page.QueryParams,
+			})
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			pageItems, err := items(resp.Body)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range pageItems {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			var ok bool
+			if page, ok = next(resp, page); !ok {
+				return
+			}
+
+			// Pages are only followed on the API itself
+			if u, err := url.Parse(page.Endpoint); err == nil && u.IsAbs() && !p.Client.sameOrigin(u) {
+				yield(zero, fmt.Errorf("next page %s is not on %s", u.Redacted(), p.Client.BaseURL))
+				return
+			}
+		}
+	}
+}
+
+// Collect gathers every item of every page into a slice
+func (p *Paginator[T]) Collect(ctx context.Context) ([]T, error) {
+	var result []T
+
+	for item, err := range p.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// LinkHeaderNext follows the rel="next" entry of an RFC 8288 Link header.
+// Relative links are resolved against the URL of the response, or the
+// client's BaseURL when it is unknown, and the next page is requested at the
+// resulting absolute URL. The link already carries its query string, so no
+// extra parameters are sent.
+func LinkHeaderNext(c *Client) NextPageFunc {
+	return func(resp *Response, _ Page) (Page, bool) {
+		link := parseLinkHeader(resp.Headers.Get("Link"))["next"]
+		if link == "" {
+			return Page{}, false
+		}
+
+		target, err := url.Parse(link)
+		if err != nil {
+			return Page{}, false
+		}
+
+		base := resp.URL
+		if base == nil {
+			if base, err = url.Parse(c.BaseURL); err != nil {
+				return Page{}, false
+			}
+		}
+
+		return Page{Endpoint: base.ResolveReference(target).String()}, true
+	}
+}
+
+// CursorNext reads a cursor from the given top-level JSON field of the
+// response body and sends it as the param query parameter on the next request.
+func CursorNext(field, param string) NextPageFunc {
+	return func(resp *Response, current Page) (Page, bool) {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			return Page{}, false
+		}
+
+		var cursor string
+		if err := json.Unmarshal(body[field], &cursor); err != nil || cursor == "" {
+			return Page{}, false
+		}
+
+		query := make(map[string]string, len(current.QueryParams)+1)
+		for k, v := range current.QueryParams {
+			query[k] = v
+		}
+
+		query[param] = cursor
+
+		return Page{Endpoint: current.Endpoint, QueryParams: query}, true
+	}
+}
+
+// FieldItems extracts page items from the given top-level JSON field, for
+// APIs that wrap results in an envelope object
+func FieldItems[T any](field string) func(body []byte) ([]T, error) {
+	return func(body []byte) ([]T, error) {
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+		}
+
+		raw, ok := envelope[field]
+		if !ok {
+			return nil, nil
+		}
+
+		return jsonArrayItems[T](raw)
+	}
+}
+
+func jsonArrayItems[T any](body []byte) ([]T, error) {
+	var items []T
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal page items: %w", err)
+	}
+
+	return items, nil
+}
+
+// parseLinkHeader parses a Link header into a map of rel to URL
+func parseLinkHeader(header string) map[string]string {
+	links := make(map[string]string)
+
+	for _, part := range strings.Split(header, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		target = strings.Trim(target, "<>")
+
+		for _, param := range segments[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(key) != "rel" {
+				continue
+			}
+
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				links[rel] = target
+			}
+		}
+	}
+
+	return links
+}
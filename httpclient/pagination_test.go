@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLinkHeaderNext(t *testing.T) {
+	c := &Client{BaseURL: "https://api.example.com/v1/"}
+	current, _ := url.Parse("https://api.example.com/v1/items?page=1")
+
+	tests := []struct {
+		name string
+		link string
+		want string // Empty when there is no next page
+	}{
+		{name: "relative", link: `<items?page=2>; rel="next"`, want: "https://api.example.com/v1/items?page=2"},
+		{name: "root relative", link: `</v2/items?page=2>; rel="next"`, want: "https://api.example.com/v2/items?page=2"},
+		{name: "absolute same host", link: `<https://api.example.com/v1/items?page=2>; rel="next", <https://api.example.com/v1/items?page=9>; rel="last"`, want: "https://api.example.com/v1/items?page=2"},
+		{name: "foreign host", link: `<https://evil.example.net/items?page=2>; rel="next"`, want: "https://evil.example.net/items?page=2"},
+		{name: "last page", link: `<https://api.example.com/v1/items?page=1>; rel="first"`},
+		{name: "no header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{Headers: http.Header{}, URL: current}
+			if tt.link != "" {
+				resp.Headers.Set("Link", tt.link)
+			}
+
+			page, ok := LinkHeaderNext(c)(resp, Page{})
+			if ok != (tt.want != "") || page.Endpoint != tt.want {
+				t.Errorf("LinkHeaderNext() = %q, %v, want %q", page.Endpoint, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorNext(t *testing.T) {
+	next := CursorNext("next_cursor", "cursor")
+	current := Page{Endpoint: "/items", QueryParams: map[string]string{"per_page": "2"}}
+
+	page, ok := next(&Response{Body: []byte(`{"items": [], "next_cursor": "abc"}`)}, current)
+	if !ok || page.Endpoint != "/items" || page.QueryParams["cursor"] != "abc" || page.QueryParams["per_page"] != "2" {
+		t.Errorf("CursorNext() = %+v, %v, want the cursor added to the current page", page, ok)
+	}
+
+	if _, ok := current.QueryParams["cursor"]; ok {
+		t.Error("CursorNext() modified the query of the current page")
+	}
+
+	for _, body := range []string{`{"items": []}`, `{"next_cursor": ""}`, `not json`} {
+		if page, ok := next(&Response{Body: []byte(body)}, current); ok {
+			t.Errorf("CursorNext(%s) = %+v, want the last page", body, page)
+		}
+	}
+}
+
+func TestPaginatorFollowsLinksToTheLastPage(t *testing.T) {
+	var requests []string
+
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+
+		if r.Header.Get("Authorization") != "token" {
+			t.Errorf("%s: Authorization = %q, want the token", r.URL, r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.RequestURI() {
+		case "/api/items":
+			w.Header().Set("Link", `<items?page=2>; rel="next"`)
+			fmt.Fprint(w, `[1, 2]`)
+		case "/api/items?page=2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/items?page=3>; rel="next"`, srv.URL))
+			fmt.Fprint(w, `[3]`)
+		default:
+			fmt.Fprint(w, `[4]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL + "/api/", AuthToken: "token"}
+
+	items, err := NewPaginator[int](c, "items", nil).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if fmt.Sprint(items) != "[1 2 3 4]" {
+		t.Errorf("Collect() = %v, want [1 2 3 4]", items)
+	}
+
+	if want := "/api/items /api/items?page=2 /api/items?page=3"; strings.Join(requests, " ") != want {
+		t.Errorf("requested %v, want %s", requests, want)
+	}
+}
+
+func TestPaginatorStopsAtEarlyBreak(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Link", `<items?page=2>; rel="next"`)
+		fmt.Fprint(w, `[1, 2]`)
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL + "/"}
+
+	for item, err := range NewPaginator[int](c, "items", nil).All(context.Background()) {
+		if err != nil || item != 1 {
+			t.Fatalf("first item = %d, %v, want 1", item, err)
+		}
+
+		break
+	}
+
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
+
+func TestPaginatorRejectsForeignLinks(t *testing.T) {
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("foreign host was requested with Authorization %q", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `[]`)
+	}))
+	defer foreign.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=2>; rel="next"`, foreign.URL))
+		fmt.Fprint(w, `[1]`)
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL + "/", AuthToken: "token"}
+
+	if items, err := NewPaginator[int](c, "items", nil).Collect(context.Background()); err == nil {
+		t.Errorf("Collect() = %v, want an error for the foreign next page", items)
+	}
+}
+
+func TestAuthorizationIsOnlySentToBaseURL(t *testing.T) {
+	var got string
+
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer foreign.Close()
+
+	c := &Client{BaseURL: "https://api.example.com", AuthToken: "token"}
+
+	if _, err := c.Get(context.Background(), foreign.URL+"/items", nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if got != "" {
+		t.Errorf("foreign host received Authorization %q", got)
+	}
+}
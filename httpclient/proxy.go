@@ -0,0 +1,170 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyOptions describes how outgoing requests are routed through a proxy.
+// Proxy URLs may use the http, https or socks5 schemes. When no proxy URL is
+// set the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+// are used, with NoProxy excluding further hosts.
+type ProxyOptions struct {
+	HTTPProxy  string // Proxy for plain HTTP requests
+	HTTPSProxy string // Proxy for HTTPS requests, defaults to HTTPProxy
+	NoProxy    string // Comma-separated hosts, domains, IPs or CIDRs to reach directly
+}
+
+// IsZero reports whether no proxy option has been set
+func (o ProxyOptions) IsZero() bool {
+	return o == ProxyOptions{}
+}
+
+// ProxyFunc returns a function suitable for http.Transport.Proxy
+func (o ProxyOptions) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if o.IsZero() {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	noProxy := parseNoProxy(o.NoProxy)
+
+	if o.HTTPProxy == "" && o.HTTPSProxy == "" {
+		return func(req *http.Request) (*url.URL, error) {
+			if noProxy.matches(req.URL.Hostname()) {
+				return nil, nil
+			}
+
+			return http.ProxyFromEnvironment(req)
+		}, nil
+	}
+
+	httpProxy, err := parseProxyURL(o.HTTPProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	httpsProxy := httpProxy
+	if o.HTTPSProxy != "" {
+		if httpsProxy, err = parseProxyURL(o.HTTPSProxy); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.matches(req.URL.Hostname()) {
+			return nil, nil
+		}
+
+		if req.URL.Scheme == "https" {
+			return httpsProxy, nil
+		}
+
+		return httpProxy, nil
+	}, nil
+}
+
+// ProxyOptionsFromConfig reads proxy options from a generic configuration map
+// using the proxy, https_proxy and no_proxy keys
+func ProxyOptionsFromConfig(config map[string]interface{}) ProxyOptions {
+	var opts ProxyOptions
+
+	if v, ok := config["proxy"].(string); ok {
+		opts.HTTPProxy = v
+	}
+
+	if v, ok := config["https_proxy"].(string); ok {
+		opts.HTTPSProxy = v
+	}
+
+	if v, ok := config["no_proxy"].(string); ok {
+		opts.NoProxy = v
+	}
+
+	return opts
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	return u, nil
+}
+
+// noProxyList holds the parsed entries of a NO_PROXY value
+type noProxyList struct {
+	all     bool
+	hosts   []string
+	domains []string
+	nets    []*net.IPNet
+}
+
+func parseNoProxy(value string) noProxyList {
+	var list noProxyList
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		switch {
+		case entry == "":
+		case entry == "*":
+			list.all = true
+		case strings.HasPrefix(entry, "."):
+			list.domains = append(list.domains, entry)
+		default:
+			if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+				list.nets = append(list.nets, ipNet)
+				continue
+			}
+
+			list.hosts = append(list.hosts, entry)
+			list.domains = append(list.domains, "."+entry)
+		}
+	}
+
+	return list
+}
+
+func (l noProxyList) matches(host string) bool {
+	if l.all {
+		return true
+	}
+
+	host = strings.ToLower(host)
+
+	for _, h := range l.hosts {
+		if host == h {
+			return true
+		}
+	}
+
+	for _, d := range l.domains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range l.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
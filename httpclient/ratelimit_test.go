@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurstAndRefill(t *testing.T) {
+	l := NewRateLimiter(100, 3)
+
+	for i := 0; i < 3; i++ {
+		if delay := l.reserve("a"); delay != 0 {
+			t.Fatalf("request %d of the burst delayed by %s", i+1, delay)
+		}
+	}
+
+	if delay := l.reserve("a"); delay <= 0 || delay > 10*time.Millisecond {
+		t.Errorf("request after the burst delayed by %s, want up to 10ms", delay)
+	}
+
+	if delay := l.reserve("b"); delay != 0 {
+		t.Errorf("first request to another host delayed by %s", delay)
+	}
+
+	// 100 tokens per second refill two tokens in 25ms but never more than
+	// the burst
+	time.Sleep(25 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if delay := l.reserve("a"); delay != 0 {
+			t.Errorf("refilled request %d delayed by %s", i+1, delay)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	allowed := 0
+	for l.reserve("a") == 0 {
+		allowed++
+	}
+
+	if allowed != 3 {
+		t.Errorf("allowed %d requests after a long pause, want the burst of 3", allowed)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(100, 1)
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, "a"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	start := time.Now()
+	if err := l.Wait(ctx, "a"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("Wait() returned after %s, want about 10ms", waited)
+	}
+
+	cancelled, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+
+	if err := NewRateLimiter(0.001, 1).Wait(cancelled, "a"); err != nil {
+		t.Fatalf("Wait() with a full bucket error = %v", err)
+	}
+
+	l = NewRateLimiter(0.001, 1)
+	l.reserve("a")
+
+	if err := l.Wait(cancelled, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() on an empty bucket error = %v, want context.DeadlineExceeded", err)
+	}
+}
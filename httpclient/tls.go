@@ -0,0 +1,94 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions describes client certificates and trust roots for talking to
+// registries that use a private PKI
+type TLSOptions struct {
+	CAFile             string // PEM bundle appended to the system roots
+	CertFile           string // Client certificate for mTLS
+	KeyFile            string // Client private key for mTLS
+	ServerName         string // Overrides the server name used for verification
+	InsecureSkipVerify bool   // Disables server certificate verification
+}
+
+// IsZero reports whether no TLS option has been set
+func (o TLSOptions) IsZero() bool {
+	return o == TLSOptions{}
+}
+
+// Build creates a tls.Config from the options
+func (o TLSOptions) Build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CAFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("both cert_file and key_file are required for mTLS")
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// TLSOptionsFromConfig reads TLS options from a generic configuration map
+// using the ca_file, cert_file, key_file, server_name and
+// insecure_skip_verify keys
+func TLSOptionsFromConfig(config map[string]interface{}) TLSOptions {
+	var opts TLSOptions
+
+	if v, ok := config["ca_file"].(string); ok {
+		opts.CAFile = v
+	}
+
+	if v, ok := config["cert_file"].(string); ok {
+		opts.CertFile = v
+	}
+
+	if v, ok := config["key_file"].(string); ok {
+		opts.KeyFile = v
+	}
+
+	if v, ok := config["server_name"].(string); ok {
+		opts.ServerName = v
+	}
+
+	if v, ok := config["insecure_skip_verify"].(bool); ok {
+		opts.InsecureSkipVerify = v
+	}
+
+	return opts
+}
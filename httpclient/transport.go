@@ -0,0 +1,49 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// TransportOptions groups the connection-level settings of an HTTP transport
+type TransportOptions struct {
+	TLS   TLSOptions
+	Proxy ProxyOptions
+}
+
+// TransportOptionsFromConfig reads TLS and proxy options from a generic
+// configuration map
+func TransportOptionsFromConfig(config map[string]interface{}) TransportOptions {
+	return TransportOptions{
+		TLS:   TLSOptionsFromConfig(config),
+		Proxy: ProxyOptionsFromConfig(config),
+	}
+}
+
+// IsZero reports whether no transport option has been set
+func (o TransportOptions) IsZero() bool {
+	return o.TLS.IsZero() && o.Proxy.IsZero()
+}
+
+// NewTransport creates an HTTP transport configured with the given options
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !opts.TLS.IsZero() {
+		tlsConfig, err := opts.TLS.Build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build TLS config: %w", err)
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	proxy, err := opts.Proxy.ProxyFunc()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+
+	transport.Proxy = proxy
+
+	return transport, nil
+}
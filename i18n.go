@@ -0,0 +1,167 @@
+package extension
+
+import (
+	"errors"
+	"strings"
+)
+
+// MessageKey identifies a user-facing message so that embedding
+// applications can translate it
+type MessageKey string
+
+const (
+	MsgNotInstalled        MessageKey = "plugin.not_installed"      // plugin
+	MsgPluginDisabled      MessageKey = "plugin.disabled"           // plugin, status
+	MsgInvalidTransition   MessageKey = "plugin.invalid_transition" // plugin, from, to
+	MsgUpToDate            MessageKey = "plugin.up_to_date"
+	MsgReadOnlyPlugin      MessageKey = "plugin.read_only"          // plugin, dir
+	MsgDowngrade           MessageKey = "upgrade.downgrade"         // plugin, installed, requested
+	MsgVersionYanked       MessageKey = "install.version_yanked"    // plugin, version, message
+	MsgIncompatible        MessageKey = "install.incompatible"      // plugin, reasons
+	MsgScanRejected        MessageKey = "install.scan_rejected"     // plugin, findings
+	MsgMalformedArchive    MessageKey = "install.malformed_archive" // plugin, format
+	MsgProvenance          MessageKey = "install.provenance"        // plugin, level, minLevel
+	MsgPhaseTimeout        MessageKey = "install.phase_timeout"     // plugin, phase, timeout
+	MsgPermissionDenied    MessageKey = "install.permission_denied"
+	MsgPolicyDenied        MessageKey = "policy.denied" // plugin, action, reasons
+	MsgImageUnverified     MessageKey = "verify.image_unverified"
+	MsgMetadataTampered    MessageKey = "verify.metadata_tampered"    // plugin, reason
+	MsgNamespaceUnverified MessageKey = "verify.namespace_unverified" // plugin, owner, identities
+	MsgInvalidConfig       MessageKey = "config.invalid"              // plugin, error
+	MsgUnrecordedFiles     MessageKey = "uninstall.unrecorded_files"  // plugin, files
+	MsgWorkDirConflict     MessageKey = "execute.conflict"            // paths
+	MsgQueueTimeout        MessageKey = "execute.queue_timeout"
+	MsgManagerClosed       MessageKey = "manager.closed"
+	MsgOperationFailed     MessageKey = "operation.failed" // op, plugin, group, store, error
+	MsgStatusPrefix        MessageKey = "status."          // Followed by the status, e.g. status.quarantined
+)
+
+// Message is a user-facing message: its key and the values it refers to
+type Message struct {
+	Key    MessageKey
+	Params map[string]string
+}
+
+// UserMessager is implemented by errors that describe themselves as a
+// Message
+type UserMessager interface {
+	UserMessage() Message
+}
+
+// Catalog translates messages into the language of the user. Translate
+// returns false when it has no translation for the message.
+type Catalog interface {
+	Translate(msg Message) (string, bool)
+}
+
+// MapCatalog is a Catalog of templates keyed by message, in which
+// {param} is replaced with the value of the parameter
+type MapCatalog map[MessageKey]string
+
+// Translate implements Catalog
+func (c MapCatalog) Translate(msg Message) (string, bool) {
+	template, ok := c[msg.Key]
+	if !ok {
+		return "", false
+	}
+
+	replacements := make([]string, 0, 2*len(msg.Params))
+	for name, value := range msg.Params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template), true
+}
+
+// sentinelMessages are the keys of the sentinel errors of the package
+var sentinelMessages = []struct {
+	err error
+	key MessageKey
+}{
+	{ErrUpToDate, MsgUpToDate},
+	{ErrPermissionDenied, MsgPermissionDenied},
+	{ErrImageUnverified, MsgImageUnverified},
+	{ErrQueueTimeout, MsgQueueTimeout},
+	{ErrClosed, MsgManagerClosed},
+}
+
+// WithCatalog sets the catalog Localize and LocalizeStatus translate with
+func (m *Manager) WithCatalog(catalog Catalog) *Manager {
+	m.catalog = catalog
+	return m
+}
+
+// MessageOf returns the user-facing message of the first error in err's
+// chain that has one. Errors without one that an operation of the Manager
+// returned are described by MsgOperationFailed.
+func MessageOf(err error) (Message, bool) {
+	var messager UserMessager
+	if errors.As(err, &messager) {
+		return messager.UserMessage(), true
+	}
+
+	for _, s := range sentinelMessages {
+		if errors.Is(err, s.err) {
+			return Message{Key: s.key}, true
+		}
+	}
+
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return Message{Key: MsgOperationFailed, Params: map[string]string{
+			"op":     opErr.Op,
+			"plugin": opErr.Plugin,
+			"group":  opErr.Group,
+			"store":  opErr.Store,
+			"error":  opErr.Err.Error(),
+		}}, true
+	}
+
+	return Message{}, false
+}
+
+// Localize returns the message of err translated by the catalog. Joined
+// errors are translated one per line. The wrapping context of a translated
+// error is dropped; errors without a message or translation keep their
+// English text.
+func (m *Manager) Localize(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		lines := make([]string, 0, len(joined.Unwrap()))
+		for _, e := range joined.Unwrap() {
+			lines = append(lines, m.Localize(e))
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	if msg, ok := MessageOf(err); ok {
+		if s, ok := m.translate(msg); ok {
+			return s
+		}
+	}
+
+	return err.Error()
+}
+
+// LocalizeStatus returns the status translated by the catalog
+func (m *Manager) LocalizeStatus(status Status) string {
+	status = status.orDefault()
+
+	if s, ok := m.translate(Message{Key: MsgStatusPrefix + MessageKey(status)}); ok {
+		return s
+	}
+
+	return string(status)
+}
+
+func (m *Manager) translate(msg Message) (string, bool) {
+	if m.catalog == nil {
+		return "", false
+	}
+
+	return m.catalog.Translate(msg)
+}
@@ -0,0 +1,86 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrImageUnverified is returned when a container image does not satisfy
+// the executor's SignaturePolicy
+var ErrImageUnverified = errors.New("container image signature could not be verified")
+
+// SignaturePolicy requires container images to be signed with cosign before
+// they run. Images are verified against a public key or, for keyless
+// signing, a certificate identity and OIDC issuer. The zero value verifies
+// nothing.
+type SignaturePolicy struct {
+	CosignPath       string   `mapstructure:"cosign_path"`       // Defaults to cosign
+	Key              string   `mapstructure:"key"`               // Public key file or KMS URI
+	Identity         string   `mapstructure:"identity"`          // Keyless certificate identity, e.g. an email or workflow URL
+	Issuer           string   `mapstructure:"issuer"`            // Keyless OIDC issuer
+	AttestationTypes []string `mapstructure:"attestation_types"` // Attestations that must also verify, e.g. slsaprovenance
+}
+
+// Enabled reports whether the policy requires verification
+func (p SignaturePolicy) Enabled() bool {
+	return p.Key != "" || p.Identity != ""
+}
+
+// Clone returns a copy of the policy that shares no slices with it
+func (p SignaturePolicy) Clone() SignaturePolicy {
+	p.AttestationTypes = append([]string(nil), p.AttestationTypes...)
+	return p
+}
+
+// Verify checks the signature and required attestations of image. Image
+// IDs of images built locally at install time cannot be checked by cosign;
+// they are covered by the verification of the plugin itself and pass.
+func (p SignaturePolicy) Verify(ctx context.Context, image string) error {
+	if !p.Enabled() || strings.HasPrefix(image, "sha256:") {
+		return nil
+	}
+
+	if p.Key == "" && p.Issuer == "" {
+		return fmt.Errorf("keyless signature policy requires an issuer")
+	}
+
+	if err := p.cosign(ctx, "verify", image); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrImageUnverified, image, err)
+	}
+
+	for _, kind := range p.AttestationTypes {
+		if err := p.cosign(ctx, "verify-attestation", image, "--type", kind); err != nil {
+			return fmt.Errorf("%w: %s: %s attestation: %v", ErrImageUnverified, image, kind, err)
+		}
+	}
+
+	return nil
+}
+
+func (p SignaturePolicy) cosign(ctx context.Context, command, image string, extra ...string) error {
+	cosignPath := p.CosignPath
+	if cosignPath == "" {
+		cosignPath = "cosign"
+	}
+
+	args := []string{command}
+
+	if p.Key != "" {
+		args = append(args, "--key", p.Key)
+	} else {
+		args = append(args, "--certificate-identity", p.Identity, "--certificate-oidc-issuer", p.Issuer)
+	}
+
+	args = append(args, extra...)
+	args = append(args, image)
+
+	output, err := exec.CommandContext(ctx, cosignPath, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SearchLabels is the search criterion that restricts Search to plugins
+// carrying every label of a comma-separated list. It is applied by the
+// manager and not passed to stores.
+const SearchLabels = "labels"
+
+var labelPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:/-]*$`)
+
+// ListOptions filters the plugins returned by ListWithOptions
+type ListOptions struct {
+	Labels []string // Only return plugins carrying every one of these labels
+}
+
+// AddLabels attaches labels, such as team-approved or experimental, to an
+// installed plugin. Labels it already carries are ignored.
+func (m *Manager) AddLabels(ctx context.Context, name string, labels []string) error {
+	return m.editLabels(ctx, name, labels, func(current []string) []string {
+		for _, label := range labels {
+			if !slices.Contains(current, label) {
+				current = append(current, label)
+			}
+		}
+
+		return current
+	})
+}
+
+// RemoveLabels detaches labels from an installed plugin. Labels it does not
+// carry are ignored.
+func (m *Manager) RemoveLabels(ctx context.Context, name string, labels []string) error {
+	return m.editLabels(ctx, name, labels, func(current []string) []string {
+		return slices.DeleteFunc(current, func(label string) bool {
+			return slices.Contains(labels, label)
+		})
+	})
+}
+
+func (m *Manager) editLabels(ctx context.Context, name string, labels []string, edit func([]string) []string) error {
+	defer m.plugins.lock(name)()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before updating labels: %w", err)
+	}
+
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("invalid label %q", label)
+		}
+	}
+
+	err := m.updateMetadata(name, func(info *Info) error {
+		info.Labels = edit(info.Labels)
+		sort.Strings(info.Labels)
+
+		if len(info.Labels) == 0 {
+			info.Labels = nil
+		}
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ErrNotInstalled{Plugin: name}
+	}
+
+	return err
+}
+
+// ListWithOptions returns the installed plugins matching opts
+func (m *Manager) ListWithOptions(ctx context.Context, opts ListOptions) ([]Info, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.Labels) == 0 {
+		return plugins, nil
+	}
+
+	return filterLabels(plugins, opts.Labels), nil
+}
+
+// HasLabels reports whether the plugin carries every one of labels
+func (i *Info) HasLabels(labels ...string) bool {
+	for _, label := range labels {
+		if !slices.Contains(i.Labels, label) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func filterLabels(plugins []Info, labels []string) []Info {
+	var matched []Info
+	for _, info := range plugins {
+		if info.HasLabels(labels...) {
+			matched = append(matched, info)
+		}
+	}
+
+	return matched
+}
+
+// splitLabels parses the value of the SearchLabels criterion
+func splitLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,79 @@
+package extension
+
+import (
+	"strings"
+	"sync"
+)
+
+// pluginLocks hands out one read-write lock per plugin name so operations on
+// different plugins run concurrently. Locks are dropped once unused.
+type pluginLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pluginLock
+}
+
+type pluginLock struct {
+	sync.RWMutex
+	refs int
+}
+
+func newPluginLocks() *pluginLocks {
+	return &pluginLocks{locks: make(map[string]*pluginLock)}
+}
+
+// lock acquires the exclusive lock of a plugin and returns its release
+func (l *pluginLocks) lock(name string) func() {
+	pl := l.acquire(name)
+	pl.Lock()
+
+	return func() {
+		pl.Unlock()
+		l.release(name)
+	}
+}
+
+// rlock acquires the shared lock of a plugin and returns its release
+func (l *pluginLocks) rlock(name string) func() {
+	pl := l.acquire(name)
+	pl.RLock()
+
+	return func() {
+		pl.RUnlock()
+		l.release(name)
+	}
+}
+
+func (l *pluginLocks) acquire(name string) *pluginLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pl, ok := l.locks[name]
+	if !ok {
+		pl = &pluginLock{}
+		l.locks[name] = pl
+	}
+
+	pl.refs++
+
+	return pl
+}
+
+func (l *pluginLocks) release(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pl := l.locks[name]
+	pl.refs--
+
+	if pl.refs == 0 {
+		delete(l.locks, name)
+	}
+}
+
+// isPluginDirName reports whether a plugin directory entry holds an
+// installed plugin rather than a hidden, staged or backup directory
+func isPluginDirName(name string) bool {
+	return !strings.HasPrefix(name, ".") &&
+		!strings.HasSuffix(name, ".upgrade") &&
+		!strings.HasSuffix(name, ".backup")
+}
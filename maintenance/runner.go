@@ -0,0 +1,265 @@
+// Package maintenance runs a Manager's housekeeping in the background:
+// update checks, garbage collection, cache pruning and health checks, each
+// on its own schedule. By default tasks only report what they find through
+// events; jobs configured to act also apply it.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/events"
+)
+
+// Task is a maintenance operation
+type Task string
+
+const (
+	// TaskUpdateCheck checks installed plugins for newer versions and
+	// publishes events.UpdateAvailable. Acting upgrades the plugins that
+	// are not pinned.
+	TaskUpdateCheck Task = "update-check"
+
+	// TaskGC finds directories interrupted operations left behind. Acting
+	// removes them.
+	TaskGC Task = "gc"
+
+	// TaskCachePrune drops the Manager's in-memory metadata cache. It only
+	// runs when acting.
+	TaskCachePrune Task = "cache-prune"
+
+	// TaskHealthCheck runs Manager.Doctor and publishes
+	// events.PluginUnhealthy. It never changes anything.
+	TaskHealthCheck Task = "health-check"
+)
+
+// Job runs a task on a schedule
+type Job struct {
+	Task     Task   `mapstructure:"task"`
+	Schedule string `mapstructure:"schedule"` // See ParseSchedule
+	Act      bool   `mapstructure:"act"`      // Apply the findings instead of only reporting them
+}
+
+type job struct {
+	Job
+	schedule *Schedule
+}
+
+// Runner runs maintenance jobs for a Manager
+type Runner struct {
+	manager *extension.Manager
+	bus     *events.Bus
+	jobs    []job
+	logger  logr.Logger
+
+	mu sync.Mutex // Serializes task runs
+}
+
+// NewRunner creates a runner publishing its findings on bus, which may be
+// nil
+func NewRunner(manager *extension.Manager, bus *events.Bus, jobs ...Job) (*Runner, error) {
+	r := &Runner{
+		manager: manager,
+		bus:     bus,
+		logger:  logr.Discard(),
+	}
+
+	for _, j := range jobs {
+		switch j.Task {
+		case TaskUpdateCheck, TaskGC, TaskCachePrune, TaskHealthCheck:
+		default:
+			return nil, fmt.Errorf("unknown maintenance task %q", j.Task)
+		}
+
+		schedule, err := ParseSchedule(j.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule of task %s: %w", j.Task, err)
+		}
+
+		r.jobs = append(r.jobs, job{Job: j, schedule: schedule})
+	}
+
+	return r, nil
+}
+
+// WithLogger sets the logger
+func (r *Runner) WithLogger(logger logr.Logger) *Runner {
+	r.logger = logger
+	return r
+}
+
+// Run runs the jobs on their schedules until ctx is cancelled. Failing
+// tasks are logged and reported through events.MaintenanceRun; they do not
+// stop the runner.
+func (r *Runner) Run(ctx context.Context) error {
+	if len(r.jobs) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	now := time.Now()
+
+	next := make([]time.Time, len(r.jobs))
+	for i, j := range r.jobs {
+		next[i] = j.schedule.Next(now)
+	}
+
+	for {
+		due := 0
+		for i := range next {
+			if next[i].Before(next[due]) {
+				due = i
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next[due]))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		j := r.jobs[due]
+
+		if err := r.RunTask(ctx, j.Task, j.Act); err != nil && ctx.Err() == nil {
+			r.logger.Error(err, "maintenance task failed", "task", j.Task)
+		}
+
+		next[due] = j.schedule.Next(time.Now())
+	}
+}
+
+// RunTask runs a task once, now
+func (r *Runner) RunTask(ctx context.Context, task Task, act bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger.V(1).Info("running maintenance task", "task", task, "act", act)
+
+	summary := map[string]string{"task": string(task), "act": strconv.FormatBool(act)}
+
+	var err error
+
+	switch task {
+	case TaskUpdateCheck:
+		err = r.checkUpdates(ctx, act, summary)
+	case TaskGC:
+		err = r.collectGarbage(ctx, act, summary)
+	case TaskCachePrune:
+		if act {
+			err = r.manager.Refresh(ctx)
+		}
+	case TaskHealthCheck:
+		err = r.checkHealth(ctx, summary)
+	default:
+		err = fmt.Errorf("unknown maintenance task %q", task)
+	}
+
+	r.publish(events.Event{Type: events.MaintenanceRun, Metadata: summary, Err: err})
+
+	return err
+}
+
+func (r *Runner) checkUpdates(ctx context.Context, act bool, summary map[string]string) error {
+	statuses, err := r.manager.CheckUpdates(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	available := 0
+
+	for _, status := range statuses {
+		if status.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", status.Name, status.Err))
+			continue
+		}
+
+		if !status.UpdateAvailable {
+			continue
+		}
+
+		available++
+
+		r.publish(events.Event{
+			Type:     events.UpdateAvailable,
+			Plugin:   status.Name,
+			Version:  status.Latest,
+			Metadata: map[string]string{"installed": status.Installed, "channel": string(status.Channel)},
+		})
+	}
+
+	summary["available"] = strconv.Itoa(available)
+
+	if act && available > 0 {
+		report, err := r.manager.UpgradeAll(ctx, extension.UpgradeAllOptions{})
+		if err != nil {
+			return err
+		}
+
+		summary["upgraded"] = strconv.Itoa(report.Count(extension.UpgradeUpgraded))
+		summary["failed"] = strconv.Itoa(report.Count(extension.UpgradeFailed))
+
+		errs = append(errs, report.Err())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Runner) collectGarbage(ctx context.Context, act bool, summary map[string]string) error {
+	paths, err := r.manager.CollectGarbage(ctx, !act)
+
+	summary["paths"] = strings.Join(paths, ",")
+	summary["count"] = strconv.Itoa(len(paths))
+
+	return err
+}
+
+func (r *Runner) checkHealth(ctx context.Context, summary map[string]string) error {
+	report, err := r.manager.Doctor(ctx)
+	if err != nil {
+		return err
+	}
+
+	unhealthy := 0
+
+	for _, plugin := range report.Plugins {
+		if plugin.Healthy() {
+			continue
+		}
+
+		unhealthy++
+
+		checks := make([]string, len(plugin.Problems))
+		for i, problem := range plugin.Problems {
+			checks[i] = problem.Check
+		}
+
+		r.publish(events.Event{
+			Type:     events.PluginUnhealthy,
+			Plugin:   plugin.Name,
+			Version:  plugin.Version,
+			Metadata: map[string]string{"checks": strings.Join(checks, ",")},
+		})
+	}
+
+	summary["unhealthy"] = strconv.Itoa(unhealthy)
+	summary["dangling"] = strconv.Itoa(len(report.Dangling))
+
+	return nil
+}
+
+func (r *Runner) publish(e events.Event) {
+	r.bus.Publish(e)
+}
@@ -7,13 +7,16 @@ import (
 	"compress/bzip2"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,29 +27,161 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4"
+
+	"github.com/edsonmichaque/pluginkit/events"
+	"github.com/edsonmichaque/pluginkit/metrics"
 )
 
 // Manager implements the Store interface
 type Manager struct {
 	pluginDir string
+	fs        FS // Filesystem pluginDir is on, OSFS by default
 	store     Store
-	mu        sync.RWMutex
+	mu        sync.RWMutex // Guards the executors, crash counters, closed and searchIndex
+	plugins   *pluginLocks // Serializes operations on the same plugin
+	dirMu     sync.RWMutex // Held briefly while plugin directories appear or disappear
 	logger    logr.Logger
+	metrics   *metrics.Metrics
+	events    *events.Bus
+	registry  *Registry
+	approve   ApprovalFunc
+	policy    Policy
+
+	sourceRules *SourceRules
+	metadataKey []byte
+	trust       *TrustConfig
+	namespaces  map[string]Namespace // Keyed by lowercase owner
+	runApproval RunApprovalFunc
+
+	popularity PopularitySource
+	catalog    Catalog
+
+	hostVersion string
+	compatMode  CompatibilityMode
+
+	assetPreferences []AssetPreference
+	extractionLimits ExtractionLimits
+
+	systemDir       string
+	systemExecutors map[string]Executor
+
+	crashThreshold int
+	crashes        map[string]int
+
+	executors         map[string]Executor
+	executorFactories map[string]ExecutorFactory // Executors rebuilt for each profile
+	scheduler         *Scheduler
+	closed            bool
+	inflight          sync.WaitGroup // Executions started through the Manager
+	scanners          []Scanner
+
+	provenance *ProvenancePolicy
+	timeouts   Timeouts
+
+	cache       *infoCache
+	searchIndex *searchIndex // Built on the first SearchInstalled
+
+	historyMu      sync.Mutex
+	historyRecords int
+	historyOutput  int
+
+	baseDir string // Plugin directory of the default profile
+	profile string
 }
 
-// NewManager creates a new plugin manager instance
+// NewManager creates a new plugin manager instance. An empty pluginDir
+// defaults to the platform's conventional plugin directory for
+// DefaultAppName, see DefaultDirs.
 func NewManager(pluginDir string, store Store, logger logr.Logger) *Manager {
+	if pluginDir == "" {
+		dir, err := DefaultPluginDir(DefaultAppName)
+		if err != nil {
+			logger.Error(err, "failed to find the default plugin directory, using the working directory")
+		}
+
+		pluginDir = dir
+	}
+
 	return &Manager{
-		pluginDir: pluginDir,
-		store:     store,
-		logger:    logger.WithName("plugin-manager"),
+		pluginDir:      pluginDir,
+		fs:             OSFS{},
+		store:          store,
+		logger:         logger.WithName("plugin-manager"),
+		crashThreshold: defaultCrashThreshold,
+		plugins:        newPluginLocks(),
+		cache:          newInfoCache(OSFS{}),
+		historyRecords: defaultHistoryRecords,
+		historyOutput:  defaultHistoryOutput,
+	}
+}
+
+// WithMetrics records plugin operations on the given collectors
+func (m *Manager) WithMetrics(metrics *metrics.Metrics) *Manager {
+	m.metrics = metrics
+	return m
+}
+
+// WithEvents publishes lifecycle notifications on the given bus
+func (m *Manager) WithEvents(bus *events.Bus) *Manager {
+	m.events = bus
+	return m
+}
+
+// InstallOptions controls how a plugin is installed
+type InstallOptions struct {
+	Version     string   // Version, version constraint or @channel, defaults to latest
+	Store       string   // Source to install from, overriding the store/ prefix of the name
+	Runtime     string   // Runtime to record instead of the one reported by the store
+	Force       bool     // Replace an existing installation
+	SkipVerify  bool     // Skip scanners, the provenance policy and namespace verification
+	Alias       string   // Local name to install the plugin under
+	Platform    Platform // Platform to fetch for, defaults to the one in the context
+	AllowYanked bool     // Install a version its store withdrew
+}
+
+// InstallVersion installs a plugin at the given version.
+//
+// Deprecated: use Install with InstallOptions.
+func (m *Manager) InstallVersion(ctx context.Context, name, version string) error {
+	return m.Install(ctx, name, InstallOptions{Version: version})
+}
+
+// Install handles plugin installation. The name may select a specific
+// source using the store/name@version syntax.
+func (m *Manager) Install(ctx context.Context, name string, opts InstallOptions) error {
+	store := opts.Store
+	if store == "" {
+		store = m.parseReference(name).Store
 	}
+
+	return wrapOp(OpInstall, name, store, m.install(ctx, name, opts))
 }
 
-// Install handles plugin installation
-func (m *Manager) Install(ctx context.Context, name, version string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *Manager) install(ctx context.Context, name string, opts InstallOptions) error {
+	version := opts.Version
+
+	ref := m.parseReference(name)
+	if ref.Version != "" && (version == "" || version == "latest") {
+		version = ref.Version
+	}
+
+	if opts.Store != "" {
+		ref.Store = opts.Store
+	}
+
+	if opts.Platform != (Platform{}) {
+		ctx = ContextWithPlatform(ctx, opts.Platform)
+	}
+
+	// The plugin is fetched by its upstream name and installed under its alias
+	name = ref.Name
+
+	localName := name
+	if opts.Alias != "" {
+		localName = opts.Alias
+	}
+
+	defer m.plugins.lock(localName)()
 
 	logger := m.logger.WithValues("plugin", name, "version", version)
 	logger.V(1).Info("starting plugin installation")
@@ -54,6 +189,7 @@ func (m *Manager) Install(ctx context.Context, name, version string) error {
 	// Early validation
 	if err := ctx.Err(); err != nil {
 		logger.Error(err, "context cancelled before installation")
+		m.metrics.Failed("install", metrics.ReasonCancelled)
 		return fmt.Errorf("context cancelled before installation: %w", err)
 	}
 
@@ -62,170 +198,429 @@ func (m *Manager) Install(ctx context.Context, name, version string) error {
 		return fmt.Errorf("no valid plugin directory found")
 	}
 
-	pluginDir := filepath.Join(m.pluginDir, name)
+	pluginDir := filepath.Join(m.pluginDir, localName)
+	defer m.cache.invalidate(pluginDir)
+
 	logger = logger.WithValues("dir", pluginDir)
 
-	// Check if plugin is already installed
-	if _, err := os.Stat(pluginDir); err == nil {
-		logger.Error(nil, "plugin is already installed")
-		return fmt.Errorf("plugin %s is already installed", name)
+	// Check if plugin is already installed. Forced installs keep the
+	// existing installation aside until the new one succeeds. A directory
+	// without metadata only holds the user data of the system plugin this
+	// install shadows, which the new installation inherits.
+	var replacedDir string
+
+	inheritUserData := m.systemDir != "" && !hasMetadata(m.fs, pluginDir)
+
+	if _, err := m.fs.Stat(pluginDir); err == nil {
+		if !opts.Force && !inheritUserData {
+			logger.Error(nil, "plugin is already installed")
+			m.metrics.Failed("install", metrics.ReasonConflict)
+			return fmt.Errorf("plugin %s is already installed", localName)
+		}
+
+		replacedDir = pluginDir + ".backup"
+
+		m.dirMu.Lock()
+		err := m.fs.Rename(pluginDir, replacedDir)
+		m.dirMu.Unlock()
+
+		if err != nil {
+			m.metrics.Failed("install", metrics.ReasonWrite)
+			return fmt.Errorf("failed to move existing installation aside: %w", err)
+		}
 	}
 
 	// Setup cleanup in case of failure
 	var success bool
 	defer func() {
-		if !success {
-			os.RemoveAll(pluginDir)
+		if success {
+			if replacedDir != "" {
+				if inheritUserData {
+					if _, err := carryOverUserData(m.fs, replacedDir, pluginDir); err != nil {
+						logger.Error(err, "failed to carry over user data of the shadowed system plugin")
+					}
+				}
+
+				m.fs.RemoveAll(replacedDir)
+			}
+
+			return
 		}
-	}()
 
-	logger.V(1).Info("os.MkdirAll(pluginDir, 0755)")
+		m.dirMu.Lock()
+		m.fs.RemoveAll(pluginDir)
+
+		if replacedDir != "" {
+			m.fs.Rename(replacedDir, pluginDir)
+		}
+		m.dirMu.Unlock()
+	}()
 
 	// Create the plugin directory
-	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+	if err := m.fs.MkdirAll(pluginDir, 0755); err != nil {
 		return fmt.Errorf("failed to create plugin directory: %w", err)
 	}
 
-	logger.V(1).Info("m.store.Fetch(ctx, name, version)")
+	logger.V(1).Info("fetching plugin from store")
+
+	store, err := m.storeFor(ref.Store)
+	if err != nil {
+		return err
+	}
+
+	planner := AdaptStore(store)
+
+	// Follow a release channel such as @beta from its latest version on
+	channel, followChannel := ParseChannel(version)
+	if followChannel {
+		resolved, err := resolveChannel(ctx, store, name, channel)
+		if err != nil {
+			m.metrics.Failed("install", metrics.ReasonFetch)
+			return fmt.Errorf("failed to resolve plugin version: %w", err)
+		}
+
+		logger.V(1).Info("resolved release channel", "channel", channel, "resolved", resolved)
+		version = resolved
+	}
+
+	// Pick the highest version satisfying a range such as ^1.2
+	if IsVersionConstraint(version) {
+		resolved, err := planner.Resolve(ctx, name, version)
+		if err != nil {
+			m.metrics.Failed("install", metrics.ReasonFetch)
+			return fmt.Errorf("failed to resolve plugin version: %w", err)
+		}
+
+		logger.V(1).Info("resolved version constraint", "constraint", version, "resolved", resolved)
+		version = resolved
+	}
+
+	// Check compatibility before downloading when the store can describe
+	// the plugin without its content
+	if describesWithoutContent(store) {
+		meta, err := planner.Describe(ctx, name, version)
+		if err != nil {
+			m.metrics.Failed("install", metrics.ReasonFetch)
+			return fmt.Errorf("failed to fetch plugin metadata: %w", err)
+		}
+
+		if err := m.checkCompatibility(ctx, meta); err != nil {
+			return err
+		}
+
+		if err := m.checkDeprecation("install", meta, version, opts.AllowYanked); err != nil {
+			return err
+		}
+	}
 
 	// Fetch plugin from store
-	info, err := m.store.Fetch(ctx, name, version)
+	var info *Info
+
+	err = m.runPhase(ctx, name, version, PhaseFetch, func(ctx context.Context) (err error) {
+		info, err = store.Fetch(m.fetchContext(ctx), name, version)
+		return err
+	})
 	if err != nil {
+		m.metrics.Failed("install", metrics.ReasonFetch)
 		return fmt.Errorf("failed to fetch plugin: %w", err)
 	}
 
-	logger.V(1).Info("writePluginFiles(ctx, pluginDir, info)")
+	// Record the version "latest" stands for, as reported by the store
+	if (version == "" || version == "latest") && info.Version != "" {
+		version = info.Version
+	}
+
+	if !describesWithoutContent(store) {
+		if err := m.checkCompatibility(ctx, info); err != nil {
+			return err
+		}
+
+		if err := m.checkDeprecation("install", info, version, opts.AllowYanked); err != nil {
+			return err
+		}
+	}
+
+	var slsaLevel int
+
+	if !opts.SkipVerify {
+		slsaLevel, err = m.checkProvenance("install", info)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Info("skipping plugin verification")
+	}
+
+	if opts.Runtime != "" {
+		info.Runtime = opts.Runtime
+	}
+
+	admission := policyInput(PolicyInstall, localName, name, ref.Store, info, slsaLevel)
+	if admission.Version == "" {
+		admission.Version = version
+	}
+
+	if err := m.applyTrust(&admission, info, opts.Runtime); err != nil {
+		return err
+	}
+
+	if !opts.SkipVerify {
+		if err := m.verifyNamespace(ctx, admission); err != nil {
+			return err
+		}
+	}
+
+	if err := m.admit(ctx, admission); err != nil {
+		return err
+	}
+
+	// Stores may report a name other than the one requested, such as the bare
+	// repository name; the plugin is always recorded under its local name and
+	// the upstream name is only kept for aliases
+	var upstream string
+	if opts.Alias != "" {
+		upstream = name
+	}
+
+	info.Name = localName
+
+	if err := m.approvePermissions(ctx, info); err != nil {
+		logger.Error(err, "plugin permissions rejected", "permissions", info.Permissions.String())
+		return err
+	}
+
+	size := contentSize(info.Content)
+	digest := contentDigest(info.Content)
+
+	logger.V(1).Info("writing plugin files", "size", size)
+
 	// Write plugin data
-	if err := writePluginFiles(ctx, pluginDir, info); err != nil {
+	err = m.runPhase(ctx, name, version, PhaseExtract, func(ctx context.Context) error {
+		return writePluginFiles(m.extractionContext(ctx), m.fs, logger, pluginDir, info)
+	})
+	if err != nil {
+		m.metrics.Failed("install", metrics.ReasonWrite)
 		return fmt.Errorf("failed to install plugin: %w", err)
 	}
 
+	// Scan the extracted files before the plugin becomes active
+	if !opts.SkipVerify {
+		err = m.runPhase(ctx, name, version, PhaseVerify, func(ctx context.Context) error {
+			return m.scan(ctx, "install", pluginDir, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create metadata
 	info.Version = version
-	info.Status = "enabled"
+	info.Status = StatusEnabled
 	info.Metadata = map[string]string{
 		"installed": time.Now().Format(time.RFC3339),
 	}
 
+	if upstream != "" {
+		info.Metadata["upstream"] = upstream
+	}
+
+	if ref.Store != "" {
+		info.Metadata["source"] = ref.Store
+	}
+
+	if followChannel {
+		info.Metadata["channel"] = string(channel)
+	}
+
+	if slsaLevel > 0 {
+		info.Metadata["slsa_level"] = fmt.Sprintf("%d", slsaLevel)
+	}
+
+	recordPolicyInput(info.Metadata, admission)
+
+	err = m.runPhase(ctx, name, version, PhaseBuild, func(ctx context.Context) error {
+		return m.buildImage(ctx, pluginDir, info)
+	})
+	if err != nil {
+		m.metrics.Failed("install", metrics.ReasonWrite)
+		return fmt.Errorf("failed to build plugin image: %w", err)
+	}
+
+	if err := writeAttestations(m.fs, pluginDir, info); err != nil {
+		m.metrics.Failed("install", metrics.ReasonWrite)
+		return err
+	}
+
+	if sum, err := fileDigest(m.fs, binaryPath(pluginDir, info)); err == nil {
+		info.Metadata["binary_sha256"] = sum
+	}
+
+	if digest != "" {
+		info.Metadata["artifact_sha256"] = strings.TrimPrefix(digest, "sha256:")
+	}
+
+	if info.Files, err = recordFiles(m.fs, pluginDir); err != nil {
+		m.metrics.Failed("install", metrics.ReasonMetadata)
+		return fmt.Errorf("failed to hash plugin files: %w", err)
+	}
+
 	// Save metadata
 	metadataBytes, err := json.MarshalIndent(info, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	logger.V(1).Info("metadataPath := filepath.Join(pluginDir, \"metadata.json\")")
+	logger.V(1).Info("saving plugin metadata")
 
 	metadataPath := filepath.Join(pluginDir, "metadata.json")
-	if err := os.WriteFile(metadataPath, metadataBytes, 0644); err != nil {
+	if err := writeMetadata(m.fs, metadataPath, metadataBytes); err != nil {
+		m.metrics.Failed("install", metrics.ReasonMetadata)
 		return fmt.Errorf("failed to save metadata: %w", err)
 	}
 
+	if err := m.signMetadata(metadataPath, metadataBytes); err != nil {
+		m.metrics.Failed("install", metrics.ReasonMetadata)
+		return err
+	}
+
 	success = true
 
+	m.metrics.Installed(info.Store, size)
+	m.events.Publish(events.Event{
+		Type:     events.PluginInstalled,
+		Plugin:   localName,
+		Version:  version,
+		Store:    info.Store,
+		Runtime:  info.Runtime,
+		Metadata: map[string]string{"digest": digest},
+	})
+
 	return nil
 }
 
-// Uninstall removes a plugin from the filesystem
+// UninstallOptions controls how a plugin is uninstalled
+type UninstallOptions struct {
+	KeepData bool // Keep the volumes, caches and VM disks of the plugin
+
+	// Strict refuses to remove a plugin whose directory holds files that
+	// are not in its bill of files, such as files added by hand or
+	// dependency environments its runtime created, with *ErrUnrecordedFiles
+	Strict bool
+}
+
+// Uninstall removes a plugin and the artifacts its runtime created for it
 func (m *Manager) Uninstall(ctx context.Context, name string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.UninstallWithOptions(ctx, name, UninstallOptions{})
+}
+
+// UninstallWithOptions removes a plugin from the filesystem after reclaiming
+// the images, containers, volumes and disks its executor and runtime
+// created for it
+func (m *Manager) UninstallWithOptions(ctx context.Context, name string, opts UninstallOptions) error {
+	store := m.storeOf(name)
+
+	return wrapOp(OpUninstall, name, store, m.uninstall(ctx, name, opts))
+}
+
+func (m *Manager) uninstall(ctx context.Context, name string, opts UninstallOptions) error {
+	defer m.plugins.lock(name)()
 
 	if err := ctx.Err(); err != nil {
 		return fmt.Errorf("context cancelled during uninstall: %w", err)
 	}
 
+	if err := m.checkWritable(name); err != nil {
+		return err
+	}
+
 	pluginDir := filepath.Join(m.pluginDir, name)
+	defer m.cache.invalidate(pluginDir)
 
 	// Check if plugin directory exists
-	if _, err := os.Stat(pluginDir); os.IsNotExist(err) {
+	if _, err := m.fs.Stat(pluginDir); os.IsNotExist(err) {
+		m.metrics.Failed("uninstall", metrics.ReasonNotFound)
 		return fmt.Errorf("plugin %s not found in plugin directory", name)
 	}
 
-	if err := os.RemoveAll(pluginDir); err != nil {
-		return fmt.Errorf("failed to remove plugin directory: %w", err)
+	if err := m.checkUnrecordedFiles(name, pluginDir, opts.Strict); err != nil {
+		m.metrics.Failed("uninstall", metrics.ReasonConflict)
+		return err
 	}
 
-	return nil
-}
-
-// Enable activates a plugin
-func (m *Manager) Enable(ctx context.Context, name string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if err := ctx.Err(); err != nil {
-		return fmt.Errorf("context cancelled before enabling plugin: %w", err)
+	// Remove runtime artifacts first so that a failure leaves the plugin
+	// installed and the uninstall can be retried
+	if err := m.removeArtifacts(ctx, name, pluginDir, opts); err != nil {
+		m.metrics.Failed("uninstall", metrics.ReasonWrite)
+		return err
 	}
 
-	pluginDir := filepath.Join(m.pluginDir, name)
-	metadataPath := filepath.Join(pluginDir, "metadata.json")
+	// Move the plugin out of sight first so List never sees it half removed
+	removingDir := filepath.Join(m.pluginDir, "."+name+".removing")
 
-	var info Info
+	m.dirMu.Lock()
+	err := m.fs.Rename(pluginDir, removingDir)
+	m.dirMu.Unlock()
 
-	data, err := os.ReadFile(metadataPath)
 	if err != nil {
-		return fmt.Errorf("failed to read metadata: %w", err)
+		m.metrics.Failed("uninstall", metrics.ReasonWrite)
+		return fmt.Errorf("failed to remove plugin directory: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &info); err != nil {
-		return fmt.Errorf("failed to parse metadata: %w", err)
+	if err := m.fs.RemoveAll(removingDir); err != nil {
+		m.metrics.Failed("uninstall", metrics.ReasonWrite)
+		return fmt.Errorf("failed to remove plugin directory: %w", err)
 	}
 
-	info.Status = "enabled"
-
-	metadataBytes, err := json.MarshalIndent(info, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+	if err := m.fs.Remove(m.configPath(name)); err != nil && !os.IsNotExist(err) {
+		m.logger.Error(err, "failed to remove plugin configuration", "plugin", name)
 	}
 
-	if err := os.WriteFile(metadataPath, metadataBytes, 0644); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
-	}
+	m.metrics.Uninstalled()
+	m.events.Publish(events.Event{
+		Type:   events.PluginRemoved,
+		Plugin: name,
+	})
 
 	return nil
 }
 
-// Disable deactivates a plugin
-func (m *Manager) Disable(ctx context.Context, name string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if err := ctx.Err(); err != nil {
-		return fmt.Errorf("context cancelled before disabling plugin: %w", err)
-	}
-
-	pluginDir := filepath.Join(m.pluginDir, name)
-	metadataPath := filepath.Join(pluginDir, "metadata.json")
+// Enable activates a plugin. Enabling an enabled plugin is a no-op. It
+// returns *ErrNotInstalled when the plugin is not installed.
+func (m *Manager) Enable(ctx context.Context, name string) error {
+	return wrapOp(OpEnable, name, m.storeOf(name), m.enable(ctx, name))
+}
 
-	var info Info
+func (m *Manager) enable(ctx context.Context, name string) error {
+	defer m.plugins.lock(name)()
 
-	data, err := os.ReadFile(metadataPath)
-	if err != nil {
-		return fmt.Errorf("failed to read metadata: %w", err)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before enabling plugin: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &info); err != nil {
-		return fmt.Errorf("failed to parse metadata: %w", err)
-	}
+	return m.setStatus(name, StatusEnabled)
+}
 
-	info.Status = "disabled"
+// Disable deactivates a plugin. Disabling a disabled plugin is a no-op. It
+// returns *ErrNotInstalled when the plugin is not installed.
+func (m *Manager) Disable(ctx context.Context, name string) error {
+	return wrapOp(OpDisable, name, m.storeOf(name), m.disable(ctx, name))
+}
 
-	metadataBytes, err := json.MarshalIndent(info, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
+func (m *Manager) disable(ctx context.Context, name string) error {
+	defer m.plugins.lock(name)()
 
-	if err := os.WriteFile(metadataPath, metadataBytes, 0644); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before disabling plugin: %w", err)
 	}
 
-	return nil
+	return m.setStatus(name, StatusDisabled)
 }
 
-// List returns information about all installed plugins
+// List returns information about all installed plugins. Use
+// ListWithOptions to filter them.
 func (m *Manager) List(ctx context.Context) ([]Info, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.dirMu.RLock()
+	defer m.dirMu.RUnlock()
 
 	if err := ctx.Err(); err != nil {
 		return nil, fmt.Errorf("context cancelled before listing plugins: %w", err)
@@ -233,53 +628,68 @@ func (m *Manager) List(ctx context.Context) ([]Info, error) {
 
 	var plugins []Info
 
-	entries, err := os.ReadDir(m.pluginDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return plugins, nil
-		}
-
+	names, err := m.cache.pluginNames(m.pluginDir)
+	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	shadowed := make(map[string]bool, len(names))
 
-		metadataPath := filepath.Join(m.pluginDir, entry.Name(), "metadata.json")
+	for _, name := range names {
+		metadataPath := filepath.Join(m.pluginDir, name, "metadata.json")
 
-		data, err := os.ReadFile(metadataPath)
+		info, err := m.cache.read(metadataPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
 			}
 
-			return nil, fmt.Errorf("failed to read metadata for plugin %s: %w", entry.Name(), err)
+			return nil, fmt.Errorf("failed to read metadata for plugin %s: %w", name, err)
 		}
 
-		var info Info
-		if err := json.Unmarshal(data, &info); err != nil {
-			return nil, fmt.Errorf("failed to parse metadata for plugin %s: %w", entry.Name(), err)
+		if m.systemDir != "" {
+			info.Layer = LayerUser
 		}
 
-		plugins = append(plugins, info)
+		shadowed[name] = true
+		plugins = append(plugins, *info)
+	}
+
+	system, err := m.listSystem(shadowed)
+	if err != nil {
+		return nil, err
 	}
 
-	return plugins, nil
+	return append(plugins, system...), nil
 }
 
-// Search returns available plugins from the store with installation status
+// Search returns available plugins from the store with installation status.
+// Installed plugins carry their labels, which the SearchLabels criterion
+// filters on. The SearchSort criterion orders the results by name or
+// popularity.
 func (m *Manager) Search(ctx context.Context, searchOptions SearchOptions) ([]Info, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	results, err := m.search(ctx, searchOptions)
+	if err != nil {
+		return nil, wrapOp(OpSearch, "", "", err)
+	}
+
+	return results, nil
+}
 
+func (m *Manager) search(ctx context.Context, searchOptions SearchOptions) ([]Info, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, fmt.Errorf("context cancelled before search: %w", err)
 	}
 
-	// Get available plugins from store using Search
-	available, err := m.store.Search(ctx, searchOptions)
+	labels := splitLabels(searchOptions[SearchLabels])
+	order := searchOptions[SearchSort]
+
+	// Criteria applied by the manager only are not passed to stores
+	searchOptions = maps.Clone(searchOptions)
+	delete(searchOptions, SearchLabels)
+
+	// Get available plugins from every configured store
+	available, err := m.searchSources(ctx, searchOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search available plugins: %w", err)
 	}
@@ -291,136 +701,464 @@ func (m *Manager) Search(ctx context.Context, searchOptions SearchOptions) ([]In
 	}
 
 	// Mark installed plugins and their versions
-	installedMap := make(map[string]string)
+	installedMap := make(map[string]Info)
 	for _, plugin := range installed {
-		installedMap[plugin.Name] = plugin.Version
+		installedMap[plugin.Name] = plugin
 	}
 
 	// Update available plugins with installation status
 	for i := range available {
-		if version, ok := installedMap[available[i].Name]; ok {
-			available[i].Status = "installed"
-			available[i].Metadata["installed_version"] = version
+		if plugin, ok := installedMap[available[i].Name]; ok {
+			available[i].Status = StatusInstalled
+			available[i].Labels = plugin.Labels
+
+			if available[i].Metadata == nil {
+				available[i].Metadata = make(map[string]string)
+			}
+
+			available[i].Metadata["installed_version"] = plugin.Version
 		} else {
-			available[i].Status = "available"
+			available[i].Status = StatusAvailable
+			available[i].Labels = nil
+		}
+	}
+
+	if len(labels) > 0 {
+		available = filterLabels(available, labels)
+	}
+
+	m.mergePopularity(ctx, available)
+
+	if order != "" {
+		if err := sortInfos(available, order); err != nil {
+			return nil, err
 		}
 	}
 
 	return available, nil
 }
 
+// ErrUpToDate is returned by Upgrade when the plugin is already at the
+// requested version
+var ErrUpToDate = errors.New("plugin is already up to date")
+
+// UpgradeOptions controls how a plugin is upgraded
+type UpgradeOptions struct {
+	Version string // Version, version constraint or @channel, defaults to latest
+
+	// AllowDowngrade permits moving to an older version than the installed
+	// one, which is otherwise refused with *ErrDowngrade
+	AllowDowngrade bool
+
+	// OnDowngrade is called before a permitted downgrade is applied, e.g. to
+	// warn the user
+	OnDowngrade func(plugin, from, to string)
+}
+
+// Upgrade moves a plugin to another version. Downgrades are refused; use
+// UpgradeWithOptions to allow them.
 func (m *Manager) Upgrade(ctx context.Context, name string, version string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.UpgradeWithOptions(ctx, name, UpgradeOptions{Version: version})
+}
+
+// UpgradeWithOptions moves a plugin to another version
+func (m *Manager) UpgradeWithOptions(ctx context.Context, name string, opts UpgradeOptions) error {
+	return wrapOp(OpUpgrade, name, m.storeOf(name), m.upgrade(ctx, name, opts))
+}
+
+func (m *Manager) upgrade(ctx context.Context, name string, opts UpgradeOptions) error {
+	version := opts.Version
+
+	defer m.plugins.lock(name)()
 
 	if err := ctx.Err(); err != nil {
 		return fmt.Errorf("context cancelled before upgrade: %w", err)
 	}
 
+	if err := m.checkWritable(name); err != nil {
+		return err
+	}
+
 	// Check if plugin exists
 	pluginDir := filepath.Join(m.pluginDir, name)
-	if _, err := os.Stat(pluginDir); os.IsNotExist(err) {
+	defer m.cache.invalidate(pluginDir)
+	if _, err := m.fs.Stat(pluginDir); os.IsNotExist(err) {
 		return fmt.Errorf("plugin %s is not installed", name)
 	}
 
 	// Read current metadata
-	currentInfo, err := readMetadata(filepath.Join(pluginDir, "metadata.json"))
+	currentInfo, err := readMetadata(m.fs, filepath.Join(pluginDir, "metadata.json"))
 	if err != nil {
 		return fmt.Errorf("failed to read current plugin metadata: %w", err)
 	}
 
+	// Plugins installed under an alias are fetched by their upstream name
+	upstream := name
+	if currentInfo.Metadata["upstream"] != "" {
+		upstream = currentInfo.Metadata["upstream"]
+	}
+
+	// Plugins are upgraded from the source they were installed from
+	store, err := m.storeFor(currentInfo.Metadata["source"])
+	if err != nil {
+		return err
+	}
+
+	// Plugins installed from a channel keep following it unless another
+	// channel or a version is requested
+	channel := Channel(currentInfo.Metadata["channel"])
+	if requested, ok := ParseChannel(version); ok {
+		channel, version = requested, ""
+	}
+
+	if channel != "" && (version == "" || version == "latest") {
+		resolved, err := resolveChannel(ctx, store, upstream, channel)
+		if err != nil {
+			m.metrics.Failed("upgrade", metrics.ReasonFetch)
+			return fmt.Errorf("failed to resolve plugin version: %w", err)
+		}
+
+		version = resolved
+	} else {
+		channel = ""
+	}
+
+	// Resolve ranges, and "latest" when the store can do so without
+	// downloading, so that an up-to-date plugin is detected early
+	if IsVersionConstraint(version) || ((version == "" || version == "latest") && describesWithoutContent(store)) {
+		resolved, err := AdaptStore(store).Resolve(ctx, upstream, version)
+		if err != nil {
+			m.metrics.Failed("upgrade", metrics.ReasonFetch)
+			return fmt.Errorf("failed to resolve plugin version: %w", err)
+		}
+
+		version = resolved
+	}
+
 	// Skip if already at requested version
 	if currentInfo.Version == version {
-		return fmt.Errorf("plugin %s is already at version %s", name, version)
+		return fmt.Errorf("plugin %s is already at version %s: %w", name, version, ErrUpToDate)
+	}
+
+	unresolved := version == "" || version == "latest"
+
+	if !unresolved {
+		if err := m.checkDowngrade(name, currentInfo.Version, version, opts); err != nil {
+			return err
+		}
 	}
 
 	// Create temporary upgrade directory
 	tmpDir := pluginDir + ".upgrade"
-	defer os.RemoveAll(tmpDir)
+	defer m.fs.RemoveAll(tmpDir)
 
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+	if err := m.fs.MkdirAll(tmpDir, 0755); err != nil {
 		return fmt.Errorf("failed to create temporary upgrade directory: %w", err)
 	}
 
 	// Fetch new version
-	newInfo, err := m.store.Fetch(ctx, name, version)
+	var newInfo *Info
+
+	err = m.runPhase(ctx, name, version, PhaseFetch, func(ctx context.Context) (err error) {
+		newInfo, err = store.Fetch(m.fetchContext(ctx), upstream, version)
+		return err
+	})
 	if err != nil {
+		m.metrics.Failed("upgrade", metrics.ReasonFetch)
 		return fmt.Errorf("failed to fetch plugin upgrade: %w", err)
 	}
 
+	// Only now is the version "latest" stands for known
+	if unresolved && newInfo.Version != "" {
+		version = newInfo.Version
+
+		if currentInfo.Version == version {
+			return fmt.Errorf("plugin %s is already at version %s: %w", name, version, ErrUpToDate)
+		}
+
+		if err := m.checkDowngrade(name, currentInfo.Version, version, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := m.checkDeprecation("upgrade", newInfo, version, false); err != nil {
+		return err
+	}
+
+	slsaLevel, err := m.checkProvenance("upgrade", newInfo)
+	if err != nil {
+		return err
+	}
+
+	admission := policyInput(PolicyUpgrade, name, upstream, currentInfo.Metadata["source"], newInfo, slsaLevel)
+	admission.Version = version
+	admission.Labels = currentInfo.Labels
+
+	if err := m.applyTrust(&admission, newInfo, ""); err != nil {
+		return err
+	}
+
+	if err := m.verifyNamespace(ctx, admission); err != nil {
+		return err
+	}
+
+	if err := m.admit(ctx, admission); err != nil {
+		return err
+	}
+
+	if err := m.approvePermissions(ctx, newInfo); err != nil {
+		return err
+	}
+
+	newInfo.Name = currentInfo.Name
+
+	size := contentSize(newInfo.Content)
+	digest := contentDigest(newInfo.Content)
+
 	// Write new plugin files
-	if err := writePluginFiles(ctx, tmpDir, newInfo); err != nil {
+	err = m.runPhase(ctx, name, version, PhaseExtract, func(ctx context.Context) error {
+		return writePluginFiles(m.extractionContext(ctx), m.fs, m.logger.WithValues("plugin", name, "version", version), tmpDir, newInfo)
+	})
+	if err != nil {
+		m.metrics.Failed("upgrade", metrics.ReasonWrite)
 		return fmt.Errorf("failed to write upgraded plugin files: %w", err)
 	}
 
+	err = m.runPhase(ctx, name, version, PhaseVerify, func(ctx context.Context) error {
+		return m.scan(ctx, "upgrade", tmpDir, newInfo)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Update metadata
 	newInfo.Version = version
 	newInfo.Status = currentInfo.Status
+	newInfo.Groups = currentInfo.Groups
+	newInfo.Labels = currentInfo.Labels
 	newInfo.Metadata = map[string]string{
 		"installed":        time.Now().Format(time.RFC3339),
-		"upgraded_from":    currentInfo.Version,
 		"previous_install": currentInfo.Metadata["installed"],
 	}
 
+	direction := "upgraded_from"
+	if CompareVersions(version, currentInfo.Version) < 0 {
+		direction = "downgraded_from"
+	}
+
+	newInfo.Metadata[direction] = currentInfo.Version
+
+	if upstream != name {
+		newInfo.Metadata["upstream"] = upstream
+	}
+
+	if channel != "" {
+		newInfo.Metadata["channel"] = string(channel)
+	}
+
+	if source := currentInfo.Metadata["source"]; source != "" {
+		newInfo.Metadata["source"] = source
+	}
+
+	// A quarantined plugin stays quarantined, and is released to the status
+	// it had before
+	if currentInfo.Status == StatusQuarantined {
+		for _, key := range []string{"quarantine_previous_status", "quarantine_reason", "quarantined"} {
+			if value, ok := currentInfo.Metadata[key]; ok {
+				newInfo.Metadata[key] = value
+			}
+		}
+	}
+
+	if IsPinned(currentInfo) {
+		newInfo.Metadata["pinned"] = currentInfo.Metadata["pinned"]
+	}
+
+	if sum, err := fileDigest(m.fs, binaryPath(tmpDir, newInfo)); err == nil {
+		newInfo.Metadata["binary_sha256"] = sum
+	}
+
+	if slsaLevel > 0 {
+		newInfo.Metadata["slsa_level"] = fmt.Sprintf("%d", slsaLevel)
+	}
+
+	recordPolicyInput(newInfo.Metadata, admission)
+
+	err = m.runPhase(ctx, name, version, PhaseBuild, func(ctx context.Context) error {
+		return m.buildImage(ctx, tmpDir, newInfo)
+	})
+	if err != nil {
+		m.metrics.Failed("upgrade", metrics.ReasonWrite)
+		return fmt.Errorf("failed to build plugin image: %w", err)
+	}
+
+	if err := writeAttestations(m.fs, tmpDir, newInfo); err != nil {
+		m.metrics.Failed("upgrade", metrics.ReasonWrite)
+		return err
+	}
+
+	if digest != "" {
+		newInfo.Metadata["artifact_sha256"] = strings.TrimPrefix(digest, "sha256:")
+	}
+
+	if newInfo.Files, err = recordFiles(m.fs, tmpDir); err != nil {
+		m.metrics.Failed("upgrade", metrics.ReasonMetadata)
+		return fmt.Errorf("failed to hash plugin files: %w", err)
+	}
+
+	eventMetadata := map[string]string{direction: currentInfo.Version, "digest": digest}
+
+	// Diff the file sets, unless the installed version predates bills
+	if currentInfo.Files != nil {
+		diff := DiffFiles(currentInfo.Files, newInfo.Files)
+
+		m.logger.V(1).Info("upgrade changes plugin files", "plugin", name, "added", diff.Added, "removed", diff.Removed, "changed", diff.Changed)
+
+		eventMetadata["files_added"] = strconv.Itoa(len(diff.Added))
+		eventMetadata["files_removed"] = strconv.Itoa(len(diff.Removed))
+		eventMetadata["files_changed"] = strconv.Itoa(len(diff.Changed))
+	}
+
+	// An approved plugin stays approved across upgrades
+	if currentInfo.Metadata["approved_fingerprint"] != "" {
+		if fingerprint := runFingerprint(tmpDir, newInfo); fingerprint != "" {
+			newInfo.Metadata["approved_fingerprint"] = fingerprint
+			newInfo.Metadata["approved"] = currentInfo.Metadata["approved"]
+		}
+	}
+
 	// Write new metadata
 	metadataBytes, err := json.MarshalIndent(newInfo, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	if err := os.WriteFile(filepath.Join(tmpDir, "metadata.json"), metadataBytes, 0644); err != nil {
+	if err := writeMetadata(m.fs, filepath.Join(tmpDir, "metadata.json"), metadataBytes); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	// Atomic swap
+	if err := m.signMetadata(filepath.Join(tmpDir, "metadata.json"), metadataBytes); err != nil {
+		return err
+	}
+
+	// Atomic swap, hidden from List while the plugin directory is missing
 	backupDir := pluginDir + ".backup"
-	if err := os.Rename(pluginDir, backupDir); err != nil {
+
+	m.dirMu.Lock()
+
+	if err := m.fs.Rename(pluginDir, backupDir); err != nil {
+		m.dirMu.Unlock()
 		return fmt.Errorf("failed to backup existing plugin: %w", err)
 	}
 
-	if err := os.Rename(tmpDir, pluginDir); err != nil {
+	// Plugin data, settings and run history survive the upgrade
+	restoreUserData, err := carryOverUserData(m.fs, backupDir, tmpDir)
+	if err != nil {
+		m.fs.Rename(backupDir, pluginDir)
+		m.dirMu.Unlock()
+		m.metrics.Failed("upgrade", metrics.ReasonWrite)
+		return err
+	}
+
+	if err := m.fs.Rename(tmpDir, pluginDir); err != nil {
 		// Attempt to restore backup
-		os.Rename(backupDir, pluginDir)
+		restoreUserData()
+		m.fs.Rename(backupDir, pluginDir)
+		m.dirMu.Unlock()
+		m.metrics.Failed("upgrade", metrics.ReasonWrite)
 		return fmt.Errorf("failed to install upgrade: %w", err)
 	}
 
+	m.dirMu.Unlock()
+
 	// Clean up backup
-	os.RemoveAll(backupDir)
+	m.fs.RemoveAll(backupDir)
+
+	m.metrics.Upgraded(newInfo.Store, size)
+	m.events.Publish(events.Event{
+		Type:     events.PluginUpgraded,
+		Plugin:   name,
+		Version:  version,
+		Store:    newInfo.Store,
+		Runtime:  newInfo.Runtime,
+		Metadata: eventMetadata,
+	})
 
 	return nil
 }
 
 func (m *Manager) Fetch(ctx context.Context, name string) (*Info, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	defer m.plugins.rlock(name)()
+
+	info, err := m.readLayered(name)
+	if err != nil {
+		return nil, wrapOp(OpFetch, name, "", err)
+	}
+
+	return info, nil
+}
+
+// contentSize returns the size of in-memory plugin content, or 0 for streams
+func contentSize(content interface{}) int64 {
+	switch v := content.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	default:
+		return 0
+	}
+}
+
+// contentDigest returns the SHA-256 digest of in-memory plugin content, or an
+// empty string for streams
+func contentDigest(content interface{}) string {
+	var data []byte
 
-	metadataPath := filepath.Join(m.pluginDir, name, "metadata.json")
+	switch v := content.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
 
-	return readMetadata(metadataPath)
+// binaryPath returns where writePluginFiles places the plugin file
+func binaryPath(dir string, info *Info) string {
+	return filepath.Join(dir, info.Name, info.FileName)
 }
 
-// Helper function for reading metadata
-func readMetadata(path string) (*Info, error) {
-	data, err := os.ReadFile(path)
+// fileDigest returns the hex SHA-256 digest of a file
+func fileDigest(fsys FS, path string) (string, error) {
+	f, err := fsys.Open(path)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	defer f.Close()
 
-	var info Info
-	if err := json.Unmarshal(data, &info); err != nil {
-		return nil, err
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
 	}
 
-	return &info, nil
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
 // Helper function for writing plugin files
-func writePluginFiles(ctx context.Context, dir string, info *Info) error {
+func writePluginFiles(ctx context.Context, fsys FS, logger logr.Logger, dir string, info *Info) error {
 	// Create plugin-specific directory
-	plugindir := filepath.Join(dir, info.Name)
-	log.Printf("[Manager.Install] plugindir: %s", plugindir)
+	plugindir, err := safeJoin(dir, info.Name)
+	if err != nil {
+		return fmt.Errorf("invalid plugin name: %w", err)
+	}
+
+	logger = logger.WithValues("plugindir", plugindir)
 
-	if err := os.MkdirAll(plugindir, 0755); err != nil {
+	if err := fsys.MkdirAll(plugindir, 0755); err != nil {
 		return fmt.Errorf("failed to create plugin-specific directory: %w", err)
 	}
 
@@ -429,34 +1167,32 @@ func writePluginFiles(ctx context.Context, dir string, info *Info) error {
 
 	switch v := info.Content.(type) {
 	case string:
-		log.Printf("[Manager.Install] v: %s", v)
+		logger.V(2).Info("detecting content type", "source", "string", "size", len(v))
 		contentType = http.DetectContentType([]byte(v))
 	case []byte:
-		log.Printf("[Manager.Install] v: %s", v)
+		logger.V(2).Info("detecting content type", "source", "bytes", "size", len(v))
 		contentType = http.DetectContentType(v)
 	case io.Reader:
-		log.Println("[Manager.Install] v is io.Reader")
+		logger.V(2).Info("detecting content type", "source", "reader")
 
-		// Read just enough for content type detection
-		sniffBuf := make([]byte, 512)
+		// Read just enough for content type detection. A single Read may
+		// return fewer bytes than are available.
+		sniffBuf := make([]byte, contentSniffingSize)
 
-		n, err := v.Read(sniffBuf)
-		if err != nil && err != io.EOF {
+		n, err := io.ReadFull(v, sniffBuf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return fmt.Errorf("failed to read content for type detection: %w", err)
 		}
 
 		contentType = http.DetectContentType(sniffBuf[:n])
-		log.Printf("[Manager.Install] contentType: %s", contentType)
 
 		// Try to seek back if possible
 		if seeker, ok := v.(io.Seeker); ok {
-			log.Println("[Manager.Install] seeker is io.Seeker")
-
 			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
 				return fmt.Errorf("failed to seek back after type detection: %w", err)
 			}
 		} else {
-			log.Println("[Manager.Install] seeker is not io.Seeker")
+			logger.V(2).Info("content is not seekable, replaying sniffed bytes")
 			// If we can't seek, prepend the read bytes to a new reader
 			info.Content = io.MultiReader(bytes.NewReader(sniffBuf[:n]), v)
 		}
@@ -477,32 +1213,31 @@ func writePluginFiles(ctx context.Context, dir string, info *Info) error {
 		return fmt.Errorf("unsupported content type: %T", info.Content)
 	}
 
-	binPath := filepath.Join(plugindir, info.FileName)
-
-	binFile, err := os.OpenFile(binPath, os.O_CREATE|os.O_WRONLY, 0755)
+	binPath, err := safeJoin(plugindir, info.FileName)
 	if err != nil {
-		return fmt.Errorf("failed to create plugin file: %w", err)
+		return fmt.Errorf("invalid plugin file name: %w", err)
 	}
-	defer binFile.Close()
 
 	// Handle different content types
+	logger = logger.WithValues("contentType", contentType)
+
 	processors, ok := fileProcessorMap[contentType]
 	if !ok {
-		log.Println("[Manager.Install] extracting other")
-
-		if _, err := io.Copy(binFile, reader); err != nil {
-			return fmt.Errorf("failed to write plugin data: %w", err)
-		}
-
-		return nil
+		logger.V(1).Info("writing plugin content as-is")
+		return writeBinary(fsys, binPath, reader)
 	}
 
-	log.Printf("[Manager.Install] extracting %s", contentType)
+	logger.V(1).Info("extracting plugin archive")
 
 	// Process through the chain of processors
-	reader, err = processFile(ctx, reader, plugindir, processors...)
+	reader, err = extractArchive(ctx, fsys, reader, info.Name, contentType, plugindir, processors)
 	if err != nil {
-		return fmt.Errorf("failed to process file: %w", err)
+		return err
+	}
+
+	// Archives are extracted in place and leave nothing more to write
+	if reader == nil {
+		return nil
 	}
 
 	// Close if the final reader implements io.Closer
@@ -510,7 +1245,18 @@ func writePluginFiles(ctx context.Context, dir string, info *Info) error {
 		defer closer.Close()
 	}
 
-	if _, err := io.Copy(binFile, reader); err != nil {
+	return writeBinary(fsys, binPath, reader)
+}
+
+// writeBinary writes the plugin executable to path
+func writeBinary(fsys FS, path string, r io.Reader) error {
+	binFile, err := fsys.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create plugin file: %w", err)
+	}
+	defer binFile.Close()
+
+	if _, err := io.Copy(binFile, r); err != nil {
 		return fmt.Errorf("failed to write plugin data: %w", err)
 	}
 
@@ -518,7 +1264,7 @@ func writePluginFiles(ctx context.Context, dir string, info *Info) error {
 }
 
 // extractGz decompresses a gzipped reader and returns a new reader
-func extractGz(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractGz(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	gr, err := gzip.NewReader(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
@@ -528,8 +1274,9 @@ func extractGz(_ context.Context, r io.Reader, destDir string) (io.Reader, error
 }
 
 // extractTar extracts a tar archive from a reader to the destination directory
-func extractTar(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractTar(ctx context.Context, fsys FS, r io.Reader, destDir string) (io.Reader, error) {
 	tr := tar.NewReader(r)
+	budget := newExtractionBudget(ctx)
 
 	for {
 		header, err := tr.Next()
@@ -542,28 +1289,38 @@ func extractTar(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 		}
 
 		// Sanitize file path to prevent directory traversal
-		target := filepath.Join(destDir, filepath.Clean(header.Name))
-		if !strings.HasPrefix(target, destDir) {
-			return nil, fmt.Errorf("invalid tar path: %s", header.Name)
+		target, err := safeJoin(destDir, header.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			if target, err = safeFileJoin(destDir, header.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := budget.entry(); err != nil {
+			return nil, err
 		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(target, 0755); err != nil {
+			if err := fsys.MkdirAll(target, 0755); err != nil {
 				return nil, fmt.Errorf("failed to create directory: %w", err)
 			}
 		case tar.TypeReg:
 			dir := filepath.Dir(target)
-			if err := os.MkdirAll(dir, 0755); err != nil {
+			if err := fsys.MkdirAll(dir, 0755); err != nil {
 				return nil, fmt.Errorf("failed to create directory: %w", err)
 			}
 
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, os.FileMode(header.Mode))
+			f, err := fsys.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
 			if err != nil {
 				return nil, fmt.Errorf("failed to create file: %w", err)
 			}
 
-			if _, err := io.Copy(f, tr); err != nil {
+			if err := budget.copy(f, tr); err != nil {
 				f.Close()
 				return nil, fmt.Errorf("failed to write file: %w", err)
 			}
@@ -575,18 +1332,20 @@ func extractTar(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 	return nil, nil
 }
 
-func extractZip(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractZip(ctx context.Context, fsys FS, r io.Reader, destDir string) (io.Reader, error) {
 	// Create a temporary file to store the zip content
-	tmpFile, err := os.CreateTemp("", "plugin-*.zip")
+	tmpFile, err := fsys.CreateTemp("", "plugin-*.zip")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temporary file: %w", err)
 	}
 
-	defer os.Remove(tmpFile.Name())
+	defer fsys.Remove(tmpFile.Name())
 	defer tmpFile.Close()
 
+	budget := newExtractionBudget(ctx)
+
 	// Copy zip content to temporary file
-	if _, err := io.Copy(tmpFile, r); err != nil {
+	if err := budget.copy(tmpFile, r); err != nil {
 		return nil, fmt.Errorf("failed to write zip content: %w", err)
 	}
 
@@ -604,26 +1363,39 @@ func extractZip(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 
 	for _, file := range zipReader.File {
 		// Sanitize file path to prevent directory traversal
-		target := filepath.Join(destDir, filepath.Clean(file.Name))
-		if !strings.HasPrefix(target, destDir) {
-			return nil, fmt.Errorf("invalid zip path: %s", file.Name)
+		target, err := safeJoin(destDir, file.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := budget.entry(); err != nil {
+			return nil, err
 		}
 
 		if file.FileInfo().IsDir() {
-			if err := os.MkdirAll(target, 0755); err != nil {
+			if err := fsys.MkdirAll(target, 0755); err != nil {
 				return nil, fmt.Errorf("failed to create directory: %w", err)
 			}
 
 			continue
 		}
 
+		// Symbolic links and other special files are not extracted
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		if target, err = safeFileJoin(destDir, file.Name); err != nil {
+			return nil, err
+		}
+
 		// Create parent directories if needed
-		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		if err := fsys.MkdirAll(filepath.Dir(target), 0755); err != nil {
 			return nil, fmt.Errorf("failed to create directory: %w", err)
 		}
 
 		// Create and write file
-		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, file.Mode())
+		f, err := fsys.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode().Perm())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create file: %w", err)
 		}
@@ -634,7 +1406,7 @@ func extractZip(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 			return nil, fmt.Errorf("failed to open zip file: %w", err)
 		}
 
-		_, err = io.Copy(f, rc)
+		err = budget.copy(f, rc)
 		rc.Close()
 		f.Close()
 
@@ -646,18 +1418,19 @@ func extractZip(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 	return nil, nil
 }
 
-// extractZstd decompresses a zstd compressed reader and returns a new reader
-func extractZstd(_ context.Context, r io.Reader) (io.Reader, error) {
+// extractZstd decompresses a zstd compressed reader and returns a new reader.
+// Closing it releases the decoder's goroutines.
+func extractZstd(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	decoder, err := zstd.NewReader(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create zstd reader: %w", err)
 	}
 
-	return decoder, nil
+	return decoder.IOReadCloser(), nil
 }
 
 // extractXz decompresses an xz compressed reader and returns a new reader
-func extractXz(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractXz(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	decoder, err := xz.NewReader(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create xz reader: %w", err)
@@ -667,7 +1440,7 @@ func extractXz(_ context.Context, r io.Reader, destDir string) (io.Reader, error
 }
 
 // extractBzip2 decompresses a bzip2 compressed reader and returns a new reader
-func extractBzip2(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractBzip2(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	decoder := bzip2.NewReader(r)
 	if decoder == nil {
 		return nil, fmt.Errorf("failed to create bzip2 reader")
@@ -677,7 +1450,7 @@ func extractBzip2(_ context.Context, r io.Reader, destDir string) (io.Reader, er
 }
 
 // extractLz4 decompresses an LZ4 compressed reader and returns a new reader
-func extractLz4(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractLz4(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	decoder := lz4.NewReader(r)
 	if decoder == nil {
 		return nil, fmt.Errorf("failed to create lz4 reader")
@@ -687,7 +1460,7 @@ func extractLz4(_ context.Context, r io.Reader, destDir string) (io.Reader, erro
 }
 
 // extractBrotli decompresses a Brotli compressed reader and returns a new reader
-func extractBrotli(_ context.Context, r io.Reader, destDir string) (io.Reader, error) {
+func extractBrotli(_ context.Context, _ FS, r io.Reader, destDir string) (io.Reader, error) {
 	decoder := brotli.NewReader(r)
 	if decoder == nil {
 		return nil, fmt.Errorf("failed to create brotli reader")
@@ -696,7 +1469,7 @@ func extractBrotli(_ context.Context, r io.Reader, destDir string) (io.Reader, e
 	return decoder, nil
 }
 
-type fileProcessor func(ctx context.Context, r io.Reader, destDir string) (io.Reader, error)
+type fileProcessor func(ctx context.Context, fsys FS, r io.Reader, destDir string) (io.Reader, error)
 
 var fileProcessorMap map[string][]fileProcessor = map[string][]fileProcessor{
 	"application/gzip":     {extractGz, extractTar},
@@ -706,17 +1479,31 @@ var fileProcessorMap map[string][]fileProcessor = map[string][]fileProcessor{
 	"application/x-bzip2":  {extractBzip2, extractTar},
 	"application/x-lz4":    {extractLz4, extractTar},
 	"application/x-brotli": {extractBrotli, extractTar},
+	"application/zstd":     {extractZstd, extractTar},
 }
 
-func processFile(ctx context.Context, r io.Reader, destDir string, processors ...fileProcessor) (io.Reader, error) {
-	var reader io.Reader = r
+func processFile(ctx context.Context, fsys FS, r io.Reader, destDir string, processors ...fileProcessor) (result io.Reader, err error) {
+	var (
+		reader  io.Reader = r
+		closers []io.Closer
+	)
+
+	// Readers of intermediate steps are closed once the chain is done; the
+	// reader returned, if any, is closed by the caller
+	defer func() {
+		for _, closer := range closers {
+			if any(closer) != result {
+				closer.Close()
+			}
+		}
+	}()
 
 	for _, process := range processors {
 		if err := ctx.Err(); err != nil {
 			return nil, fmt.Errorf("processing cancelled: %w", err)
 		}
 
-		processed, err := process(ctx, reader, destDir)
+		processed, err := process(ctx, fsys, reader, destDir)
 		if err != nil {
 			return nil, fmt.Errorf("processing failed: %w", err)
 		}
@@ -725,6 +1512,10 @@ func processFile(ctx context.Context, r io.Reader, destDir string, processors ..
 			return nil, nil // Early return if processor indicates completion (e.g., after extraction)
 		}
 
+		if closer, ok := processed.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+
 		reader = processed
 	}
 
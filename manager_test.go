@@ -0,0 +1,153 @@
+package extension_test
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/runtime/runtimetest"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestUpgradeFetchesFromRecordedSource(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	defaultStore := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "9.0.0", Content: content})
+	mirror := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.0.0", Content: content})
+
+	registry := extension.NewRegistry()
+	registry.RegisterStore("mirror", mirror)
+
+	manager := extension.NewManager("/plugins", defaultStore, logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithRegistry(registry).
+		WithExecutor("native", runtimetest.New())
+
+	if err := manager.Install(ctx, "mirror/hello", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	mirror.Add(extension.Info{Name: "hello", Version: "1.1.0", Content: content})
+
+	if err := manager.Upgrade(ctx, "hello", "latest"); err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	info, err := manager.Fetch(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if info.Version != "1.1.0" {
+		t.Errorf("upgraded to %s, want 1.1.0 from the mirror", info.Version)
+	}
+
+	if got := info.Metadata["source"]; got != "mirror" {
+		t.Errorf("source = %q after upgrade, want mirror", got)
+	}
+
+	if got := info.Metadata["upgraded_from"]; got != "1.0.0" {
+		t.Errorf("upgraded_from = %q, want 1.0.0", got)
+	}
+
+	if calls := defaultStore.Calls(); len(calls) != 0 {
+		t.Errorf("default store was called: %v", calls)
+	}
+}
+
+func TestInstallRecordsFetchedVersion(t *testing.T) {
+	ctx := context.Background()
+
+	store := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.2.0", Content: []byte("#!/bin/sh\necho hello\n")})
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithExecutor("native", runtimetest.New())
+
+	for _, version := range []string{"", "latest"} {
+		if err := manager.Install(ctx, "hello", extension.InstallOptions{Version: version}); err != nil {
+			t.Fatalf("Install(%q) error = %v", version, err)
+		}
+
+		info, err := manager.Fetch(ctx, "hello")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		if info.Version != "1.2.0" {
+			t.Errorf("Install(%q) recorded version %q, want 1.2.0", version, info.Version)
+		}
+
+		if err := manager.Uninstall(ctx, "hello"); err != nil {
+			t.Fatalf("Uninstall() error = %v", err)
+		}
+	}
+}
+
+// bareNameStore reports plugins by their bare repository name, as the GitHub
+// store does for owner/name references
+type bareNameStore struct {
+	*storetest.Fake
+}
+
+func (s bareNameStore) Fetch(ctx context.Context, name, version string) (*extension.Info, error) {
+	info, err := s.Fake.Fetch(ctx, name, version)
+	if err == nil {
+		info.Name = path.Base(info.Name)
+	}
+
+	return info, err
+}
+
+func (s bareNameStore) Describe(ctx context.Context, name, version string) (*extension.Info, error) {
+	info, err := s.Fake.Describe(ctx, name, version)
+	if err == nil {
+		info.Name = path.Base(info.Name)
+	}
+
+	return info, err
+}
+
+func TestInstallKeepsRequestedNameWhenStoreReportsAnother(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	fake := storetest.New().
+		Add(extension.Info{Name: "acme/hello", Version: "1.0.0", FileName: "hello", Content: content})
+
+	manager := extension.NewManager("/plugins", bareNameStore{fake}, logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithExecutor("native", runtimetest.New())
+
+	if err := manager.Install(ctx, "acme/hello", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	info, err := manager.Fetch(ctx, "acme/hello")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if info.Name != "acme/hello" || info.Metadata["upstream"] != "" {
+		t.Errorf("installed as %q with upstream %q, want acme/hello and no upstream", info.Name, info.Metadata["upstream"])
+	}
+
+	fake.Add(extension.Info{Name: "acme/hello", Version: "1.1.0", FileName: "hello", Content: content})
+
+	if err := manager.Upgrade(ctx, "acme/hello", "latest"); err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	for _, call := range fake.Calls() {
+		if call.Name != "acme/hello" {
+			t.Errorf("store was asked for %q, want acme/hello", call.Name)
+		}
+	}
+}
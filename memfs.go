@@ -0,0 +1,448 @@
+package extension
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an FS kept in memory, for tests and for hosts that stage plugins
+// without touching the disk. The zero value is not usable; call NewMemFS.
+type MemFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+	temp  int
+}
+
+type memNode struct {
+	dir     bool
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory filesystem
+func NewMemFS() *MemFS {
+	root := filepath.VolumeName(os.TempDir()) + string(filepath.Separator)
+
+	return &MemFS{nodes: map[string]*memNode{
+		root: {dir: true, mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+func (m *MemFS) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	node, ok := m.nodes[name]
+
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if err := m.checkParent("open", name); err != nil {
+			return nil, err
+		}
+
+		node = &memNode{mode: perm.Perm()}
+		m.add(name, node)
+	case node.dir && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if flag&os.O_TRUNC != 0 && !node.dir {
+		node.data = nil
+		node.modTime = time.Now()
+	}
+
+	return &memFile{fs: m, name: name, node: node, flag: flag}, nil
+}
+
+func (m *MemFS) CreateTemp(dir, pattern string) (File, error) {
+	if dir == "" {
+		dir = os.TempDir()
+
+		if err := m.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+
+	for {
+		m.mu.Lock()
+		m.temp++
+		n := m.temp
+		m.mu.Unlock()
+
+		f, err := m.OpenFile(filepath.Join(dir, prefix+strconv.Itoa(n)+suffix), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil || !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return append([]byte(nil), node.data...), nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir := memPath(name)
+
+	var entries []fs.DirEntry
+
+	for path, child := range m.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(path)))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.info(memPath(name)), nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = memPath(path)
+
+	var missing []string
+
+	for p := path; ; p = filepath.Dir(p) {
+		if node, ok := m.nodes[p]; ok {
+			if !node.dir {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+			}
+
+			break
+		}
+
+		missing = append(missing, p)
+
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		m.add(missing[i], &memNode{dir: true, mode: fs.ModeDir | perm.Perm()})
+	}
+
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, err := m.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+
+	node.mode = node.mode&fs.ModeType | mode.Perm()
+
+	return nil
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = memPath(oldpath), memPath(newpath)
+
+	node, err := m.lookup("rename", oldpath)
+	if err != nil {
+		return err
+	}
+
+	if err := m.checkParent("rename", newpath); err != nil {
+		return err
+	}
+
+	if target, ok := m.nodes[newpath]; ok && target.dir && (!node.dir || m.hasChildren(newpath)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+
+	if node.dir && strings.HasPrefix(newpath, oldpath+string(filepath.Separator)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+
+	m.remove(newpath)
+
+	for path, child := range m.nodes {
+		if rel, ok := strings.CutPrefix(path, oldpath+string(filepath.Separator)); ok {
+			delete(m.nodes, path)
+			m.nodes[filepath.Join(newpath, rel)] = child
+		}
+	}
+
+	delete(m.nodes, oldpath)
+	m.touch(oldpath)
+	m.add(newpath, node)
+
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = memPath(name)
+
+	if _, err := m.lookup("remove", name); err != nil {
+		return err
+	}
+
+	if m.hasChildren(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+	}
+
+	m.remove(name)
+
+	return nil
+}
+
+func (m *MemFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(memPath(path))
+
+	return nil
+}
+
+func (m *MemFS) lookup(op, name string) (*memNode, error) {
+	node, ok := m.nodes[memPath(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return node, nil
+}
+
+func (m *MemFS) checkParent(op, name string) error {
+	parent, ok := m.nodes[filepath.Dir(name)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	if !parent.dir {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	return nil
+}
+
+func (m *MemFS) hasChildren(dir string) bool {
+	for path := range m.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add stores a node and updates its directory's modification time
+func (m *MemFS) add(name string, node *memNode) {
+	node.modTime = time.Now()
+	m.nodes[name] = node
+	m.touch(name)
+}
+
+// remove deletes a node and its descendants
+func (m *MemFS) remove(name string) {
+	if _, ok := m.nodes[name]; !ok {
+		return
+	}
+
+	for path := range m.nodes {
+		if strings.HasPrefix(path, name+string(filepath.Separator)) {
+			delete(m.nodes, path)
+		}
+	}
+
+	delete(m.nodes, name)
+	m.touch(name)
+}
+
+func (m *MemFS) touch(name string) {
+	if parent, ok := m.nodes[filepath.Dir(name)]; ok && filepath.Dir(name) != name {
+		parent.modTime = time.Now()
+	}
+}
+
+func memPath(name string) string {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+
+	return path
+}
+
+func (n *memNode) info(path string) fs.FileInfo {
+	mode := n.mode
+	if n.dir {
+		mode |= fs.ModeDir
+	}
+
+	return memInfo{name: filepath.Base(path), size: int64(len(n.data)), mode: mode, modTime: n.modTime}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memFile is an open MemFS file. Writes are visible to other readers at
+// once, as with files on disk.
+type memFile struct {
+	fs     *MemFS
+	name   string
+	node   *memNode
+	flag   int
+	offset int64
+	closed bool
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	return f.node.info(f.name), nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if f.flag&os.O_WRONLY != 0 || f.node.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+
+	copy(f.node.data[f.offset:], p)
+	f.offset += int64(len(p))
+	f.node.modTime = time.Now()
+
+	return len(p), nil
+}
+
+func (f *memFile) Sync() error { return nil }
+
+func (f *memFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+
+	f.closed = true
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
+
+// metadataBackupSuffix names the copy of the previous metadata kept to
+// recover from a corrupted metadata file
+const metadataBackupSuffix = ".bak"
+
+// writeMetadata replaces a metadata file atomically. The data is written to
+// a temporary file in the same directory, synced and renamed over the
+// target, and the directory is synced so the rename survives a crash. The
+// previous valid metadata is kept as a .bak copy.
+func writeMetadata(fsys FS, path string, data []byte) error {
+	if _, err := decodeMetadata(data); err != nil {
+		return fmt.Errorf("refusing to write invalid metadata: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+
+	if previous, err := fsys.ReadFile(path); err == nil {
+		if _, err := decodeMetadata(previous); err == nil {
+			if err := writeFileSync(fsys, path+metadataBackupSuffix, previous); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writeFileSync(fsys, path, data); err != nil {
+		return err
+	}
+
+	return syncDir(fsys, dir)
+}
+
+// writeFileSync atomically replaces path with data through a synced
+// temporary file
+func writeFileSync(fsys FS, path string, data []byte) error {
+	tmp, err := fsys.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer fsys.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(path), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := fsys.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", filepath.Base(path), err)
+	}
+
+	if err := fsys.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
+
+// syncDir flushes a directory entry so renames within it are durable.
+// Windows does not support syncing directories.
+func syncDir(fsys FS, dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := fsys.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", dir, err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// readMetadata reads and validates a metadata file. When the file is
+// missing or corrupted and a valid backup exists, the backup is returned;
+// the next write restores the metadata file.
+func readMetadata(fsys FS, path string) (*Info, error) {
+	data, err := fsys.ReadFile(path)
+	if err == nil {
+		info, decodeErr := decodeMetadata(data)
+		if decodeErr == nil {
+			return info, nil
+		}
+
+		err = decodeErr
+	}
+
+	backup, backupErr := fsys.ReadFile(path + metadataBackupSuffix)
+	if backupErr != nil {
+		return nil, err
+	}
+
+	info, backupErr := decodeMetadata(backup)
+	if backupErr != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// decodeMetadata parses metadata and checks that it describes a plugin
+func decodeMetadata(data []byte) (*Info, error) {
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	if info.Name == "" {
+		return nil, fmt.Errorf("invalid metadata: missing plugin name")
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,215 @@
+package extension
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metadataSignatureSuffix names the file holding the HMAC of a metadata file
+const metadataSignatureSuffix = ".sig"
+
+// ErrMetadataTampered is returned when an installed plugin's metadata or
+// files were changed outside the Manager
+type ErrMetadataTampered struct {
+	Plugin string
+	Reason string
+}
+
+func (e *ErrMetadataTampered) Error() string {
+	return fmt.Sprintf("plugin %s was modified outside the plugin manager: %s", e.Plugin, e.Reason)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrMetadataTampered) UserMessage() Message {
+	return Message{Key: MsgMetadataTampered, Params: map[string]string{"plugin": e.Plugin, "reason": e.Reason}}
+}
+
+// WithMetadataKey signs every metadata file the Manager writes with an
+// HMAC-SHA256 under key, and verifies the signature and the recorded
+// checksum of the plugin file before each execution. A plugin whose
+// metadata was edited, or whose file was swapped, is quarantined. The key
+// may come from LoadOrCreateKey or from the OS keychain.
+func (m *Manager) WithMetadataKey(key []byte) *Manager {
+	m.metadataKey = key
+	return m
+}
+
+// LoadOrCreateKey reads a metadata signing key from path, creating a random
+// one readable only by the current user when it does not exist
+func LoadOrCreateKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < 32 {
+			return nil, fmt.Errorf("invalid metadata key in %s", path)
+		}
+
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read metadata key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate metadata key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			// Created concurrently by another process
+			return LoadOrCreateKey(path)
+		}
+
+		return nil, fmt.Errorf("failed to create metadata key: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		return nil, fmt.Errorf("failed to write metadata key: %w", err)
+	}
+
+	return key, nil
+}
+
+// SignMetadata signs the current metadata of installed plugins that have no
+// signature yet, such as those installed before a key was configured.
+// Only call it when the installed plugins are known to be untampered.
+func (m *Manager) SignMetadata(ctx context.Context) error {
+	if m.metadataKey == nil {
+		return fmt.Errorf("no metadata key configured")
+	}
+
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, info := range plugins {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("signing cancelled: %w", err)
+		}
+
+		if err := m.signUnsigned(info.Name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sign metadata of %s: %w", info.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) signUnsigned(name string) error {
+	defer m.plugins.lock(name)()
+
+	path := filepath.Join(m.pluginDir, name, "metadata.json")
+	if _, err := m.fs.Stat(path + metadataSignatureSuffix); err == nil {
+		return nil
+	}
+
+	data, err := m.fs.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return m.signMetadata(path, data)
+}
+
+// signMetadata writes the signature of metadata just written to path. It
+// does nothing without a key.
+func (m *Manager) signMetadata(path string, data []byte) error {
+	if m.metadataKey == nil {
+		return nil
+	}
+
+	if err := writeFileSync(m.fs, path+metadataSignatureSuffix, []byte(m.metadataMAC(data)+"\n")); err != nil {
+		return fmt.Errorf("failed to sign metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) metadataMAC(data []byte) string {
+	mac := hmac.New(sha256.New, m.metadataKey)
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyMetadata checks the signature of an installed plugin's metadata and
+// the checksum of its file. It returns *ErrMetadataTampered when either
+// does not match.
+func (m *Manager) verifyMetadata(name string) error {
+	if m.metadataKey == nil {
+		return nil
+	}
+
+	path := filepath.Join(m.pluginDir, name, "metadata.json")
+
+	data, err := m.fs.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	signature, err := m.fs.ReadFile(path + metadataSignatureSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ErrMetadataTampered{Plugin: name, Reason: "metadata is not signed"}
+		}
+
+		return fmt.Errorf("failed to read metadata signature: %w", err)
+	}
+
+	expected := m.metadataMAC(data)
+	if !hmac.Equal([]byte(strings.TrimSpace(string(signature))), []byte(expected)) {
+		return &ErrMetadataTampered{Plugin: name, Reason: "metadata signature does not match"}
+	}
+
+	info, err := decodeMetadata(data)
+	if err != nil {
+		return err
+	}
+
+	if sum := info.Metadata["binary_sha256"]; sum != "" {
+		actual, err := fileDigest(m.fs, binaryPath(filepath.Join(m.pluginDir, name), info))
+		if err != nil || actual != sum {
+			return &ErrMetadataTampered{Plugin: name, Reason: "plugin file does not match its recorded checksum"}
+		}
+	}
+
+	return nil
+}
+
+// checkTampering verifies an installed plugin before it runs and
+// quarantines it when it was tampered with
+func (m *Manager) checkTampering(ctx context.Context, name string) error {
+	err := m.verifyMetadata(name)
+
+	var tampered *ErrMetadataTampered
+	if !errors.As(err, &tampered) {
+		return err
+	}
+
+	m.logger.Error(err, "quarantining tampered plugin", "plugin", name)
+
+	if qerr := m.ReportVerificationFailure(ctx, name, err); qerr != nil {
+		m.logger.Error(qerr, "failed to quarantine tampered plugin", "plugin", name)
+	}
+
+	return err
+}
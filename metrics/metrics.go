@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "plugin"
+
+// Failure reasons used for the failures counter
+const (
+	ReasonCancelled  = "cancelled"
+	ReasonFetch      = "fetch"
+	ReasonWrite      = "write"
+	ReasonMetadata   = "metadata"
+	ReasonNotFound   = "not_found"
+	ReasonConflict   = "conflict"
+	ReasonExecute    = "execute"
+	ReasonScan       = "scan"
+	ReasonProvenance = "provenance"
+	ReasonPolicy     = "policy"
+)
+
+// Metrics holds the Prometheus collectors for plugin operations
+type Metrics struct {
+	Installs         *prometheus.CounterVec
+	Upgrades         *prometheus.CounterVec
+	Uninstalls       *prometheus.CounterVec
+	Failures         *prometheus.CounterVec
+	DownloadBytes    *prometheus.CounterVec
+	ExecutionSeconds *prometheus.HistogramVec
+	ActiveExecutions *prometheus.GaugeVec
+}
+
+// New creates the plugin collectors and registers them on reg
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		Installs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "installs_total",
+			Help:      "Number of successful plugin installations.",
+		}, []string{"store"}),
+		Upgrades: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upgrades_total",
+			Help:      "Number of successful plugin upgrades.",
+		}, []string{"store"}),
+		Uninstalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uninstalls_total",
+			Help:      "Number of successful plugin removals.",
+		}, []string{}),
+		Failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failures_total",
+			Help:      "Number of failed plugin operations by operation and reason.",
+		}, []string{"operation", "reason"}),
+		DownloadBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "download_bytes_total",
+			Help:      "Number of plugin bytes downloaded from stores.",
+		}, []string{"store"}),
+		ExecutionSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "execution_duration_seconds",
+			Help:      "Duration of plugin executions by runtime.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		}, []string{"runtime", "success"}),
+		ActiveExecutions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_executions",
+			Help:      "Number of plugin executions currently running by runtime.",
+		}, []string{"runtime"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.Installs,
+		m.Upgrades,
+		m.Uninstalls,
+		m.Failures,
+		m.DownloadBytes,
+		m.ExecutionSeconds,
+		m.ActiveExecutions,
+	}
+
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Installed records a successful installation of size bytes from store
+func (m *Metrics) Installed(store string, size int64) {
+	if m == nil {
+		return
+	}
+
+	m.Installs.WithLabelValues(store).Inc()
+	m.DownloadBytes.WithLabelValues(store).Add(float64(size))
+}
+
+// Upgraded records a successful upgrade of size bytes from store
+func (m *Metrics) Upgraded(store string, size int64) {
+	if m == nil {
+		return
+	}
+
+	m.Upgrades.WithLabelValues(store).Inc()
+	m.DownloadBytes.WithLabelValues(store).Add(float64(size))
+}
+
+// Uninstalled records a successful removal
+func (m *Metrics) Uninstalled() {
+	if m == nil {
+		return
+	}
+
+	m.Uninstalls.WithLabelValues().Inc()
+}
+
+// Failed records a failed operation with the given reason
+func (m *Metrics) Failed(operation, reason string) {
+	if m == nil {
+		return
+	}
+
+	m.Failures.WithLabelValues(operation, reason).Inc()
+}
+
+// StartExecution marks an execution on runtime as active. The returned
+// function must be called when the execution finishes.
+func (m *Metrics) StartExecution(runtime string) func(success bool) {
+	if m == nil {
+		return func(bool) {}
+	}
+
+	start := time.Now()
+
+	m.ActiveExecutions.WithLabelValues(runtime).Inc()
+
+	return func(success bool) {
+		m.ActiveExecutions.WithLabelValues(runtime).Dec()
+
+		label := "false"
+		if success {
+			label = "true"
+		}
+
+		m.ExecutionSeconds.WithLabelValues(runtime, label).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,350 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+// Target receives mirrored plugin assets. Assets are addressed by plugin
+// name, version and platform using the <name>/<version>/<os>-<arch>/ layout
+// served by the local store.
+type Target interface {
+	// Lookup returns the metadata of a mirrored asset after verifying its
+	// integrity. It returns false when the asset is missing or corrupted.
+	Lookup(ctx context.Context, name, version string, platform extension.Platform) (*extension.Info, bool, error)
+
+	// Put stores an asset and its metadata
+	Put(ctx context.Context, info *extension.Info, platform extension.Platform, content []byte) error
+}
+
+// Options selects what a mirror run copies
+type Options struct {
+	Plugins   []string                // Plugins to mirror; when empty the source is enumerated with Criteria
+	Criteria  extension.SearchOptions // Search criteria used to enumerate the source
+	Platforms []extension.Platform    // Platforms to mirror; defaults to the local platform
+	Version   string                  // Version to mirror; defaults to latest
+}
+
+// Result records the outcome of mirroring one asset
+type Result struct {
+	Name     string
+	Version  string
+	Platform extension.Platform
+	Skipped  bool  // Already present and verified in the target
+	Err      error // Non-nil when the asset could not be mirrored
+}
+
+// Mirror copies plugin releases from a store into a target
+type Mirror struct {
+	source extension.Store
+	target Target
+	log    logr.Logger
+}
+
+// New creates a mirror from source to target
+func New(source extension.Store, target Target, logger logr.Logger) *Mirror {
+	return &Mirror{
+		source: source,
+		target: target,
+		log:    logger,
+	}
+}
+
+// Sync mirrors the selected plugins for every selected platform. Assets that
+// are already present in the target and pass verification are skipped, so
+// repeated runs only transfer what changed. Per-asset failures are reported
+// in the results; the returned error is reserved for failures that stop the
+// whole run.
+func (m *Mirror) Sync(ctx context.Context, opts Options) ([]Result, error) {
+	platforms := opts.Platforms
+	if len(platforms) == 0 {
+		platforms = []extension.Platform{extension.CurrentPlatform()}
+	}
+
+	plugins := make(map[string]string) // name to version
+	for _, name := range opts.Plugins {
+		plugins[name] = opts.Version
+	}
+
+	if len(opts.Plugins) == 0 {
+		infos, err := m.source.Search(ctx, opts.Criteria)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enumerate source store: %w", err)
+		}
+
+		for _, info := range infos {
+			version := opts.Version
+			if version == "" {
+				version = info.Version
+			}
+
+			plugins[info.Name] = version
+		}
+	}
+
+	var results []Result
+
+	for name, version := range plugins {
+		for _, platform := range platforms {
+			if err := ctx.Err(); err != nil {
+				return results, fmt.Errorf("mirror cancelled: %w", err)
+			}
+
+			results = append(results, m.syncOne(ctx, name, version, platform))
+		}
+	}
+
+	return results, nil
+}
+
+func (m *Mirror) syncOne(ctx context.Context, name, version string, platform extension.Platform) Result {
+	result := Result{Name: name, Version: version, Platform: platform}
+	logger := m.log.WithValues("name", name, "version", version, "platform", platform.String())
+
+	if version != "" && version != "latest" {
+		if _, ok, err := m.target.Lookup(ctx, name, version, platform); err != nil {
+			result.Err = err
+			return result
+		} else if ok {
+			logger.V(1).Info("asset already mirrored")
+			result.Skipped = true
+
+			return result
+		}
+	}
+
+	info, err := m.source.Fetch(extension.ContextWithPlatform(ctx, platform), name, version)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to fetch %s: %w", name, err)
+		return result
+	}
+
+	result.Version = info.Version
+
+	content, err := readContent(info.Content)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	digest := fmt.Sprintf("%x", sha256.Sum256(content))
+	if expected := info.Metadata["sha256"]; expected != "" && expected != digest {
+		result.Err = fmt.Errorf("checksum mismatch for %s@%s: expected %s, got %s", name, info.Version, expected, digest)
+		return result
+	}
+
+	// The version is only known after fetching "latest"
+	if version == "" || version == "latest" {
+		if existing, ok, err := m.target.Lookup(ctx, name, info.Version, platform); err == nil && ok && existing.Metadata["sha256"] == digest {
+			logger.V(1).Info("asset already mirrored", "version", info.Version)
+			result.Skipped = true
+
+			return result
+		}
+	}
+
+	mirrored := *info
+	mirrored.Content = nil
+	mirrored.Metadata = make(map[string]string, len(info.Metadata)+1)
+
+	for k, v := range info.Metadata {
+		mirrored.Metadata[k] = v
+	}
+
+	mirrored.Metadata["sha256"] = digest
+
+	if mirrored.FileName == "" {
+		mirrored.FileName = name
+	}
+
+	if err := m.target.Put(ctx, &mirrored, platform, content); err != nil {
+		result.Err = fmt.Errorf("failed to store %s@%s: %w", name, info.Version, err)
+		return result
+	}
+
+	logger.Info("mirrored asset", "version", info.Version, "size", len(content))
+
+	return result
+}
+
+func readContent(content interface{}) ([]byte, error) {
+	switch v := content.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case io.Reader:
+		data, err := io.ReadAll(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plugin content: %w", err)
+		}
+
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported plugin data type: %T", content)
+	}
+}
+
+// assetKey returns the slash-separated location of an asset directory
+func assetKey(name, version string, platform extension.Platform) string {
+	return path.Join(name, version, platform.OS+"-"+platform.Arch)
+}
+
+var _ Target = &DirTarget{}
+
+// DirTarget mirrors assets into a local directory
+type DirTarget struct {
+	Dir string
+}
+
+// Lookup implements Target
+func (t *DirTarget) Lookup(_ context.Context, name, version string, platform extension.Platform) (*extension.Info, bool, error) {
+	dir := filepath.Join(t.Dir, filepath.FromSlash(assetKey(name, version, platform)))
+
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read mirrored metadata: %w", err)
+	}
+
+	var info extension.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, false, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, info.FileName))
+	if err != nil {
+		return nil, false, nil
+	}
+
+	return &info, verify(&info, content), nil
+}
+
+// Put implements Target
+func (t *DirTarget) Put(_ context.Context, info *extension.Info, platform extension.Platform, content []byte) error {
+	dir := filepath.Join(t.Dir, filepath.FromSlash(assetKey(info.Name, info.Version, platform)))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	metadata, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	// Write the asset first so that metadata only ever describes a complete file
+	if err := writeAtomic(filepath.Join(dir, info.FileName), content, 0755); err != nil {
+		return err
+	}
+
+	return writeAtomic(filepath.Join(dir, "metadata.json"), metadata, 0644)
+}
+
+// Bucket is the minimal object storage API needed to mirror into a bucket.
+// It is satisfied by thin wrappers around S3-compatible clients.
+type Bucket interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+var _ Target = &BucketTarget{}
+
+// BucketTarget mirrors assets into object storage under an optional prefix,
+// using the same layout as DirTarget
+type BucketTarget struct {
+	Bucket Bucket
+	Prefix string
+}
+
+// Lookup implements Target. Any read error is treated as a missing asset.
+func (t *BucketTarget) Lookup(ctx context.Context, name, version string, platform extension.Platform) (*extension.Info, bool, error) {
+	key := path.Join(t.Prefix, assetKey(name, version, platform))
+
+	data, err := t.Bucket.Get(ctx, key+"/metadata.json")
+	if err != nil {
+		return nil, false, nil
+	}
+
+	var info extension.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, false, nil
+	}
+
+	content, err := t.Bucket.Get(ctx, key+"/"+info.FileName)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	return &info, verify(&info, content), nil
+}
+
+// Put implements Target
+func (t *BucketTarget) Put(ctx context.Context, info *extension.Info, platform extension.Platform, content []byte) error {
+	key := path.Join(t.Prefix, assetKey(info.Name, info.Version, platform))
+
+	metadata, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := t.Bucket.Put(ctx, key+"/"+info.FileName, content); err != nil {
+		return fmt.Errorf("failed to upload asset: %w", err)
+	}
+
+	if err := t.Bucket.Put(ctx, key+"/metadata.json", metadata); err != nil {
+		return fmt.Errorf("failed to upload metadata: %w", err)
+	}
+
+	return nil
+}
+
+// verify reports whether content matches the digest recorded in info
+func verify(info *extension.Info, content []byte) bool {
+	expected := info.Metadata["sha256"]
+
+	return expected != "" && expected == fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+func writeAtomic(target string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(target), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(target), err)
+	}
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", filepath.Base(target), err)
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(target), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// namespaceTXTPrefix is prepended to a namespace's domain to find the TXT
+// records listing its signing identities
+const namespaceTXTPrefix = "_pluginkit."
+
+// Namespace binds a publisher namespace, the owner part of owner/name
+// plugin names, to the identities allowed to sign its plugins. Plugins of
+// a bound namespace are refused unless their provenance was signed by one
+// of them, whichever store they are installed from.
+type Namespace struct {
+	Owner string `mapstructure:"owner"` // e.g. acme, for acme/*
+
+	// Identities are glob patterns of certificate identities or builder
+	// IDs, e.g. https://github.com/acme/*
+	Identities []string `mapstructure:"identities"`
+
+	// Domain publishes further identities in TXT records of
+	// _pluginkit.<domain>, one "identity=<pattern>" per record, so the
+	// publisher can maintain them
+	Domain string `mapstructure:"domain"`
+}
+
+// ErrNamespaceUnverified is returned when a plugin of a bound namespace was
+// not signed by an identity of the namespace
+type ErrNamespaceUnverified struct {
+	Plugin     string
+	Owner      string
+	Identities []string // Identities the plugin was signed with
+}
+
+func (e *ErrNamespaceUnverified) Error() string {
+	if len(e.Identities) == 0 {
+		return fmt.Sprintf("plugin %s claims namespace %s but carries no signed provenance", e.Plugin, e.Owner)
+	}
+
+	return fmt.Sprintf("plugin %s claims namespace %s but was signed by %s", e.Plugin, e.Owner, strings.Join(e.Identities, ", "))
+}
+
+// UserMessage implements UserMessager
+func (e *ErrNamespaceUnverified) UserMessage() Message {
+	return Message{Key: MsgNamespaceUnverified, Params: map[string]string{
+		"plugin": e.Plugin, "owner": e.Owner, "identities": strings.Join(e.Identities, ", "),
+	}}
+}
+
+// WithNamespaces binds publisher namespaces to their signing identities.
+// Installs and upgrades of plugins in other namespaces are not affected.
+func (m *Manager) WithNamespaces(namespaces ...Namespace) *Manager {
+	if m.namespaces == nil {
+		m.namespaces = make(map[string]Namespace, len(namespaces))
+	}
+
+	for _, ns := range namespaces {
+		m.namespaces[strings.ToLower(ns.Owner)] = ns
+	}
+
+	return m
+}
+
+// verifyNamespace checks that a plugin about to be installed or upgraded
+// was signed by an identity bound to its namespace
+func (m *Manager) verifyNamespace(ctx context.Context, input PolicyInput) error {
+	owner := namespaceOwner(input)
+	if owner == "" {
+		return nil
+	}
+
+	ns, ok := m.namespaces[strings.ToLower(owner)]
+	if !ok {
+		return nil
+	}
+
+	allowed := ns.Identities
+
+	if ns.Domain != "" {
+		published, err := lookupNamespaceIdentities(ctx, ns.Domain)
+		if err != nil {
+			m.metrics.Failed(string(input.Action), metrics.ReasonProvenance)
+			return fmt.Errorf("failed to look up identities of namespace %s: %w", ns.Owner, err)
+		}
+
+		allowed = append(append([]string(nil), allowed...), published...)
+	}
+
+	if !matchAny(allowed, input.SignatureIdentities...) {
+		m.metrics.Failed(string(input.Action), metrics.ReasonProvenance)
+		return &ErrNamespaceUnverified{Plugin: input.Plugin, Owner: owner, Identities: input.SignatureIdentities}
+	}
+
+	return nil
+}
+
+// namespaceOwner returns the namespace the plugin's name claims. Only the
+// name decides, so that a store reporting another owner cannot clear it.
+func namespaceOwner(input PolicyInput) string {
+	name := input.Upstream
+	if name == "" {
+		name = input.Plugin
+	}
+
+	owner, _, ok := strings.Cut(name, "/")
+	if !ok {
+		return ""
+	}
+
+	return owner
+}
+
+// lookupNamespaceIdentities reads the identities a domain publishes
+func lookupNamespaceIdentities(ctx context.Context, domain string) ([]string, error) {
+	records, err := net.DefaultResolver.LookupTXT(ctx, namespaceTXTPrefix+domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []string
+	for _, record := range records {
+		if identity, ok := strings.CutPrefix(strings.TrimSpace(record), "identity="); ok && identity != "" {
+			identities = append(identities, identity)
+		}
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,134 @@
+package extension
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Operations reported in OpError.Op
+const (
+	OpInstall   = "install"
+	OpUninstall = "uninstall"
+	OpUpgrade   = "upgrade"
+	OpExecute   = "execute"
+	OpExplain   = "explain"
+	OpFetch     = "fetch"
+	OpHelp      = "help"
+	OpSearch    = "search"
+	OpVerify    = "verify"
+	OpEnable    = "enable"
+	OpDisable   = "disable"
+	OpSetStatus = "set status"
+	OpSync      = "sync"
+
+	OpDefineGroup    = "define group"
+	OpDeleteGroup    = "delete group"
+	OpInstallGroup   = "install group"
+	OpUpgradeGroup   = "upgrade group"
+	OpUninstallGroup = "uninstall group"
+	OpSetGroupStatus = "set group status"
+)
+
+// OpError is returned by the operations of the Manager. It records the
+// operation, the plugin and the store involved, so that failures can be
+// reported and logged consistently. The cause is kept in Err and can be
+// inspected with errors.Is and errors.As.
+type OpError struct {
+	Op     string
+	Plugin string // Empty for operations on no single plugin, e.g. search
+	Group  string // Group of the group operations
+	Store  string // Source the plugin came from, empty for the default store
+	Err    error
+}
+
+func (e *OpError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(e.Op)
+
+	if e.Plugin != "" {
+		b.WriteString(" " + e.Plugin)
+	}
+
+	if e.Group != "" {
+		b.WriteString(" " + e.Group)
+	}
+
+	if e.Store != "" {
+		b.WriteString(" (" + e.Store + ")")
+	}
+
+	b.WriteString(": " + e.Err.Error())
+
+	return b.String()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// KeysAndValues returns the context of the error as logr key/value pairs:
+//
+//	logger.Error(err, "operation failed", opErr.KeysAndValues()...)
+func (e *OpError) KeysAndValues() []interface{} {
+	kv := []interface{}{"op", e.Op}
+
+	if e.Plugin != "" {
+		kv = append(kv, "plugin", e.Plugin)
+	}
+
+	if e.Group != "" {
+		kv = append(kv, "group", e.Group)
+	}
+
+	if e.Store != "" {
+		kv = append(kv, "store", e.Store)
+	}
+
+	return kv
+}
+
+// wrapOp returns err as an *OpError. Errors already carrying the context of
+// the same plugin, as when an operation calls another, are returned
+// unchanged.
+func wrapOp(op, plugin, store string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var opErr *OpError
+	if errors.As(err, &opErr) && opErr.Plugin == plugin && opErr.Group == "" {
+		return err
+	}
+
+	return &OpError{Op: op, Plugin: plugin, Store: store, Err: err}
+}
+
+// wrapGroupOp returns err as an *OpError of an operation on a group
+func wrapGroupOp(op, group string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var opErr *OpError
+	if errors.As(err, &opErr) && opErr.Plugin == "" && opErr.Group == group {
+		return err
+	}
+
+	return &OpError{Op: op, Group: group, Err: err}
+}
+
+// storeOf returns the source an installed plugin was installed from, as
+// recorded in its metadata, or "" for the default store or when it is not
+// installed
+func (m *Manager) storeOf(name string) string {
+	dir, _ := m.locate(name)
+
+	info, err := m.cache.read(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return ""
+	}
+
+	return info.Metadata["source"]
+}
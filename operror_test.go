@@ -0,0 +1,68 @@
+package extension_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestOperationErrorsCarryContext(t *testing.T) {
+	ctx := context.Background()
+
+	mirror := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.0.0", Runtime: "wasm", Content: []byte("hello")})
+
+	registry := extension.NewRegistry()
+	registry.RegisterStore("mirror", mirror)
+
+	manager := extension.NewManager("/plugins", storetest.New(), logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithRegistry(registry)
+
+	if err := manager.Install(ctx, "mirror/hello", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	if err := manager.DefineGroup(ctx, extension.Group{Name: "tools", Plugins: []string{"mirror/hello", "missing"}}); err != nil {
+		t.Fatalf("DefineGroup() error = %v", err)
+	}
+
+	_, executeErr := manager.Execute(ctx, "hello", extension.ExecuteOptions{})
+
+	tests := []struct {
+		name   string
+		err    error
+		op     string
+		plugin string
+		group  string
+		store  string
+	}{
+		{name: "execute", err: executeErr, op: extension.OpExecute, plugin: "hello", store: "mirror"},
+		{name: "upgrade", err: manager.Upgrade(ctx, "hello", "2.0.0"), op: extension.OpUpgrade, plugin: "hello", store: "mirror"},
+		{name: "enable", err: manager.Enable(ctx, "missing"), op: extension.OpEnable, plugin: "missing"},
+		{name: "disable", err: manager.Disable(ctx, "missing"), op: extension.OpDisable, plugin: "missing"},
+		{name: "set status", err: manager.SetStatus(ctx, []string{"missing"}, extension.StatusDisabled), op: extension.OpSetStatus, plugin: "missing"},
+		{name: "install group", err: manager.InstallGroup(ctx, "tools", extension.InstallOptions{}), op: extension.OpInstallGroup, group: "tools"},
+		{name: "delete group", err: manager.DeleteGroup(ctx, "absent"), op: extension.OpDeleteGroup, group: "absent"},
+		{name: "sync", err: func() error { _, err := manager.Sync(ctx, "/absent/.extensions.yaml"); return err }(), op: extension.OpSync},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opErr *extension.OpError
+			if !errors.As(tt.err, &opErr) {
+				t.Fatalf("error = %v, want *OpError", tt.err)
+			}
+
+			if opErr.Op != tt.op || opErr.Plugin != tt.plugin || opErr.Group != tt.group || opErr.Store != tt.store {
+				t.Errorf("OpError = {%q %q %q %q}, want {%q %q %q %q}",
+					opErr.Op, opErr.Plugin, opErr.Group, opErr.Store, tt.op, tt.plugin, tt.group, tt.store)
+			}
+		})
+	}
+}
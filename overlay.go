@@ -0,0 +1,162 @@
+package extension
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Layers a plugin can be installed in, reported in Info.Layer when a system
+// plugin directory is configured
+const (
+	LayerUser   = "user"
+	LayerSystem = "system"
+)
+
+// ErrReadOnlyPlugin is returned when changing a plugin installed in the
+// system plugin directory
+type ErrReadOnlyPlugin struct {
+	Plugin string
+	Dir    string
+}
+
+func (e *ErrReadOnlyPlugin) Error() string {
+	return fmt.Sprintf("plugin %s is installed system-wide in %s and cannot be changed", e.Plugin, e.Dir)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrReadOnlyPlugin) UserMessage() Message {
+	return Message{Key: MsgReadOnlyPlugin, Params: map[string]string{"plugin": e.Plugin, "dir": e.Dir}}
+}
+
+// WithSystemDir layers a read-only plugin directory, such as
+// /usr/lib/app/plugins, under the Manager's own. List, Fetch and Execute
+// see the plugins of both; a plugin installed in the user directory
+// shadows the system plugin of the same name, which Install allows.
+// Upgrade, Uninstall and status changes refuse system plugins with
+// *ErrReadOnlyPlugin.
+//
+// System plugins are trusted as installed by the administrator: their
+// metadata is not checked against the Manager's key and their first run is
+// not submitted for approval. Executors are created for one plugin
+// directory, so the ones running system plugins are registered with
+// WithSystemExecutor.
+func (m *Manager) WithSystemDir(dir string) *Manager {
+	m.systemDir = dir
+	return m
+}
+
+// WithSystemExecutor registers the executor running the system plugins of
+// the given runtime
+func (m *Manager) WithSystemExecutor(runtime string, executor Executor) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.systemExecutors == nil {
+		m.systemExecutors = make(map[string]Executor)
+	}
+
+	m.systemExecutors[runtime] = executor
+
+	return m
+}
+
+// locate returns the directory and layer of an installed plugin. Plugins
+// that are not installed are located in the user directory.
+func (m *Manager) locate(name string) (string, string) {
+	userDir := filepath.Join(m.pluginDir, name)
+	if m.systemDir == "" || hasMetadata(m.fs, userDir) {
+		return userDir, LayerUser
+	}
+
+	if systemDir := filepath.Join(m.systemDir, name); hasMetadata(m.fs, systemDir) {
+		return systemDir, LayerSystem
+	}
+
+	return userDir, LayerUser
+}
+
+// readLayered reads the metadata of an installed plugin from the layer that
+// provides it
+func (m *Manager) readLayered(name string) (*Info, error) {
+	dir, layer := m.locate(name)
+
+	info, err := m.cache.read(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if m.systemDir != "" {
+		info.Layer = layer
+	}
+
+	return info, nil
+}
+
+// listSystem returns the system plugins not shadowed by a user plugin
+func (m *Manager) listSystem(shadowed map[string]bool) ([]Info, error) {
+	if m.systemDir == "" {
+		return nil, nil
+	}
+
+	entries, err := m.fs.ReadDir(m.systemDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read system plugin directory: %w", err)
+	}
+
+	var plugins []Info
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !isPluginDirName(name) || shadowed[name] {
+			continue
+		}
+
+		info, err := m.cache.read(filepath.Join(m.systemDir, name, "metadata.json"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read metadata for system plugin %s: %w", name, err)
+		}
+
+		info.Layer = LayerSystem
+		plugins = append(plugins, *info)
+	}
+
+	return plugins, nil
+}
+
+// checkWritable refuses changes to a plugin only installed system-wide
+func (m *Manager) checkWritable(name string) error {
+	if dir, layer := m.locate(name); layer == LayerSystem {
+		return &ErrReadOnlyPlugin{Plugin: name, Dir: dir}
+	}
+
+	return nil
+}
+
+// executorFor returns the executor running an installed plugin
+func (m *Manager) executorFor(info *Info) (Executor, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if info.Layer == LayerSystem {
+		executor, ok := m.systemExecutors[info.Runtime]
+		return executor, ok
+	}
+
+	executor, ok := m.executors[info.Runtime]
+
+	return executor, ok
+}
+
+func hasMetadata(fsys FS, dir string) bool {
+	_, err := fsys.Stat(filepath.Join(dir, "metadata.json"))
+	return err == nil
+}
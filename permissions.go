@@ -0,0 +1,266 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/hostctx"
+)
+
+// ErrPermissionDenied is returned when the host declines the permissions a
+// plugin requests
+var ErrPermissionDenied = errors.New("plugin permissions were not approved")
+
+// Permissions declares the capabilities a plugin needs. A nil *Permissions
+// means the plugin did not declare any and is not restricted.
+type Permissions struct {
+	Network    bool     `json:"network,omitempty"`    // Outbound network access
+	Filesystem []string `json:"filesystem,omitempty"` // Host paths the plugin may access
+	Env        []string `json:"env,omitempty"`        // Environment variable names or glob patterns passed through
+	Secrets    []string `json:"secrets,omitempty"`    // Named secrets the plugin may receive
+	Devices    []string `json:"devices,omitempty"`    // Host devices passed to container plugins, e.g. /dev/fuse
+	GPUs       string   `json:"gpus,omitempty"`       // GPUs passed to container plugins: all, a count or device=<ids>
+}
+
+// DevicePolicy limits the devices container executors pass through to
+// plugins. The zero value permits none.
+type DevicePolicy struct {
+	AllowGPUs      bool     `mapstructure:"allow_gpus"`
+	AllowedDevices []string `mapstructure:"allowed_devices"` // Host device paths or glob patterns
+}
+
+// ApprovalFunc is called at install time with the permissions a plugin
+// requests. Returning false aborts the installation.
+type ApprovalFunc func(ctx context.Context, info Info, permissions Permissions) (bool, error)
+
+// String returns a short human readable summary for consent prompts
+func (p *Permissions) String() string {
+	if p == nil {
+		return "unrestricted"
+	}
+
+	var parts []string
+
+	if p.Network {
+		parts = append(parts, "network")
+	}
+
+	if len(p.Filesystem) > 0 {
+		parts = append(parts, "filesystem: "+strings.Join(p.Filesystem, ", "))
+	}
+
+	if len(p.Env) > 0 {
+		parts = append(parts, "env: "+strings.Join(p.Env, ", "))
+	}
+
+	if len(p.Secrets) > 0 {
+		parts = append(parts, "secrets: "+strings.Join(p.Secrets, ", "))
+	}
+
+	if len(p.Devices) > 0 {
+		parts = append(parts, "devices: "+strings.Join(p.Devices, ", "))
+	}
+
+	if p.GPUs != "" {
+		parts = append(parts, "gpus: "+p.GPUs)
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// AllowsEnv reports whether the environment variable may be passed to the
+// plugin. The host context variable is always allowed.
+func (p *Permissions) AllowsEnv(name string) bool {
+	if p == nil || name == hostctx.EnvVar {
+		return true
+	}
+
+	for _, pattern := range append(p.Env, p.Secrets...) {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterEnv returns the subset of env the plugin is allowed to see
+func (p *Permissions) FilterEnv(env map[string]string) map[string]string {
+	if p == nil || env == nil {
+		return env
+	}
+
+	filtered := make(map[string]string, len(env))
+
+	for k, v := range env {
+		if p.AllowsEnv(k) {
+			filtered[k] = v
+		}
+	}
+
+	return filtered
+}
+
+// AllowsPath reports whether the host path lies within a granted filesystem path
+func (p *Permissions) AllowsPath(path string) bool {
+	if p == nil {
+		return true
+	}
+
+	path = filepath.Clean(path)
+
+	for _, allowed := range p.Filesystem {
+		allowed = filepath.Clean(allowed)
+		if path == allowed || strings.HasPrefix(path, allowed+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withPath returns a copy of the permissions that also grants path
+func (p *Permissions) withPath(path string) *Permissions {
+	if p == nil {
+		return nil
+	}
+
+	granted := *p
+	granted.Filesystem = append(append([]string{}, p.Filesystem...), path)
+
+	return &granted
+}
+
+// Restrict returns the permissions of p that limit also grants. Nil
+// permissions are unrestricted, so restricting them yields limit, and a nil
+// limit leaves p as is.
+func (p *Permissions) Restrict(limit *Permissions) *Permissions {
+	if limit == nil {
+		return p
+	}
+
+	if p == nil {
+		p = &Permissions{
+			Network:    true,
+			Filesystem: limit.Filesystem,
+			Env:        limit.Env,
+			Secrets:    limit.Secrets,
+			Devices:    limit.Devices,
+			GPUs:       limit.GPUs,
+		}
+	}
+
+	restricted := &Permissions{Network: p.Network && limit.Network}
+
+	for _, path := range p.Filesystem {
+		if limit.AllowsPath(path) {
+			restricted.Filesystem = append(restricted.Filesystem, path)
+		}
+	}
+
+	for _, name := range p.Env {
+		if matchAny(limit.Env, name) {
+			restricted.Env = append(restricted.Env, name)
+		}
+	}
+
+	for _, name := range p.Secrets {
+		if matchAny(limit.Secrets, name) {
+			restricted.Secrets = append(restricted.Secrets, name)
+		}
+	}
+
+	for _, device := range p.Devices {
+		host, _, _ := strings.Cut(device, ":")
+		if matchAny(limit.Devices, device, host) {
+			restricted.Devices = append(restricted.Devices, device)
+		}
+	}
+
+	if limit.GPUs != "" {
+		restricted.GPUs = p.GPUs
+	}
+
+	return restricted
+}
+
+// NetworkMode returns the container network mode to use, falling back to
+// "none" when the plugin did not request network access
+func (p *Permissions) NetworkMode(configured string) string {
+	if p != nil && !p.Network {
+		return "none"
+	}
+
+	return configured
+}
+
+// GrantedDevices returns the devices and GPUs the plugin requested, failing
+// when the policy does not permit one of them. Plugins without declared
+// permissions get no devices.
+func (p *Permissions) GrantedDevices(policy DevicePolicy) ([]string, string, error) {
+	if p == nil {
+		return nil, "", nil
+	}
+
+	if p.GPUs != "" && !policy.AllowGPUs {
+		return nil, "", fmt.Errorf("plugin requests GPUs but the executor does not permit them")
+	}
+
+	for _, device := range p.Devices {
+		if !policy.allowsDevice(device) {
+			return nil, "", fmt.Errorf("plugin requests device %s which the executor does not permit", device)
+		}
+	}
+
+	return p.Devices, p.GPUs, nil
+}
+
+// Clone returns a copy of the policy that shares no slices with it
+func (p DevicePolicy) Clone() DevicePolicy {
+	p.AllowedDevices = append([]string(nil), p.AllowedDevices...)
+	return p
+}
+
+func (p DevicePolicy) allowsDevice(device string) bool {
+	// Only the host side of host:container[:mode] is checked
+	host, _, _ := strings.Cut(device, ":")
+
+	for _, pattern := range p.AllowedDevices {
+		if matched, _ := filepath.Match(pattern, host); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// approvePermissions runs the approval callback for the plugin's permissions
+func (m *Manager) approvePermissions(ctx context.Context, info *Info) error {
+	if m.approve == nil || info.Permissions == nil {
+		return nil
+	}
+
+	ok, err := m.approve(ctx, *info, *info.Permissions)
+	if err != nil {
+		return fmt.Errorf("failed to approve permissions: %w", err)
+	}
+
+	if !ok {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// WithApproval sets the callback used to approve plugin permissions
+func (m *Manager) WithApproval(approve ApprovalFunc) *Manager {
+	m.approve = approve
+	return m
+}
@@ -0,0 +1,131 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/events"
+)
+
+// Phase is a stage of an install or upgrade that can be given its own deadline
+type Phase string
+
+const (
+	PhaseFetch   Phase = "fetch"   // Downloading the plugin from its store
+	PhaseExtract Phase = "extract" // Writing and unpacking the plugin files
+	PhaseVerify  Phase = "verify"  // Scanning the extracted plugin
+	PhaseBuild   Phase = "build"   // Building the plugin's container image
+)
+
+// Timeouts bounds each phase of an install or upgrade. A zero duration
+// leaves the phase bounded only by the caller's context.
+type Timeouts struct {
+	Fetch     time.Duration
+	Extract   time.Duration
+	Verify    time.Duration
+	Build     time.Duration
+	Heartbeat time.Duration // Interval of progress events while a phase runs, 0 disables them
+}
+
+func (t Timeouts) phase(p Phase) time.Duration {
+	switch p {
+	case PhaseFetch:
+		return t.Fetch
+	case PhaseExtract:
+		return t.Extract
+	case PhaseVerify:
+		return t.Verify
+	case PhaseBuild:
+		return t.Build
+	default:
+		return 0
+	}
+}
+
+// PhaseTimeoutError is returned when a phase exceeds its configured timeout
+type PhaseTimeoutError struct {
+	Plugin  string
+	Phase   Phase
+	Timeout time.Duration
+}
+
+func (e *PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("plugin %s: %s phase timed out after %s", e.Plugin, e.Phase, e.Timeout)
+}
+
+// UserMessage implements UserMessager
+func (e *PhaseTimeoutError) UserMessage() Message {
+	return Message{Key: MsgPhaseTimeout, Params: map[string]string{
+		"plugin": e.Plugin, "phase": string(e.Phase), "timeout": e.Timeout.String(),
+	}}
+}
+
+func (e *PhaseTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WithTimeouts sets per-phase deadlines and the progress heartbeat interval
+// for installs and upgrades
+func (m *Manager) WithTimeouts(t Timeouts) *Manager {
+	m.timeouts = t
+	return m
+}
+
+// runPhase runs fn under the deadline configured for the phase and
+// publishes progress heartbeats until it returns. A deadline hit by the
+// phase itself, rather than by the caller's context, is reported as
+// *PhaseTimeoutError.
+func (m *Manager) runPhase(ctx context.Context, name, version string, phase Phase, fn func(ctx context.Context) error) error {
+	timeout := m.timeouts.phase(phase)
+
+	phaseCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		phaseCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if interval := m.timeouts.Heartbeat; interval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+
+		go m.heartbeat(done, name, version, phase, interval)
+	}
+
+	err := fn(phaseCtx)
+	if err != nil && timeout > 0 && ctx.Err() == nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return &PhaseTimeoutError{Plugin: name, Phase: phase, Timeout: timeout}
+	}
+
+	return err
+}
+
+// heartbeat publishes a progress event every interval until done is closed
+func (m *Manager) heartbeat(done <-chan struct{}, name, version string, phase Phase, interval time.Duration) {
+	start := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			elapsed := time.Since(start).Round(time.Millisecond)
+
+			m.logger.V(1).Info("phase in progress", "plugin", name, "phase", string(phase), "elapsed", elapsed.String())
+			m.events.Publish(events.Event{
+				Type:    events.InstallProgress,
+				Plugin:  name,
+				Version: version,
+				Metadata: map[string]string{
+					"phase":   string(phase),
+					"elapsed": elapsed.String(),
+				},
+			})
+		}
+	}
+}
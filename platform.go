@@ -0,0 +1,52 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform identifies an operating system and architecture pair
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// CurrentPlatform returns the platform of the running process
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses an os/arch string such as linux/arm64
+func ParsePlatform(s string) (Platform, error) {
+	goos, arch, ok := strings.Cut(s, "/")
+	if !ok || goos == "" || arch == "" || strings.Contains(arch, "/") {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected <os>/<arch>", s)
+	}
+
+	return Platform{OS: goos, Arch: arch}, nil
+}
+
+// String returns the platform in os/arch form
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+type platformKey struct{}
+
+// ContextWithPlatform returns a context instructing stores to fetch assets
+// for the given platform instead of the local one
+func ContextWithPlatform(ctx context.Context, p Platform) context.Context {
+	return context.WithValue(ctx, platformKey{}, p)
+}
+
+// PlatformFromContext returns the target platform carried by ctx, falling
+// back to the platform of the running process
+func PlatformFromContext(ctx context.Context) Platform {
+	if p, ok := ctx.Value(platformKey{}).(Platform); ok {
+		return p
+	}
+
+	return CurrentPlatform()
+}
@@ -1,6 +1,7 @@
 package extension
 
 import (
+	"encoding/json"
 	"io"
 	"time"
 )
@@ -23,13 +24,29 @@ type Plugin struct {
 
 // Info represents metadata about a plugin
 type Info struct {
-	Name        string            `json:"name"`
-	FileName    string            `json:"filename"`
-	Version     string            `json:"version"`
-	Description string            `json:"description"`
-	Store       string            `json:"store"`              // Identifier for the store (github, gitlab, local, etc)
-	Runtime     string            `json:"runtime"`            // Identifier for the runtime (local, docker, etc)
-	Metadata    map[string]string `json:"metadata,omitempty"` // Additional store/runner specific metadata
-	Status      string            `json:"status,omitempty"`   // Status of the plugin (enabled, disabled)
-	Content     interface{}       `json:"content,omitempty"`  // Content of the plugin file
+	Name         string                   `json:"name"`
+	FileName     string                   `json:"filename"`
+	Version      string                   `json:"version"`
+	Description  string                   `json:"description"`
+	Store        string                   `json:"store"`                  // Identifier for the store (github, gitlab, local, etc)
+	Runtime      string                   `json:"runtime"`                // Identifier for the runtime (local, docker, etc)
+	Metadata     map[string]string        `json:"metadata,omitempty"`     // Additional store/runner specific metadata
+	Status       Status                   `json:"status,omitempty"`       // Status of the plugin (enabled, disabled)
+	Content      interface{}              `json:"content,omitempty"`      // Content of the plugin file
+	Entrypoint   string                   `json:"entrypoint,omitempty"`   // Script path or module:function started by interpreted runtimes
+	Sources      []string                 `json:"sources,omitempty"`      // Stores offering this plugin, in priority order
+	Permissions  *Permissions             `json:"permissions,omitempty"`  // Capabilities requested by the plugin
+	Requirements *Requirements            `json:"requirements,omitempty"` // Environment constraints of the plugin
+	Attestations []Attestation            `json:"attestations,omitempty"` // SBOMs and provenance published with the plugin
+	ConfigSchema json.RawMessage          `json:"configSchema,omitempty"` // JSON schema of the user configuration
+	Volumes      []Volume                 `json:"volumes,omitempty"`      // Named volumes and caches mounted into container plugins
+	Image        string                   `json:"image,omitempty"`        // Container image ID built at install time, run instead of the plugin name
+	Groups       []string                 `json:"groups,omitempty"`       // Groups the installed plugin belongs to
+	Labels       []string                 `json:"labels,omitempty"`       // Labels the host attached to the installed plugin
+	Deprecation  *Deprecation             `json:"deprecation,omitempty"`  // Set by stores for deprecated or yanked versions
+	Popularity   *Popularity              `json:"popularity,omitempty"`   // Downloads, stars and ratings reported for search results
+	Keywords     []string                 `json:"keywords,omitempty"`     // Search terms describing the plugin
+	Commands     []Command                `json:"commands,omitempty"`     // Subcommands the plugin contributes to the host CLI
+	Files        map[string]InstalledFile `json:"files,omitempty"`        // Bill of the installed files, keyed by path relative to the plugin directory
+	Layer        string                   `json:"layer,omitempty"`        // Directory layer the plugin is installed in (user, system), set when a system directory is configured
 }
@@ -0,0 +1,201 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// PolicyAction is the operation a policy is asked to admit
+type PolicyAction string
+
+const (
+	PolicyInstall PolicyAction = "install"
+	PolicyUpgrade PolicyAction = "upgrade"
+	PolicyExecute PolicyAction = "execute"
+)
+
+// PolicyInput describes the plugin an operation applies to. Its JSON form
+// is what CEL expressions and rego queries evaluate, e.g.
+//
+//	input.action == "install" && input.owner in ["acme", "acme-labs"]
+type PolicyInput struct {
+	Action              PolicyAction `json:"action"`
+	Plugin              string       `json:"plugin"`
+	Version             string       `json:"version"`
+	Upstream            string       `json:"upstream,omitempty"` // Name the plugin is published under, when installed under an alias
+	Source              string       `json:"source,omitempty"`   // Configured store the plugin comes from, empty for the default store
+	Store               string       `json:"store"`              // Type of that store, e.g. github
+	Owner               string       `json:"owner,omitempty"`    // Publishing user or organization
+	Runtime             string       `json:"runtime"`
+	Stars               int          `json:"stars"` // Reported by the store at install and upgrade, 0 otherwise
+	Permissions         *Permissions `json:"permissions,omitempty"`
+	SignatureIdentities []string     `json:"signatureIdentities,omitempty"` // Signers and builders of the provenance covering the artifact
+	SLSALevel           int          `json:"slsaLevel"`
+	Labels              []string     `json:"labels,omitempty"`
+	Trust               TrustLevel   `json:"trust,omitempty"` // Set when the host configured trust levels
+}
+
+// PolicyDecision is the outcome of a policy evaluation
+type PolicyDecision struct {
+	Allow   bool
+	Reasons []string // Why the operation was denied, shown to the user
+}
+
+// Policy decides centrally whether plugins may be installed, upgraded or
+// run. Implementations typically wrap a compiled CEL program or rego query
+// evaluated against the JSON form of PolicyInput.
+type Policy interface {
+	Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+}
+
+// PolicyFunc adapts a function to the Policy interface
+type PolicyFunc func(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+
+// Evaluate implements Policy
+func (f PolicyFunc) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	return f(ctx, input)
+}
+
+// PolicyDeniedError is returned when a policy refuses an operation
+type PolicyDeniedError struct {
+	Plugin  string
+	Action  PolicyAction
+	Reasons []string
+}
+
+func (e *PolicyDeniedError) Error() string {
+	msg := fmt.Sprintf("policy denied %s of plugin %s", e.Action, e.Plugin)
+	if len(e.Reasons) > 0 {
+		msg += ": " + strings.Join(e.Reasons, "; ")
+	}
+
+	return msg
+}
+
+// UserMessage implements UserMessager
+func (e *PolicyDeniedError) UserMessage() Message {
+	return Message{Key: MsgPolicyDenied, Params: map[string]string{
+		"plugin": e.Plugin, "action": string(e.Action), "reasons": strings.Join(e.Reasons, "; "),
+	}}
+}
+
+// WithPolicy evaluates policy before every install, upgrade and execution.
+// An operation the policy denies, or that it fails to evaluate, is refused.
+func (m *Manager) WithPolicy(policy Policy) *Manager {
+	m.policy = policy
+	return m
+}
+
+// admit evaluates the source rules, then the policy, for an operation on
+// the plugin
+func (m *Manager) admit(ctx context.Context, input PolicyInput) error {
+	var policies []Policy
+	if m.sourceRules != nil {
+		policies = append(policies, m.sourceRules)
+	}
+
+	if m.policy != nil {
+		policies = append(policies, m.policy)
+	}
+
+	for _, policy := range policies {
+		decision, err := policy.Evaluate(ctx, input)
+		if err != nil {
+			m.metrics.Failed(string(input.Action), metrics.ReasonPolicy)
+			return fmt.Errorf("failed to evaluate policy: %w", err)
+		}
+
+		if !decision.Allow {
+			m.metrics.Failed(string(input.Action), metrics.ReasonPolicy)
+			m.logger.Info("policy denied operation", "plugin", input.Plugin, "action", input.Action, "reasons", decision.Reasons)
+
+			return &PolicyDeniedError{Plugin: input.Plugin, Action: input.Action, Reasons: decision.Reasons}
+		}
+	}
+
+	return nil
+}
+
+// policyInput describes a fetched plugin about to be installed or upgraded
+// under name
+func policyInput(action PolicyAction, name, upstream, source string, info *Info, slsaLevel int) PolicyInput {
+	input := PolicyInput{
+		Action:              action,
+		Plugin:              name,
+		Version:             info.Version,
+		Source:              source,
+		Store:               info.Store,
+		Owner:               info.Metadata["owner"],
+		Runtime:             info.Runtime,
+		Permissions:         info.Permissions,
+		SignatureIdentities: signatureIdentities(info),
+		SLSALevel:           slsaLevel,
+		Labels:              info.Labels,
+	}
+
+	if upstream != name {
+		input.Upstream = upstream
+	}
+
+	// Stores of owner/name plugins do not all report the owner
+	if owner, _, ok := strings.Cut(upstream, "/"); ok && input.Owner == "" {
+		input.Owner = owner
+	}
+
+	if info.Popularity != nil {
+		input.Stars = info.Popularity.Stars
+	}
+
+	return input
+}
+
+// executePolicyInput describes an installed plugin about to run, from what
+// was recorded at install time
+func executePolicyInput(name string, info *Info) PolicyInput {
+	level, _ := strconv.Atoi(info.Metadata["slsa_level"])
+
+	input := PolicyInput{
+		Action:      PolicyExecute,
+		Plugin:      name,
+		Version:     info.Version,
+		Upstream:    info.Metadata["upstream"],
+		Source:      info.Metadata["source"],
+		Store:       info.Store,
+		Owner:       info.Metadata["owner"],
+		Runtime:     info.Runtime,
+		Permissions: info.Permissions,
+		SLSALevel:   level,
+		Labels:      info.Labels,
+		Trust:       TrustLevel(info.Metadata["trust_level"]),
+	}
+
+	if identities := info.Metadata["signature_identities"]; identities != "" {
+		input.SignatureIdentities = strings.Split(identities, ",")
+	}
+
+	return input
+}
+
+// recordPolicyInput keeps the attributes of the input that are not part of
+// the plugin's metadata, so that executions are evaluated on them too
+func recordPolicyInput(metadata map[string]string, input PolicyInput) {
+	if input.Source != "" {
+		metadata["source"] = input.Source
+	}
+
+	if input.Owner != "" {
+		metadata["owner"] = input.Owner
+	}
+
+	if len(input.SignatureIdentities) > 0 {
+		metadata["signature_identities"] = strings.Join(input.SignatureIdentities, ",")
+	}
+
+	if input.Trust != "" {
+		metadata["trust_level"] = string(input.Trust)
+	}
+}
@@ -0,0 +1,138 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Popularity is usage and rating data that helps frontends rank plugins.
+// Fields a store or community index does not provide are left zero.
+type Popularity struct {
+	Downloads   int64     `json:"downloads,omitempty"`
+	Stars       int       `json:"stars,omitempty"`
+	LastRelease time.Time `json:"lastRelease,omitempty"`
+	Rating      float64   `json:"rating,omitempty"`      // Average rating out of 5
+	RatingCount int       `json:"ratingCount,omitempty"` // Number of ratings averaged in Rating
+}
+
+// PopularitySource provides popularity data for plugins independently of
+// the stores they are offered by, such as a community index
+type PopularitySource interface {
+	// Popularity returns the data known for the named plugins. Plugins it
+	// knows nothing about are omitted.
+	Popularity(ctx context.Context, names []string) (map[string]Popularity, error)
+}
+
+// SearchSort is the search criterion that orders Search results. The
+// manager sorts the merged results; stores may also use it to pick which
+// results they return first.
+const SearchSort = "sort"
+
+// Orders accepted by the SearchSort criterion. Every order but SortName
+// ranks the highest values first.
+const (
+	SortName      = "name"
+	SortDownloads = "downloads"
+	SortStars     = "stars"
+	SortUpdated   = "updated"
+	SortRating    = "rating"
+)
+
+// WithPopularitySource merges the data of source into Search results,
+// filling in what stores do not report
+func (m *Manager) WithPopularitySource(source PopularitySource) *Manager {
+	m.popularity = source
+	return m
+}
+
+// mergePopularity fills the popularity of results from the configured
+// source. The source is advisory, so its failures are only logged.
+func (m *Manager) mergePopularity(ctx context.Context, results []Info) {
+	if m.popularity == nil || len(results) == 0 {
+		return
+	}
+
+	names := make([]string, len(results))
+	for i := range results {
+		names[i] = results[i].Name
+	}
+
+	known, err := m.popularity.Popularity(ctx, names)
+	if err != nil {
+		m.logger.Error(err, "failed to fetch plugin popularity")
+		return
+	}
+
+	for i := range results {
+		p, ok := known[results[i].Name]
+		if !ok {
+			continue
+		}
+
+		if results[i].Popularity == nil {
+			results[i].Popularity = &Popularity{}
+		}
+
+		results[i].Popularity.merge(p)
+	}
+}
+
+// merge copies the fields of other that p does not set
+func (p *Popularity) merge(other Popularity) {
+	if p.Downloads == 0 {
+		p.Downloads = other.Downloads
+	}
+
+	if p.Stars == 0 {
+		p.Stars = other.Stars
+	}
+
+	if p.LastRelease.IsZero() {
+		p.LastRelease = other.LastRelease
+	}
+
+	if p.RatingCount == 0 {
+		p.Rating, p.RatingCount = other.Rating, other.RatingCount
+	}
+}
+
+// sortInfos orders plugins by one of the Sort orders. Plugins without the
+// data are ranked last, and ties keep the order of the stores.
+func sortInfos(plugins []Info, order string) error {
+	var less func(a, b *Popularity) bool
+
+	switch order {
+	case "", SortName:
+		sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+		return nil
+	case SortDownloads:
+		less = func(a, b *Popularity) bool { return a.Downloads > b.Downloads }
+	case SortStars:
+		less = func(a, b *Popularity) bool { return a.Stars > b.Stars }
+	case SortUpdated:
+		less = func(a, b *Popularity) bool { return a.LastRelease.After(b.LastRelease) }
+	case SortRating:
+		less = func(a, b *Popularity) bool { return a.Rating > b.Rating }
+	default:
+		return fmt.Errorf("unsupported sort order %q", order)
+	}
+
+	var none Popularity
+
+	sort.SliceStable(plugins, func(i, j int) bool {
+		a, b := plugins[i].Popularity, plugins[j].Popularity
+		if a == nil {
+			a = &none
+		}
+
+		if b == nil {
+			b = &none
+		}
+
+		return less(a, b)
+	})
+
+	return nil
+}
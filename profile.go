@@ -0,0 +1,156 @@
+package extension
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile is the profile whose plugins live directly in the plugin
+// directory the Manager was created with
+const DefaultProfile = "default"
+
+// profilesDir holds the plugin directories of named profiles
+const profilesDir = ".profiles"
+
+var profileNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// WithProfile returns a Manager scoped to the named profile. Each profile
+// has its own plugin directory and metadata, while stores, metrics and
+// events are shared with m. Executors registered with WithExecutorFactory
+// are created afresh for the profile directory; other executors are shared.
+func (m *Manager) WithProfile(name string) (*Manager, error) {
+	if name != DefaultProfile && !profileNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	baseDir := m.baseDir
+	if baseDir == "" {
+		baseDir = m.pluginDir
+	}
+
+	pluginDir := baseDir
+	if name != DefaultProfile {
+		pluginDir = filepath.Join(baseDir, profilesDir, name)
+	}
+
+	executors := make(map[string]Executor, len(m.executors))
+	for k, v := range m.executors {
+		executors[k] = v
+	}
+
+	factories := make(map[string]ExecutorFactory, len(m.executorFactories))
+	for k, factory := range m.executorFactories {
+		executors[k] = factory(pluginDir)
+		factories[k] = factory
+	}
+
+	return &Manager{
+		pluginDir:         pluginDir,
+		fs:                m.fs,
+		baseDir:           baseDir,
+		profile:           name,
+		store:             m.store,
+		logger:            m.logger.WithValues("profile", name),
+		metrics:           m.metrics,
+		events:            m.events,
+		registry:          m.registry,
+		approve:           m.approve,
+		policy:            m.policy,
+		sourceRules:       m.sourceRules,
+		metadataKey:       m.metadataKey,
+		trust:             m.trust,
+		namespaces:        m.namespaces,
+		runApproval:       m.runApproval,
+		popularity:        m.popularity,
+		catalog:           m.catalog,
+		hostVersion:       m.hostVersion,
+		compatMode:        m.compatMode,
+		assetPreferences:  m.assetPreferences,
+		extractionLimits:  m.extractionLimits,
+		systemDir:         m.systemDir,
+		systemExecutors:   m.systemExecutors,
+		crashThreshold:    m.crashThreshold,
+		plugins:           newPluginLocks(),
+		cache:             newInfoCache(m.fs),
+		executors:         executors,
+		executorFactories: factories,
+		scheduler:         m.scheduler,
+		scanners:          m.scanners,
+		provenance:        m.provenance,
+		timeouts:          m.timeouts,
+		historyRecords:    m.historyRecords,
+		historyOutput:     m.historyOutput,
+	}, nil
+}
+
+// Profile returns the name of the profile the Manager is scoped to
+func (m *Manager) Profile() string {
+	if m.profile == "" {
+		return DefaultProfile
+	}
+
+	return m.profile
+}
+
+// Profiles returns the names of every profile that has a plugin directory,
+// including the default profile
+func (m *Manager) Profiles() ([]string, error) {
+	baseDir := m.baseDir
+	if baseDir == "" {
+		baseDir = m.pluginDir
+	}
+
+	profiles := []string{DefaultProfile}
+
+	entries, err := m.fs.ReadDir(filepath.Join(baseDir, profilesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && profileNameRegexp.MatchString(entry.Name()) {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+
+	sort.Strings(profiles[1:])
+
+	return profiles, nil
+}
+
+// RemoveProfile deletes a named profile and every plugin installed in it
+func (m *Manager) RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the default profile cannot be removed")
+	}
+
+	if !profileNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+
+	baseDir := m.baseDir
+	if baseDir == "" {
+		baseDir = m.pluginDir
+	}
+
+	dir := filepath.Join(baseDir, profilesDir, name)
+	if _, err := m.fs.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("profile %s not found", name)
+	}
+
+	if err := m.fs.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove profile %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package extension_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/runtime/runtimetest"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestProfileExecutorsAndRemoval(t *testing.T) {
+	ctx := context.Background()
+
+	store := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.0.0", Content: []byte("#!/bin/sh\necho hello\n")})
+
+	var dirs []string
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(extension.NewMemFS()).
+		WithExecutorFactory("native", func(pluginDir string) extension.Executor {
+			dirs = append(dirs, pluginDir)
+			return runtimetest.New()
+		})
+
+	work, err := manager.WithProfile("work")
+	if err != nil {
+		t.Fatalf("WithProfile() error = %v", err)
+	}
+
+	want := []string{"/plugins", filepath.Join("/plugins", ".profiles", "work")}
+	if len(dirs) != len(want) || dirs[0] != want[0] || dirs[1] != want[1] {
+		t.Errorf("executors created for %v, want %v", dirs, want)
+	}
+
+	if err := work.Install(ctx, "hello", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	if err := manager.RemoveProfile("work"); err != nil {
+		t.Fatalf("RemoveProfile() error = %v", err)
+	}
+
+	if err := manager.RemoveProfile("work"); err == nil {
+		t.Error("RemoveProfile() of a removed profile succeeded")
+	}
+}
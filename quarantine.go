@@ -0,0 +1,189 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/events"
+)
+
+// defaultCrashThreshold is the number of consecutive crashes after which a
+// plugin is quarantined
+const defaultCrashThreshold = 3
+
+// WithCrashThreshold sets how many consecutive crashes quarantine a plugin.
+// A threshold of zero or less disables crash quarantine.
+func (m *Manager) WithCrashThreshold(n int) *Manager {
+	m.crashThreshold = n
+	return m
+}
+
+// Quarantine excludes a plugin from execution until Unquarantine is called
+func (m *Manager) Quarantine(ctx context.Context, name, reason string) error {
+	defer m.plugins.lock(name)()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before quarantining plugin: %w", err)
+	}
+
+	return m.quarantine(name, reason)
+}
+
+// Unquarantine clears the quarantine of a plugin and restores the status it
+// had before
+func (m *Manager) Unquarantine(ctx context.Context, name string) error {
+	defer m.plugins.lock(name)()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before unquarantining plugin: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.crashes, name)
+	m.mu.Unlock()
+
+	return m.updateMetadata(name, func(info *Info) error {
+		if info.Status != StatusQuarantined {
+			return fmt.Errorf("plugin %s is not quarantined", name)
+		}
+
+		previous := Status(info.Metadata["quarantine_previous_status"]).orDefault()
+		if err := validateTransition(name, info.Status, previous); err != nil {
+			return err
+		}
+
+		info.Status = previous
+
+		delete(info.Metadata, "quarantine_previous_status")
+		delete(info.Metadata, "quarantine_reason")
+		delete(info.Metadata, "quarantined")
+
+		return nil
+	})
+}
+
+// ReportVerificationFailure quarantines a plugin whose integrity check
+// failed after installation
+func (m *Manager) ReportVerificationFailure(ctx context.Context, name string, cause error) error {
+	defer m.plugins.lock(name)()
+
+	m.events.Publish(events.Event{
+		Type:   events.VerificationFailed,
+		Plugin: name,
+		Err:    cause,
+	})
+
+	return m.quarantine(name, fmt.Sprintf("verification failed: %v", cause))
+}
+
+// ReportExecution records the outcome of running a plugin. A plugin that
+// crashes (is killed by a signal) repeatedly is quarantined.
+func (m *Manager) ReportExecution(ctx context.Context, name string, result *ExecuteResult) error {
+	if !m.countCrash(name, result) {
+		return nil
+	}
+
+	defer m.plugins.lock(name)()
+
+	return m.quarantine(name, fmt.Sprintf("crashed %d times in a row", m.crashThreshold))
+}
+
+// countCrash updates the consecutive crash count of a plugin and reports
+// whether it reached the quarantine threshold
+func (m *Manager) countCrash(name string, result *ExecuteResult) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if result == nil || !crashed(result) {
+		delete(m.crashes, name)
+		return false
+	}
+
+	if m.crashes == nil {
+		m.crashes = make(map[string]int)
+	}
+
+	m.crashes[name]++
+
+	if m.crashThreshold <= 0 || m.crashes[name] < m.crashThreshold {
+		return false
+	}
+
+	delete(m.crashes, name)
+
+	return true
+}
+
+// crashed reports whether the plugin process was terminated abnormally
+func crashed(result *ExecuteResult) bool {
+	return result.ExitCode < 0 || result.ExitCode >= 128
+}
+
+// quarantine marks a plugin as quarantined. The caller must hold the
+// plugin's lock.
+func (m *Manager) quarantine(name, reason string) error {
+	err := m.updateMetadata(name, func(info *Info) error {
+		if err := validateTransition(name, info.Status, StatusQuarantined); err != nil {
+			return err
+		}
+
+		if info.Status != StatusQuarantined {
+			info.Metadata["quarantine_previous_status"] = string(info.Status)
+		}
+
+		info.Status = StatusQuarantined
+		info.Metadata["quarantine_reason"] = reason
+		info.Metadata["quarantined"] = time.Now().Format(time.RFC3339)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("plugin quarantined", "plugin", name, "reason", reason)
+
+	return nil
+}
+
+// updateMetadata applies fn to a plugin's metadata and saves it. The caller
+// must hold the plugin's lock.
+func (m *Manager) updateMetadata(name string, fn func(info *Info) error) error {
+	if err := m.checkWritable(name); err != nil {
+		return err
+	}
+
+	metadataPath := filepath.Join(m.pluginDir, name, "metadata.json")
+	defer m.cache.invalidate(filepath.Dir(metadataPath))
+
+	info, err := readMetadata(m.fs, metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	if info.Metadata == nil {
+		info.Metadata = make(map[string]string)
+	}
+
+	if err := fn(info); err != nil {
+		return err
+	}
+
+	metadataBytes, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := writeMetadata(m.fs, metadataPath, metadataBytes); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	if err := m.signMetadata(metadataPath, metadataBytes); err != nil {
+		return err
+	}
+
+	return nil
+}
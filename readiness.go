@@ -0,0 +1,121 @@
+package extension
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuntimeReadiness is the result of pinging one executor
+type RuntimeReadiness struct {
+	Runtime string        `json:"runtime"`
+	Ready   bool          `json:"ready"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+	Checked bool          `json:"checked"` // False when the executor does not implement Pinger
+}
+
+// ReadinessReport is the outcome of Registry.Ready and Manager.Ready
+type ReadinessReport struct {
+	Runtimes []RuntimeReadiness `json:"runtimes"`
+}
+
+// Ready reports whether every runtime is ready
+func (r *ReadinessReport) Ready() bool {
+	for _, rt := range r.Runtimes {
+		if !rt.Ready {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NotReady returns the runtimes that failed their check
+func (r *ReadinessReport) NotReady() []RuntimeReadiness {
+	var failed []RuntimeReadiness
+	for _, rt := range r.Runtimes {
+		if !rt.Ready {
+			failed = append(failed, rt)
+		}
+	}
+
+	return failed
+}
+
+// Ready pings every registered executor concurrently. Executors that do not
+// implement Pinger are reported ready without being checked. Use a context
+// deadline to bound how long a host waits on unreachable backends.
+func (r *Registry) Ready(ctx context.Context) *ReadinessReport {
+	r.mu.RLock()
+	executors := make(map[string]Executor, len(r.executors))
+	for name, executor := range r.executors {
+		executors[name] = executor
+	}
+	r.mu.RUnlock()
+
+	return checkReadiness(ctx, executors)
+}
+
+// Ready pings the executors registered with WithExecutor, as Registry.Ready
+// does
+func (m *Manager) Ready(ctx context.Context) *ReadinessReport {
+	m.mu.RLock()
+	executors := make(map[string]Executor, len(m.executors))
+	for name, executor := range m.executors {
+		executors[name] = executor
+	}
+	m.mu.RUnlock()
+
+	return checkReadiness(ctx, executors)
+}
+
+func checkReadiness(ctx context.Context, executors map[string]Executor) *ReadinessReport {
+	report := &ReadinessReport{Runtimes: make([]RuntimeReadiness, 0, len(executors))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for name, executor := range executors {
+		pinger, ok := executor.(Pinger)
+		if !ok {
+			mu.Lock()
+			report.Runtimes = append(report.Runtimes, RuntimeReadiness{Runtime: name, Ready: true})
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, pinger Pinger) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := pinger.Ping(ctx)
+
+			result := RuntimeReadiness{
+				Runtime: name,
+				Ready:   err == nil,
+				Latency: time.Since(start),
+				Checked: true,
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Runtimes = append(report.Runtimes, result)
+			mu.Unlock()
+		}(name, pinger)
+	}
+
+	wg.Wait()
+
+	sort.Slice(report.Runtimes, func(i, j int) bool {
+		return report.Runtimes[i].Runtime < report.Runtimes[j].Runtime
+	})
+
+	return report
+}
@@ -0,0 +1,100 @@
+package redact
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "[REDACTED]"
+
+// DefaultPatterns match the names of variables that commonly hold secrets
+var DefaultPatterns = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*API_KEY*",
+	"*APIKEY*",
+	"*PRIVATE_KEY*",
+	"*CREDENTIAL*",
+	"*AUTH*",
+	"*SESSION*",
+}
+
+// Default is the redactor used when none is configured
+var Default = New(DefaultPatterns...)
+
+// Redactor hides the values of keys matching a set of glob patterns. Matching
+// is case-insensitive. A nil *Redactor behaves like Default.
+type Redactor struct {
+	patterns []string
+}
+
+// New creates a redactor for the given key patterns
+func New(patterns ...string) *Redactor {
+	upper := make([]string, len(patterns))
+	for i, p := range patterns {
+		upper[i] = strings.ToUpper(p)
+	}
+
+	return &Redactor{patterns: upper}
+}
+
+// Matches reports whether the value of key must be redacted
+func (r *Redactor) Matches(key string) bool {
+	if r == nil {
+		r = Default
+	}
+
+	key = strings.ToUpper(key)
+
+	for _, p := range r.patterns {
+		if matched, _ := filepath.Match(p, key); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Map returns a copy of m with sensitive values replaced
+func (r *Redactor) Map(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(m))
+
+	for k, v := range m {
+		if r.Matches(k) && v != "" {
+			v = Placeholder
+		}
+
+		result[k] = v
+	}
+
+	return result
+}
+
+// String replaces every occurrence of a sensitive value from env in s, such
+// as secrets passed as -e KEY=VALUE on a logged command line
+func (r *Redactor) String(s string, env map[string]string) string {
+	var secrets []string
+
+	for k, v := range env {
+		if v != "" && r.Matches(k) {
+			secrets = append(secrets, v)
+		}
+	}
+
+	// Replace longer secrets first so overlapping values are fully hidden
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+
+	return s
+}
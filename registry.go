@@ -4,26 +4,45 @@ import "sync"
 
 // Registry maintains plugins and their stores/runners
 type Registry struct {
-	mu       sync.RWMutex
-	plugins  map[string]*Plugin
-	stores   map[string]Store
-	runtimes map[string]Runtime
+	mu         sync.RWMutex
+	plugins    map[string]*Plugin
+	stores     map[string]Store
+	storeOrder []string
+	runtimes   map[string]Runtime
+	executors  map[string]Executor
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		plugins:  make(map[string]*Plugin),
-		stores:   make(map[string]Store),
-		runtimes: make(map[string]Runtime),
+		plugins:   make(map[string]*Plugin),
+		stores:    make(map[string]Store),
+		runtimes:  make(map[string]Runtime),
+		executors: make(map[string]Executor),
 	}
 }
 
 func (r *Registry) RegisterStore(name string, store Store) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+
+	if _, ok := r.stores[name]; !ok {
+		r.storeOrder = append(r.storeOrder, name)
+	}
+
 	r.stores[name] = store
 }
 
+// Stores returns the names of the registered stores in registration order
+func (r *Registry) Stores() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, len(r.storeOrder))
+	copy(names, r.storeOrder)
+
+	return names
+}
+
 func (r *Registry) RegisterRuntime(name string, runtime Runtime) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -47,3 +66,33 @@ func (r *Registry) GetRuntime(name string) (Runtime, bool) {
 
 	return runtime, ok
 }
+
+// RegisterExecutor registers the executor used for a runtime so that its
+// readiness is reported by Ready
+func (r *Registry) RegisterExecutor(name string, executor Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[name] = executor
+}
+
+// GetExecutor returns an executor by runtime name
+func (r *Registry) GetExecutor(name string) (Executor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	executor, ok := r.executors[name]
+
+	return executor, ok
+}
+
+// executorNames returns the runtimes executors are registered for
+func (r *Registry) executorNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.executors))
+	for name := range r.executors {
+		names = append(names, name)
+	}
+
+	return names
+}
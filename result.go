@@ -0,0 +1,255 @@
+package extension
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// OutputEncoding selects how captured output is written when a result is
+// marshalled
+type OutputEncoding string
+
+const (
+	// OutputText writes valid UTF-8 output as a string and falls back to
+	// base64 for binary output
+	OutputText OutputEncoding = "text"
+
+	// OutputBase64 always writes output as base64
+	OutputBase64 OutputEncoding = "base64"
+)
+
+// resultJSON is the stable serialized form of an ExecuteResult. Field names
+// must not change; add new fields instead.
+type resultJSON struct {
+	ExitCode       int               `json:"exit_code"`
+	Success        bool              `json:"success"`
+	Stdout         string            `json:"stdout,omitempty"`
+	StdoutEncoding OutputEncoding    `json:"stdout_encoding,omitempty"` // Set when Stdout is base64
+	Stderr         string            `json:"stderr,omitempty"`
+	StderrEncoding OutputEncoding    `json:"stderr_encoding,omitempty"` // Set when Stderr is base64
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        time.Time         `json:"end_time"`
+	Duration       string            `json:"duration"` // Go duration string, e.g. 1.5s
+	CommandLine    string            `json:"command_line,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	Image          string            `json:"image,omitempty"`
+	Mounts         []Mount           `json:"mounts,omitempty"`
+	Network        string            `json:"network,omitempty"`
+	WorkingDir     string            `json:"working_dir,omitempty"`
+	Environment    map[string]string `json:"environment,omitempty"`
+	PID            int               `json:"pid,omitempty"`
+	Structured     map[string]any    `json:"structured,omitempty"`
+	Timings        *timingsJSON      `json:"timings,omitempty"`
+}
+
+// timingsJSON is the serialized form of Timings, with Go duration strings
+type timingsJSON struct {
+	Compile     string `json:"compile,omitempty"`
+	Instantiate string `json:"instantiate,omitempty"`
+	Run         string `json:"run,omitempty"`
+	CPU         string `json:"cpu,omitempty"`
+}
+
+// MarshalJSON encodes the result with stable field names, the duration as a
+// Go duration string and binary output as base64
+func (r ExecuteResult) MarshalJSON() ([]byte, error) {
+	return MarshalResult(&r, OutputText)
+}
+
+// UnmarshalJSON decodes a result written by MarshalJSON or MarshalResult.
+// Output is always decoded to []byte, as executors return it.
+func (r *ExecuteResult) UnmarshalJSON(data []byte) error {
+	var v resultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	stdout, err := decodeOutput(v.Stdout, v.StdoutEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to decode stdout: %w", err)
+	}
+
+	stderr, err := decodeOutput(v.Stderr, v.StderrEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to decode stderr: %w", err)
+	}
+
+	duration, err := parseDuration(v.Duration)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+
+	timings, err := decodeTimings(v.Timings)
+	if err != nil {
+		return fmt.Errorf("invalid timings: %w", err)
+	}
+
+	*r = ExecuteResult{
+		ExitCode:    v.ExitCode,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		StartTime:   v.StartTime,
+		EndTime:     v.EndTime,
+		Duration:    duration,
+		CommandLine: v.CommandLine,
+		Command:     v.Command,
+		Image:       v.Image,
+		Mounts:      v.Mounts,
+		Network:     v.Network,
+		WorkingDir:  v.WorkingDir,
+		Environment: v.Environment,
+		PID:         v.PID,
+		Success:     v.Success,
+		Structured:  v.Structured,
+		Timings:     timings,
+	}
+
+	return nil
+}
+
+// MarshalResult encodes a result like MarshalJSON, writing output with the
+// given encoding
+func MarshalResult(result *ExecuteResult, encoding OutputEncoding) ([]byte, error) {
+	if result == nil {
+		return []byte("null"), nil
+	}
+
+	stdout, stdoutEncoding, err := encodeOutput(result.Stdout, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode stdout: %w", err)
+	}
+
+	stderr, stderrEncoding, err := encodeOutput(result.Stderr, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode stderr: %w", err)
+	}
+
+	return json.Marshal(resultJSON{
+		ExitCode:       result.ExitCode,
+		Success:        result.Success,
+		Stdout:         stdout,
+		StdoutEncoding: stdoutEncoding,
+		Stderr:         stderr,
+		StderrEncoding: stderrEncoding,
+		StartTime:      result.StartTime,
+		EndTime:        result.EndTime,
+		Duration:       result.Duration.String(),
+		CommandLine:    result.CommandLine,
+		Command:        result.Command,
+		Image:          result.Image,
+		Mounts:         result.Mounts,
+		Network:        result.Network,
+		WorkingDir:     result.WorkingDir,
+		Environment:    result.Environment,
+		PID:            result.PID,
+		Structured:     result.Structured,
+		Timings:        encodeTimings(result.Timings),
+	})
+}
+
+// encodeOutput converts captured output to its serialized form and reports
+// the encoding used, which is empty for plain text
+func encodeOutput(output interface{}, encoding OutputEncoding) (string, OutputEncoding, error) {
+	var data []byte
+
+	switch v := output.(type) {
+	case nil:
+		return "", "", nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return "", "", fmt.Errorf("unsupported output type %T", output)
+	}
+
+	switch encoding {
+	case OutputBase64:
+		return base64.StdEncoding.EncodeToString(data), OutputBase64, nil
+	case OutputText, "":
+		if utf8.Valid(data) {
+			return string(data), "", nil
+		}
+
+		return base64.StdEncoding.EncodeToString(data), OutputBase64, nil
+	default:
+		return "", "", fmt.Errorf("unsupported output encoding %q", encoding)
+	}
+}
+
+// decodeOutput reverses encodeOutput
+func decodeOutput(output string, encoding OutputEncoding) ([]byte, error) {
+	switch encoding {
+	case "", OutputText:
+		if output == "" {
+			return nil, nil
+		}
+
+		return []byte(output), nil
+	case OutputBase64:
+		return base64.StdEncoding.DecodeString(output)
+	default:
+		return nil, fmt.Errorf("unsupported output encoding %q", encoding)
+	}
+}
+
+func encodeTimings(t *Timings) *timingsJSON {
+	if t == nil {
+		return nil
+	}
+
+	return &timingsJSON{
+		Compile:     formatDuration(t.Compile),
+		Instantiate: formatDuration(t.Instantiate),
+		Run:         formatDuration(t.Run),
+		CPU:         formatDuration(t.CPU),
+	}
+}
+
+func decodeTimings(v *timingsJSON) (*Timings, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var (
+		t   Timings
+		err error
+	)
+
+	for _, field := range []struct {
+		value string
+		into  *time.Duration
+	}{
+		{v.Compile, &t.Compile},
+		{v.Instantiate, &t.Instantiate},
+		{v.Run, &t.Run},
+		{v.CPU, &t.CPU},
+	} {
+		if *field.into, err = parseDuration(field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// formatDuration writes unmeasured, zero durations as an empty string so
+// they are omitted
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return d.String()
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(s)
+}
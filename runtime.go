@@ -10,7 +10,8 @@ type Runtime interface {
 	List(ctx context.Context) ([]*Plugin, error)
 	// Install sets up a new plugin
 	Install(ctx context.Context, plugin *Plugin) error
-	// Uninstall removes a plugin
+	// Uninstall removes a plugin and reclaims the artifacts the runtime
+	// created for it. Data is kept when KeepDataFromContext reports true.
 	Uninstall(ctx context.Context, plugin *Plugin) error
 	// Execute runs a plugin with given arguments
 	Execute(ctx context.Context, plugin *Plugin, args []string) error
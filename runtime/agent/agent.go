@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// defaultHeartbeat is how often an agent re-registers with the host
+	defaultHeartbeat = 30 * time.Second
+
+	// processWaitDelay bounds how long Wait blocks on output pipes after
+	// the plugin exits or is killed
+	processWaitDelay = 5 * time.Second
+)
+
+// Agent runs plugins installed on a remote machine on behalf of a host. It
+// registers with the host's Registry and serves ExecuteRequests, streaming
+// the plugin's output back as it is written.
+type Agent struct {
+	registration Registration
+	pluginDir    string
+	client       *http.Client
+	heartbeat    time.Duration
+	logger       logr.Logger
+}
+
+// NewAgent creates an agent serving the plugins installed in pluginDir
+func NewAgent(registration Registration, pluginDir string) *Agent {
+	if registration.Version == "" {
+		registration.Version = ProtocolVersion
+	}
+
+	return &Agent{
+		registration: registration,
+		pluginDir:    pluginDir,
+		client:       http.DefaultClient,
+		heartbeat:    defaultHeartbeat,
+		logger:       logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (a *Agent) WithLogger(logger logr.Logger) *Agent {
+	a.logger = logger.WithName("agent").WithValues("agent", a.registration.ID)
+	return a
+}
+
+// WithClient sets the HTTP client used to reach the host. It must present
+// the agent's client certificate.
+func (a *Agent) WithClient(client *http.Client) *Agent {
+	a.client = client
+	return a
+}
+
+// WithHeartbeat sets how often the agent re-registers with the host
+func (a *Agent) WithHeartbeat(interval time.Duration) *Agent {
+	a.heartbeat = interval
+	return a
+}
+
+// Run registers the agent with the host at hostURL and keeps re-registering
+// until ctx is done, then deregisters it
+func (a *Agent) Run(ctx context.Context, hostURL string) error {
+	if err := a.register(ctx, hostURL); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(a.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Deregister with a fresh context since ctx is already done
+			deregisterCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := a.deregister(deregisterCtx, hostURL); err != nil {
+				a.logger.Error(err, "failed to deregister")
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+			if err := a.register(ctx, hostURL); err != nil {
+				a.logger.Error(err, "heartbeat failed")
+			}
+		}
+	}
+}
+
+func (a *Agent) register(ctx context.Context, hostURL string) error {
+	body, err := json.Marshal(a.registration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registration: %w", err)
+	}
+
+	return a.send(ctx, http.MethodPost, strings.TrimSuffix(hostURL, "/")+RegisterPath, body)
+}
+
+func (a *Agent) deregister(ctx context.Context, hostURL string) error {
+	return a.send(ctx, http.MethodDelete, strings.TrimSuffix(hostURL, "/")+RegisterPath+"/"+url.PathEscape(a.registration.ID), nil)
+}
+
+func (a *Agent) send(ctx context.Context, method, endpoint string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach host: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("host rejected %s %s: %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// Handler serves ExecuteRequests from the host. It must be served over
+// mTLS with a configuration that only trusts the host's certificate, such
+// as one built by ServerTLSConfig.
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+ExecutePath, func(w http.ResponseWriter, req *http.Request) {
+		var request ExecuteRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+
+		stream := newFrameWriter(w)
+
+		exitCode, pid, err := a.execute(req.Context(), request, stream)
+		if err != nil {
+			stream.send(Frame{Kind: FrameError, Error: err.Error()})
+			return
+		}
+
+		stream.send(Frame{Kind: FrameExit, ExitCode: exitCode, PID: pid})
+	})
+
+	return mux
+}
+
+// execute runs the requested plugin, streaming its output as frames
+func (a *Agent) execute(ctx context.Context, request ExecuteRequest, stream *frameWriter) (int, int, error) {
+	name := request.Plugin
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return 0, 0, fmt.Errorf("invalid plugin name %q", name)
+	}
+
+	logger := a.logger.WithValues("plugin", name)
+	logger.V(1).Info("executing plugin", "args", len(request.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !request.IgnoreStatus {
+		if err := CheckStatus(a.pluginDir, name); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := request.Permissions.FilterEnv(request.Environment)
+	if request.WorkingDir != "" && !request.Permissions.AllowsPath(request.WorkingDir) {
+		return 0, 0, fmt.Errorf("plugin is not permitted to access %s", request.WorkingDir)
+	}
+
+	cmd := exec.CommandContext(ctx, filepath.Join(a.pluginDir, name, name), request.Args...)
+	cmd.WaitDelay = processWaitDelay
+	cmd.Dir = request.WorkingDir
+	cmd.Stdout = stream.writer(FrameStdout)
+	cmd.Stderr = stream.writer(FrameStderr)
+
+	if environment != nil {
+		env := make([]string, 0, len(environment))
+		for k, v := range environment {
+			env = append(env, k+"="+v)
+		}
+		cmd.Env = env
+	} else {
+		cmd.Env = os.Environ()
+	}
+
+	startTime := time.Now()
+
+	err := cmd.Run()
+	exitCode := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return 0, 0, fmt.Errorf("failed to execute plugin: %w", err)
+		}
+
+		exitCode = exitErr.ExitCode()
+	}
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", time.Since(startTime))
+
+	return exitCode, cmd.Process.Pid, nil
+}
+
+// frameWriter encodes frames onto the response, flushing each one so that
+// the host sees output as it is produced
+type frameWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func newFrameWriter(w http.ResponseWriter) *frameWriter {
+	flusher, _ := w.(http.Flusher)
+	return &frameWriter{encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+func (s *frameWriter) send(frame Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(frame); err != nil {
+		return err
+	}
+
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+
+	return nil
+}
+
+// writer returns an io.Writer sending everything written as frames of kind
+func (s *frameWriter) writer(kind string) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		if err := s.send(Frame{Kind: kind, Data: p}); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,72 @@
+package agent
+
+import "maps"
+
+// Config is the typed configuration of an AgentExecutor
+type Config struct {
+	Selector   map[string]string `mapstructure:"selector"`
+	CAFile     string            `mapstructure:"ca_file"`
+	CertFile   string            `mapstructure:"cert_file"`
+	KeyFile    string            `mapstructure:"key_file"`
+	ServerName string            `mapstructure:"server_name"`
+}
+
+// Option customizes the configuration of an AgentExecutor
+type Option func(*Config)
+
+// WithSelector only dispatches to agents carrying every given label
+func WithSelector(selector map[string]string) Option {
+	return func(c *Config) { c.Selector = selector }
+}
+
+// WithCAFile sets the CA bundle agent certificates are verified against
+func WithCAFile(path string) Option {
+	return func(c *Config) { c.CAFile = path }
+}
+
+// WithClientCertificate sets the certificate the host presents to agents
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(c *Config) {
+		c.CertFile = certFile
+		c.KeyFile = keyFile
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *AgentExecutor) Config() Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	config := e.config
+	config.Selector = maps.Clone(config.Selector)
+
+	return config
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *AgentExecutor) ApplyConfig(config Config) error {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return &ConfigError{Fields: []FieldError{
+			{Field: "/cert_file", Message: "is required for mTLS"},
+			{Field: "/key_file", Message: "is required for mTLS"},
+		}}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.config = config
+	e.client = nil
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *AgentExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -0,0 +1,50 @@
+package agent
+
+// Paths of the agent protocol. Agents register with the host under
+// RegisterPath and the host dispatches executions to ExecutePath on the
+// agent. Both sides authenticate each other with mTLS.
+const (
+	RegisterPath = "/v1/agents"
+	ExecutePath  = "/v1/execute"
+)
+
+// ProtocolVersion identifies the version of the agent protocol
+const ProtocolVersion = "pluginkit.agent/v1"
+
+// Registration announces an agent to the host. Agents repeat it
+// periodically as a heartbeat.
+type Registration struct {
+	ID      string            `json:"id"`               // Unique agent ID, matching its certificate
+	Address string            `json:"address"`          // Base URL the host dispatches executions to
+	Labels  map[string]string `json:"labels,omitempty"` // Attributes used to select the agent
+	Version string            `json:"version"`          // Protocol version spoken by the agent
+}
+
+// ExecuteRequest asks an agent to run one of its installed plugins
+type ExecuteRequest struct {
+	Plugin       string            `json:"plugin"`
+	Args         []string          `json:"args,omitempty"`
+	Environment  map[string]string `json:"environment,omitempty"`
+	WorkingDir   string            `json:"workingDir,omitempty"`
+	Permissions  *Permissions      `json:"permissions,omitempty"`
+	IgnoreStatus bool              `json:"ignoreStatus,omitempty"`
+}
+
+// Frame kinds
+const (
+	FrameStdout = "stdout"
+	FrameStderr = "stderr"
+	FrameExit   = "exit"
+	FrameError  = "error"
+)
+
+// Frame is one message of the newline-delimited JSON stream an agent
+// answers an ExecuteRequest with. Output frames are sent as the plugin
+// writes them; the stream ends with exactly one exit or error frame.
+type Frame struct {
+	Kind     string `json:"kind"`
+	Data     []byte `json:"data,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	PID      int    `json:"pid,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
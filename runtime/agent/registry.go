@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTTL is how long an agent stays selectable after its last heartbeat
+const defaultTTL = 90 * time.Second
+
+// ErrNoAgent is returned when no live agent matches a selector
+type ErrNoAgent struct {
+	Selector map[string]string
+}
+
+func (e *ErrNoAgent) Error() string {
+	return fmt.Sprintf("no live agent matches selector %v", e.Selector)
+}
+
+// AgentInfo describes a registered agent
+type AgentInfo struct {
+	Registration
+	LastSeen time.Time
+}
+
+// Registry tracks the agents registered with the host. Agents that miss
+// their heartbeats for longer than the TTL are no longer selected.
+type Registry struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	agents map[string]*AgentInfo
+	next   int // Rotates selection between matching agents
+}
+
+// NewRegistry creates a registry. A zero ttl uses the default of 90 seconds.
+func NewRegistry(ttl time.Duration) *Registry {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Registry{
+		ttl:    ttl,
+		agents: make(map[string]*AgentInfo),
+	}
+}
+
+// Register records a new agent or refreshes the heartbeat of a known one
+func (r *Registry) Register(registration Registration) error {
+	if registration.ID == "" {
+		return fmt.Errorf("agent ID is required")
+	}
+
+	address, err := url.Parse(registration.Address)
+	if err != nil || address.Host == "" {
+		return fmt.Errorf("invalid address for agent %s: %q", registration.ID, registration.Address)
+	}
+
+	if address.Scheme != "https" {
+		return fmt.Errorf("agent %s must be reachable over https", registration.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.agents[registration.ID] = &AgentInfo{Registration: registration, LastSeen: time.Now()}
+
+	return nil
+}
+
+// Deregister removes an agent
+func (r *Registry) Deregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.agents, id)
+}
+
+// Agents returns the live agents sorted by ID
+func (r *Registry) Agents() []AgentInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.live(nil)
+}
+
+// Select returns a live agent carrying every label of the selector,
+// rotating between the matching agents
+func (r *Registry) Select(selector map[string]string) (*AgentInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agents := r.live(selector)
+	if len(agents) == 0 {
+		return nil, &ErrNoAgent{Selector: selector}
+	}
+
+	agent := agents[r.next%len(agents)]
+	r.next++
+
+	return &agent, nil
+}
+
+// live returns copies of the live agents matching selector, dropping the
+// expired ones. The caller must hold r.mu.
+func (r *Registry) live(selector map[string]string) []AgentInfo {
+	cutoff := time.Now().Add(-r.ttl)
+
+	var agents []AgentInfo
+
+	for id, agent := range r.agents {
+		if agent.LastSeen.Before(cutoff) {
+			delete(r.agents, id)
+			continue
+		}
+
+		if matches(agent.Labels, selector) {
+			info := *agent
+			info.Labels = maps.Clone(agent.Labels)
+			agents = append(agents, info)
+		}
+	}
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+
+	return agents
+}
+
+// Handler serves agent registrations and heartbeats. It must be served
+// over mTLS: an agent may only register under an ID that matches the
+// common name or a DNS name of its client certificate.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+RegisterPath, func(w http.ResponseWriter, req *http.Request) {
+		var registration Registration
+		if err := json.NewDecoder(req.Body).Decode(&registration); err != nil {
+			http.Error(w, fmt.Sprintf("invalid registration: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if !authorized(req, registration.ID) {
+			http.Error(w, "client certificate does not match agent ID", http.StatusForbidden)
+			return
+		}
+
+		if err := r.Register(registration); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE "+RegisterPath+"/{id}", func(w http.ResponseWriter, req *http.Request) {
+		id := req.PathValue("id")
+		if !authorized(req, id) {
+			http.Error(w, "client certificate does not match agent ID", http.StatusForbidden)
+			return
+		}
+
+		r.Deregister(id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// authorized reports whether the verified client certificate of req
+// belongs to the agent id
+func authorized(req *http.Request, id string) bool {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return false
+	}
+
+	cert := req.TLS.VerifiedChains[0][0]
+
+	return cert.Subject.CommonName == id || slices.Contains(cert.DNSNames, id)
+}
+
+func matches(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
+)
+
+// maxFrameSize bounds a single frame of the agent's output stream
+const maxFrameSize = 4 << 20
+
+type outputKey struct{}
+
+type output struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// ContextWithOutput returns a context instructing the AgentExecutor to copy
+// the plugin's output to stdout and stderr as the agent streams it, in
+// addition to collecting it in the result
+func ContextWithOutput(ctx context.Context, stdout, stderr io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, output{stdout: stdout, stderr: stderr})
+}
+
+// AgentExecutor implements the Executor interface by dispatching plugins to
+// remote agents registered with a Registry. It generalizes the SSH executor
+// from a single host to a fleet: each execution goes to a live agent
+// matching the configured selector, over mTLS, with the output streamed
+// back while the plugin runs.
+type AgentExecutor struct {
+	registry *Registry
+	logger   logr.Logger
+
+	mu     sync.Mutex
+	config Config
+	client *http.Client // Built from config on first use
+}
+
+// NewExecutor creates an executor dispatching to the agents of registry
+func NewExecutor(registry *Registry, opts ...Option) *AgentExecutor {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &AgentExecutor{
+		registry: registry,
+		config:   config,
+		logger:   logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *AgentExecutor) WithLogger(logger logr.Logger) *AgentExecutor {
+	e.logger = logger.WithName("agent-executor")
+	return e
+}
+
+// Ping verifies that at least one live agent matches the selector
+func (e *AgentExecutor) Ping(_ context.Context) error {
+	if _, err := e.registry.Select(e.Config().Selector); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *AgentExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"type":        "object",
+				"description": "Labels an agent must carry to run plugins",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"ca_file": map[string]interface{}{
+				"type":        "string",
+				"description": "CA bundle agent certificates are verified against",
+			},
+			"cert_file": map[string]interface{}{
+				"type":        "string",
+				"description": "Client certificate presented to agents",
+			},
+			"key_file": map[string]interface{}{
+				"type":        "string",
+				"description": "Private key of the client certificate",
+			},
+			"server_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Overrides the server name used to verify agents",
+			},
+		},
+		"required": []string{"cert_file", "key_file"},
+	}
+}
+
+// Execute runs a plugin on a selected agent
+func (e *AgentExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	startTime := time.Now()
+
+	config := e.Config()
+
+	agent, err := e.registry.Select(config.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := e.logger.WithValues("plugin", pluginName, "agent", agent.ID)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	httpClient, err := e.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// Restrict the plugin to its granted capabilities before the
+	// environment leaves the host
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
+	body, err := json.Marshal(ExecuteRequest{
+		Plugin:       pluginName,
+		Args:         opts.Args,
+		Environment:  environment,
+		WorkingDir:   opts.WorkingDir,
+		Permissions:  opts.Permissions,
+		IgnoreStatus: opts.IgnoreStatus,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal execute request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(agent.Address, "/")+ExecutePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach agent %s: %w", agent.ID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("agent %s rejected execution: %s: %s", agent.ID, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	stdout, stderr, exit, err := readFrames(resp.Body, outputFromContext(ctx))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, fmt.Errorf("agent %s: %w", agent.ID, err)
+	}
+
+	endTime := time.Now()
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exit.ExitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exit.ExitCode,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: fmt.Sprintf("agent://%s/%s %s", agent.ID, pluginName, strings.Join(opts.Args, " ")),
+		Command:     append([]string{pluginName}, opts.Args...),
+		WorkingDir:  opts.WorkingDir,
+		Environment: environment,
+		PID:         exit.PID, // PID on the agent's machine
+		Success:     exit.ExitCode == 0,
+	})), nil
+}
+
+// Explain returns the request Execute would send and the agent it would
+// currently be dispatched to, without contacting the agent
+func (e *AgentExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	agent, err := e.registry.Select(e.Config().Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     "agent",
+		Command:     append([]string{pluginName}, opts.Args...),
+		CommandLine: fmt.Sprintf("agent://%s/%s %s", agent.ID, pluginName, strings.Join(opts.Args, " ")),
+		Security:    []string{"agent=" + agent.ID, "address=" + agent.Address},
+		Environment: opts.Permissions.FilterEnv(opts.Environment),
+		WorkingDir:  opts.WorkingDir,
+	}), nil
+}
+
+// readFrames collects the output frames of an agent's stream until its exit
+// frame, copying output to out as it arrives
+func readFrames(r io.Reader, out output) ([]byte, []byte, *Frame, error) {
+	var stdout, stderr bytes.Buffer
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFrameSize)
+
+	for scanner.Scan() {
+		var frame Frame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, nil, nil, fmt.Errorf("malformed frame: %w", err)
+		}
+
+		switch frame.Kind {
+		case FrameStdout:
+			stdout.Write(frame.Data)
+			if out.stdout != nil {
+				out.stdout.Write(frame.Data)
+			}
+		case FrameStderr:
+			stderr.Write(frame.Data)
+			if out.stderr != nil {
+				out.stderr.Write(frame.Data)
+			}
+		case FrameExit:
+			return stdout.Bytes(), stderr.Bytes(), &frame, nil
+		case FrameError:
+			return nil, nil, nil, fmt.Errorf("execution failed: %s", frame.Error)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read output stream: %w", err)
+	}
+
+	return nil, nil, nil, fmt.Errorf("output stream ended before the plugin exited")
+}
+
+func outputFromContext(ctx context.Context) output {
+	out, _ := ctx.Value(outputKey{}).(output)
+	return out
+}
+
+// Close releases the idle connections to agents. Executions still streaming
+// keep their connections until they finish.
+func (e *AgentExecutor) Close(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client != nil {
+		e.client.CloseIdleConnections()
+		e.client = nil
+	}
+
+	return nil
+}
+
+// httpClient returns the mTLS client used to reach agents
+func (e *AgentExecutor) httpClient() (*http.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	if e.config.CertFile == "" || e.config.KeyFile == "" {
+		return nil, fmt.Errorf("agent executor requires a client certificate for mTLS")
+	}
+
+	tlsConfig, err := client.TLSOptions{
+		CAFile:     e.config.CAFile,
+		CertFile:   e.config.CertFile,
+		KeyFile:    e.config.KeyFile,
+		ServerName: e.config.ServerName,
+	}.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return e.client, nil
+}
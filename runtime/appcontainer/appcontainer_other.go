@@ -0,0 +1,17 @@
+//go:build !windows
+
+package appcontainer
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func supported() error {
+	return fmt.Errorf("AppContainer is not supported on %s", runtime.GOOS)
+}
+
+func (e *AppContainerExecutor) run(_ context.Context, _ Config, _, _ string, _ []string, _ ExecuteOptions) (*ExecuteResult, error) {
+	return nil, supported()
+}
@@ -0,0 +1,444 @@
+//go:build windows
+
+package appcontainer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	userenv = windows.NewLazySystemDLL("userenv.dll")
+
+	procCreateAppContainerProfile                 = userenv.NewProc("CreateAppContainerProfile")
+	procDeriveAppContainerSidFromAppContainerName = userenv.NewProc("DeriveAppContainerSidFromAppContainerName")
+)
+
+const (
+	// procThreadAttributeSecurityCapabilities is
+	// PROC_THREAD_ATTRIBUTE_SECURITY_CAPABILITIES
+	procThreadAttributeSecurityCapabilities = 0x00020009
+
+	// hresultAlreadyExists is HRESULT_FROM_WIN32(ERROR_ALREADY_EXISTS)
+	hresultAlreadyExists = 0x800700B7
+
+	// internetClientSID is the capability granting outbound network access
+	internetClientSID = "S-1-15-3-1"
+)
+
+// securityCapabilities mirrors SECURITY_CAPABILITIES
+type securityCapabilities struct {
+	AppContainerSid *windows.SID
+	Capabilities    *windows.SIDAndAttributes
+	CapabilityCount uint32
+	Reserved        uint32
+}
+
+func supported() error {
+	if err := procCreateAppContainerProfile.Find(); err != nil {
+		return fmt.Errorf("AppContainer is not supported on this version of Windows: %w", err)
+	}
+
+	return nil
+}
+
+// run starts the plugin suspended inside its AppContainer, assigns it to a
+// job object so that cancellation terminates every process it spawned, and
+// waits for it to exit
+func (e *AppContainerExecutor) run(ctx context.Context, config Config, profile, pluginPath string, env []string, opts ExecuteOptions) (*ExecuteResult, error) {
+	pluginPath, err := executablePath(pluginPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := profileSID(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.FreeSid(sid)
+
+	// Let the container read the plugin's files and use the granted paths
+	release, err := e.grant(filepath.Dir(pluginPath), sid, windows.GENERIC_READ|windows.GENERIC_EXECUTE)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	for _, path := range grantPaths(opts) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		release, err := e.grant(path, sid, windows.GENERIC_ALL)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	capabilities, err := capabilitySIDs(config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	security := securityCapabilities{
+		AppContainerSid: sid,
+		CapabilityCount: uint32(len(capabilities)),
+	}
+	if len(capabilities) > 0 {
+		security.Capabilities = &capabilities[0]
+	}
+
+	// Only the standard handles are inherited by the plugin
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		return nil, err
+	}
+	defer stdin.Close()
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	defer stdoutReader.Close()
+	defer stdoutWriter.Close()
+
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	defer stderrReader.Close()
+	defer stderrWriter.Close()
+
+	handles := []windows.Handle{
+		windows.Handle(stdin.Fd()),
+		windows.Handle(stdoutWriter.Fd()),
+		windows.Handle(stderrWriter.Fd()),
+	}
+
+	for _, handle := range handles {
+		if err := windows.SetHandleInformation(handle, windows.HANDLE_FLAG_INHERIT, windows.HANDLE_FLAG_INHERIT); err != nil {
+			return nil, fmt.Errorf("failed to make handle inheritable: %w", err)
+		}
+	}
+
+	attributes, err := windows.NewProcThreadAttributeList(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attribute list: %w", err)
+	}
+	defer attributes.Delete()
+
+	if err := attributes.Update(procThreadAttributeSecurityCapabilities, unsafe.Pointer(&security), unsafe.Sizeof(security)); err != nil {
+		return nil, fmt.Errorf("failed to set security capabilities: %w", err)
+	}
+
+	if err := attributes.Update(windows.PROC_THREAD_ATTRIBUTE_HANDLE_LIST, unsafe.Pointer(&handles[0]), uintptr(len(handles))*unsafe.Sizeof(handles[0])); err != nil {
+		return nil, fmt.Errorf("failed to set inherited handles: %w", err)
+	}
+
+	startup := &windows.StartupInfoEx{ProcThreadAttributeList: attributes.List()}
+	startup.Cb = uint32(unsafe.Sizeof(*startup))
+	startup.Flags = windows.STARTF_USESTDHANDLES
+	startup.StdInput = handles[0]
+	startup.StdOutput = handles[1]
+	startup.StdErr = handles[2]
+
+	application, err := windows.UTF16PtrFromString(pluginPath)
+	if err != nil {
+		return nil, err
+	}
+
+	commandLine, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(append([]string{pluginPath}, opts.Args...)))
+	if err != nil {
+		return nil, err
+	}
+
+	var dir *uint16
+	if opts.WorkingDir != "" {
+		if dir, err = windows.UTF16PtrFromString(opts.WorkingDir); err != nil {
+			return nil, err
+		}
+	}
+
+	flags := uint32(windows.CREATE_UNICODE_ENVIRONMENT | windows.EXTENDED_STARTUPINFO_PRESENT | windows.CREATE_SUSPENDED | windows.CREATE_NO_WINDOW)
+
+	var process windows.ProcessInformation
+	err = windows.CreateProcess(application, commandLine, nil, nil, true, flags, environmentBlock(env), dir, &startup.StartupInfo, &process)
+
+	// The plugin holds its own copies of the write ends now
+	stdoutWriter.Close()
+	stderrWriter.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin in AppContainer: %w", err)
+	}
+	defer windows.CloseHandle(process.Process)
+	defer windows.CloseHandle(process.Thread)
+
+	job, err := newJob(process.Process)
+	if err != nil {
+		windows.TerminateProcess(process.Process, 1)
+		return nil, err
+	}
+	defer windows.CloseHandle(job)
+
+	if _, err := windows.ResumeThread(process.Thread); err != nil {
+		windows.TerminateJobObject(job, 1)
+		return nil, fmt.Errorf("failed to resume plugin: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		io.Copy(&stdout, stdoutReader)
+	}()
+
+	go func() {
+		defer wg.Done()
+		io.Copy(&stderr, stderrReader)
+	}()
+
+	// Terminate the whole job when the context is done
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			windows.TerminateJobObject(job, 1)
+		case <-done:
+		}
+	}()
+
+	_, err = windows.WaitForSingleObject(process.Process, windows.INFINITE)
+	close(done)
+
+	// Orphaned descendants must not keep the output pipes open
+	windows.TerminateJobObject(job, 1)
+	wg.Wait()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for plugin: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(process.Process, &exitCode); err != nil {
+		return nil, fmt.Errorf("failed to read plugin exit code: %w", err)
+	}
+
+	return &ExecuteResult{
+		ExitCode: int(exitCode),
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		PID:      int(process.ProcessId),
+	}, nil
+}
+
+// executablePath resolves the plugin executable, which CreateProcess
+// requires to include its extension
+func executablePath(path string) (string, error) {
+	for _, candidate := range []string{path, path + ".exe"} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("plugin executable not found: %s", path)
+}
+
+// profileSID creates the AppContainer profile, or derives the SID of the
+// existing one. The SID must be released with windows.FreeSid.
+func profileSID(name string) (*windows.SID, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var sid *windows.SID
+
+	hr, _, _ := procCreateAppContainerProfile.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(namePtr)),
+		uintptr(unsafe.Pointer(namePtr)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&sid)),
+	)
+	if uint32(hr) == hresultAlreadyExists {
+		hr, _, _ = procDeriveAppContainerSidFromAppContainerName.Call(
+			uintptr(unsafe.Pointer(namePtr)),
+			uintptr(unsafe.Pointer(&sid)),
+		)
+	}
+
+	if hr != 0 {
+		return nil, fmt.Errorf("failed to create AppContainer profile %s: HRESULT 0x%08x", name, uint32(hr))
+	}
+
+	return sid, nil
+}
+
+// capabilitySIDs returns the capabilities granted to the plugin's container
+func capabilitySIDs(config Config, opts ExecuteOptions) ([]windows.SIDAndAttributes, error) {
+	var names []string
+	if opts.Permissions == nil || opts.Permissions.Network {
+		names = append(names, internetClientSID)
+	}
+
+	names = append(names, config.Capabilities...)
+
+	capabilities := make([]windows.SIDAndAttributes, 0, len(names))
+
+	for _, name := range names {
+		sid, err := windows.StringToSid(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capability SID %s: %w", name, err)
+		}
+
+		capabilities = append(capabilities, windows.SIDAndAttributes{Sid: sid, Attributes: windows.SE_GROUP_ENABLED})
+	}
+
+	return capabilities, nil
+}
+
+// grant adds an access control entry for the container to path and returns
+// a function revoking it. Grants are reference counted so that concurrent
+// executions of the same plugin do not revoke each other's access.
+func (e *AppContainerExecutor) grant(path string, sid *windows.SID, mask windows.ACCESS_MASK) (func(), error) {
+	key := sid.String() + "|" + path
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.grants[key] == 0 {
+		if err := setAccess(path, sid, mask, windows.GRANT_ACCESS); err != nil {
+			return nil, err
+		}
+	}
+
+	e.grants[key]++
+
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		e.grants[key]--
+		if e.grants[key] > 0 {
+			return
+		}
+
+		delete(e.grants, key)
+
+		if err := setAccess(path, sid, 0, windows.REVOKE_ACCESS); err != nil {
+			e.logger.Error(err, "failed to revoke AppContainer access", "path", path)
+		}
+	}, nil
+}
+
+// setAccess merges an access control entry for sid into the DACL of path
+func setAccess(path string, sid *windows.SID, mask windows.ACCESS_MASK, mode windows.ACCESS_MODE) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	inheritance := uint32(windows.NO_INHERITANCE)
+	if info.IsDir() {
+		inheritance = windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT
+	}
+
+	descriptor, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("failed to read ACL of %s: %w", path, err)
+	}
+
+	dacl, _, err := descriptor.DACL()
+	if err != nil {
+		return fmt.Errorf("failed to read ACL of %s: %w", path, err)
+	}
+
+	acl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: mask,
+		AccessMode:        mode,
+		Inheritance:       inheritance,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_WELL_KNOWN_GROUP,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		},
+	}}, dacl)
+	if err != nil {
+		return fmt.Errorf("failed to build ACL of %s: %w", path, err)
+	}
+
+	if err := windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, acl, nil); err != nil {
+		return fmt.Errorf("failed to update ACL of %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// newJob creates a job object that kills its processes when closed and
+// assigns process to it
+func newJob(process windows.Handle) (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create job object: %w", err)
+	}
+
+	limits := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&limits)),
+		uint32(unsafe.Sizeof(limits)),
+	); err != nil {
+		windows.CloseHandle(job)
+		return 0, fmt.Errorf("failed to configure job object: %w", err)
+	}
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return 0, fmt.Errorf("failed to assign plugin to job object: %w", err)
+	}
+
+	return job, nil
+}
+
+// environmentBlock encodes env as a Unicode environment block
+func environmentBlock(env []string) *uint16 {
+	var block []uint16
+	for _, kv := range env {
+		block = append(block, utf16.Encode([]rune(kv))...)
+		block = append(block, 0)
+	}
+
+	if len(block) == 0 {
+		block = append(block, 0)
+	}
+
+	block = append(block, 0)
+
+	return &block[0]
+}
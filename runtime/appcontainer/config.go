@@ -0,0 +1,59 @@
+package appcontainer
+
+// Config is the typed configuration of an AppContainerExecutor
+type Config struct {
+	PluginDir     string   `mapstructure:"plugin_dir"`
+	ProfilePrefix string   `mapstructure:"profile_prefix"`
+	Capabilities  []string `mapstructure:"capabilities"`
+}
+
+// Option customizes the configuration of an AppContainerExecutor
+type Option func(*Config)
+
+// WithProfilePrefix sets the prefix of the AppContainer profile created for
+// each plugin
+func WithProfilePrefix(prefix string) Option {
+	return func(c *Config) { c.ProfilePrefix = prefix }
+}
+
+// WithCapabilities grants every plugin additional capability SIDs, such as
+// S-1-15-3-3 for private network access
+func WithCapabilities(sids ...string) Option {
+	return func(c *Config) { c.Capabilities = sids }
+}
+
+func defaultConfig(pluginDir string) Config {
+	return Config{
+		PluginDir:     pluginDir,
+		ProfilePrefix: defaultProfilePrefix,
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *AppContainerExecutor) Config() Config {
+	config := e.config
+	config.Capabilities = append([]string(nil), config.Capabilities...)
+
+	return config
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *AppContainerExecutor) ApplyConfig(config Config) error {
+	if config.ProfilePrefix == "" {
+		config.ProfilePrefix = defaultProfilePrefix
+	}
+
+	e.config = config
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *AppContainerExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
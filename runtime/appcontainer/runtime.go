@@ -0,0 +1,204 @@
+package appcontainer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	defaultProfilePrefix = "pluginkit"
+
+	// maxProfileNameLength is the longest AppContainer name Windows accepts
+	maxProfileNameLength = 64
+)
+
+// AppContainerExecutor implements the Executor interface for native plugins
+// run inside a Windows AppContainer. Each plugin gets its own AppContainer
+// identity, which can only read its plugin directory and read and write the
+// paths it was granted, and which only receives the internetClient
+// capability when the plugin requested network access. It is only
+// supported on Windows.
+type AppContainerExecutor struct {
+	config Config
+	logger logr.Logger
+
+	mu     sync.Mutex
+	grants map[string]int // Reference counts of ACL grants by SID and path
+}
+
+// NewExecutor creates a new AppContainerExecutor instance
+func NewExecutor(pluginDir string, opts ...Option) *AppContainerExecutor {
+	config := defaultConfig(pluginDir)
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &AppContainerExecutor{
+		config: config,
+		logger: logr.Discard(),
+		grants: make(map[string]int),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *AppContainerExecutor) WithLogger(logger logr.Logger) *AppContainerExecutor {
+	e.logger = logger.WithName("appcontainer-executor")
+	return e
+}
+
+// Ping verifies that AppContainers are supported on this host
+func (e *AppContainerExecutor) Ping(_ context.Context) error {
+	return supported()
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *AppContainerExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"profile_prefix": map[string]interface{}{
+				"type":        "string",
+				"description": "Prefix of the AppContainer profile created for each plugin",
+				"default":     defaultProfilePrefix,
+			},
+			"capabilities": map[string]interface{}{
+				"type":        "array",
+				"description": "Additional capability SIDs granted to every plugin (e.g., S-1-15-3-3)",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+	}
+}
+
+// Execute runs a plugin inside its AppContainer
+func (e *AppContainerExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	if err := supported(); err != nil {
+		return nil, err
+	}
+
+	config := e.Config()
+
+	// Construct the full path to the plugin executable
+	pluginPath := filepath.Join(config.PluginDir, pluginName, pluginName)
+
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(config.PluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	env := os.Environ()
+	if environment != nil {
+		env = make([]string, 0, len(environment))
+		for k, v := range environment {
+			env = append(env, k+"="+v)
+		}
+	}
+
+	result, err := e.run(ctx, config, e.profileName(config, pluginName), pluginPath, env, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	endTime := time.Now()
+
+	logger.V(1).Info("plugin execution finished", "exitCode", result.ExitCode, "duration", endTime.Sub(startTime))
+
+	result.StartTime = startTime
+	result.EndTime = endTime
+	result.Duration = endTime.Sub(startTime)
+	result.CommandLine = pluginPath + " " + strings.Join(opts.Args, " ")
+	result.Command = append([]string{pluginPath}, opts.Args...)
+	result.WorkingDir = opts.WorkingDir
+	result.Environment = environment
+	result.Success = result.ExitCode == 0
+
+	return RedactResult(opts.Redactor, ExtractStructured(result)), nil
+}
+
+// Explain returns the command and AppContainer identity Execute would use,
+// without running anything
+func (e *AppContainerExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	config := e.Config()
+	pluginPath := filepath.Join(config.PluginDir, pluginName, pluginName)
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	plan := &ExecutionPlan{
+		Runtime:     "appcontainer",
+		Command:     append([]string{pluginPath}, opts.Args...),
+		CommandLine: pluginPath + " " + strings.Join(opts.Args, " "),
+		Mounts:      []Mount{{Source: filepath.Dir(pluginPath), Target: filepath.Dir(pluginPath), ReadOnly: true}},
+		Network:     "none",
+		Security:    []string{"appcontainer=" + e.profileName(config, pluginName)},
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	if opts.Permissions == nil || opts.Permissions.Network {
+		plan.Network = "internetClient"
+	}
+
+	for _, sid := range config.Capabilities {
+		plan.Security = append(plan.Security, "capability="+sid)
+	}
+
+	for _, path := range grantPaths(opts) {
+		plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// profileName returns the AppContainer profile name of a plugin
+func (e *AppContainerExecutor) profileName(config Config, pluginName string) string {
+	name := config.ProfilePrefix + "." + pluginName
+	if len(name) > maxProfileNameLength {
+		name = name[:maxProfileNameLength]
+	}
+
+	return name
+}
+
+// grantPaths returns the paths the plugin's AppContainer is given read and
+// write access to. Plugins without declared permissions only get their
+// working directory.
+func grantPaths(opts ExecuteOptions) []string {
+	if opts.Permissions != nil {
+		return opts.Permissions.Filesystem
+	}
+
+	if opts.WorkingDir != "" {
+		return []string{opts.WorkingDir}
+	}
+
+	return nil
+}
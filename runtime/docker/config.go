@@ -0,0 +1,95 @@
+package extension
+
+import "maps"
+
+// DockerConfig is the typed configuration of a DockerExecutor
+type DockerConfig struct {
+	PluginDir    string            `mapstructure:"plugin_dir"`
+	DockerPath   string            `mapstructure:"docker_path"`
+	NetworkMode  string            `mapstructure:"network_mode"`
+	ExtraLabels  map[string]string `mapstructure:"extra_labels"`
+	ExtraOptions []string          `mapstructure:"extra_options"`
+	Devices      DevicePolicy      `mapstructure:"devices"`
+	Signature    SignaturePolicy   `mapstructure:"signature"`
+}
+
+// DockerOption customizes the configuration of a DockerExecutor
+type DockerOption func(*DockerConfig)
+
+// WithDockerPath sets the docker executable
+func WithDockerPath(path string) DockerOption {
+	return func(c *DockerConfig) { c.DockerPath = path }
+}
+
+// WithNetworkMode sets the network mode of plugin containers
+func WithNetworkMode(mode string) DockerOption {
+	return func(c *DockerConfig) { c.NetworkMode = mode }
+}
+
+// WithExtraLabels adds labels to plugin containers
+func WithExtraLabels(labels map[string]string) DockerOption {
+	return func(c *DockerConfig) { c.ExtraLabels = labels }
+}
+
+// WithExtraOptions adds docker run options
+func WithExtraOptions(options ...string) DockerOption {
+	return func(c *DockerConfig) { c.ExtraOptions = options }
+}
+
+// WithDevicePolicy sets the devices and GPUs plugins may request
+func WithDevicePolicy(policy DevicePolicy) DockerOption {
+	return func(c *DockerConfig) { c.Devices = policy }
+}
+
+// WithSignaturePolicy requires images to be signed before they run
+func WithSignaturePolicy(policy SignaturePolicy) DockerOption {
+	return func(c *DockerConfig) { c.Signature = policy }
+}
+
+func defaultDockerConfig(pluginDir string) DockerConfig {
+	return DockerConfig{
+		PluginDir:   pluginDir,
+		DockerPath:  "docker",
+		NetworkMode: "host",
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *DockerExecutor) Config() DockerConfig {
+	return DockerConfig{
+		PluginDir:    e.pluginDir,
+		DockerPath:   e.dockerPath,
+		NetworkMode:  e.networkMode,
+		ExtraLabels:  maps.Clone(e.extraLabels),
+		ExtraOptions: append([]string(nil), e.extraOptions...),
+		Devices:      e.devices.Clone(),
+		Signature:    e.signature.Clone(),
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *DockerExecutor) ApplyConfig(config DockerConfig) error {
+	if config.PluginDir == "" {
+		return &ConfigError{Fields: []FieldError{{Field: "/plugin_dir", Message: "is required"}}}
+	}
+
+	e.pluginDir = config.PluginDir
+	e.dockerPath = config.DockerPath
+	e.networkMode = config.NetworkMode
+	e.extraLabels = config.ExtraLabels
+	e.extraOptions = config.ExtraOptions
+	e.devices = config.Devices
+	e.signature = config.Signature
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *DockerExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
 // DockerExecutor implements the Executor interface for Docker-based plugins
@@ -16,39 +19,55 @@ type DockerExecutor struct {
 	networkMode  string
 	extraLabels  map[string]string
 	extraOptions []string
+	devices      DevicePolicy
+	signature    SignaturePolicy
+	logger       logr.Logger
 }
 
 // NewDockerExecutor creates a new DockerExecutor instance
-func NewDockerExecutor(pluginDir string) *DockerExecutor {
+func NewDockerExecutor(pluginDir string, opts ...DockerOption) *DockerExecutor {
+	config := defaultDockerConfig(pluginDir)
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &DockerExecutor{
-		pluginDir: pluginDir,
+		pluginDir:    config.PluginDir,
+		dockerPath:   config.DockerPath,
+		networkMode:  config.NetworkMode,
+		extraLabels:  config.ExtraLabels,
+		extraOptions: config.ExtraOptions,
+		devices:      config.Devices,
+		signature:    config.Signature,
+		logger:       logr.Discard(),
 	}
 }
 
+// WithLogger sets the logger used for execution diagnostics
+func (e *DockerExecutor) WithLogger(logger logr.Logger) *DockerExecutor {
+	e.logger = logger.WithName("docker-executor")
+	return e
+}
+
 // Execute runs a Docker plugin with the given options
 func (e *DockerExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
 	startTime := time.Now()
 
-	// Build Docker command arguments
-	args := []string{"run", "--rm"}
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
 
-	// Add environment variables
-	for k, v := range opts.Environment {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add working directory mount if specified
-	if opts.WorkingDir != "" {
-		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
-		args = append(args, "-w", "/app")
+	// Refuse to run images that do not satisfy the signature policy
+	if err := e.signature.Verify(ctx, plan.Image); err != nil {
+		return nil, err
 	}
 
-	// Add image name and command arguments
-	args = append(args, pluginName)
-	args = append(args, opts.Args...)
-
 	// Create command
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := exec.CommandContext(ctx, plan.Command[0], plan.Command[1:]...)
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
@@ -56,7 +75,7 @@ func (e *DockerExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 	cmd.Stderr = &stderr
 
 	// Execute command
-	err := cmd.Run()
+	err = cmd.Run()
 	endTime := time.Now()
 
 	// Handle exit code
@@ -69,69 +88,272 @@ func (e *DockerExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 		}
 	}
 
-	// Build command line for logging
-	commandLine := fmt.Sprintf("docker %s", strings.Join(args, " "))
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
 
-	return &ExecuteResult{
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
 		StartTime:   startTime,
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
-		CommandLine: commandLine,
+		CommandLine: plan.CommandLine,
+		Command:     plan.Command,
+		Image:       plan.Image,
+		Mounts:      plan.Mounts,
+		Network:     plan.Network,
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: plan.Environment,
 		PID:         0, // Docker containers don't expose host PIDs
 		Success:     exitCode == 0,
-	}, nil
+	})), nil
 }
 
-// Configure applies the provided configuration map
-func (e *DockerExecutor) Configure(config map[string]interface{}) error {
-	// Extract plugin directory
-	if pluginDir, ok := config["plugin_dir"].(string); ok {
-		e.pluginDir = pluginDir
+// Explain returns the docker command Execute would run, without running it
+func (e *DockerExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract docker path
-	if dockerPath, ok := config["docker_path"].(string); ok {
-		e.dockerPath = dockerPath
-	} else {
-		e.dockerPath = "docker" // default
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// plan builds the docker command that runs the plugin
+func (e *DockerExecutor) plan(pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
 	}
 
-	// Extract network mode
-	if networkMode, ok := config["network_mode"].(string); ok {
-		e.networkMode = networkMode
-	} else {
-		e.networkMode = "host" // default
+	// Run the image built at install time, if any, pinned by its ID
+	image := pluginName
+	if opts.Image != "" {
+		image = opts.Image
 	}
 
-	// Extract extra labels
-	if labels, ok := config["extra_labels"].(map[string]interface{}); ok {
-		e.extraLabels = make(map[string]string)
-		for k, v := range labels {
-			if strVal, ok := v.(string); ok {
-				e.extraLabels[k] = strVal
-			}
+	plan := &ExecutionPlan{
+		Runtime:     "docker",
+		Image:       image,
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	// Pass through the devices and GPUs the plugin was granted
+	devices, gpus, err := opts.Permissions.GrantedDevices(e.devices)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		plan.Security = append(plan.Security, "--device="+device)
+	}
+
+	if gpus != "" {
+		plan.Security = append(plan.Security, "--gpus="+gpus)
+	}
+
+	// Build Docker command arguments
+	args := append([]string{"run", "--rm"}, plan.Security...)
+
+	// Add network mode, denying network access unless the plugin was granted it
+	if network := opts.Permissions.NetworkMode(e.networkMode); network != "" {
+		args = append(args, "--network", network)
+		plan.Network = network
+	}
+
+	// Add environment variables
+	for k, v := range environment {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Mount the host paths the plugin was granted
+	if opts.Permissions != nil {
+		for _, path := range opts.Permissions.Filesystem {
+			args = append(args, "-v", fmt.Sprintf("%s:%s", path, path))
+			plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
 		}
 	}
 
-	// Extract extra options
-	if options, ok := config["extra_options"].([]interface{}); ok {
-		e.extraOptions = make([]string, 0, len(options))
-		for _, opt := range options {
-			if strOpt, ok := opt.(string); ok {
-				e.extraOptions = append(e.extraOptions, strOpt)
-			}
+	// Mount the plugin's named volumes, which the runtime creates on first use
+	for _, volume := range opts.Volumes {
+		if err := volume.Validate(); err != nil {
+			return nil, err
 		}
+
+		name := VolumeName(pluginName, volume)
+		args = append(args, "-v", fmt.Sprintf("%s:%s", name, volume.Path))
+		plan.Mounts = append(plan.Mounts, Mount{Source: name, Target: volume.Path})
+	}
+
+	// Add working directory mount if specified
+	if opts.WorkingDir != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
+		args = append(args, "-w", "/app")
+		plan.Mounts = append(plan.Mounts, Mount{Source: opts.WorkingDir, Target: "/app"})
 	}
 
-	// Validate required fields
-	if e.pluginDir == "" {
-		return fmt.Errorf("plugin_dir is required")
+	// Add image name and command arguments
+	args = append(args, image)
+	args = append(args, opts.Args...)
+
+	plan.Command = append([]string{"docker"}, args...)
+	plan.CommandLine = strings.Join(plan.Command, " ")
+
+	return plan, nil
+}
+
+// Ping verifies that the docker daemon is running and reachable
+func (e *DockerExecutor) Ping(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("docker daemon is not reachable: %w: %s", err, strings.TrimSpace(string(output)))
 	}
 
 	return nil
 }
+
+// RemoveVolumes removes the named volumes of a plugin
+func (e *DockerExecutor) RemoveVolumes(ctx context.Context, pluginName string, volumes []Volume) error {
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	args := []string{"volume", "rm", "--force"}
+	for _, volume := range volumes {
+		args = append(args, VolumeName(pluginName, volume))
+	}
+
+	if output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove volumes: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RemoveArtifacts removes the image built for the plugin and, unless
+// keepData is set, its volumes
+func (e *DockerExecutor) RemoveArtifacts(ctx context.Context, pluginName string, info Info, keepData bool) error {
+	if info.Image != "" {
+		args := []string{"image", "rm", "--force", info.Image}
+		if output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput(); err != nil && !ImageNotFound(output) {
+			return fmt.Errorf("failed to remove image %s: %w: %s", info.Image, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if keepData {
+		return nil
+	}
+
+	return e.RemoveVolumes(ctx, pluginName, info.Volumes)
+}
+
+// BuildImage builds a plugin image with docker build and returns its ID
+func (e *DockerExecutor) BuildImage(ctx context.Context, contextDir, dockerfile, tag string) (string, error) {
+	iidFile, err := os.CreateTemp("", "pluginkit-iid-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image ID file: %w", err)
+	}
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	args := []string{"build", "--tag", tag, "--file", dockerfile, "--iidfile", iidFile.Name(), contextDir}
+	cmd := exec.CommandContext(ctx, "docker", args...)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to build image %s: %w: %s", tag, err, strings.TrimSpace(string(output)))
+	}
+
+	raw, err := os.ReadFile(iidFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read image ID: %w", err)
+	}
+
+	return ImageID(string(raw))
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *DockerExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"docker_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to docker executable",
+				"default":     "docker",
+			},
+			"network_mode": map[string]interface{}{
+				"type":        "string",
+				"description": "Network mode for containers (e.g., host, bridge)",
+				"default":     "host",
+			},
+			"extra_labels": map[string]interface{}{
+				"type":        "object",
+				"description": "Additional labels to add to containers",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"devices": map[string]interface{}{
+				"type":        "object",
+				"description": "Devices plugins may request in their permissions",
+				"properties": map[string]interface{}{
+					"allow_gpus": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Permit plugins to request GPUs",
+						"default":     false,
+					},
+					"allowed_devices": map[string]interface{}{
+						"type":        "array",
+						"description": "Host device paths or glob patterns plugins may request",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+			"signature": map[string]interface{}{
+				"type":        "object",
+				"description": "Cosign signature policy images must satisfy before they run",
+				"properties": map[string]interface{}{
+					"cosign_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to cosign executable",
+						"default":     "cosign",
+					},
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Public key file or KMS URI images must be signed with",
+					},
+					"identity": map[string]interface{}{
+						"type":        "string",
+						"description": "Certificate identity of keyless signatures",
+					},
+					"issuer": map[string]interface{}{
+						"type":        "string",
+						"description": "OIDC issuer of keyless signatures",
+					},
+					"attestation_types": map[string]interface{}{
+						"type":        "array",
+						"description": "Attestation types that must also verify, e.g. slsaprovenance",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+			"extra_options": map[string]interface{}{
+				"type":        "array",
+				"description": "Additional docker run options",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,153 @@
+//go:build cgo && (linux || darwin || freebsd)
+
+package dylib
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+
+typedef int (*negotiate_fn)(int, int);
+typedef int (*abi_version_fn)(void);
+typedef int (*run_v1_fn)(int, char **, char **, char **, size_t *, char **, size_t *);
+typedef void (*free_fn)(void *);
+
+static int call_negotiate(void *fn, int min, int max) {
+	return ((negotiate_fn)fn)(min, max);
+}
+
+static int call_abi_version(void *fn) {
+	return ((abi_version_fn)fn)();
+}
+
+static int call_run_v1(void *fn, int argc, char **argv, char **envp, char **out, size_t *out_len, char **err, size_t *err_len) {
+	return ((run_v1_fn)fn)(argc, argv, envp, out, out_len, err, err_len);
+}
+
+static void call_free(void *fn, void *ptr) {
+	((free_fn)fn)(ptr);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// library is an opened shared library with its resolved entrypoints
+type library struct {
+	handle unsafe.Pointer
+	abi    int
+	entry  unsafe.Pointer
+	free   unsafe.Pointer
+}
+
+func supported() error {
+	return nil
+}
+
+func openLibrary(path string) (*library, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	handle := C.dlopen(cpath, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return nil, fmt.Errorf("failed to load %s: %s", path, C.GoString(C.dlerror()))
+	}
+
+	lib := &library{handle: handle}
+
+	var abi int
+	if fn := lookup(handle, "pluginkit_negotiate"); fn != nil {
+		abi = int(C.call_negotiate(fn, C.int(MinABIVersion), C.int(MaxABIVersion)))
+	} else if fn := lookup(handle, "pluginkit_abi_version"); fn != nil {
+		abi = int(C.call_abi_version(fn))
+	} else {
+		C.dlclose(handle)
+		return nil, fmt.Errorf("%s does not export pluginkit_negotiate or pluginkit_abi_version", path)
+	}
+
+	if abi < MinABIVersion || abi > MaxABIVersion {
+		C.dlclose(handle)
+		return nil, fmt.Errorf("%s uses plugin ABI %d, host supports %d to %d", path, abi, MinABIVersion, MaxABIVersion)
+	}
+
+	lib.abi = abi
+	lib.entry = lookup(handle, fmt.Sprintf("pluginkit_run_v%d", abi))
+	lib.free = lookup(handle, "pluginkit_free")
+
+	if lib.entry == nil || lib.free == nil {
+		C.dlclose(handle)
+		return nil, fmt.Errorf("%s does not export pluginkit_run_v%d and pluginkit_free", path, abi)
+	}
+
+	return lib, nil
+}
+
+func lookup(handle unsafe.Pointer, symbol string) unsafe.Pointer {
+	csym := C.CString(symbol)
+	defer C.free(unsafe.Pointer(csym))
+
+	return C.dlsym(handle, csym)
+}
+
+// run calls the plugin entrypoint and returns its exit code and output
+func (l *library) run(argv, env []string) (int, []byte, []byte) {
+	cargv := cStrings(argv)
+	defer freeStrings(cargv)
+
+	cenv := cStrings(env)
+	defer freeStrings(cenv)
+
+	var (
+		out, errOut       *C.char
+		outLen, errOutLen C.size_t
+	)
+
+	code := C.call_run_v1(l.entry, C.int(len(argv)), &cargv[0], &cenv[0], &out, &outLen, &errOut, &errOutLen)
+
+	stdout := l.take(out, outLen)
+	stderr := l.take(errOut, errOutLen)
+
+	return int(code), stdout, stderr
+}
+
+// close unloads the library
+func (l *library) close() error {
+	if C.dlclose(l.handle) != 0 {
+		return fmt.Errorf("%s", C.GoString(C.dlerror()))
+	}
+
+	return nil
+}
+
+// take copies a plugin-allocated buffer and releases it
+func (l *library) take(buf *C.char, n C.size_t) []byte {
+	if buf == nil {
+		return nil
+	}
+
+	data := C.GoBytes(unsafe.Pointer(buf), C.int(n))
+	C.call_free(l.free, unsafe.Pointer(buf))
+
+	return data
+}
+
+// cStrings builds a NULL-terminated C string array
+func cStrings(values []string) []*C.char {
+	array := make([]*C.char, len(values)+1)
+	for i, v := range values {
+		array[i] = C.CString(v)
+	}
+
+	return array
+}
+
+func freeStrings(array []*C.char) {
+	for _, s := range array {
+		if s != nil {
+			C.free(unsafe.Pointer(s))
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !cgo || !(linux || darwin || freebsd)
+
+package dylib
+
+import "fmt"
+
+// library is unavailable without cgo
+type library struct {
+	abi int
+}
+
+func supported() error {
+	return fmt.Errorf("shared library plugins require cgo on linux, darwin or freebsd")
+}
+
+func openLibrary(path string) (*library, error) {
+	return nil, fmt.Errorf("cannot load %s: shared library plugins require cgo on linux, darwin or freebsd", path)
+}
+
+func (l *library) run(_, _ []string) (int, []byte, []byte) {
+	return -1, nil, nil
+}
+
+func (l *library) close() error {
+	return nil
+}
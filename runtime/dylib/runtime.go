@@ -0,0 +1,275 @@
+// Package dylib runs plugins built as shared libraries inside the host
+// process.
+//
+// A plugin library exports a C ABI:
+//
+//	int  pluginkit_negotiate(int host_min, int host_max); // optional
+//	int  pluginkit_abi_version(void);                      // used when negotiate is absent
+//	int  pluginkit_run_v1(int argc, char **argv, char **envp,
+//	                      char **out, size_t *out_len,
+//	                      char **err, size_t *err_len);
+//	void pluginkit_free(void *ptr);
+//
+// The host and the plugin agree on an ABI version before the versioned run
+// symbol is looked up, so either side can evolve without silently calling a
+// mismatched entrypoint. Output buffers are allocated by the plugin and
+// released with pluginkit_free.
+//
+// Crash isolation: a library runs in the host's address space, so a crash,
+// abort or memory corruption in the plugin takes the whole host down, and
+// permissions other than environment filtering cannot be enforced. Only load
+// trusted libraries. Hosts that need isolation should run this executor in
+// a dedicated helper process and talk to it over the native runtime, or use
+// the native or wasm runtimes instead.
+package dylib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// ABI versions supported by the host
+const (
+	MinABIVersion = 1
+	MaxABIVersion = 1
+)
+
+// DylibExecutor implements the Executor interface for shared library plugins
+type DylibExecutor struct {
+	pluginDir string
+	logger    logr.Logger
+
+	mu        sync.Mutex
+	libraries map[string]*library // Loaded libraries by path, kept open for reuse
+	closed    bool
+	active    sync.WaitGroup // Executions calling into a loaded library
+}
+
+// NewExecutor creates a new DylibExecutor instance
+func NewExecutor(pluginDir string) *DylibExecutor {
+	return &DylibExecutor{
+		pluginDir: pluginDir,
+		logger:    logr.Discard(),
+		libraries: make(map[string]*library),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *DylibExecutor) WithLogger(logger logr.Logger) *DylibExecutor {
+	e.logger = logger.WithName("dylib-executor")
+	return e
+}
+
+// Ping verifies that shared libraries can be loaded on this platform
+func (e *DylibExecutor) Ping(_ context.Context) error {
+	if err := supported(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(e.pluginDir); err != nil {
+		return fmt.Errorf("plugin directory is not accessible: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *DylibExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+		},
+	}
+}
+
+// Execute calls the run entrypoint of a shared library plugin
+func (e *DylibExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.WorkingDir != "" {
+		return nil, fmt.Errorf("in-process plugins cannot run in a separate working directory")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("execution cancelled: %w", err)
+	}
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
+	path, err := e.libraryPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	lib, err := e.load(path)
+	if err != nil {
+		return nil, err
+	}
+	defer e.active.Done()
+
+	logger.V(1).Info("loaded library", "path", path, "abi", lib.abi)
+
+	env := make([]string, 0, len(environment))
+	for k, v := range environment {
+		env = append(env, k+"="+v)
+	}
+
+	argv := append([]string{pluginName}, opts.Args...)
+
+	exitCode, stdout, stderr := lib.run(argv, env)
+	endTime := time.Now()
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exitCode,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Command:     append([]string{path}, opts.Args...),
+		Image:       path,
+		Environment: environment,
+		PID:         os.Getpid(),
+		Success:     exitCode == 0,
+	})), nil
+}
+
+// Explain returns the library Execute would load and call, without loading
+// it
+func (e *DylibExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	if opts.WorkingDir != "" {
+		return nil, fmt.Errorf("in-process plugins cannot run in a separate working directory")
+	}
+
+	path, err := e.libraryPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     "dylib",
+		Command:     append([]string{path}, opts.Args...),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Image:       path,
+		Security:    []string{"in-process"},
+		Environment: opts.Permissions.FilterEnv(opts.Environment),
+	}), nil
+}
+
+// Close waits for running plugins until ctx is done, then unloads every
+// library. Libraries still in use when ctx is done are left loaded.
+func (e *DylibExecutor) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+
+	e.closed = true
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("plugins still running, libraries left loaded: %w", ctx.Err())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+	for path, lib := range e.libraries {
+		if err := lib.close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unload %s: %w", path, err))
+		}
+
+		delete(e.libraries, path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// load opens a library once and negotiates its ABI version. On success the
+// execution is counted as active and the caller must call e.active.Done.
+func (e *DylibExecutor) load(path string) (*library, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, fmt.Errorf("dylib executor is closed")
+	}
+
+	lib, ok := e.libraries[path]
+	if !ok {
+		var err error
+		if lib, err = openLibrary(path); err != nil {
+			return nil, err
+		}
+
+		e.libraries[path] = lib
+	}
+
+	e.active.Add(1)
+
+	return lib, nil
+}
+
+// libraryPath locates the shared library of an installed plugin
+func (e *DylibExecutor) libraryPath(pluginName string) (string, error) {
+	dir := filepath.Join(e.pluginDir, pluginName)
+
+	var info Info
+	if data, err := os.ReadFile(filepath.Join(dir, "metadata.json")); err == nil {
+		if err := json.Unmarshal(data, &info); err != nil {
+			return "", fmt.Errorf("failed to parse metadata for plugin %s: %w", pluginName, err)
+		}
+	}
+
+	if info.Name == "" {
+		info.Name = pluginName
+	}
+
+	if info.FileName != "" {
+		return filepath.Join(dir, info.Name, info.FileName), nil
+	}
+
+	ext := ".so"
+	if runtime.GOOS == "darwin" {
+		ext = ".dylib"
+	}
+
+	return filepath.Join(dir, info.Name, pluginName+ext), nil
+}
@@ -0,0 +1,27 @@
+package goplugin
+
+// Config is the typed configuration of a GoPluginExecutor
+type Config struct {
+	PluginDir string `mapstructure:"plugin_dir"`
+}
+
+// Config returns the executor's current configuration
+func (e *GoPluginExecutor) Config() Config {
+	return Config{PluginDir: e.pluginDir}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *GoPluginExecutor) ApplyConfig(config Config) error {
+	e.pluginDir = config.PluginDir
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *GoPluginExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -0,0 +1,94 @@
+package goplugin
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// FingerprintError lists why a Go plugin cannot be loaded into this host
+type FingerprintError struct {
+	Path       string
+	Mismatches []string
+}
+
+func (e *FingerprintError) Error() string {
+	return fmt.Sprintf("plugin %s was not built compatibly with this host: %s", e.Path, strings.Join(e.Mismatches, "; "))
+}
+
+// CheckFingerprint compares the build information of a plugin with that of
+// the running host. The Go runtime refuses plugins built with a different
+// toolchain or different versions of shared packages, with errors that do
+// not say which package differs; this reports every mismatch up front.
+func CheckFingerprint(path string) error {
+	host, ok := debug.ReadBuildInfo()
+	if !ok {
+		return fmt.Errorf("host build information is unavailable")
+	}
+
+	plugin, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read build information of %s: %w", path, err)
+	}
+
+	var mismatches []string
+
+	if plugin.GoVersion != host.GoVersion {
+		mismatches = append(mismatches, fmt.Sprintf("built with %s, host uses %s", plugin.GoVersion, host.GoVersion))
+	}
+
+	for _, key := range []string{"GOOS", "GOARCH", "CGO_ENABLED", "-trimpath"} {
+		if p, h := setting(plugin, key), setting(host, key); p != h {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %q, host has %q", key, p, h))
+		}
+	}
+
+	hostModules := modules(host)
+
+	for path, p := range modules(plugin) {
+		h, ok := hostModules[path]
+		if !ok {
+			continue
+		}
+
+		if p.Version != h.Version {
+			mismatches = append(mismatches, fmt.Sprintf("module %s is %s, host has %s", path, p.Version, h.Version))
+		} else if p.Sum != "" && h.Sum != "" && p.Sum != h.Sum {
+			mismatches = append(mismatches, fmt.Sprintf("module %s has checksum %s, host has %s", path, p.Sum, h.Sum))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &FingerprintError{Path: path, Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+func setting(info *debug.BuildInfo, key string) string {
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+
+	return ""
+}
+
+// modules returns the dependencies of a build by module path, following
+// replacements
+func modules(info *debug.BuildInfo) map[string]*debug.Module {
+	result := make(map[string]*debug.Module, len(info.Deps))
+
+	for _, dep := range info.Deps {
+		m := dep
+		if dep.Replace != nil {
+			m = dep.Replace
+		}
+
+		result[dep.Path] = m
+	}
+
+	return result
+}
@@ -0,0 +1,31 @@
+//go:build cgo && (linux || darwin || freebsd)
+
+package goplugin
+
+import (
+	"fmt"
+	"plugin"
+)
+
+func supported() error {
+	return nil
+}
+
+func openPlugin(path string) (RunFunc, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %w", path, err)
+	}
+
+	sym, err := p.Lookup(RunSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s does not export %s: %w", path, RunSymbol, err)
+	}
+
+	run, ok := sym.(RunFunc)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s exports %s with type %T, expected %T", path, RunSymbol, sym, RunFunc(nil))
+	}
+
+	return run, nil
+}
@@ -0,0 +1,13 @@
+//go:build !cgo || !(linux || darwin || freebsd)
+
+package goplugin
+
+import "fmt"
+
+func supported() error {
+	return fmt.Errorf("Go plugins require cgo on linux, darwin or freebsd")
+}
+
+func openPlugin(path string) (RunFunc, error) {
+	return nil, fmt.Errorf("cannot open %s: Go plugins require cgo on linux, darwin or freebsd", path)
+}
@@ -0,0 +1,197 @@
+package goplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// RunSymbol is the function a Go plugin must export, with the signature of
+// RunFunc
+const RunSymbol = "Run"
+
+// RunFunc is the entrypoint of a Go plugin. It returns the exit code of the
+// invocation.
+type RunFunc = func(ctx context.Context, args []string, env map[string]string, stdout, stderr io.Writer) int
+
+// GoPluginExecutor implements the Executor interface for Go plugins loaded
+// in-process with the standard library plugin package. Loaded plugins can
+// never be unloaded and run with the full privileges of the host, so this
+// runtime is only suitable for trusted extensions.
+type GoPluginExecutor struct {
+	pluginDir string
+	logger    logr.Logger
+
+	mu     sync.Mutex
+	loaded map[string]RunFunc // Entrypoints by plugin path
+}
+
+// NewExecutor creates a new GoPluginExecutor instance
+func NewExecutor(pluginDir string) *GoPluginExecutor {
+	return &GoPluginExecutor{
+		pluginDir: pluginDir,
+		logger:    logr.Discard(),
+		loaded:    make(map[string]RunFunc),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *GoPluginExecutor) WithLogger(logger logr.Logger) *GoPluginExecutor {
+	e.logger = logger.WithName("goplugin-executor")
+	return e
+}
+
+// Ping verifies that Go plugins can be opened on this platform
+func (e *GoPluginExecutor) Ping(_ context.Context) error {
+	if err := supported(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(e.pluginDir); err != nil {
+		return fmt.Errorf("plugin directory is not accessible: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *GoPluginExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+		},
+	}
+}
+
+// Execute calls the Run function of a Go plugin
+func (e *GoPluginExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.WorkingDir != "" {
+		return nil, fmt.Errorf("in-process plugins cannot run in a separate working directory")
+	}
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
+	path, err := e.pluginPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := e.load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	exitCode := run(ctx, opts.Args, environment, &stdout, &stderr)
+	endTime := time.Now()
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exitCode,
+		Stdout:      stdout.Bytes(),
+		Stderr:      stderr.Bytes(),
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Command:     append([]string{path}, opts.Args...),
+		Image:       path,
+		Environment: environment,
+		PID:         os.Getpid(),
+		Success:     exitCode == 0,
+	})), nil
+}
+
+// Explain returns the Go plugin Execute would open and call, without
+// opening it
+func (e *GoPluginExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	if opts.WorkingDir != "" {
+		return nil, fmt.Errorf("in-process plugins cannot run in a separate working directory")
+	}
+
+	path, err := e.pluginPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     "goplugin",
+		Command:     append([]string{path}, opts.Args...),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Image:       path,
+		Security:    []string{"in-process"},
+		Environment: opts.Permissions.FilterEnv(opts.Environment),
+	}), nil
+}
+
+// load validates and opens a plugin once
+func (e *GoPluginExecutor) load(path string) (RunFunc, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if run, ok := e.loaded[path]; ok {
+		return run, nil
+	}
+
+	if err := CheckFingerprint(path); err != nil {
+		return nil, err
+	}
+
+	run, err := openPlugin(path)
+	if err != nil {
+		return nil, err
+	}
+
+	e.loaded[path] = run
+
+	return run, nil
+}
+
+// pluginPath locates the shared object of an installed plugin
+func (e *GoPluginExecutor) pluginPath(pluginName string) (string, error) {
+	dir := filepath.Join(e.pluginDir, pluginName)
+
+	var info Info
+	if data, err := os.ReadFile(filepath.Join(dir, "metadata.json")); err == nil {
+		if err := json.Unmarshal(data, &info); err != nil {
+			return "", fmt.Errorf("failed to parse metadata for plugin %s: %w", pluginName, err)
+		}
+	}
+
+	if info.Name == "" {
+		info.Name = pluginName
+	}
+
+	if info.FileName != "" {
+		return filepath.Join(dir, info.Name, info.FileName), nil
+	}
+
+	return filepath.Join(dir, info.Name, pluginName+".so"), nil
+}
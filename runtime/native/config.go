@@ -0,0 +1,42 @@
+package native
+
+// Config is the typed configuration of a NativeExecutor
+type Config struct {
+	PluginDir        string `mapstructure:"plugin_dir"`
+	NetworkIsolation bool   `mapstructure:"network_isolation"`
+}
+
+// Option customizes the configuration of a NativeExecutor
+type Option func(*Config)
+
+// WithNetworkIsolation runs plugins that were not granted network access in
+// an empty network namespace
+func WithNetworkIsolation(enabled bool) Option {
+	return func(c *Config) { c.NetworkIsolation = enabled }
+}
+
+// Config returns the executor's current configuration
+func (e *NativeExecutor) Config() Config {
+	return Config{
+		PluginDir:        e.pluginDir,
+		NetworkIsolation: e.isolateNetwork,
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *NativeExecutor) ApplyConfig(config Config) error {
+	e.pluginDir = config.PluginDir
+	e.isolateNetwork = config.NetworkIsolation
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *NativeExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
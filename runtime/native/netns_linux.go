@@ -0,0 +1,26 @@
+//go:build linux
+
+package native
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolateNetwork starts the plugin in a new, empty network namespace. A user
+// namespace mapping the caller's IDs is created alongside so that no extra
+// privileges are needed where unprivileged user namespaces are enabled.
+func isolateNetwork(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package native
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func isolateNetwork(_ *exec.Cmd) error {
+	return fmt.Errorf("network isolation is not supported on %s", runtime.GOOS)
+}
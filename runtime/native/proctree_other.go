@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package native
+
+import "os/exec"
+
+// processTree falls back to killing only the direct child on platforms
+// without process groups or job objects
+type processTree struct{}
+
+func newProcessTree(_ *exec.Cmd) *processTree {
+	return &processTree{}
+}
+
+func (t *processTree) attach() error {
+	return nil
+}
+
+func (t *processTree) release() {}
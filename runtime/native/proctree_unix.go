@@ -0,0 +1,40 @@
+//go:build unix
+
+package native
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// processTree places the plugin in its own process group so that
+// cancellation signals every process it spawned, not only the direct child
+type processTree struct {
+	cmd *exec.Cmd
+}
+
+func newProcessTree(cmd *exec.Cmd) *processTree {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	t := &processTree{cmd: cmd}
+	cmd.Cancel = t.kill
+
+	return t
+}
+
+// attach is called once the plugin has started
+func (t *processTree) attach() error {
+	return nil
+}
+
+// kill terminates the whole process group
+func (t *processTree) kill() error {
+	if t.cmd.Process == nil {
+		return nil
+	}
+
+	return syscall.Kill(-t.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// release frees resources held for the process tree
+func (t *processTree) release() {}
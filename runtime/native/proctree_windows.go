@@ -0,0 +1,86 @@
+//go:build windows
+
+package native
+
+import (
+	"fmt"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processTree assigns the plugin to a job object so that cancellation
+// terminates every process it spawned, not only the direct child
+type processTree struct {
+	cmd *exec.Cmd
+	job windows.Handle
+}
+
+func newProcessTree(cmd *exec.Cmd) *processTree {
+	t := &processTree{cmd: cmd}
+	cmd.Cancel = t.kill
+
+	return t
+}
+
+// attach creates the job object and assigns the started plugin to it.
+// Processes the plugin spawns afterwards inherit the job.
+func (t *processTree) attach() error {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create job object: %w", err)
+	}
+
+	limits := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&limits)),
+		uint32(unsafe.Sizeof(limits)),
+	); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to configure job object: %w", err)
+	}
+
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(t.cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to open plugin process: %w", err)
+	}
+	defer windows.CloseHandle(process)
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("failed to assign plugin to job object: %w", err)
+	}
+
+	t.job = job
+
+	return nil
+}
+
+// kill terminates every process in the job
+func (t *processTree) kill() error {
+	if t.job == 0 {
+		if t.cmd.Process != nil {
+			return t.cmd.Process.Kill()
+		}
+
+		return nil
+	}
+
+	return windows.TerminateJobObject(t.job, 1)
+}
+
+// release closes the job object
+func (t *processTree) release() {
+	if t.job != 0 {
+		windows.CloseHandle(t.job)
+	}
+}
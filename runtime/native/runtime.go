@@ -3,22 +3,81 @@ package native
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
+// processWaitDelay bounds how long Wait blocks on output pipes after the
+// plugin exits or is killed
+const processWaitDelay = 5 * time.Second
+
 // NativeExecutor implements the Executor interface
 type NativeExecutor struct {
-	pluginDir string
+	pluginDir      string
+	isolateNetwork bool
+	logger         logr.Logger
 }
 
 // NewExecutor creates a new DefaultExecutor instance
-func NewExecutor(pluginDir string) *NativeExecutor {
+func NewExecutor(pluginDir string, opts ...Option) *NativeExecutor {
+	config := Config{PluginDir: pluginDir}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &NativeExecutor{
-		pluginDir: pluginDir,
+		pluginDir:      config.PluginDir,
+		isolateNetwork: config.NetworkIsolation,
+		logger:         logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *NativeExecutor) WithLogger(logger logr.Logger) *NativeExecutor {
+	e.logger = logger.WithName("native-executor")
+	return e
+}
+
+// WithNetworkIsolation runs plugins that were not granted network access in
+// an empty network namespace. It is only supported on Linux; elsewhere such
+// plugins fail to start rather than run with network access.
+func (e *NativeExecutor) WithNetworkIsolation(enabled bool) *NativeExecutor {
+	e.isolateNetwork = enabled
+	return e
+}
+
+// Ping verifies that the plugin directory is accessible
+func (e *NativeExecutor) Ping(_ context.Context) error {
+	if _, err := os.Stat(e.pluginDir); err != nil {
+		return fmt.Errorf("plugin directory is not accessible: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *NativeExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"network_isolation": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run plugins without network permission in an empty network namespace (Linux only)",
+				"default":     false,
+			},
+		},
 	}
 }
 
@@ -29,23 +88,73 @@ func (e *NativeExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 
 	startTime := time.Now()
 
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
 	// Create command with context
 	cmd := exec.CommandContext(ctx, pluginPath, opts.Args...)
 
+	// Make cancellation terminate the whole process tree
+	tree := newProcessTree(cmd)
+	defer tree.release()
+
+	// Do not wait forever on pipes held open by orphaned descendants
+	cmd.WaitDelay = processWaitDelay
+
+	// Cut off the network unless the plugin was granted access
+	network := "host"
+
+	isolated := e.isolateNetwork && opts.Permissions != nil && !opts.Permissions.Network
+	if isolated {
+		if err := isolateNetwork(cmd); err != nil {
+			return nil, err
+		}
+
+		network = "none"
+
+		logger.V(1).Info("running plugin without network access")
+	}
+
 	// Set working directory if specified
 	if opts.WorkingDir != "" {
 		cmd.Dir = opts.WorkingDir
 	}
 
 	// Set environment variables
-	if opts.Environment != nil {
-		env := make([]string, 0, len(opts.Environment))
-		for k, v := range opts.Environment {
+	if environment != nil {
+		env := make([]string, 0, len(environment))
+		for k, v := range environment {
 			env = append(env, k+"="+v)
 		}
 		cmd.Env = env
 	}
 
+	// Give the plugin a dedicated descriptor for its structured result
+	resultReader, resultWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result pipe: %w", err)
+	}
+	defer resultReader.Close()
+
+	cmd.ExtraFiles = []*os.File{resultWriter}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, ResultFDEnvVar+"=3")
+
 	// Capture stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -57,10 +166,26 @@ func (e *NativeExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 	}
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	resultWriter.Close()
+	if err != nil {
+		if isolated {
+			return nil, fmt.Errorf("failed to start plugin in an isolated network namespace (are unprivileged user namespaces enabled?): %w", err)
+		}
+
 		return nil, err
 	}
 
+	if err := tree.attach(); err != nil {
+		logger.Error(err, "descendant processes will not be terminated on cancellation")
+	}
+
+	resultCh := make(chan []byte, 1)
+	go func() {
+		data, _ := readAll(resultReader)
+		resultCh <- data
+	}()
+
 	// Read output
 	stdoutData, err := readAll(stdout)
 	if err != nil {
@@ -83,7 +208,16 @@ func (e *NativeExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 		}
 	}
 
-	return &ExecuteResult{
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	var structured map[string]any
+	if data := <-resultCh; len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &structured); err != nil {
+			logger.Error(err, "ignoring malformed structured result")
+		}
+	}
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdoutData,
 		Stderr:      stderrData,
@@ -91,11 +225,40 @@ func (e *NativeExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
 		CommandLine: pluginPath + " " + strings.Join(opts.Args, " "),
+		Command:     cmd.Args,
+		Network:     network,
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: environment,
 		PID:         cmd.Process.Pid,
 		Success:     exitCode == 0,
-	}, nil
+		Structured:  structured,
+	})), nil
+}
+
+// Explain returns the command Execute would run, without running it
+func (e *NativeExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	pluginPath := filepath.Join(e.pluginDir, pluginName, pluginName)
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	plan := &ExecutionPlan{
+		Runtime:     "native",
+		Command:     append([]string{pluginPath}, opts.Args...),
+		Network:     "host",
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+	plan.CommandLine = pluginPath + " " + strings.Join(opts.Args, " ")
+
+	if e.isolateNetwork && opts.Permissions != nil && !opts.Permissions.Network {
+		plan.Network = "none"
+		plan.Security = []string{"network-namespace"}
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
 }
 
 // Helper function to read all data from a pipe
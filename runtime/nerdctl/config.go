@@ -0,0 +1,127 @@
+package extension
+
+import "maps"
+
+// NerdctlConfig is the typed configuration of a NerdctlExecutor
+type NerdctlConfig struct {
+	PluginDir    string            `mapstructure:"plugin_dir"`
+	NerdctlPath  string            `mapstructure:"nerdctl_path"`
+	Address      string            `mapstructure:"address"`     // containerd socket, e.g. for rootless setups
+	Namespace    string            `mapstructure:"namespace"`   // containerd namespace, e.g. k8s.io
+	Snapshotter  string            `mapstructure:"snapshotter"` // containerd snapshotter, e.g. overlayfs or stargz
+	NetworkMode  string            `mapstructure:"network_mode"`
+	ExtraLabels  map[string]string `mapstructure:"extra_labels"`
+	ExtraOptions []string          `mapstructure:"extra_options"`
+	Security     SecurityOptions   `mapstructure:"security_opts"`
+	Devices      DevicePolicy      `mapstructure:"devices"`
+	Signature    SignaturePolicy   `mapstructure:"signature"`
+}
+
+// SecurityOptions are the resource limits and capabilities of plugin
+// containers
+type SecurityOptions struct {
+	MemoryLimit         string   `mapstructure:"memory_limit"`
+	CPUShares           int      `mapstructure:"cpu_shares"`
+	PidsLimit           int      `mapstructure:"pids_limit"`
+	AllowedCapabilities []string `mapstructure:"allowed_capabilities"`
+}
+
+// NerdctlOption customizes the configuration of a NerdctlExecutor
+type NerdctlOption func(*NerdctlConfig)
+
+// WithNerdctlPath sets the nerdctl executable
+func WithNerdctlPath(path string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.NerdctlPath = path }
+}
+
+// WithAddress sets the containerd address, such as the socket of a
+// rootless containerd
+func WithAddress(address string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Address = address }
+}
+
+// WithNamespace sets the containerd namespace plugins run in
+func WithNamespace(namespace string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Namespace = namespace }
+}
+
+// WithSnapshotter sets the containerd snapshotter
+func WithSnapshotter(snapshotter string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Snapshotter = snapshotter }
+}
+
+// WithNetworkMode sets the network mode of plugins granted network access
+func WithNetworkMode(mode string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.NetworkMode = mode }
+}
+
+// WithExtraLabels adds labels to plugin containers
+func WithExtraLabels(labels map[string]string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.ExtraLabels = labels }
+}
+
+// WithExtraOptions adds nerdctl run options
+func WithExtraOptions(options ...string) NerdctlOption {
+	return func(c *NerdctlConfig) { c.ExtraOptions = options }
+}
+
+// WithSecurityOptions sets the resource limits and capabilities of plugin
+// containers
+func WithSecurityOptions(security SecurityOptions) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Security = security }
+}
+
+// WithDevicePolicy sets the devices and GPUs plugins may request
+func WithDevicePolicy(policy DevicePolicy) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Devices = policy }
+}
+
+// WithSignaturePolicy requires images to be signed before they run
+func WithSignaturePolicy(policy SignaturePolicy) NerdctlOption {
+	return func(c *NerdctlConfig) { c.Signature = policy }
+}
+
+func defaultNerdctlConfig(pluginDir string) NerdctlConfig {
+	return NerdctlConfig{
+		PluginDir:   pluginDir,
+		NerdctlPath: "nerdctl",
+		Security: SecurityOptions{
+			MemoryLimit: "512m",
+			CPUShares:   1024,
+			PidsLimit:   100,
+		},
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *NerdctlExecutor) Config() NerdctlConfig {
+	config := e.config
+	config.ExtraLabels = maps.Clone(config.ExtraLabels)
+	config.ExtraOptions = append([]string(nil), config.ExtraOptions...)
+	config.Security.AllowedCapabilities = append([]string(nil), config.Security.AllowedCapabilities...)
+	config.Devices = config.Devices.Clone()
+	config.Signature = config.Signature.Clone()
+
+	return config
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *NerdctlExecutor) ApplyConfig(config NerdctlConfig) error {
+	if config.NerdctlPath == "" {
+		config.NerdctlPath = "nerdctl"
+	}
+
+	e.config = config
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *NerdctlExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -4,20 +4,232 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
 // NerdctlExecutor implements the Executor interface for Nerdctl-based plugins
 type NerdctlExecutor struct {
-	pluginDir string
+	config NerdctlConfig
+	logger logr.Logger
 }
 
 // NewNerdctlExecutor creates a new NerdctlExecutor instance
-func NewNerdctlExecutor(pluginDir string) *NerdctlExecutor {
+func NewNerdctlExecutor(pluginDir string, opts ...NerdctlOption) *NerdctlExecutor {
+	config := defaultNerdctlConfig(pluginDir)
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &NerdctlExecutor{
-		pluginDir: pluginDir,
+		config: config,
+		logger: logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *NerdctlExecutor) WithLogger(logger logr.Logger) *NerdctlExecutor {
+	e.logger = logger.WithName("nerdctl-executor")
+	return e
+}
+
+// Ping verifies that containerd is reachable through nerdctl
+func (e *NerdctlExecutor) Ping(ctx context.Context) error {
+	args := append(globalArgs(e.config), "version")
+
+	if output, err := exec.CommandContext(ctx, e.config.NerdctlPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("containerd is not reachable: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RemoveVolumes removes the named volumes of a plugin
+func (e *NerdctlExecutor) RemoveVolumes(ctx context.Context, pluginName string, volumes []Volume) error {
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	args := append(globalArgs(e.config), "volume", "rm", "--force")
+	for _, volume := range volumes {
+		args = append(args, VolumeName(pluginName, volume))
+	}
+
+	if output, err := exec.CommandContext(ctx, e.config.NerdctlPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove volumes: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RemoveArtifacts removes the image built for the plugin and, unless
+// keepData is set, its volumes
+func (e *NerdctlExecutor) RemoveArtifacts(ctx context.Context, pluginName string, info Info, keepData bool) error {
+	if info.Image != "" {
+		args := append(globalArgs(e.config), "image", "rm", "--force", info.Image)
+		if output, err := exec.CommandContext(ctx, e.config.NerdctlPath, args...).CombinedOutput(); err != nil && !ImageNotFound(output) {
+			return fmt.Errorf("failed to remove image %s: %w: %s", info.Image, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if keepData {
+		return nil
+	}
+
+	return e.RemoveVolumes(ctx, pluginName, info.Volumes)
+}
+
+// BuildImage builds a plugin image with nerdctl build and returns its ID
+func (e *NerdctlExecutor) BuildImage(ctx context.Context, contextDir, dockerfile, tag string) (string, error) {
+	iidFile, err := os.CreateTemp("", "pluginkit-iid-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image ID file: %w", err)
+	}
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	args := append(globalArgs(e.config), "build", "--tag", tag, "--file", dockerfile, "--iidfile", iidFile.Name(), contextDir)
+	cmd := exec.CommandContext(ctx, e.config.NerdctlPath, args...)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to build image %s: %w: %s", tag, err, strings.TrimSpace(string(output)))
+	}
+
+	raw, err := os.ReadFile(iidFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read image ID: %w", err)
+	}
+
+	return ImageID(string(raw))
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *NerdctlExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"nerdctl_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to nerdctl executable",
+				"default":     "nerdctl",
+			},
+			"address": map[string]interface{}{
+				"type":        "string",
+				"description": "containerd address (e.g., unix:///run/user/1000/containerd/containerd.sock for rootless setups)",
+			},
+			"namespace": map[string]interface{}{
+				"type":        "string",
+				"description": "containerd namespace (e.g., k8s.io)",
+			},
+			"snapshotter": map[string]interface{}{
+				"type":        "string",
+				"description": "containerd snapshotter (e.g., overlayfs, stargz)",
+			},
+			"network_mode": map[string]interface{}{
+				"type":        "string",
+				"description": "Network mode for containers granted network access (e.g., host, bridge)",
+			},
+			"extra_labels": map[string]interface{}{
+				"type":        "object",
+				"description": "Additional labels to add to containers",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"devices": map[string]interface{}{
+				"type":        "object",
+				"description": "Devices plugins may request in their permissions",
+				"properties": map[string]interface{}{
+					"allow_gpus": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Permit plugins to request GPUs",
+						"default":     false,
+					},
+					"allowed_devices": map[string]interface{}{
+						"type":        "array",
+						"description": "Host device paths or glob patterns plugins may request",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+			"signature": map[string]interface{}{
+				"type":        "object",
+				"description": "Cosign signature policy images must satisfy before they run",
+				"properties": map[string]interface{}{
+					"cosign_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to cosign executable",
+						"default":     "cosign",
+					},
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Public key file or KMS URI images must be signed with",
+					},
+					"identity": map[string]interface{}{
+						"type":        "string",
+						"description": "Certificate identity of keyless signatures",
+					},
+					"issuer": map[string]interface{}{
+						"type":        "string",
+						"description": "OIDC issuer of keyless signatures",
+					},
+					"attestation_types": map[string]interface{}{
+						"type":        "array",
+						"description": "Attestation types that must also verify, e.g. slsaprovenance",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+			"extra_options": map[string]interface{}{
+				"type":        "array",
+				"description": "Additional nerdctl run options",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"security_opts": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"memory_limit": map[string]interface{}{
+						"type":        "string",
+						"description": "Container memory limit (e.g., 512m, 1g)",
+						"default":     "512m",
+					},
+					"cpu_shares": map[string]interface{}{
+						"type":        "integer",
+						"description": "CPU shares (relative weight)",
+						"minimum":     2,
+						"default":     1024,
+					},
+					"pids_limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of processes",
+						"minimum":     1,
+						"default":     100,
+					},
+					"allowed_capabilities": map[string]interface{}{
+						"type":        "array",
+						"description": "List of allowed Linux capabilities",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+						"default": []interface{}{},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -25,26 +237,21 @@ func NewNerdctlExecutor(pluginDir string) *NerdctlExecutor {
 func (e *NerdctlExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
 	startTime := time.Now()
 
-	// Build Nerdctl command arguments
-	args := []string{"run", "--rm"}
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
 
-	// Add environment variables
-	for k, v := range opts.Environment {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add working directory mount if specified
-	if opts.WorkingDir != "" {
-		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
-		args = append(args, "-w", "/app")
+	// Refuse to run images that do not satisfy the signature policy
+	if err := e.config.Signature.Verify(ctx, plan.Image); err != nil {
+		return nil, err
 	}
 
-	// Add image name and command arguments
-	args = append(args, pluginName)
-	args = append(args, opts.Args...)
-
 	// Create command
-	cmd := exec.CommandContext(ctx, "nerdctl", args...)
+	cmd := exec.CommandContext(ctx, plan.Command[0], plan.Command[1:]...)
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
@@ -52,7 +259,7 @@ func (e *NerdctlExecutor) Execute(ctx context.Context, pluginName string, opts E
 	cmd.Stderr = &stderr
 
 	// Execute command
-	err := cmd.Run()
+	err = cmd.Run()
 	endTime := time.Now()
 
 	// Handle exit code
@@ -65,20 +272,175 @@ func (e *NerdctlExecutor) Execute(ctx context.Context, pluginName string, opts E
 		}
 	}
 
-	// Build command line for logging
-	commandLine := fmt.Sprintf("nerdctl %s", strings.Join(args, " "))
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
 
-	return &ExecuteResult{
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
 		StartTime:   startTime,
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
-		CommandLine: commandLine,
+		CommandLine: plan.CommandLine,
+		Command:     plan.Command,
+		Image:       plan.Image,
+		Mounts:      plan.Mounts,
+		Network:     plan.Network,
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: plan.Environment,
 		PID:         0, // Nerdctl containers don't expose host PIDs
 		Success:     exitCode == 0,
-	}, nil
+	})), nil
+}
+
+// Explain returns the nerdctl command Execute would run, without running it
+func (e *NerdctlExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// plan builds the nerdctl command that runs the plugin
+func (e *NerdctlExecutor) plan(pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	// Run the image built at install time, if any, pinned by its ID
+	image := pluginName
+	if opts.Image != "" {
+		image = opts.Image
+	}
+
+	config := e.config
+
+	plan := &ExecutionPlan{
+		Runtime:     "nerdctl",
+		Image:       image,
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	// Global options select the containerd instance, namespace and snapshotter
+	args := globalArgs(config)
+
+	// Build Nerdctl command arguments with security defaults
+	plan.Security = []string{
+		"--security-opt=no-new-privileges", // Prevent privilege escalation
+		"--cap-drop=ALL",                   // Drop all capabilities by default
+		"--read-only",                      // Make root filesystem read-only
+		"--tmpfs=/tmp:rw,noexec,nosuid",    // Secure temp directory
+	}
+
+	if config.Security.PidsLimit > 0 {
+		plan.Security = append(plan.Security, fmt.Sprintf("--pids-limit=%d", config.Security.PidsLimit))
+	}
+
+	if config.Security.MemoryLimit != "" {
+		plan.Security = append(plan.Security, "--memory="+config.Security.MemoryLimit)
+	}
+
+	if config.Security.CPUShares > 0 {
+		plan.Security = append(plan.Security, fmt.Sprintf("--cpu-shares=%d", config.Security.CPUShares))
+	}
+
+	for _, capability := range config.Security.AllowedCapabilities {
+		plan.Security = append(plan.Security, "--cap-add="+capability)
+	}
+
+	// Pass through the devices and GPUs the plugin was granted
+	devices, gpus, err := opts.Permissions.GrantedDevices(config.Devices)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		plan.Security = append(plan.Security, "--device="+device)
+	}
+
+	if gpus != "" {
+		plan.Security = append(plan.Security, "--gpus="+gpus)
+	}
+
+	args = append(args, "run", "--rm")
+	args = append(args, plan.Security...)
+
+	// Add network mode, denying network access unless the plugin was granted it
+	if network := opts.Permissions.NetworkMode(config.NetworkMode); network != "" {
+		args = append(args, "--network", network)
+		plan.Network = network
+	}
+
+	// Add labels
+	for k, v := range config.ExtraLabels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Add extra options
+	args = append(args, config.ExtraOptions...)
+
+	// Add environment variables
+	for k, v := range environment {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Mount the host paths the plugin was granted
+	if opts.Permissions != nil {
+		for _, path := range opts.Permissions.Filesystem {
+			args = append(args, "-v", fmt.Sprintf("%s:%s", path, path))
+			plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
+		}
+	}
+
+	// Mount the plugin's named volumes, which the runtime creates on first use
+	for _, volume := range opts.Volumes {
+		if err := volume.Validate(); err != nil {
+			return nil, err
+		}
+
+		name := VolumeName(pluginName, volume)
+		args = append(args, "-v", fmt.Sprintf("%s:%s", name, volume.Path))
+		plan.Mounts = append(plan.Mounts, Mount{Source: name, Target: volume.Path})
+	}
+
+	// Add working directory mount if specified
+	if opts.WorkingDir != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
+		args = append(args, "-w", "/app")
+		plan.Mounts = append(plan.Mounts, Mount{Source: opts.WorkingDir, Target: "/app"})
+	}
+
+	// Add image name and command arguments
+	args = append(args, image)
+	args = append(args, opts.Args...)
+
+	plan.Command = append([]string{config.NerdctlPath}, args...)
+	plan.CommandLine = strings.Join(plan.Command, " ")
+
+	return plan, nil
+}
+
+// globalArgs returns the options selecting the containerd instance,
+// namespace and snapshotter, which nerdctl expects before the subcommand
+func globalArgs(config NerdctlConfig) []string {
+	var args []string
+
+	if config.Address != "" {
+		args = append(args, "--address", config.Address)
+	}
+
+	if config.Namespace != "" {
+		args = append(args, "--namespace", config.Namespace)
+	}
+
+	if config.Snapshotter != "" {
+		args = append(args, "--snapshotter", config.Snapshotter)
+	}
+
+	return args
 }
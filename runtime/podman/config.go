@@ -0,0 +1,95 @@
+package extension
+
+import "maps"
+
+// PodmanConfig is the typed configuration of a PodmanExecutor
+type PodmanConfig struct {
+	PluginDir    string            `mapstructure:"plugin_dir"`
+	PodmanPath   string            `mapstructure:"podman_path"`
+	NetworkMode  string            `mapstructure:"network_mode"`
+	ExtraLabels  map[string]string `mapstructure:"extra_labels"`
+	ExtraOptions []string          `mapstructure:"extra_options"`
+	Devices      DevicePolicy      `mapstructure:"devices"`
+	Signature    SignaturePolicy   `mapstructure:"signature"`
+}
+
+// PodmanOption customizes the configuration of a PodmanExecutor
+type PodmanOption func(*PodmanConfig)
+
+// WithPodmanPath sets the podman executable
+func WithPodmanPath(path string) PodmanOption {
+	return func(c *PodmanConfig) { c.PodmanPath = path }
+}
+
+// WithNetworkMode sets the network mode of plugin containers
+func WithNetworkMode(mode string) PodmanOption {
+	return func(c *PodmanConfig) { c.NetworkMode = mode }
+}
+
+// WithExtraLabels adds labels to plugin containers
+func WithExtraLabels(labels map[string]string) PodmanOption {
+	return func(c *PodmanConfig) { c.ExtraLabels = labels }
+}
+
+// WithExtraOptions adds podman run options
+func WithExtraOptions(options ...string) PodmanOption {
+	return func(c *PodmanConfig) { c.ExtraOptions = options }
+}
+
+// WithDevicePolicy sets the devices and GPUs plugins may request
+func WithDevicePolicy(policy DevicePolicy) PodmanOption {
+	return func(c *PodmanConfig) { c.Devices = policy }
+}
+
+// WithSignaturePolicy requires images to be signed before they run
+func WithSignaturePolicy(policy SignaturePolicy) PodmanOption {
+	return func(c *PodmanConfig) { c.Signature = policy }
+}
+
+func defaultPodmanConfig(pluginDir string) PodmanConfig {
+	return PodmanConfig{
+		PluginDir:   pluginDir,
+		PodmanPath:  "podman",
+		NetworkMode: "host",
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *PodmanExecutor) Config() PodmanConfig {
+	return PodmanConfig{
+		PluginDir:    e.pluginDir,
+		PodmanPath:   e.podmanPath,
+		NetworkMode:  e.networkMode,
+		ExtraLabels:  maps.Clone(e.extraLabels),
+		ExtraOptions: append([]string(nil), e.extraOptions...),
+		Devices:      e.devices.Clone(),
+		Signature:    e.signature.Clone(),
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *PodmanExecutor) ApplyConfig(config PodmanConfig) error {
+	if config.PluginDir == "" {
+		return &ConfigError{Fields: []FieldError{{Field: "/plugin_dir", Message: "is required"}}}
+	}
+
+	e.pluginDir = config.PluginDir
+	e.podmanPath = config.PodmanPath
+	e.networkMode = config.NetworkMode
+	e.extraLabels = config.ExtraLabels
+	e.extraOptions = config.ExtraOptions
+	e.devices = config.Devices
+	e.signature = config.Signature
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *PodmanExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
 // PodmanExecutor implements the Executor interface for Podman-based plugins
@@ -16,6 +19,9 @@ type PodmanExecutor struct {
 	extraLabels  map[string]string
 	podmanPath   string
 	extraOptions []string
+	devices      DevicePolicy
+	signature    SignaturePolicy
+	logger       logr.Logger
 }
 
 // Name returns the executor's name
@@ -23,6 +29,75 @@ func (e *PodmanExecutor) Name() string {
 	return "podman"
 }
 
+// Ping verifies that podman can reach its service socket
+func (e *PodmanExecutor) Ping(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, e.podmanPath, "info", "--format", "{{.Host.OS}}")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("podman is not reachable: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RemoveVolumes removes the named volumes of a plugin
+func (e *PodmanExecutor) RemoveVolumes(ctx context.Context, pluginName string, volumes []Volume) error {
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	args := []string{"volume", "rm", "--force"}
+	for _, volume := range volumes {
+		args = append(args, VolumeName(pluginName, volume))
+	}
+
+	if output, err := exec.CommandContext(ctx, e.podmanPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove volumes: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RemoveArtifacts removes the image built for the plugin and, unless
+// keepData is set, its volumes
+func (e *PodmanExecutor) RemoveArtifacts(ctx context.Context, pluginName string, info Info, keepData bool) error {
+	if info.Image != "" {
+		args := []string{"image", "rm", "--force", info.Image}
+		if output, err := exec.CommandContext(ctx, e.podmanPath, args...).CombinedOutput(); err != nil && !ImageNotFound(output) {
+			return fmt.Errorf("failed to remove image %s: %w: %s", info.Image, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if keepData {
+		return nil
+	}
+
+	return e.RemoveVolumes(ctx, pluginName, info.Volumes)
+}
+
+// BuildImage builds a plugin image with podman build and returns its ID
+func (e *PodmanExecutor) BuildImage(ctx context.Context, contextDir, dockerfile, tag string) (string, error) {
+	iidFile, err := os.CreateTemp("", "pluginkit-iid-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image ID file: %w", err)
+	}
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	args := []string{"build", "--tag", tag, "--file", dockerfile, "--iidfile", iidFile.Name(), contextDir}
+	cmd := exec.CommandContext(ctx, e.podmanPath, args...)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to build image %s: %w: %s", tag, err, strings.TrimSpace(string(output)))
+	}
+
+	raw, err := os.ReadFile(iidFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read image ID: %w", err)
+	}
+
+	return ImageID(string(raw))
+}
+
 // ConfigSchema returns the JSON schema for the executor's configuration
 func (e *PodmanExecutor) ConfigSchema() map[string]interface{} {
 	return map[string]interface{}{
@@ -49,6 +124,54 @@ func (e *PodmanExecutor) ConfigSchema() map[string]interface{} {
 				"description": "Path to podman executable",
 				"default":     "podman",
 			},
+			"devices": map[string]interface{}{
+				"type":        "object",
+				"description": "Devices plugins may request in their permissions",
+				"properties": map[string]interface{}{
+					"allow_gpus": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Permit plugins to request GPUs",
+						"default":     false,
+					},
+					"allowed_devices": map[string]interface{}{
+						"type":        "array",
+						"description": "Host device paths or glob patterns plugins may request",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+			"signature": map[string]interface{}{
+				"type":        "object",
+				"description": "Cosign signature policy images must satisfy before they run",
+				"properties": map[string]interface{}{
+					"cosign_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Path to cosign executable",
+						"default":     "cosign",
+					},
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Public key file or KMS URI images must be signed with",
+					},
+					"identity": map[string]interface{}{
+						"type":        "string",
+						"description": "Certificate identity of keyless signatures",
+					},
+					"issuer": map[string]interface{}{
+						"type":        "string",
+						"description": "OIDC issuer of keyless signatures",
+					},
+					"attestation_types": map[string]interface{}{
+						"type":        "array",
+						"description": "Attestation types that must also verify, e.g. slsaprovenance",
+						"items": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
 			"extra_options": map[string]interface{}{
 				"type":        "array",
 				"description": "Additional podman run options",
@@ -85,63 +208,53 @@ func (e *PodmanExecutor) ConfigSchema() map[string]interface{} {
 				},
 			},
 		},
-		"required": []string{"plugin_dir"},
 	}
 }
 
 // NewPodmanExecutor creates a new PodmanExecutor instance
-func NewPodmanExecutor(pluginDir string) *PodmanExecutor {
+func NewPodmanExecutor(pluginDir string, opts ...PodmanOption) *PodmanExecutor {
+	config := defaultPodmanConfig(pluginDir)
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &PodmanExecutor{
-		pluginDir: pluginDir,
+		pluginDir:    config.PluginDir,
+		networkMode:  config.NetworkMode,
+		extraLabels:  config.ExtraLabels,
+		podmanPath:   config.PodmanPath,
+		extraOptions: config.ExtraOptions,
+		devices:      config.Devices,
+		signature:    config.Signature,
+		logger:       logr.Discard(),
 	}
 }
 
+// WithLogger sets the logger used for execution diagnostics
+func (e *PodmanExecutor) WithLogger(logger logr.Logger) *PodmanExecutor {
+	e.logger = logger.WithName("podman-executor")
+	return e
+}
+
 // Execute runs a Podman plugin with the given options
 func (e *PodmanExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
 	startTime := time.Now()
 
-	// Build Podman command arguments with security defaults
-	args := []string{"run", "--rm",
-		"--security-opt=no-new-privileges", // Prevent privilege escalation
-		"--cap-drop=ALL",                   // Drop all capabilities by default
-		"--read-only",                      // Make root filesystem read-only
-		"--tmpfs=/tmp:rw,noexec,nosuid",    // Secure temp directory
-		"--pids-limit=100",                 // Limit number of processes
-		"--memory=512m",                    // Limit memory usage
-		"--cpu-shares=1024",                // Limit CPU usage
-	}
-
-	// Add network mode (consider restricting to specific networks)
-	if e.networkMode == "" {
-		e.networkMode = "none" // Default to no network access
-	}
-	args = append(args, fmt.Sprintf("--network=%s", e.networkMode))
-
-	// Add labels
-	for k, v := range e.extraLabels {
-		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
-	}
-
-	// Add extra options
-	args = append(args, e.extraOptions...)
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
 
-	// Add environment variables
-	for k, v := range opts.Environment {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add working directory mount if specified
-	if opts.WorkingDir != "" {
-		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
-		args = append(args, "-w", "/app")
+	// Refuse to run images that do not satisfy the signature policy
+	if err := e.signature.Verify(ctx, plan.Image); err != nil {
+		return nil, err
 	}
 
-	// Add image name and command arguments
-	args = append(args, pluginName)
-	args = append(args, opts.Args...)
-
 	// Create command (use configured podman path)
-	cmd := exec.CommandContext(ctx, e.podmanPath, args...)
+	cmd := exec.CommandContext(ctx, plan.Command[0], plan.Command[1:]...)
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
@@ -149,7 +262,7 @@ func (e *PodmanExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 	cmd.Stderr = &stderr
 
 	// Execute command
-	err := cmd.Run()
+	err = cmd.Run()
 	endTime := time.Now()
 
 	// Handle exit code
@@ -162,69 +275,170 @@ func (e *PodmanExecutor) Execute(ctx context.Context, pluginName string, opts Ex
 		}
 	}
 
-	// Build command line for logging
-	commandLine := fmt.Sprintf("podman %s", strings.Join(args, " "))
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
 
-	return &ExecuteResult{
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
 		StartTime:   startTime,
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
-		CommandLine: commandLine,
+		CommandLine: plan.CommandLine,
+		Command:     plan.Command,
+		Image:       plan.Image,
+		Mounts:      plan.Mounts,
+		Network:     plan.Network,
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: plan.Environment,
 		PID:         0, // Podman containers don't expose host PIDs
 		Success:     exitCode == 0,
-	}, nil
+	})), nil
 }
 
-// Configure applies the provided configuration map
-func (e *PodmanExecutor) Configure(config map[string]interface{}) error {
-	// Extract plugin directory
-	if pluginDir, ok := config["plugin_dir"].(string); ok {
-		e.pluginDir = pluginDir
+// Explain returns the podman command Execute would run, without running it
+func (e *PodmanExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	plan, err := e.plan(pluginName, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract network mode
-	if networkMode, ok := config["network_mode"].(string); ok {
-		e.networkMode = networkMode
-	} else {
-		e.networkMode = "host" // default
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// securityDefaults are the isolation and resource limit flags every plugin
+// container runs with
+var securityDefaults = []string{
+	"--security-opt=no-new-privileges", // Prevent privilege escalation
+	"--cap-drop=ALL",                   // Drop all capabilities by default
+	"--read-only",                      // Make root filesystem read-only
+	"--tmpfs=/tmp:rw,noexec,nosuid",    // Secure temp directory
+	"--pids-limit=100",                 // Limit number of processes
+	"--memory=512m",                    // Limit memory usage
+	"--cpu-shares=1024",                // Limit CPU usage
+}
+
+// plan builds the podman command that runs the plugin
+func (e *PodmanExecutor) plan(pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
 	}
 
-	// Extract extra labels
-	if labels, ok := config["extra_labels"].(map[string]interface{}); ok {
-		e.extraLabels = make(map[string]string)
-		for k, v := range labels {
-			if strVal, ok := v.(string); ok {
-				e.extraLabels[k] = strVal
-			}
-		}
+	// Run the image built at install time, if any, pinned by its ID
+	image := pluginName
+	if opts.Image != "" {
+		image = opts.Image
+	}
+
+	plan := &ExecutionPlan{
+		Runtime:     "podman",
+		Image:       image,
+		Security:    append([]string(nil), securityDefaults...),
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	// Pass through the devices and GPUs the plugin was granted
+	devices, gpus, err := opts.Permissions.GrantedDevices(e.devices)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract podman path
-	if podmanPath, ok := config["podman_path"].(string); ok {
-		e.podmanPath = podmanPath
-	} else {
-		e.podmanPath = "podman" // default
+	for _, device := range devices {
+		plan.Security = append(plan.Security, "--device="+device)
 	}
 
-	// Extract extra options
-	if options, ok := config["extra_options"].([]interface{}); ok {
-		e.extraOptions = make([]string, 0, len(options))
-		for _, opt := range options {
-			if strOpt, ok := opt.(string); ok {
-				e.extraOptions = append(e.extraOptions, strOpt)
-			}
+	// Podman exposes GPUs as CDI devices
+	gpuDevices, err := cdiGPUs(gpus)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range gpuDevices {
+		plan.Security = append(plan.Security, "--device="+device)
+	}
+
+	// Build Podman command arguments with security defaults
+	args := append([]string{"run", "--rm"}, plan.Security...)
+
+	// Add network mode (consider restricting to specific networks)
+	networkMode := e.networkMode
+	if networkMode == "" {
+		networkMode = "none" // Default to no network access
+	}
+	plan.Network = opts.Permissions.NetworkMode(networkMode)
+	args = append(args, fmt.Sprintf("--network=%s", plan.Network))
+
+	// Add labels
+	for k, v := range e.extraLabels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Add extra options
+	args = append(args, e.extraOptions...)
+
+	// Add environment variables
+	for k, v := range environment {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Mount the host paths the plugin was granted
+	if opts.Permissions != nil {
+		for _, path := range opts.Permissions.Filesystem {
+			args = append(args, "-v", fmt.Sprintf("%s:%s", path, path))
+			plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
 		}
 	}
 
-	// Validate required fields
-	if e.pluginDir == "" {
-		return fmt.Errorf("plugin_dir is required")
+	// Mount the plugin's named volumes, which the runtime creates on first use
+	for _, volume := range opts.Volumes {
+		if err := volume.Validate(); err != nil {
+			return nil, err
+		}
+
+		name := VolumeName(pluginName, volume)
+		args = append(args, "-v", fmt.Sprintf("%s:%s", name, volume.Path))
+		plan.Mounts = append(plan.Mounts, Mount{Source: name, Target: volume.Path})
 	}
 
-	return nil
+	// Add working directory mount if specified
+	if opts.WorkingDir != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:/app", opts.WorkingDir))
+		args = append(args, "-w", "/app")
+		plan.Mounts = append(plan.Mounts, Mount{Source: opts.WorkingDir, Target: "/app"})
+	}
+
+	// Add image name and command arguments
+	args = append(args, image)
+	args = append(args, opts.Args...)
+
+	plan.Command = append([]string{e.podmanPath}, args...)
+	plan.CommandLine = strings.Join(plan.Command, " ")
+
+	return plan, nil
+}
+
+// cdiGPUs converts a docker style --gpus value to podman CDI device names
+func cdiGPUs(gpus string) ([]string, error) {
+	if gpus == "" {
+		return nil, nil
+	}
+
+	if gpus == "all" {
+		return []string{"nvidia.com/gpu=all"}, nil
+	}
+
+	ids, ok := strings.CutPrefix(gpus, "device=")
+	if !ok {
+		return nil, fmt.Errorf("podman cannot pass a GPU count, request all or device=<ids> instead of %q", gpus)
+	}
+
+	var devices []string
+	for _, id := range strings.Split(ids, ",") {
+		devices = append(devices, "nvidia.com/gpu="+strings.TrimSpace(id))
+	}
+
+	return devices, nil
 }
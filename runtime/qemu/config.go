@@ -0,0 +1,88 @@
+package extension
+
+// QEMUConfig holds configuration for the QEMU executor
+type QEMUConfig struct {
+	ImageDir   string `mapstructure:"image_dir"`
+	SSHKeyPath string `mapstructure:"ssh_key_path"`
+	SSHPort    int    `mapstructure:"ssh_port"`
+	Memory     string `mapstructure:"memory"`
+	CPUs       int    `mapstructure:"cpus"`
+}
+
+// QEMUOption customizes the configuration of a QEMUExecutor
+type QEMUOption func(*QEMUConfig)
+
+// WithSSHPort sets the host port forwarded to the VM's SSH server
+func WithSSHPort(port int) QEMUOption {
+	return func(c *QEMUConfig) { c.SSHPort = port }
+}
+
+// WithMemory sets the VM memory size, e.g. 2G
+func WithMemory(memory string) QEMUOption {
+	return func(c *QEMUConfig) { c.Memory = memory }
+}
+
+// WithCPUs sets the number of virtual CPUs
+func WithCPUs(cpus int) QEMUOption {
+	return func(c *QEMUConfig) { c.CPUs = cpus }
+}
+
+// withDefaults fills in the unset optional fields
+func (c QEMUConfig) withDefaults() QEMUConfig {
+	if c.SSHPort == 0 {
+		c.SSHPort = 2222
+	}
+	if c.Memory == "" {
+		c.Memory = "2G"
+	}
+	if c.CPUs == 0 {
+		c.CPUs = 2
+	}
+
+	return c
+}
+
+// Config returns the executor's current configuration
+func (e *QEMUExecutor) Config() QEMUConfig {
+	return QEMUConfig{
+		ImageDir:   e.imageDir,
+		SSHKeyPath: e.sshKeyPath,
+		SSHPort:    e.sshPort,
+		Memory:     e.memory,
+		CPUs:       e.cpus,
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *QEMUExecutor) ApplyConfig(config QEMUConfig) error {
+	config = config.withDefaults()
+
+	var missing []FieldError
+	if config.ImageDir == "" {
+		missing = append(missing, FieldError{Field: "/image_dir", Message: "is required"})
+	}
+	if config.SSHKeyPath == "" {
+		missing = append(missing, FieldError{Field: "/ssh_key_path", Message: "is required"})
+	}
+	if missing != nil {
+		return &ConfigError{Fields: missing}
+	}
+
+	e.imageDir = config.ImageDir
+	e.sshKeyPath = config.SSHKeyPath
+	e.sshPort = config.SSHPort
+	e.memory = config.Memory
+	e.cpus = config.CPUs
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *QEMUExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
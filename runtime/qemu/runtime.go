@@ -9,71 +9,62 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
 // QEMUExecutor implements the Executor interface for QEMU-based plugins
 type QEMUExecutor struct {
-	imageDir   string // Directory containing VM images
-	sshKeyPath string // Path to SSH private key
-	sshPort    int    // SSH port for communication
-	memory     string // VM memory allocation (e.g., "2G")
-	cpus       int    // Number of CPU cores
-}
-
-// QEMUConfig holds configuration for the QEMU executor
-type QEMUConfig struct {
-	ImageDir   string
-	SSHKeyPath string
-	SSHPort    int
-	Memory     string
-	CPUs       int
+	imageDir   string      // Directory containing VM images
+	sshKeyPath string      // Path to SSH private key
+	sshPort    int         // SSH port for communication
+	memory     string      // VM memory allocation (e.g., "2G")
+	cpus       int         // Number of CPU cores
+	logger     logr.Logger // Logger for execution diagnostics
 }
 
 // NewQEMUExecutor creates a new QEMUExecutor instance
-func NewQEMUExecutor(config QEMUConfig) *QEMUExecutor {
-	if config.SSHPort == 0 {
-		config.SSHPort = 2222
-	}
-	if config.Memory == "" {
-		config.Memory = "2G"
-	}
-	if config.CPUs == 0 {
-		config.CPUs = 2
+func NewQEMUExecutor(config QEMUConfig, opts ...QEMUOption) *QEMUExecutor {
+	for _, opt := range opts {
+		opt(&config)
 	}
+
+	config = config.withDefaults()
+
 	return &QEMUExecutor{
 		imageDir:   config.ImageDir,
 		sshKeyPath: config.SSHKeyPath,
 		sshPort:    config.SSHPort,
 		memory:     config.Memory,
 		cpus:       config.CPUs,
+		logger:     logr.Discard(),
 	}
 }
 
+// WithLogger sets the logger used for execution diagnostics
+func (e *QEMUExecutor) WithLogger(logger logr.Logger) *QEMUExecutor {
+	e.logger = logger.WithName("qemu-executor")
+	return e
+}
+
 // Execute runs a plugin in a QEMU VM
 func (e *QEMUExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
 	startTime := time.Now()
 
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
 	// Construct paths
 	imagePath := filepath.Join(e.imageDir, pluginName, "disk.qcow2")
 	if _, err := os.Stat(imagePath); err != nil {
 		return nil, fmt.Errorf("VM image not found: %w", err)
 	}
 
-	// Prepare QEMU arguments
-	qemuArgs := []string{
-		"-machine", "type=q35,accel=kvm",
-		"-cpu", "host",
-		"-smp", fmt.Sprintf("%d", e.cpus),
-		"-m", e.memory,
-		"-drive", fmt.Sprintf("file=%s,if=virtio,cache=writeback,discard=unmap,format=qcow2", imagePath),
-		"-net", "nic,model=virtio",
-		"-net", fmt.Sprintf("user,hostfwd=tcp::%d-:22", e.sshPort),
-		"-display", "none",
-		"-daemonize",
-	}
-
 	// Start QEMU VM
-	startCmd := exec.CommandContext(ctx, "qemu-system-x86_64", qemuArgs...)
+	startCmd := exec.CommandContext(ctx, "qemu-system-x86_64", e.qemuArgs(imagePath)...)
 	if err := startCmd.Run(); err != nil {
 		return nil, fmt.Errorf("failed to start VM: %w", err)
 	}
@@ -84,8 +75,8 @@ func (e *QEMUExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 	}
 
 	// Prepare environment variables
-	envVars := make([]string, 0, len(opts.Environment))
-	for k, v := range opts.Environment {
+	envVars := make([]string, 0, len(environment))
+	for k, v := range environment {
 		envVars = append(envVars, fmt.Sprintf("export %s=%s;", k, v))
 	}
 
@@ -128,7 +119,9 @@ func (e *QEMUExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 		}
 	}
 
-	return &ExecuteResult{
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
@@ -136,11 +129,48 @@ func (e *QEMUExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
 		CommandLine: fmt.Sprintf("qemu://%s/%s", imagePath, strings.Join(opts.Args, " ")),
+		Command:     cmd.Args,
+		Image:       imagePath,
+		Network:     "user",
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: environment,
 		PID:         0, // VM PID not exposed
 		Success:     exitCode == 0,
-	}, nil
+	})), nil
+}
+
+// Explain returns the command that starts the plugin's VM, without
+// starting it
+func (e *QEMUExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	imagePath := filepath.Join(e.imageDir, pluginName, "disk.qcow2")
+	if _, err := os.Stat(imagePath); err != nil {
+		return nil, fmt.Errorf("VM image not found: %w", err)
+	}
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     "qemu",
+		Command:     append([]string{"qemu-system-x86_64"}, e.qemuArgs(imagePath)...),
+		CommandLine: fmt.Sprintf("qemu://%s/%s", imagePath, strings.Join(opts.Args, " ")),
+		Image:       imagePath,
+		Network:     "user",
+		Environment: opts.Permissions.FilterEnv(opts.Environment),
+		WorkingDir:  opts.WorkingDir,
+	}), nil
+}
+
+// qemuArgs builds the arguments that start the VM from imagePath
+func (e *QEMUExecutor) qemuArgs(imagePath string) []string {
+	return []string{
+		"-machine", "type=q35,accel=kvm",
+		"-cpu", "host",
+		"-smp", fmt.Sprintf("%d", e.cpus),
+		"-m", e.memory,
+		"-drive", fmt.Sprintf("file=%s,if=virtio,cache=writeback,discard=unmap,format=qcow2", imagePath),
+		"-net", "nic,model=virtio",
+		"-net", fmt.Sprintf("user,hostfwd=tcp::%d-:22", e.sshPort),
+		"-display", "none",
+		"-daemonize",
+	}
 }
 
 // waitForSSH attempts to establish SSH connection until successful or timeout
@@ -184,46 +214,64 @@ func (e *QEMUExecutor) shutdownVM(ctx context.Context) error {
 	return cmd.Run()
 }
 
-// Configure applies the provided configuration map
-func (e *QEMUExecutor) Configure(config map[string]interface{}) error {
-	// Extract image directory
-	if imageDir, ok := config["image_dir"].(string); ok {
-		e.imageDir = imageDir
+// Ping verifies that qemu is installed and the image directory exists
+func (e *QEMUExecutor) Ping(_ context.Context) error {
+	if _, err := exec.LookPath("qemu-system-x86_64"); err != nil {
+		return fmt.Errorf("qemu is not installed: %w", err)
 	}
 
-	// Extract SSH key path
-	if sshKeyPath, ok := config["ssh_key_path"].(string); ok {
-		e.sshKeyPath = sshKeyPath
+	if _, err := os.Stat(e.imageDir); err != nil {
+		return fmt.Errorf("image directory is not accessible: %w", err)
 	}
 
-	// Extract SSH port
-	if sshPort, ok := config["ssh_port"].(float64); ok {
-		e.sshPort = int(sshPort)
-	} else {
-		e.sshPort = 2222 // default
-	}
-
-	// Extract memory
-	if memory, ok := config["memory"].(string); ok {
-		e.memory = memory
-	} else {
-		e.memory = "2G" // default
-	}
+	return nil
+}
 
-	// Extract CPUs
-	if cpus, ok := config["cpus"].(float64); ok {
-		e.cpus = int(cpus)
-	} else {
-		e.cpus = 2 // default
+// RemoveArtifacts removes the plugin's VM disk unless keepData is set
+func (e *QEMUExecutor) RemoveArtifacts(_ context.Context, pluginName string, _ Info, keepData bool) error {
+	if keepData {
+		return nil
 	}
 
-	// Validate required fields
-	if e.imageDir == "" {
-		return fmt.Errorf("image_dir is required")
-	}
-	if e.sshKeyPath == "" {
-		return fmt.Errorf("ssh_key_path is required")
+	if err := os.RemoveAll(filepath.Join(e.imageDir, pluginName)); err != nil {
+		return fmt.Errorf("failed to remove VM disk: %w", err)
 	}
 
 	return nil
 }
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *QEMUExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"image_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing VM images",
+			},
+			"ssh_key_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Private key used to reach the VM",
+			},
+			"ssh_port": map[string]interface{}{
+				"type":        "integer",
+				"description": "Host port forwarded to the VM's SSH server",
+				"minimum":     1,
+				"maximum":     65535,
+				"default":     2222,
+			},
+			"memory": map[string]interface{}{
+				"type":        "string",
+				"description": "VM memory size (e.g., 2G)",
+				"default":     "2G",
+			},
+			"cpus": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of virtual CPUs",
+				"minimum":     1,
+				"default":     2,
+			},
+		},
+		"required": []string{"image_dir", "ssh_key_path"},
+	}
+}
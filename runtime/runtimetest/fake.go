@@ -0,0 +1,287 @@
+// Package runtimetest provides a scriptable Executor for testing
+// applications that embed the plugin manager without starting processes,
+// containers or virtual machines.
+//
+//	executor := runtimetest.New()
+//	executor.Respond("hello", extension.ExecuteResult{Stdout: "hello\n"})
+//	executor.FailNext("flaky", errors.New("container exited unexpectedly"))
+//
+//	manager.WithExecutor("native", executor)
+package runtimetest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+var (
+	_ extension.Executor  = &Fake{}
+	_ extension.Explainer = &Fake{}
+	_ extension.Pinger    = &Fake{}
+	_ extension.Closer    = &Fake{}
+)
+
+// HandlerFunc runs a plugin in place of the fake's canned behavior
+type HandlerFunc func(ctx context.Context, pluginName string, opts extension.ExecuteOptions) (*extension.ExecuteResult, error)
+
+// Call records an execution
+type Call struct {
+	Plugin string
+	Opts   extension.ExecuteOptions
+}
+
+// Fake is an Executor returning canned results. Plugins without a scripted
+// behavior succeed with empty output. Its zero value is not usable; call
+// New. It is safe for concurrent use.
+type Fake struct {
+	mu        sync.Mutex
+	handlers  map[string]HandlerFunc // Keyed by plugin name, "" for any plugin
+	latency   time.Duration
+	failures  map[string][]error // Errors returned by the next executions, consumed in order
+	pingErr   error
+	config    map[string]interface{}
+	calls     []Call
+	running   int
+	maxActive int
+	closed    bool
+}
+
+// New creates a fake executor
+func New() *Fake {
+	return &Fake{
+		handlers: make(map[string]HandlerFunc),
+		failures: make(map[string][]error),
+	}
+}
+
+// Handle runs fn for executions of the plugin, or of every plugin without
+// its own handler when pluginName is empty
+func (f *Fake) Handle(pluginName string, fn HandlerFunc) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers[pluginName] = fn
+
+	return f
+}
+
+// Respond returns a copy of result for executions of the plugin. Success
+// follows ExitCode and the timing fields are filled in.
+func (f *Fake) Respond(pluginName string, result extension.ExecuteResult) *Fake {
+	return f.Handle(pluginName, func(context.Context, string, extension.ExecuteOptions) (*extension.ExecuteResult, error) {
+		r := result
+		r.Environment = maps.Clone(result.Environment)
+
+		return &r, nil
+	})
+}
+
+// WithLatency delays every execution by d. Delayed executions return early
+// when their context is done.
+func (f *Fake) WithLatency(d time.Duration) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.latency = d
+
+	return f
+}
+
+// FailNext makes the next execution of the plugin return err, or the next
+// execution of any plugin when pluginName is empty. Repeated calls queue
+// errors for the following executions.
+func (f *Fake) FailNext(pluginName string, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[pluginName] = append(f.failures[pluginName], err)
+
+	return f
+}
+
+// SetPingError makes Ping return err, as when the backend is unreachable
+func (f *Fake) SetPingError(err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pingErr = err
+
+	return f
+}
+
+// Calls returns the executions made, in the order they started
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// MaxConcurrent returns the highest number of executions that were running
+// at the same time
+func (f *Fake) MaxConcurrent() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.maxActive
+}
+
+// Config returns the configuration passed to Configure
+func (f *Fake) Config() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return maps.Clone(f.config)
+}
+
+// Configure records the configuration
+func (f *Fake) Configure(config map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config = maps.Clone(config)
+
+	return nil
+}
+
+// Execute records the execution and runs the plugin's scripted behavior
+func (f *Fake) Execute(ctx context.Context, pluginName string, opts extension.ExecuteOptions) (*extension.ExecuteResult, error) {
+	start := time.Now()
+
+	f.mu.Lock()
+
+	if f.closed {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake executor is closed")
+	}
+
+	f.calls = append(f.calls, Call{Plugin: pluginName, Opts: opts})
+
+	f.running++
+	if f.running > f.maxActive {
+		f.maxActive = f.running
+	}
+
+	err := f.nextFailure(pluginName)
+
+	handler, ok := f.handlers[pluginName]
+	if !ok {
+		handler = f.handlers[""]
+	}
+
+	latency := f.latency
+
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &extension.ExecuteResult{}
+
+	if handler != nil {
+		if result, err = handler(ctx, pluginName, opts); err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = &extension.ExecuteResult{}
+		}
+	}
+
+	command := append([]string{pluginName}, opts.Args...)
+
+	if result.Command == nil {
+		result.Command = command
+		result.CommandLine = strings.Join(command, " ")
+	}
+
+	if result.WorkingDir == "" {
+		result.WorkingDir = opts.WorkingDir
+	}
+
+	if result.Environment == nil {
+		result.Environment = maps.Clone(opts.Environment)
+	}
+
+	if result.StartTime.IsZero() {
+		result.StartTime = start
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(start)
+	}
+
+	result.Success = result.ExitCode == 0
+
+	return result, nil
+}
+
+// Explain describes the execution without recording it
+func (f *Fake) Explain(ctx context.Context, pluginName string, opts extension.ExecuteOptions) (*extension.ExecutionPlan, error) {
+	command := append([]string{pluginName}, opts.Args...)
+
+	return &extension.ExecutionPlan{
+		Runtime:     "fake",
+		Command:     command,
+		CommandLine: strings.Join(command, " "),
+		Environment: maps.Clone(opts.Environment),
+		WorkingDir:  opts.WorkingDir,
+	}, nil
+}
+
+// Ping returns the error set with SetPingError
+func (f *Fake) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.pingErr
+}
+
+// Close makes later executions fail
+func (f *Fake) Close(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+
+	return nil
+}
+
+// nextFailure pops the error queued for the plugin, then for any plugin.
+// f.mu must be held.
+func (f *Fake) nextFailure(pluginName string) error {
+	for _, key := range []string{pluginName, ""} {
+		if queued := f.failures[key]; len(queued) > 0 {
+			f.failures[key] = queued[1:]
+			return queued[0]
+		}
+	}
+
+	return nil
+}
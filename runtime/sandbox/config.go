@@ -0,0 +1,65 @@
+package sandbox
+
+// Config is the typed configuration of a SandboxExecutor
+type Config struct {
+	PluginDir       string   `mapstructure:"plugin_dir"`
+	SandboxExecPath string   `mapstructure:"sandbox_exec_path"`
+	ReadPaths       []string `mapstructure:"read_paths"`
+	ExtraRules      []string `mapstructure:"extra_rules"`
+}
+
+// Option customizes the configuration of a SandboxExecutor
+type Option func(*Config)
+
+// WithSandboxExecPath sets the sandbox-exec executable
+func WithSandboxExecPath(path string) Option {
+	return func(c *Config) { c.SandboxExecPath = path }
+}
+
+// WithReadPaths grants every plugin read-only access to additional paths,
+// such as a Homebrew prefix holding shared libraries
+func WithReadPaths(paths ...string) Option {
+	return func(c *Config) { c.ReadPaths = paths }
+}
+
+// WithExtraRules appends raw sandbox profile rules to the generated profile
+func WithExtraRules(rules ...string) Option {
+	return func(c *Config) { c.ExtraRules = rules }
+}
+
+func defaultConfig(pluginDir string) Config {
+	return Config{
+		PluginDir:       pluginDir,
+		SandboxExecPath: defaultSandboxExecPath,
+	}
+}
+
+// Config returns the executor's current configuration
+func (e *SandboxExecutor) Config() Config {
+	config := e.config
+	config.ReadPaths = append([]string(nil), config.ReadPaths...)
+	config.ExtraRules = append([]string(nil), config.ExtraRules...)
+
+	return config
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *SandboxExecutor) ApplyConfig(config Config) error {
+	if config.SandboxExecPath == "" {
+		config.SandboxExecPath = defaultSandboxExecPath
+	}
+
+	e.config = config
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *SandboxExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -0,0 +1,107 @@
+package sandbox
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// systemReadPaths are readable by every plugin so that dynamically linked
+// executables, interpreters and the system configuration keep working
+var systemReadPaths = []string{
+	"/System",
+	"/Library",
+	"/usr",
+	"/bin",
+	"/sbin",
+	"/opt",
+	"/private/etc",
+	"/private/var/db/timezone",
+	"/dev",
+}
+
+// profile is a generated sandbox-exec profile. Paths are passed as
+// parameters rather than spliced into the profile text so that they never
+// need quoting.
+type profile struct {
+	rules  []string
+	params []string
+}
+
+// args returns the sandbox-exec arguments that apply the profile
+func (p *profile) args() []string {
+	args := []string{"-p", strings.Join(p.rules, "\n")}
+	for _, param := range p.params {
+		args = append(args, "-D", param)
+	}
+
+	return args
+}
+
+// param registers a path parameter and returns its reference in the
+// profile. The sandbox matches resolved paths, so symlinks such as /tmp are
+// followed where the path exists.
+func (p *profile) param(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	name := fmt.Sprintf("PATH_%d", len(p.params))
+	p.params = append(p.params, name+"="+filepath.Clean(path))
+
+	return fmt.Sprintf("(subpath (param %q))", name)
+}
+
+// allow adds a rule allowing the operation on each of the paths
+func (p *profile) allow(operation string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	filters := make([]string, len(paths))
+	for i, path := range paths {
+		filters[i] = p.param(path)
+	}
+
+	p.rules = append(p.rules, fmt.Sprintf("(allow %s %s)", operation, strings.Join(filters, " ")))
+}
+
+// buildProfile denies everything by default and allows the plugin to read
+// its own files and the system paths, to read and write the paths it was
+// granted and its private temporary directory, and to reach the network
+// only when it was granted network access. A nil permissions value leaves
+// the plugin unrestricted, matching the other executors.
+func (e *SandboxExecutor) buildProfile(pluginPath, tmpDir string, opts ExecuteOptions) *profile {
+	p := &profile{rules: []string{"(version 1)"}}
+
+	if opts.Permissions == nil {
+		p.rules = append(p.rules, "(allow default)")
+		return p
+	}
+
+	p.rules = append(p.rules,
+		"(deny default)",
+		"(allow process-exec process-fork)",
+		"(allow signal (target same-sandbox))",
+		"(allow sysctl-read)",
+		"(allow mach-lookup)",
+		"(allow ipc-posix-shm-read*)",
+		"(allow file-read-metadata)",
+		`(allow file-read* file-write-data (literal "/dev/null") (literal "/dev/zero") (literal "/dev/tty"))`,
+	)
+
+	readPaths := append(append([]string{}, systemReadPaths...), e.config.ReadPaths...)
+	readPaths = append(readPaths, filepath.Dir(pluginPath))
+	p.allow("file-read*", readPaths)
+
+	writePaths := append([]string{tmpDir}, opts.Permissions.Filesystem...)
+	p.allow("file-read* file-write*", writePaths)
+
+	if opts.Permissions.Network {
+		p.rules = append(p.rules, "(allow network*)")
+	}
+
+	p.rules = append(p.rules, e.config.ExtraRules...)
+
+	return p
+}
@@ -0,0 +1,280 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// processWaitDelay bounds how long Wait blocks on output pipes after the
+// plugin exits or is killed
+const processWaitDelay = 5 * time.Second
+
+// SandboxExecutor implements the Executor interface for native plugins run
+// under a macOS sandbox-exec profile. The profile is generated from the
+// plugin's permissions: it restricts file access to the plugin's own files,
+// the system paths and the paths it was granted, and denies network access
+// unless the plugin requested it. This gives native plugins isolation on
+// macOS without a container runtime.
+type SandboxExecutor struct {
+	config Config
+	logger logr.Logger
+}
+
+// NewExecutor creates a new SandboxExecutor instance
+func NewExecutor(pluginDir string, opts ...Option) *SandboxExecutor {
+	config := defaultConfig(pluginDir)
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &SandboxExecutor{
+		config: config,
+		logger: logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *SandboxExecutor) WithLogger(logger logr.Logger) *SandboxExecutor {
+	e.logger = logger.WithName("sandbox-executor")
+	return e
+}
+
+// Ping verifies that sandbox-exec is available on this host
+func (e *SandboxExecutor) Ping(_ context.Context) error {
+	if err := supported(); err != nil {
+		return err
+	}
+
+	if _, err := exec.LookPath(e.config.SandboxExecPath); err != nil {
+		return fmt.Errorf("sandbox-exec is not available: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *SandboxExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"sandbox_exec_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to sandbox-exec executable",
+				"default":     "/usr/bin/sandbox-exec",
+			},
+			"read_paths": map[string]interface{}{
+				"type":        "array",
+				"description": "Additional paths every plugin may read (e.g., /opt/homebrew)",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"extra_rules": map[string]interface{}{
+				"type":        "array",
+				"description": "Additional sandbox profile rules appended to the generated profile",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+	}
+}
+
+// Execute runs a plugin inside a sandbox-exec profile
+func (e *SandboxExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	if err := supported(); err != nil {
+		return nil, err
+	}
+
+	config := e.config
+
+	// Construct the full path to the plugin executable
+	pluginPath := filepath.Join(config.PluginDir, pluginName, pluginName)
+
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(config.PluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	// Give the plugin a private temporary directory it may write to
+	tmpDir, err := os.MkdirTemp("", "plugin-sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	profile := e.buildProfile(pluginPath, tmpDir, opts)
+
+	network := "none"
+	if opts.Permissions == nil || opts.Permissions.Network {
+		network = "host"
+	}
+
+	args := append(profile.args(), pluginPath)
+	args = append(args, opts.Args...)
+
+	// Create command with context
+	cmd := exec.CommandContext(ctx, config.SandboxExecPath, args...)
+
+	// Make cancellation terminate the whole process tree
+	killProcessGroup(cmd)
+
+	// Do not wait forever on pipes held open by orphaned descendants
+	cmd.WaitDelay = processWaitDelay
+
+	// Set working directory if specified
+	if opts.WorkingDir != "" {
+		cmd.Dir = opts.WorkingDir
+	}
+
+	// Set environment variables
+	if environment != nil {
+		env := make([]string, 0, len(environment))
+		for k, v := range environment {
+			env = append(env, k+"="+v)
+		}
+		cmd.Env = env
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "TMPDIR="+tmpDir)
+
+	// Give the plugin a dedicated descriptor for its structured result
+	resultReader, resultWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result pipe: %w", err)
+	}
+	defer resultReader.Close()
+
+	cmd.ExtraFiles = []*os.File{resultWriter}
+	cmd.Env = append(cmd.Env, ResultFDEnvVar+"=3")
+
+	// Capture stdout and stderr
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Start the command
+	err = cmd.Start()
+	resultWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin in sandbox: %w", err)
+	}
+
+	resultCh := make(chan []byte, 1)
+	go func() {
+		data, _ := readAll(resultReader)
+		resultCh <- data
+	}()
+
+	// Wait for completion
+	err = cmd.Wait()
+	endTime := time.Now()
+	exitCode := 0
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else {
+			return nil, err
+		}
+	}
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	var structured map[string]any
+	if data := <-resultCh; len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &structured); err != nil {
+			logger.Error(err, "ignoring malformed structured result")
+		}
+	}
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exitCode,
+		Stdout:      stdout.Bytes(),
+		Stderr:      stderr.Bytes(),
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: pluginPath + " " + strings.Join(opts.Args, " "),
+		Command:     cmd.Args,
+		Network:     network,
+		WorkingDir:  opts.WorkingDir,
+		Environment: environment,
+		PID:         cmd.Process.Pid,
+		Success:     exitCode == 0,
+		Structured:  structured,
+	})), nil
+}
+
+// Explain returns the sandbox-exec command and profile Execute would use,
+// without running anything
+func (e *SandboxExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	config := e.config
+	pluginPath := filepath.Join(config.PluginDir, pluginName, pluginName)
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	// The private temporary directory is only created by Execute
+	profile := e.buildProfile(pluginPath, filepath.Join(os.TempDir(), "plugin-sandbox-*"), opts)
+
+	plan := &ExecutionPlan{
+		Runtime:     "sandbox",
+		Command:     append(append([]string{config.SandboxExecPath}, profile.args()...), append([]string{pluginPath}, opts.Args...)...),
+		CommandLine: pluginPath + " " + strings.Join(opts.Args, " "),
+		Network:     "none",
+		Security:    profile.rules,
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	if opts.Permissions == nil || opts.Permissions.Network {
+		plan.Network = "host"
+	}
+
+	if opts.Permissions != nil {
+		for _, path := range opts.Permissions.Filesystem {
+			plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
+		}
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// Helper function to read all data from a pipe
+func readAll(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, r)
+	return buf.Bytes(), err
+}
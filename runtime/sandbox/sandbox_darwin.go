@@ -0,0 +1,28 @@
+//go:build darwin
+
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const defaultSandboxExecPath = "/usr/bin/sandbox-exec"
+
+// supported reports whether sandbox-exec is available on this platform
+func supported() error {
+	return nil
+}
+
+// killProcessGroup places the plugin in its own process group and makes
+// cancellation terminate every process it spawned
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
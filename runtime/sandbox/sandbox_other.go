@@ -0,0 +1,17 @@
+//go:build !darwin
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+const defaultSandboxExecPath = "sandbox-exec"
+
+func supported() error {
+	return fmt.Errorf("sandbox-exec is not supported on %s", runtime.GOOS)
+}
+
+func killProcessGroup(_ *exec.Cmd) {}
@@ -0,0 +1,63 @@
+package script
+
+// Config is the typed configuration of a ScriptExecutor
+type Config struct {
+	PluginDir   string   `mapstructure:"plugin_dir"`
+	Interpreter string   `mapstructure:"interpreter"`  // Explicit interpreter path, skips the search
+	Version     string   `mapstructure:"version"`      // Version constraint applied to every plugin
+	SearchPaths []string `mapstructure:"search_paths"` // Directories searched before PATH
+}
+
+// Option customizes the configuration of a ScriptExecutor
+type Option func(*Config)
+
+// WithInterpreter uses the given interpreter instead of searching for one
+func WithInterpreter(path string) Option {
+	return func(c *Config) { c.Interpreter = path }
+}
+
+// WithVersion requires interpreters to satisfy a version constraint
+func WithVersion(constraint string) Option {
+	return func(c *Config) { c.Version = constraint }
+}
+
+// WithSearchPaths sets the directories searched for the interpreter
+// before PATH
+func WithSearchPaths(paths ...string) Option {
+	return func(c *Config) { c.SearchPaths = paths }
+}
+
+// Config returns the executor's current configuration
+func (e *ScriptExecutor) Config() Config {
+	return Config{
+		PluginDir:   e.pluginDir,
+		Interpreter: e.interpreter,
+		Version:     e.constraint,
+		SearchPaths: append([]string(nil), e.searchPaths...),
+	}
+}
+
+// ApplyConfig replaces the executor's configuration and forgets the
+// interpreters resolved so far
+func (e *ScriptExecutor) ApplyConfig(config Config) error {
+	e.pluginDir = config.PluginDir
+	e.interpreter = config.Interpreter
+	e.constraint = config.Version
+	e.searchPaths = config.SearchPaths
+
+	e.mu.Lock()
+	e.resolved = make(map[string]interpreter)
+	e.mu.Unlock()
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *ScriptExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
@@ -0,0 +1,485 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Language identifies the interpreter family of a script plugin
+type Language string
+
+const (
+	Node   Language = "node"
+	Python Language = "python"
+	Bash   Language = "bash"
+)
+
+// interpreterNames lists the executables tried for each language, in order
+var interpreterNames = map[Language][]string{
+	Node:   {"node", "nodejs"},
+	Python: {"python3", "python"},
+	Bash:   {"bash"},
+}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// ScriptExecutor implements the Executor interface for interpreted plugins.
+// The interpreter is located in the configured search paths, then in PATH,
+// and must satisfy both the configured version constraint and the one the
+// plugin declares in its requirements.
+type ScriptExecutor struct {
+	pluginDir   string
+	language    Language
+	interpreter string   // Explicit interpreter path, skips the search
+	searchPaths []string // Directories searched before PATH
+	constraint  string   // Version constraint applied to every plugin
+	logger      logr.Logger
+
+	mu       sync.Mutex
+	resolved map[string]interpreter // Keyed by version constraint
+}
+
+type interpreter struct {
+	path    string
+	version string
+}
+
+// NewExecutor creates a new ScriptExecutor for the given language
+func NewExecutor(pluginDir string, language Language, opts ...Option) *ScriptExecutor {
+	config := Config{PluginDir: pluginDir}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &ScriptExecutor{
+		pluginDir:   config.PluginDir,
+		language:    language,
+		interpreter: config.Interpreter,
+		searchPaths: config.SearchPaths,
+		constraint:  config.Version,
+		logger:      logr.Discard(),
+		resolved:    make(map[string]interpreter),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *ScriptExecutor) WithLogger(logger logr.Logger) *ScriptExecutor {
+	e.logger = logger.WithName(string(e.language) + "-executor")
+	return e
+}
+
+// Ping verifies that an interpreter satisfying the configured constraint
+// can be found
+func (e *ScriptExecutor) Ping(ctx context.Context) error {
+	if _, err := e.resolve(ctx, e.constraint); err != nil {
+		return fmt.Errorf("no %s interpreter available: %w", e.language, err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *ScriptExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"interpreter": map[string]interface{}{
+				"type":        "string",
+				"description": "Interpreter name or path, defaults to the language's interpreter",
+			},
+			"version": map[string]interface{}{
+				"type":        "string",
+				"description": "Version constraint the interpreter must satisfy (e.g., >=3.10)",
+			},
+			"search_paths": map[string]interface{}{
+				"type":        "array",
+				"description": "Directories searched for the interpreter before PATH",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+	}
+}
+
+// Execute runs a script plugin with the given options
+func (e *ScriptExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	info, err := e.readInfo(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	interp, err := e.resolve(ctx, e.constraintFor(info))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info("resolved interpreter", "path", interp.path, "version", interp.version)
+
+	root := filepath.Join(e.pluginDir, pluginName, info.Name)
+
+	interpPath, err := e.prepare(ctx, logger, interp.path, root, info)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := e.commandArgs(root, info, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, interpPath, args...)
+
+	if opts.WorkingDir != "" {
+		cmd.Dir = opts.WorkingDir
+	}
+
+	if environment != nil {
+		env := make([]string, 0, len(environment))
+		for k, v := range environment {
+			env = append(env, k+"="+v)
+		}
+		cmd.Env = env
+	} else {
+		cmd.Env = os.Environ()
+	}
+
+	if e.language == Python {
+		cmd.Env = append(cmd.Env, "PYTHONPATH="+root)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	endTime := time.Now()
+
+	exitCode := 0
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else {
+			return nil, fmt.Errorf("failed to execute plugin: %w", err)
+		}
+	}
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exitCode,
+		Stdout:      stdout.Bytes(),
+		Stderr:      stderr.Bytes(),
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: interpPath + " " + strings.Join(args, " "),
+		Command:     cmd.Args,
+		WorkingDir:  opts.WorkingDir,
+		Environment: environment,
+		PID:         cmd.Process.Pid,
+		Success:     exitCode == 0,
+	})), nil
+}
+
+// Explain returns the interpreter command Execute would run, without
+// running the plugin or setting up its dependencies
+func (e *ScriptExecutor) Explain(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	info, err := e.readInfo(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	interp, err := e.resolve(ctx, e.constraintFor(info))
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(e.pluginDir, pluginName, info.Name)
+
+	// Plugins with Python requirements run in their virtualenv
+	interpPath := interp.path
+	if e.language == Python {
+		if _, err := os.Stat(filepath.Join(root, "requirements.txt")); err == nil {
+			interpPath = filepath.Join(root, ".venv", "bin", "python")
+		}
+	}
+
+	args, err := e.commandArgs(root, info, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     string(e.language),
+		Command:     append([]string{interpPath}, args...),
+		CommandLine: interpPath + " " + strings.Join(args, " "),
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}), nil
+}
+
+// constraintFor combines the configured interpreter constraint with the one
+// the plugin declares
+func (e *ScriptExecutor) constraintFor(info *Info) string {
+	constraint := e.constraint
+	if info.Requirements != nil && info.Requirements.Interpreter != "" {
+		constraint = strings.Trim(constraint+","+info.Requirements.Interpreter, ",")
+	}
+
+	return constraint
+}
+
+// commandArgs builds the interpreter arguments that start the plugin
+func (e *ScriptExecutor) commandArgs(root string, info *Info, args []string) ([]string, error) {
+	entrypoint := info.Entrypoint
+	if entrypoint == "" {
+		entrypoint = info.FileName
+	}
+
+	if entrypoint == "" {
+		return nil, fmt.Errorf("plugin %s does not declare an entrypoint", info.Name)
+	}
+
+	// Python console scripts are module:function references
+	if e.language == Python && strings.Contains(entrypoint, ":") {
+		module, function, _ := strings.Cut(entrypoint, ":")
+
+		// Drop any [extras] suffix from the function reference
+		fields := strings.Fields(function)
+		if strings.TrimSpace(module) == "" || len(fields) == 0 {
+			return nil, fmt.Errorf("invalid python entrypoint %q", entrypoint)
+		}
+
+		code := fmt.Sprintf("import sys; sys.argv[0] = %q; from %s import %s as main; sys.exit(main())",
+			info.Name, strings.TrimSpace(module), fields[0])
+
+		return append([]string{"-c", code}, args...), nil
+	}
+
+	script := filepath.Join(root, filepath.FromSlash(entrypoint))
+	if !strings.HasPrefix(script, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("plugin entrypoint %s escapes the plugin directory", entrypoint)
+	}
+
+	return append([]string{script}, args...), nil
+}
+
+// prepare sets up the isolated dependency environment a plugin declares,
+// once, and returns the interpreter to run the plugin with. Python plugins
+// declare dependencies with a requirements.txt and get a virtualenv; node
+// plugins declare them in package.json and get a node_modules directory.
+func (e *ScriptExecutor) prepare(ctx context.Context, logger logr.Logger, interp, root string, info *Info) (string, error) {
+	switch e.language {
+	case Python:
+		requirements := filepath.Join(root, "requirements.txt")
+		if _, err := os.Stat(requirements); err != nil {
+			return interp, nil
+		}
+
+		venv := filepath.Join(root, ".venv")
+		python := filepath.Join(venv, "bin", "python")
+
+		if _, err := os.Stat(filepath.Join(venv, ".pluginkit-ready")); err == nil {
+			return python, nil
+		}
+
+		logger.Info("creating virtualenv", "dir", venv)
+
+		if err := run(ctx, root, interp, "-m", "venv", venv); err != nil {
+			return "", err
+		}
+
+		if err := run(ctx, root, python, "-m", "pip", "install", "--quiet", "-r", requirements); err != nil {
+			return "", err
+		}
+
+		return python, os.WriteFile(filepath.Join(venv, ".pluginkit-ready"), nil, 0644)
+	case Node:
+		dir := filepath.Dir(filepath.Join(root, filepath.FromSlash(info.Entrypoint)))
+
+		// Find the package.json that owns the entrypoint
+		for ; strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+			if _, err := os.Stat(filepath.Join(dir, "package.json")); err == nil {
+				break
+			}
+		}
+
+		if !strings.HasPrefix(dir, root) || !hasNodeDependencies(filepath.Join(dir, "package.json")) {
+			return interp, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "node_modules")); err == nil {
+			return interp, nil
+		}
+
+		logger.Info("installing node dependencies", "dir", dir)
+
+		npm := filepath.Join(filepath.Dir(interp), "npm")
+		if err := run(ctx, dir, npm, "install", "--omit=dev", "--no-audit", "--no-fund"); err != nil {
+			return "", err
+		}
+
+		return interp, nil
+	default:
+		return interp, nil
+	}
+}
+
+// resolve finds an interpreter satisfying constraint
+func (e *ScriptExecutor) resolve(ctx context.Context, constraint string) (interpreter, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if interp, ok := e.resolved[constraint]; ok {
+		return interp, nil
+	}
+
+	var candidates []string
+
+	if e.interpreter != "" {
+		candidates = append(candidates, e.interpreter)
+	} else {
+		for _, dir := range e.searchPaths {
+			for _, name := range interpreterNames[e.language] {
+				candidates = append(candidates, filepath.Join(dir, name))
+			}
+		}
+
+		for _, name := range interpreterNames[e.language] {
+			if path, err := exec.LookPath(name); err == nil {
+				candidates = append(candidates, path)
+			}
+		}
+	}
+
+	var rejected []string
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+
+		version, err := interpreterVersion(ctx, candidate)
+		if err != nil {
+			e.logger.V(1).Info("skipping interpreter", "path", candidate, "error", err.Error())
+			continue
+		}
+
+		ok, err := MatchVersion(version, constraint)
+		if err != nil {
+			return interpreter{}, fmt.Errorf("invalid interpreter constraint: %w", err)
+		}
+
+		if !ok {
+			rejected = append(rejected, candidate+" ("+version+")")
+			continue
+		}
+
+		interp := interpreter{path: candidate, version: version}
+		e.resolved[constraint] = interp
+
+		return interp, nil
+	}
+
+	if len(rejected) > 0 {
+		return interpreter{}, fmt.Errorf("no %s interpreter satisfies %q, found %s", e.language, constraint, strings.Join(rejected, ", "))
+	}
+
+	return interpreter{}, fmt.Errorf("no %s interpreter found", e.language)
+}
+
+func (e *ScriptExecutor) readInfo(pluginName string) (*Info, error) {
+	data, err := os.ReadFile(filepath.Join(e.pluginDir, pluginName, "metadata.json"))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s is not installed: %w", pluginName, err)
+	}
+
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata for plugin %s: %w", pluginName, err)
+	}
+
+	if info.Name == "" {
+		info.Name = pluginName
+	}
+
+	return &info, nil
+}
+
+// interpreterVersion reports the version printed by an interpreter
+func interpreterVersion(ctx context.Context, path string) (string, error) {
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+
+	version := versionPattern.FindString(string(out))
+	if version == "" {
+		return "", fmt.Errorf("unrecognised version output %q", strings.TrimSpace(string(out)))
+	}
+
+	return version, nil
+}
+
+func hasNodeDependencies(packageJSON string) bool {
+	data, err := os.ReadFile(packageJSON)
+	if err != nil {
+		return false
+	}
+
+	var pkg struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+
+	return json.Unmarshal(data, &pkg) == nil && len(pkg.Dependencies) > 0
+}
+
+func run(ctx context.Context, dir, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", filepath.Base(name), strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package extension
+
+// SSHConfig holds configuration for the SSH executor
+type SSHConfig struct {
+	Host       string   `mapstructure:"host"`
+	User       string   `mapstructure:"user"`
+	Port       int      `mapstructure:"port"`
+	KeyPath    string   `mapstructure:"key_path"`
+	SSHOptions []string `mapstructure:"ssh_options"`
+}
+
+// SSHOption customizes the configuration of an SSHExecutor
+type SSHOption func(*SSHConfig)
+
+// WithUser sets the remote user
+func WithUser(user string) SSHOption {
+	return func(c *SSHConfig) { c.User = user }
+}
+
+// WithPort sets the SSH port
+func WithPort(port int) SSHOption {
+	return func(c *SSHConfig) { c.Port = port }
+}
+
+// WithKeyPath sets the private key used to authenticate
+func WithKeyPath(path string) SSHOption {
+	return func(c *SSHConfig) { c.KeyPath = path }
+}
+
+// WithSSHOptions replaces the default ssh -o options
+func WithSSHOptions(options ...string) SSHOption {
+	return func(c *SSHConfig) { c.SSHOptions = options }
+}
+
+// withDefaults fills in the unset optional fields
+func (c SSHConfig) withDefaults() SSHConfig {
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.User == "" {
+		c.User = "root"
+	}
+	if c.SSHOptions == nil {
+		c.SSHOptions = []string{
+			"StrictHostKeyChecking=no",
+			"UserKnownHostsFile=/dev/null",
+			"ConnectTimeout=10",
+		}
+	}
+
+	return c
+}
+
+// Config returns the executor's current configuration
+func (e *SSHExecutor) Config() SSHConfig {
+	return SSHConfig{
+		Host:       e.host,
+		User:       e.user,
+		Port:       e.port,
+		KeyPath:    e.keyPath,
+		SSHOptions: append([]string(nil), e.sshOptions...),
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *SSHExecutor) ApplyConfig(config SSHConfig) error {
+	config = config.withDefaults()
+
+	if config.Host == "" {
+		return &ConfigError{Fields: []FieldError{{Field: "/host", Message: "is required"}}}
+	}
+
+	e.host = config.Host
+	e.user = config.User
+	e.port = config.Port
+	e.keyPath = config.KeyPath
+	e.sshOptions = config.SSHOptions
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *SSHExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
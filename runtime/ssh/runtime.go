@@ -7,86 +7,55 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/go-logr/logr"
 )
 
 // SSHExecutor implements the Executor interface for SSH-based plugins
 type SSHExecutor struct {
-	host       string   // Remote host address
-	user       string   // SSH user
-	port       int      // SSH port
-	keyPath    string   // Path to SSH private key
-	sshOptions []string // Additional SSH options
-}
-
-// SSHConfig holds configuration for the SSH executor
-type SSHConfig struct {
-	Host       string
-	User       string
-	Port       int
-	KeyPath    string
-	SSHOptions []string
+	host       string      // Remote host address
+	user       string      // SSH user
+	port       int         // SSH port
+	keyPath    string      // Path to SSH private key
+	sshOptions []string    // Additional SSH options
+	logger     logr.Logger // Logger for execution diagnostics
 }
 
 // NewSSHExecutor creates a new SSHExecutor instance
-func NewSSHExecutor(config SSHConfig) *SSHExecutor {
-	if config.Port == 0 {
-		config.Port = 22
-	}
-	if config.User == "" {
-		config.User = "root"
-	}
-	defaultOptions := []string{
-		"StrictHostKeyChecking=no",
-		"UserKnownHostsFile=/dev/null",
-		"ConnectTimeout=10",
-	}
-	if config.SSHOptions == nil {
-		config.SSHOptions = defaultOptions
+func NewSSHExecutor(config SSHConfig, opts ...SSHOption) *SSHExecutor {
+	for _, opt := range opts {
+		opt(&config)
 	}
 
+	config = config.withDefaults()
+
 	return &SSHExecutor{
 		host:       config.Host,
 		user:       config.User,
 		port:       config.Port,
 		keyPath:    config.KeyPath,
 		sshOptions: config.SSHOptions,
+		logger:     logr.Discard(),
 	}
 }
 
+// WithLogger sets the logger used for execution diagnostics
+func (e *SSHExecutor) WithLogger(logger logr.Logger) *SSHExecutor {
+	e.logger = logger.WithName("ssh-executor")
+	return e
+}
+
 // Execute runs a command on the remote host via SSH
 func (e *SSHExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
 	startTime := time.Now()
 
-	// Prepare SSH arguments
-	sshArgs := []string{
-		"-p", fmt.Sprintf("%d", e.port),
-	}
-
-	// Add SSH key if specified
-	if e.keyPath != "" {
-		sshArgs = append(sshArgs, "-i", e.keyPath)
-	}
-
-	// Add SSH options
-	for _, opt := range e.sshOptions {
-		sshArgs = append(sshArgs, "-o", opt)
-	}
-
-	// Add target host
-	sshArgs = append(sshArgs, fmt.Sprintf("%s@%s", e.user, e.host))
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
 
-	// Prepare environment variables
-	envVars := make([]string, 0, len(opts.Environment))
-	for k, v := range opts.Environment {
-		envVars = append(envVars, fmt.Sprintf("export %s=%s;", k, v))
-	}
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
 
-	// Build command with environment and working directory
-	command := strings.Join(append(envVars, strings.Join(opts.Args, " ")), " ")
-	if opts.WorkingDir != "" {
-		command = fmt.Sprintf("cd %s && %s", opts.WorkingDir, command)
-	}
-	sshArgs = append(sshArgs, command)
+	sshArgs := e.sshArgs(opts, environment)
 
 	// Create command
 	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
@@ -113,7 +82,9 @@ func (e *SSHExecutor) Execute(ctx context.Context, pluginName string, opts Execu
 	// Build command line for logging
 	commandLine := fmt.Sprintf("ssh://%s@%s:%d/%s", e.user, e.host, e.port, strings.Join(opts.Args, " "))
 
-	return &ExecuteResult{
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
@@ -121,11 +92,61 @@ func (e *SSHExecutor) Execute(ctx context.Context, pluginName string, opts Execu
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
 		CommandLine: commandLine,
+		Command:     cmd.Args,
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: environment,
 		PID:         0, // Remote execution, no local PID
 		Success:     exitCode == 0,
-	}, nil
+	})), nil
+}
+
+// Explain returns the ssh command Execute would run, without running it
+func (e *SSHExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
+	return RedactPlan(opts.Redactor, &ExecutionPlan{
+		Runtime:     "ssh",
+		Command:     append([]string{"ssh"}, e.sshArgs(opts, environment)...),
+		CommandLine: fmt.Sprintf("ssh://%s@%s:%d/%s", e.user, e.host, e.port, strings.Join(opts.Args, " ")),
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}), nil
+}
+
+// sshArgs builds the ssh arguments that run the plugin on the remote host
+func (e *SSHExecutor) sshArgs(opts ExecuteOptions, environment map[string]string) []string {
+	// Prepare SSH arguments
+	sshArgs := []string{
+		"-p", fmt.Sprintf("%d", e.port),
+	}
+
+	// Add SSH key if specified
+	if e.keyPath != "" {
+		sshArgs = append(sshArgs, "-i", e.keyPath)
+	}
+
+	// Add SSH options
+	for _, opt := range e.sshOptions {
+		sshArgs = append(sshArgs, "-o", opt)
+	}
+
+	// Add target host
+	sshArgs = append(sshArgs, fmt.Sprintf("%s@%s", e.user, e.host))
+
+	// Prepare environment variables
+	envVars := make([]string, 0, len(environment))
+	for k, v := range environment {
+		envVars = append(envVars, fmt.Sprintf("export %s=%s;", k, v))
+	}
+
+	// Build command with environment and working directory
+	command := strings.Join(append(envVars, strings.Join(opts.Args, " ")), " ")
+	if opts.WorkingDir != "" {
+		command = fmt.Sprintf("cd %s && %s", opts.WorkingDir, command)
+	}
+	sshArgs = append(sshArgs, command)
+
+	return sshArgs
 }
 
 // TestConnection verifies SSH connectivity to the remote host
@@ -141,53 +162,48 @@ func (e *SSHExecutor) TestConnection(ctx context.Context) error {
 	return cmd.Run()
 }
 
-// Configure applies the provided configuration map
-func (e *SSHExecutor) Configure(config map[string]interface{}) error {
-	// Extract host
-	if host, ok := config["host"].(string); ok {
-		e.host = host
+// Ping verifies that the remote host accepts SSH connections
+func (e *SSHExecutor) Ping(ctx context.Context) error {
+	if err := e.TestConnection(ctx); err != nil {
+		return fmt.Errorf("ssh host %s is not reachable: %w", e.host, err)
 	}
 
-	// Extract user
-	if user, ok := config["user"].(string); ok {
-		e.user = user
-	} else {
-		e.user = "root" // default
-	}
-
-	// Extract port
-	if port, ok := config["port"].(float64); ok {
-		e.port = int(port)
-	} else {
-		e.port = 22 // default
-	}
-
-	// Extract key path
-	if keyPath, ok := config["key_path"].(string); ok {
-		e.keyPath = keyPath
-	}
-
-	// Extract SSH options
-	if options, ok := config["ssh_options"].([]interface{}); ok {
-		e.sshOptions = make([]string, 0, len(options))
-		for _, opt := range options {
-			if strOpt, ok := opt.(string); ok {
-				e.sshOptions = append(e.sshOptions, strOpt)
-			}
-		}
-	} else {
-		// Default SSH options
-		e.sshOptions = []string{
-			"StrictHostKeyChecking=no",
-			"UserKnownHostsFile=/dev/null",
-			"ConnectTimeout=10",
-		}
-	}
+	return nil
+}
 
-	// Validate required fields
-	if e.host == "" {
-		return fmt.Errorf("host is required")
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *SSHExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"host": map[string]interface{}{
+				"type":        "string",
+				"description": "Host to run plugins on",
+			},
+			"user": map[string]interface{}{
+				"type":        "string",
+				"description": "Remote user",
+				"default":     "root",
+			},
+			"port": map[string]interface{}{
+				"type":        "integer",
+				"description": "SSH port",
+				"minimum":     1,
+				"maximum":     65535,
+				"default":     22,
+			},
+			"key_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Private key used to authenticate",
+			},
+			"ssh_options": map[string]interface{}{
+				"type":        "array",
+				"description": "SSH -o options",
+				"items": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+		"required": []string{"host"},
 	}
-
-	return nil
 }
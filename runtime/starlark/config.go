@@ -0,0 +1,46 @@
+package starlark
+
+// Config is the typed configuration of a StarlarkExecutor
+type Config struct {
+	PluginDir string `mapstructure:"plugin_dir"`
+	MaxSteps  uint64 `mapstructure:"max_steps"` // Computation steps after which a script is cancelled
+}
+
+// Option customizes the configuration of a StarlarkExecutor
+type Option func(*Config)
+
+// WithMaxSteps bounds the computation steps of a script
+func WithMaxSteps(steps uint64) Option {
+	return func(c *Config) { c.MaxSteps = steps }
+}
+
+// Config returns the executor's current configuration
+func (e *StarlarkExecutor) Config() Config {
+	return Config{
+		PluginDir: e.pluginDir,
+		MaxSteps:  e.maxSteps,
+	}
+}
+
+// ApplyConfig replaces the executor's configuration. A zero MaxSteps
+// restores the default limit.
+func (e *StarlarkExecutor) ApplyConfig(config Config) error {
+	if config.MaxSteps == 0 {
+		config.MaxSteps = defaultMaxSteps
+	}
+
+	e.pluginDir = config.PluginDir
+	e.maxSteps = config.MaxSteps
+
+	return nil
+}
+
+// Configure applies the provided configuration map
+func (e *StarlarkExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
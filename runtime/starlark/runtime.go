@@ -0,0 +1,342 @@
+package starlark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const defaultMaxSteps = 10_000_000
+
+// StarlarkExecutor implements the Executor interface for Starlark script
+// plugins executed in-process. Scripts only see a restricted set of
+// builtins: print, json, args and env, plus read_file and write_file when
+// the plugin was granted filesystem paths. A script may define main(); its
+// return value is the exit code, or a dict that becomes the structured
+// result.
+type StarlarkExecutor struct {
+	pluginDir string
+	maxSteps  uint64
+	logger    logr.Logger
+}
+
+// NewExecutor creates a new StarlarkExecutor instance
+func NewExecutor(pluginDir string, opts ...Option) *StarlarkExecutor {
+	config := Config{PluginDir: pluginDir, MaxSteps: defaultMaxSteps}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &StarlarkExecutor{
+		pluginDir: config.PluginDir,
+		maxSteps:  config.MaxSteps,
+		logger:    logr.Discard(),
+	}
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *StarlarkExecutor) WithLogger(logger logr.Logger) *StarlarkExecutor {
+	e.logger = logger.WithName("starlark-executor")
+	return e
+}
+
+// Ping verifies that the plugin directory is accessible
+func (e *StarlarkExecutor) Ping(_ context.Context) error {
+	if _, err := os.Stat(e.pluginDir); err != nil {
+		return fmt.Errorf("plugin directory is not accessible: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *StarlarkExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"max_steps": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of Starlark computation steps",
+				"minimum":     1,
+				"default":     10000000,
+			},
+		},
+	}
+}
+
+// Execute runs a Starlark plugin with the given options
+func (e *StarlarkExecutor) Execute(ctx context.Context, pluginName string, opts ExecuteOptions) (*ExecuteResult, error) {
+	startTime := time.Now()
+
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+
+	path, err := e.scriptPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	thread := &starlark.Thread{
+		Name: pluginName,
+		Print: func(_ *starlark.Thread, msg string) {
+			stdout.WriteString(msg)
+			stdout.WriteByte('\n')
+		},
+	}
+	thread.SetMaxExecutionSteps(e.maxSteps)
+
+	// Stop the interpreter when the context is done
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	exitCode, structured := 0, map[string]any(nil)
+
+	value, err := run(thread, path, src, predeclared(opts, environment))
+	if err == nil {
+		exitCode, structured, err = interpret(value)
+	}
+
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			stderr.WriteString(evalErr.Backtrace())
+		} else {
+			stderr.WriteString(err.Error())
+		}
+
+		stderr.WriteByte('\n')
+
+		exitCode = 1
+	}
+
+	endTime := time.Now()
+
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime), "steps", thread.ExecutionSteps())
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
+		ExitCode:    exitCode,
+		Stdout:      stdout.Bytes(),
+		Stderr:      stderr.Bytes(),
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Command:     append([]string{path}, opts.Args...),
+		Network:     "none",
+		WorkingDir:  opts.WorkingDir,
+		Environment: environment,
+		PID:         os.Getpid(),
+		Success:     exitCode == 0,
+		Structured:  structured,
+	})), nil
+}
+
+// Explain returns the script Execute would interpret and the builtins it
+// would be given, without running it
+func (e *StarlarkExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	path, err := e.scriptPath(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ExecutionPlan{
+		Runtime:     "starlark",
+		Command:     append([]string{path}, opts.Args...),
+		CommandLine: path + " " + strings.Join(opts.Args, " "),
+		Network:     "none", // Scripts have no network builtins
+		Security:    []string{fmt.Sprintf("max-steps=%d", e.maxSteps)},
+		Environment: opts.Permissions.FilterEnv(opts.Environment),
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	// read_file and write_file are only predeclared for granted paths
+	if opts.Permissions != nil {
+		for _, path := range opts.Permissions.Filesystem {
+			plan.Mounts = append(plan.Mounts, Mount{Source: path, Target: path})
+		}
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
+}
+
+// run executes the script and calls its main function, if any
+func run(thread *starlark.Thread, path string, src []byte, env starlark.StringDict) (starlark.Value, error) {
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, src, env)
+	if err != nil {
+		return nil, err
+	}
+
+	main, ok := globals["main"].(starlark.Callable)
+	if !ok {
+		return starlark.None, nil
+	}
+
+	return starlark.Call(thread, main, nil, nil)
+}
+
+// interpret maps the value returned by main to an exit code and result
+func interpret(value starlark.Value) (int, map[string]any, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return 0, nil, nil
+	case starlark.Int:
+		code, ok := v.Int64()
+		if !ok {
+			return 0, nil, fmt.Errorf("exit code %s out of range", v)
+		}
+
+		return int(code), nil, nil
+	case *starlark.Dict:
+		encoded, err := starlark.Call(&starlark.Thread{}, starjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to encode result: %w", err)
+		}
+
+		var structured map[string]any
+		if err := json.Unmarshal([]byte(string(encoded.(starlark.String))), &structured); err != nil {
+			return 0, nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+
+		return 0, structured, nil
+	default:
+		return 0, nil, fmt.Errorf("main returned %s, expected None, int or dict", value.Type())
+	}
+}
+
+// predeclared builds the restricted set of builtins available to a script
+func predeclared(opts ExecuteOptions, environment map[string]string) starlark.StringDict {
+	args := make([]starlark.Value, len(opts.Args))
+	for i, arg := range opts.Args {
+		args[i] = starlark.String(arg)
+	}
+
+	env := starlark.NewDict(len(environment))
+	for k, v := range environment {
+		env.SetKey(starlark.String(k), starlark.String(v))
+	}
+
+	globals := starlark.StringDict{
+		"json": starjson.Module,
+		"args": starlark.NewList(args),
+		"env":  env,
+	}
+
+	// Filesystem access is a capability the plugin has to be granted
+	if opts.Permissions != nil && len(opts.Permissions.Filesystem) > 0 {
+		resolve := func(path string) (string, error) {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(opts.WorkingDir, path)
+			}
+
+			if !opts.Permissions.AllowsPath(path) {
+				return "", fmt.Errorf("plugin is not permitted to access %s", path)
+			}
+
+			return path, nil
+		}
+
+		globals["read_file"] = starlark.NewBuiltin("read_file", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var path string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path); err != nil {
+				return nil, err
+			}
+
+			path, err := resolve(path)
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			return starlark.String(data), nil
+		})
+
+		globals["write_file"] = starlark.NewBuiltin("write_file", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var path, data string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path, "data", &data); err != nil {
+				return nil, err
+			}
+
+			path, err := resolve(path)
+			if err != nil {
+				return nil, err
+			}
+
+			return starlark.None, os.WriteFile(path, []byte(data), 0644)
+		})
+	}
+
+	return globals
+}
+
+// scriptPath locates the script of an installed plugin
+func (e *StarlarkExecutor) scriptPath(pluginName string) (string, error) {
+	dir := filepath.Join(e.pluginDir, pluginName)
+
+	var info Info
+	if data, err := os.ReadFile(filepath.Join(dir, "metadata.json")); err == nil {
+		if err := json.Unmarshal(data, &info); err != nil {
+			return "", fmt.Errorf("failed to parse metadata for plugin %s: %w", pluginName, err)
+		}
+	}
+
+	if info.Name == "" {
+		info.Name = pluginName
+	}
+
+	if info.FileName != "" {
+		return filepath.Join(dir, info.Name, info.FileName), nil
+	}
+
+	return filepath.Join(dir, info.Name, pluginName+".star"), nil
+}
@@ -0,0 +1,57 @@
+package extension
+
+import (
+	"context"
+)
+
+// WasmConfig is the typed configuration of a WasmExecutor
+type WasmConfig struct {
+	PluginDir     string `mapstructure:"plugin_dir"`
+	Deterministic bool   `mapstructure:"deterministic"` // Fixed clocks and seeded randomness
+	Seed          int64  `mapstructure:"seed"`
+	Epoch         int64  `mapstructure:"epoch"` // Unix time the wall clock starts at, 0 for 2022-01-01
+}
+
+// Configure applies the provided configuration map
+func (e *WasmExecutor) Configure(config map[string]interface{}) error {
+	cfg := e.Config()
+	if err := DecodeConfig(e.ConfigSchema(), config, &cfg); err != nil {
+		return err
+	}
+
+	return e.ApplyConfig(cfg)
+}
+
+// Config returns the executor's current configuration
+func (e *WasmExecutor) Config() WasmConfig {
+	return WasmConfig{
+		PluginDir:     e.pluginDir,
+		Deterministic: e.deterministic,
+		Seed:          e.seed,
+		Epoch:         e.epoch,
+	}
+}
+
+// ApplyConfig replaces the executor's configuration
+func (e *WasmExecutor) ApplyConfig(config WasmConfig) error {
+	// Validate required fields
+	if config.PluginDir == "" {
+		return &ConfigError{Fields: []FieldError{{Field: "/plugin_dir", Message: "is required"}}}
+	}
+
+	e.pluginDir = config.PluginDir
+	e.deterministic = config.Deterministic
+	e.seed = config.Seed
+	e.epoch = config.Epoch
+
+	// Reinitialize runtime if needed
+	if e.runtime == nil {
+		r, err := newRuntime(context.Background())
+		if err != nil {
+			return err
+		}
+		e.runtime = r
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package extension
+
+import "time"
+
+// threadCPUTime is unavailable outside Linux
+func threadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
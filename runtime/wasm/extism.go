@@ -0,0 +1,422 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/tetratelabs/wazero"
+)
+
+const (
+	// extismModule is the import module of the Extism plugin ABI
+	extismModule = "extism:host/env"
+
+	// maxHTTPResponse bounds the response body returned to a plugin by
+	// http_request
+	maxHTTPResponse = 50 << 20
+)
+
+// Extism log levels, as returned by get_log_level
+const (
+	extismLogTrace int32 = iota
+	extismLogDebug
+	extismLogInfo
+	extismLogWarn
+	extismLogError
+)
+
+type inputKey struct{}
+
+// ContextWithInput returns a context passing input to the function called in
+// an Extism plugin. Without it the plugin receives the arguments after the
+// function name, joined by spaces.
+func ContextWithInput(ctx context.Context, input []byte) context.Context {
+	return context.WithValue(ctx, inputKey{}, input)
+}
+
+// Call runs an exported function of an Extism plugin with input and returns
+// its output. A non-zero return code or an error set by the plugin is
+// returned as an error.
+func (e *WasmExecutor) Call(ctx context.Context, pluginName, function string, input []byte, opts ExecuteOptions) ([]byte, error) {
+	opts.Args = []string{function}
+
+	result, err := e.Execute(ContextWithInput(ctx, input), pluginName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, _ := result.Stdout.([]byte)
+
+	if !result.Success {
+		stderr, _ := result.Stderr.([]byte)
+		return stdout, fmt.Errorf("%s returned %d: %s", function, result.ExitCode, strings.TrimSpace(string(stderr)))
+	}
+
+	return stdout, nil
+}
+
+// isExtism reports whether a module was built against the Extism plugin ABI
+func isExtism(module wazero.CompiledModule) bool {
+	for _, def := range module.ImportedFunctions() {
+		if moduleName, _, ok := def.Import(); ok && moduleName == extismModule {
+			return true
+		}
+	}
+
+	return false
+}
+
+// callExtism calls the function named by the first argument of an Extism
+// plugin. Its output is written to stdout and the error it sets to stderr.
+func (e *WasmExecutor) callExtism(ctx context.Context, logger logr.Logger, module wazero.CompiledModule, opts ExecuteOptions, environment map[string]string, stdout, stderr *bytes.Buffer, timings *Timings) ([]Mount, int, error) {
+	if len(opts.Args) == 0 {
+		return nil, 0, fmt.Errorf("the function to call must be the first argument of an Extism plugin")
+	}
+
+	function := opts.Args[0]
+
+	input, ok := ctx.Value(inputKey{}).([]byte)
+	if !ok {
+		input = []byte(strings.Join(opts.Args[1:], " "))
+	}
+
+	kernel := newExtismKernel(input, environment, opts.Permissions == nil || opts.Permissions.Network, logger)
+	ctx = context.WithValue(ctx, kernelKey{}, kernel)
+
+	// Reactor modules built with WASI export _initialize instead of _start
+	config, mounts := withWorkingDir(e.withSystem(wazero.NewModuleConfig().
+		WithStderr(stderr).
+		WithStartFunctions("_initialize")), opts.WorkingDir)
+
+	instantiateStart := time.Now()
+	instance, err := e.runtime.InstantiateModule(ctx, module, config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to instantiate WASM module: %w", err)
+	}
+	defer instance.Close(ctx)
+
+	timings.Instantiate = time.Since(instantiateStart)
+
+	fn := instance.ExportedFunction(function)
+	if fn == nil {
+		return nil, 0, fmt.Errorf("WASM module does not export function %q", function)
+	}
+
+	results, callErr := timeCall(timings, func() ([]uint64, error) {
+		return fn.Call(ctx)
+	})
+
+	stdout.Write(kernel.output)
+
+	exitCode := 0
+	switch {
+	case callErr != nil:
+		exitCode = exitCodeOf(logger, callErr, stderr)
+	case len(results) > 0:
+		exitCode = int(int32(results[0]))
+	}
+
+	if kernel.err != "" {
+		stderr.WriteString(kernel.err)
+
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+
+	return mounts, exitCode, nil
+}
+
+type kernelKey struct{}
+
+// extismKernel implements the Extism host ABI for one call. Extism memory
+// is kept on the host as a single arena addressed by offsets, separate from
+// the plugin's linear memory.
+type extismKernel struct {
+	memory  []byte            // Offset 0 is reserved as the null offset
+	blocks  map[uint64]uint64 // Lengths of allocated blocks by offset
+	input   []byte
+	inputAt uint64 // Offset of the input block, once input_offset allocated it
+	output  []byte
+	err     string
+	config  map[string]string
+	vars    map[string][]byte
+	network bool
+	status  int32
+	logger  logr.Logger
+}
+
+func newExtismKernel(input []byte, config map[string]string, network bool, logger logr.Logger) *extismKernel {
+	return &extismKernel{
+		memory:  make([]byte, 1),
+		blocks:  make(map[uint64]uint64),
+		input:   input,
+		config:  config,
+		vars:    make(map[string][]byte),
+		network: network,
+		logger:  logger,
+	}
+}
+
+func kernelFrom(ctx context.Context) *extismKernel {
+	k, ok := ctx.Value(kernelKey{}).(*extismKernel)
+	if !ok {
+		panic(fmt.Errorf("%s called outside of an Extism plugin call", extismModule))
+	}
+
+	return k
+}
+
+func (k *extismKernel) alloc(n uint64) uint64 {
+	if n == 0 {
+		return 0
+	}
+
+	offset := uint64(len(k.memory))
+	k.memory = append(k.memory, make([]byte, n)...)
+	k.blocks[offset] = n
+
+	return offset
+}
+
+func (k *extismKernel) allocBytes(data []byte) uint64 {
+	offset := k.alloc(uint64(len(data)))
+	copy(k.memory[offset:], data)
+
+	return offset
+}
+
+// read returns a copy of the block at offset
+func (k *extismKernel) read(offset uint64) []byte {
+	n, ok := k.blocks[offset]
+	if !ok {
+		return nil
+	}
+
+	return bytes.Clone(k.memory[offset : offset+n])
+}
+
+// span returns the n bytes of memory at offset, trapping when they are out
+// of bounds
+func (k *extismKernel) span(offset, n uint64) []byte {
+	if offset == 0 || offset+n > uint64(len(k.memory)) || offset+n < offset {
+		panic(fmt.Errorf("extism memory access out of bounds: %d+%d", offset, n))
+	}
+
+	return k.memory[offset : offset+n]
+}
+
+// extismFunctions are the host functions of the Extism plugin ABI, by name
+var extismFunctions = map[string]any{
+	"alloc": func(ctx context.Context, n uint64) uint64 {
+		return kernelFrom(ctx).alloc(n)
+	},
+	"free": func(ctx context.Context, offset uint64) {
+		delete(kernelFrom(ctx).blocks, offset)
+	},
+	"length": func(ctx context.Context, offset uint64) uint64 {
+		return kernelFrom(ctx).blocks[offset]
+	},
+	"length_unsafe": func(ctx context.Context, offset uint64) uint64 {
+		return kernelFrom(ctx).blocks[offset]
+	},
+	"load_u8": func(ctx context.Context, offset uint64) uint32 {
+		return uint32(kernelFrom(ctx).span(offset, 1)[0])
+	},
+	"load_u64": func(ctx context.Context, offset uint64) uint64 {
+		return binary.LittleEndian.Uint64(kernelFrom(ctx).span(offset, 8))
+	},
+	"store_u8": func(ctx context.Context, offset uint64, v uint32) {
+		kernelFrom(ctx).span(offset, 1)[0] = byte(v)
+	},
+	"store_u64": func(ctx context.Context, offset, v uint64) {
+		binary.LittleEndian.PutUint64(kernelFrom(ctx).span(offset, 8), v)
+	},
+	"input_offset": func(ctx context.Context) uint64 {
+		k := kernelFrom(ctx)
+		if k.inputAt == 0 {
+			k.inputAt = k.allocBytes(k.input)
+		}
+
+		return k.inputAt
+	},
+	"input_length": func(ctx context.Context) uint64 {
+		return uint64(len(kernelFrom(ctx).input))
+	},
+	"input_load_u8": func(ctx context.Context, i uint64) uint32 {
+		input := kernelFrom(ctx).input
+		if i >= uint64(len(input)) {
+			panic(fmt.Errorf("extism input access out of bounds: %d", i))
+		}
+
+		return uint32(input[i])
+	},
+	"input_load_u64": func(ctx context.Context, i uint64) uint64 {
+		input := kernelFrom(ctx).input
+		if i+8 > uint64(len(input)) || i+8 < i {
+			panic(fmt.Errorf("extism input access out of bounds: %d", i))
+		}
+
+		return binary.LittleEndian.Uint64(input[i : i+8])
+	},
+	"output_set": func(ctx context.Context, offset, n uint64) {
+		k := kernelFrom(ctx)
+		k.output = bytes.Clone(k.span(offset, n))
+	},
+	"error_set": func(ctx context.Context, offset uint64) {
+		k := kernelFrom(ctx)
+		k.err = string(k.read(offset))
+	},
+	"error_get": func(ctx context.Context) uint64 {
+		k := kernelFrom(ctx)
+		if k.err == "" {
+			return 0
+		}
+
+		return k.allocBytes([]byte(k.err))
+	},
+	"config_get": func(ctx context.Context, key uint64) uint64 {
+		k := kernelFrom(ctx)
+
+		value, ok := k.config[string(k.read(key))]
+		if !ok {
+			return 0
+		}
+
+		return k.allocBytes([]byte(value))
+	},
+	"var_get": func(ctx context.Context, key uint64) uint64 {
+		k := kernelFrom(ctx)
+
+		value, ok := k.vars[string(k.read(key))]
+		if !ok {
+			return 0
+		}
+
+		return k.allocBytes(value)
+	},
+	"var_set": func(ctx context.Context, key, value uint64) {
+		k := kernelFrom(ctx)
+
+		if value == 0 {
+			delete(k.vars, string(k.read(key)))
+			return
+		}
+
+		k.vars[string(k.read(key))] = k.read(value)
+	},
+	"http_request": func(ctx context.Context, request, body uint64) uint64 {
+		return kernelFrom(ctx).httpRequest(ctx, request, body)
+	},
+	"http_status_code": func(ctx context.Context) int32 {
+		return kernelFrom(ctx).status
+	},
+	"http_headers": func(ctx context.Context) uint64 {
+		return 0 // Response headers are not exposed
+	},
+	"get_log_level": func(ctx context.Context) int32 {
+		if kernelFrom(ctx).logger.V(1).Enabled() {
+			return extismLogTrace
+		}
+
+		return extismLogInfo
+	},
+	"reset": func(ctx context.Context) {
+		k := kernelFrom(ctx)
+		k.memory = k.memory[:1]
+		k.inputAt = 0
+		clear(k.blocks)
+	},
+	"log_trace": logFunc(extismLogTrace),
+	"log_debug": logFunc(extismLogDebug),
+	"log_info":  logFunc(extismLogInfo),
+	"log_warn":  logFunc(extismLogWarn),
+	"log_error": logFunc(extismLogError),
+}
+
+// instantiateExtismHost registers the Extism host functions with a runtime
+func instantiateExtismHost(ctx context.Context, r wazero.Runtime) error {
+	builder := r.NewHostModuleBuilder(extismModule)
+	for name, fn := range extismFunctions {
+		builder.NewFunctionBuilder().WithFunc(fn).Export(name)
+	}
+
+	_, err := builder.Instantiate(ctx)
+
+	return err
+}
+
+// logFunc returns the host function logging a message at level
+func logFunc(level int32) func(ctx context.Context, offset uint64) {
+	return func(ctx context.Context, offset uint64) {
+		k := kernelFrom(ctx)
+		msg := string(k.read(offset))
+
+		switch level {
+		case extismLogTrace, extismLogDebug:
+			k.logger.V(1).Info(msg, "source", "plugin")
+		case extismLogError:
+			k.logger.Error(nil, msg, "source", "plugin")
+		default:
+			k.logger.Info(msg, "source", "plugin", "level", level)
+		}
+	}
+}
+
+// extismHTTPRequest is the request a plugin passes to http_request
+type extismHTTPRequest struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+}
+
+// httpRequest performs a request on behalf of the plugin and returns the
+// offset of the response body
+func (k *extismKernel) httpRequest(ctx context.Context, request, body uint64) uint64 {
+	if !k.network {
+		panic(fmt.Errorf("plugin is not permitted to access the network"))
+	}
+
+	var r extismHTTPRequest
+	if err := json.Unmarshal(k.read(request), &r); err != nil {
+		panic(fmt.Errorf("invalid http request: %w", err))
+	}
+
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bytes.NewReader(k.read(body)))
+	if err != nil {
+		panic(fmt.Errorf("invalid http request: %w", err))
+	}
+
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+
+	k.logger.V(1).Info("plugin http request", "method", r.Method, "url", r.URL)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(fmt.Errorf("http request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponse))
+	if err != nil {
+		panic(fmt.Errorf("failed to read http response: %w", err))
+	}
+
+	k.status = int32(resp.StatusCode)
+
+	return k.allocBytes(data)
+}
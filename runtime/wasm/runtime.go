@@ -2,26 +2,59 @@ package extension
 
 import (
 	"context"
+	crand "crypto/rand"
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"bytes"
 
+	"github.com/go-logr/logr"
 	"github.com/tetratelabs/wazero"
 	wasip1 "github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
 )
 
+// defaultEpoch is where the wall clock starts in deterministic mode,
+// 2022-01-01T00:00:00Z
+const defaultEpoch = 1640995200
+
 // WasmExecutor implements the Executor interface for WebAssembly plugins
 type WasmExecutor struct {
-	pluginDir string
-	runtime   wazero.Runtime
+	pluginDir     string
+	runtime       wazero.Runtime
+	deterministic bool  // Fixed clocks and seeded randomness
+	seed          int64 // Seed of the random source in deterministic mode
+	epoch         int64 // Unix time the wall clock starts at in deterministic mode
+	logger        logr.Logger
+
+	mu     sync.Mutex
+	closed bool
+	active sync.WaitGroup // Executions using the runtime
 }
 
 // NewWasmExecutor creates a new WasmExecutor instance
 func NewWasmExecutor(pluginDir string) (*WasmExecutor, error) {
-	ctx := context.Background()
+	r, err := newRuntime(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &WasmExecutor{
+		pluginDir: pluginDir,
+		runtime:   r,
+		logger:    logr.Discard(),
+	}, nil
+}
+
+// newRuntime creates a runtime providing WASI and the Extism host functions
+func newRuntime(ctx context.Context) (wazero.Runtime, error) {
 	r := wazero.NewRuntime(ctx)
 
 	// Initialize WASI
@@ -30,10 +63,27 @@ func NewWasmExecutor(pluginDir string) (*WasmExecutor, error) {
 		return nil, fmt.Errorf("failed to initialize WASI: %w", err)
 	}
 
-	return &WasmExecutor{
-		pluginDir: pluginDir,
-		runtime:   r,
-	}, nil
+	if err := instantiateExtismHost(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("failed to initialize Extism host functions: %w", err)
+	}
+
+	return r, nil
+}
+
+// WithLogger sets the logger used for execution diagnostics
+func (e *WasmExecutor) WithLogger(logger logr.Logger) *WasmExecutor {
+	e.logger = logger.WithName("wasm-executor")
+	return e
+}
+
+// WithDeterministic makes every run reproducible: the wall clock starts at
+// 2022-01-01, clocks advance 1ms per read, sleeps return immediately and
+// random bytes come from a generator seeded with seed. Meant for tests.
+func (e *WasmExecutor) WithDeterministic(seed int64) *WasmExecutor {
+	e.deterministic = true
+	e.seed = seed
+	return e
 }
 
 // Execute runs a WASM plugin with the given options
@@ -42,8 +92,29 @@ func (e *WasmExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 		return nil, fmt.Errorf("plugin name cannot be empty")
 	}
 
+	if err := e.begin(); err != nil {
+		return nil, err
+	}
+	defer e.active.Done()
+
 	startTime := time.Now()
 
+	logger := e.logger.WithValues("plugin", pluginName)
+	logger.V(1).Info("executing plugin", "args", len(opts.Args))
+
+	// Refuse to run disabled or quarantined plugins
+	if !opts.IgnoreStatus {
+		if err := CheckStatus(e.pluginDir, pluginName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restrict the plugin to its granted capabilities
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
 	// Construct the full path to the WASM plugin
 	pluginPath := filepath.Join(e.pluginDir, pluginName, pluginName+".wasm")
 
@@ -54,6 +125,7 @@ func (e *WasmExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 	}
 
 	// Compile the WASM module
+	compileStart := time.Now()
 	module, err := e.runtime.CompileModule(ctx, wasmBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile WASM module: %w", err)
@@ -62,36 +134,30 @@ func (e *WasmExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 		_ = module.Close(ctx)
 	}()
 
-	// Configure the WASM instance with stdio
-	var stdout, stderr bytes.Buffer
-	config := wazero.NewModuleConfig().
-		WithArgs(opts.Args...).
-		//WithEnv(e.convertEnvToSlice(opts.Environment)).
-		WithStdout(&stdout).
-		WithStderr(&stderr)
-	if opts.WorkingDir != "" {
-		config = config.WithFSConfig(wazero.NewFSConfig().
-			WithDirMount(opts.WorkingDir, "/"))
-	}
+	timings := &Timings{Compile: time.Since(compileStart)}
 
-	// Instantiate the module
-	instance, err := e.runtime.InstantiateModule(ctx, module, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate WASM module: %w", err)
-	}
-	defer instance.Close(ctx)
+	var (
+		stdout, stderr bytes.Buffer
+		mounts         []Mount
+		exitCode       int
+	)
 
-	// Call the _start function (main entry point)
-	exitCode := 0
-	if _, err := instance.ExportedFunction("_start").Call(ctx); err != nil {
-		fmt.Fprintf(&stderr, "Error executing WASM module: %v\nType: %T\n", err, err)
-		exitCode = 1
+	// Extism plugins export named functions instead of a WASI command
+	if isExtism(module) {
+		mounts, exitCode, err = e.callExtism(ctx, logger, module, opts, environment, &stdout, &stderr, timings)
+	} else {
+		mounts, exitCode, err = e.runCommand(ctx, logger, module, opts, &stdout, &stderr, timings)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	endTime := time.Now()
 
 	// Get stdout and stderr as bytes
-	return &ExecuteResult{
+	logger.V(1).Info("plugin execution finished", "exitCode", exitCode, "duration", endTime.Sub(startTime))
+
+	return RedactResult(opts.Redactor, ExtractStructured(&ExecuteResult{
 		ExitCode:    exitCode,
 		Stdout:      stdout.Bytes(),
 		Stderr:      stderr.Bytes(),
@@ -99,11 +165,168 @@ func (e *WasmExecutor) Execute(ctx context.Context, pluginName string, opts Exec
 		EndTime:     endTime,
 		Duration:    endTime.Sub(startTime),
 		CommandLine: pluginPath,
+		Command:     append([]string{pluginPath}, opts.Args...),
+		Image:       pluginPath,
+		Mounts:      mounts,
+		Network:     "none",
 		WorkingDir:  opts.WorkingDir,
-		Environment: opts.Environment,
+		Environment: environment,
 		PID:         0, // WASM doesn't have a traditional PID
 		Success:     exitCode == 0,
-	}, nil
+		Timings:     timings,
+	})), nil
+}
+
+// runCommand instantiates a WASI command module and runs its _start
+// function, returning the host paths it was given and its exit code
+func (e *WasmExecutor) runCommand(ctx context.Context, logger logr.Logger, module wazero.CompiledModule, opts ExecuteOptions, stdout, stderr *bytes.Buffer, timings *Timings) ([]Mount, int, error) {
+	// Configure the WASM instance with stdio
+	config := wazero.NewModuleConfig().
+		WithArgs(opts.Args...).
+		//WithEnv(e.convertEnvToSlice(environment)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithStartFunctions() // _start is called below so that it is timed separately
+	config, mounts := withWorkingDir(e.withSystem(config), opts.WorkingDir)
+
+	// Instantiate the module
+	instantiateStart := time.Now()
+	instance, err := e.runtime.InstantiateModule(ctx, module, config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to instantiate WASM module: %w", err)
+	}
+	defer instance.Close(ctx)
+
+	timings.Instantiate = time.Since(instantiateStart)
+
+	start := instance.ExportedFunction("_start")
+	if start == nil {
+		return nil, 0, fmt.Errorf("WASM module does not export _start")
+	}
+
+	// Call the _start function (main entry point)
+	exitCode := 0
+	if _, err := timeCall(timings, func() ([]uint64, error) { return start.Call(ctx) }); err != nil {
+		exitCode = exitCodeOf(logger, err, stderr)
+	}
+
+	return mounts, exitCode, nil
+}
+
+// withWorkingDir mounts the working directory, if any, as the module's root
+func withWorkingDir(config wazero.ModuleConfig, dir string) (wazero.ModuleConfig, []Mount) {
+	if dir == "" {
+		return config, nil
+	}
+
+	config = config.WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/"))
+
+	return config, []Mount{{Source: dir, Target: "/"}}
+}
+
+// timeCall runs call on a locked thread, recording its wall-clock and CPU
+// time in timings
+func timeCall(timings *Timings, call func() ([]uint64, error)) ([]uint64, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cpuStart, cpuOK := threadCPUTime()
+	runStart := time.Now()
+
+	results, err := call()
+
+	timings.Run = time.Since(runStart)
+	if cpuEnd, ok := threadCPUTime(); ok && cpuOK {
+		timings.CPU = cpuEnd - cpuStart
+	}
+
+	return results, err
+}
+
+// exitCodeOf returns the exit code of a module that exited or trapped,
+// describing traps on stderr
+func exitCodeOf(logger logr.Logger, err error, stderr *bytes.Buffer) int {
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		return int(exitErr.ExitCode())
+	}
+
+	logger.Error(err, "WASM module exited with error")
+	fmt.Fprintf(stderr, "Error executing WASM module: %v\n", err)
+
+	return 1
+}
+
+// withSystem wires the module's clocks and random source to the host's, or
+// to fixed ones in deterministic mode
+func (e *WasmExecutor) withSystem(config wazero.ModuleConfig) wazero.ModuleConfig {
+	if !e.deterministic {
+		return config.
+			WithSysWalltime().
+			WithSysNanotime().
+			WithSysNanosleep().
+			WithRandSource(crand.Reader)
+	}
+
+	epoch := e.epoch
+	if epoch == 0 {
+		epoch = defaultEpoch
+	}
+
+	clock := &fixedClock{epoch: epoch}
+
+	return config.
+		WithWalltime(clock.walltime, sys.ClockResolution(time.Millisecond)).
+		WithNanotime(clock.nanotime, sys.ClockResolution(time.Millisecond)).
+		WithNanosleep(func(int64) {}).
+		WithRandSource(rand.New(rand.NewSource(e.seed)))
+}
+
+// fixedClock is a clock that advances 1ms every time it is read, so that a
+// module reading it the same way sees the same times on every run
+type fixedClock struct {
+	epoch   int64 // Unix seconds
+	elapsed atomic.Int64
+}
+
+func (c *fixedClock) walltime() (int64, int32) {
+	t := time.Unix(c.epoch, c.elapsed.Add(int64(time.Millisecond)))
+	return t.Unix(), int32(t.Nanosecond())
+}
+
+func (c *fixedClock) nanotime() int64 {
+	return c.elapsed.Add(int64(time.Millisecond))
+}
+
+// Explain returns the module Execute would instantiate, without compiling
+// or running it
+func (e *WasmExecutor) Explain(_ context.Context, pluginName string, opts ExecuteOptions) (*ExecutionPlan, error) {
+	if pluginName == "" {
+		return nil, fmt.Errorf("plugin name cannot be empty")
+	}
+
+	environment := opts.Permissions.FilterEnv(opts.Environment)
+	if opts.WorkingDir != "" && !opts.Permissions.AllowsPath(opts.WorkingDir) {
+		return nil, fmt.Errorf("plugin is not permitted to access %s", opts.WorkingDir)
+	}
+
+	pluginPath := filepath.Join(e.pluginDir, pluginName, pluginName+".wasm")
+
+	plan := &ExecutionPlan{
+		Runtime:     "wasm",
+		Command:     append([]string{pluginPath}, opts.Args...),
+		CommandLine: pluginPath,
+		Image:       pluginPath,
+		Network:     "none", // WASI modules have no sockets
+		Environment: environment,
+		WorkingDir:  opts.WorkingDir,
+	}
+
+	if opts.WorkingDir != "" {
+		plan.Mounts = []Mount{{Source: opts.WorkingDir, Target: "/"}}
+	}
+
+	return RedactPlan(opts.Redactor, plan), nil
 }
 
 // Helper function to convert environment map to slice
@@ -118,30 +341,96 @@ func (e *WasmExecutor) convertEnvToSlice(env map[string]string) []string {
 	return result
 }
 
-// Configure applies the provided configuration map
-func (e *WasmExecutor) Configure(config map[string]interface{}) error {
-	// Extract plugin directory
-	if pluginDir, ok := config["plugin_dir"].(string); ok {
-		e.pluginDir = pluginDir
+// Ping verifies that the wazero runtime is initialized
+func (e *WasmExecutor) Ping(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return fmt.Errorf("wasm executor is closed")
+	}
+
+	if e.runtime == nil {
+		return fmt.Errorf("wasm runtime is not initialized")
+	}
+
+	return nil
+}
+
+// Close waits for running modules until ctx is done, then closes the wazero
+// runtime, which also stops any module still running
+func (e *WasmExecutor) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
 	}
 
-	// Validate required fields
-	if e.pluginDir == "" {
-		return fmt.Errorf("plugin_dir is required")
+	e.closed = true
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		e.logger.Info("closing runtime with executions still running")
 	}
 
-	// Reinitialize runtime if needed
 	if e.runtime == nil {
-		ctx := context.Background()
-		r := wazero.NewRuntime(ctx)
+		return nil
+	}
 
-		// Initialize WASI
-		if _, err := wasip1.Instantiate(ctx, r); err != nil {
-			r.Close(ctx)
-			return fmt.Errorf("failed to initialize WASI: %w", err)
-		}
-		e.runtime = r
+	// Close with a fresh context since ctx may already be done
+	if err := e.runtime.Close(context.Background()); err != nil {
+		return fmt.Errorf("failed to close wasm runtime: %w", err)
 	}
 
 	return nil
 }
+
+// begin registers an execution unless the executor is closed
+func (e *WasmExecutor) begin() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return fmt.Errorf("wasm executor is closed")
+	}
+
+	e.active.Add(1)
+
+	return nil
+}
+
+// ConfigSchema returns the JSON schema for the executor's configuration
+func (e *WasmExecutor) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"plugin_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory containing plugin files",
+			},
+			"deterministic": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run plugins with fixed clocks and seeded randomness for reproducible results",
+				"default":     false,
+			},
+			"seed": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seed of the random source in deterministic mode",
+				"default":     0,
+			},
+			"epoch": map[string]interface{}{
+				"type":        "integer",
+				"description": "Unix time the wall clock starts at in deterministic mode, 0 for 2022-01-01",
+				"default":     0,
+			},
+		},
+	}
+}
@@ -0,0 +1,251 @@
+package extension
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WorkDirSandbox is a private copy of a working directory that a plugin
+// runs against. Changes only reach the original directory when Commit is
+// called.
+type WorkDirSandbox struct {
+	Original string
+	Copy     string
+
+	original map[string]fileState // Original entries at the time of the copy
+	baseline map[string]fileState // Copied entries before the plugin ran
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// ConflictError lists files that changed in the original directory while
+// the plugin was running against its copy
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("working directory changed during execution: %s", strings.Join(e.Paths, ", "))
+}
+
+// UserMessage implements UserMessager
+func (e *ConflictError) UserMessage() Message {
+	return Message{Key: MsgWorkDirConflict, Params: map[string]string{"paths": strings.Join(e.Paths, ", ")}}
+}
+
+// NewWorkDirSandbox copies dir into a temporary directory
+func NewWorkDirSandbox(dir string) (*WorkDirSandbox, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp("", "plugin-workdir-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+
+	s := &WorkDirSandbox{Original: dir, Copy: tmp}
+
+	if s.original, s.baseline, err = copyTree(dir, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return nil, fmt.Errorf("failed to copy working directory: %w", err)
+	}
+
+	return s, nil
+}
+
+// Commit applies the changes made in the copy to the original directory
+// and removes the copy. Nothing is written if any affected file changed in
+// the original since the copy was taken; a *ConflictError is returned and
+// the copy is kept for inspection.
+func (s *WorkDirSandbox) Commit() error {
+	current, err := scanTree(s.Copy)
+	if err != nil {
+		return fmt.Errorf("failed to scan sandbox: %w", err)
+	}
+
+	var (
+		writes, deletes []string
+		conflicts       []string
+	)
+
+	for rel, state := range current {
+		if before, ok := s.baseline[rel]; ok && before == state {
+			continue
+		}
+
+		writes = append(writes, rel)
+	}
+
+	for rel := range s.baseline {
+		if _, ok := current[rel]; !ok {
+			deletes = append(deletes, rel)
+		}
+	}
+
+	for _, rel := range append(append([]string{}, writes...), deletes...) {
+		if s.changedInOriginal(rel) {
+			conflicts = append(conflicts, rel)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &ConflictError{Paths: conflicts}
+	}
+
+	for _, rel := range writes {
+		if err := copyEntry(filepath.Join(s.Copy, rel), filepath.Join(s.Original, rel)); err != nil {
+			return fmt.Errorf("failed to commit %s: %w", rel, err)
+		}
+	}
+
+	for _, rel := range deletes {
+		if err := os.RemoveAll(filepath.Join(s.Original, rel)); err != nil {
+			return fmt.Errorf("failed to commit removal of %s: %w", rel, err)
+		}
+	}
+
+	return s.Discard()
+}
+
+// Discard removes the copy without touching the original directory
+func (s *WorkDirSandbox) Discard() error {
+	return os.RemoveAll(s.Copy)
+}
+
+// changedInOriginal reports whether a path of the original directory
+// changed since the copy was taken
+func (s *WorkDirSandbox) changedInOriginal(rel string) bool {
+	before, existed := s.original[rel]
+
+	info, err := os.Lstat(filepath.Join(s.Original, rel))
+	if err != nil {
+		return existed
+	}
+
+	return !existed || stateOf(info) != before
+}
+
+func stateOf(info fs.FileInfo) fileState {
+	state := fileState{mode: info.Mode()}
+
+	// Directory sizes and times change with their entries, which are
+	// tracked on their own
+	if !info.IsDir() {
+		state.size = info.Size()
+		state.modTime = info.ModTime()
+	}
+
+	return state
+}
+
+// scanTree records the state of every entry below root
+func scanTree(root string) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		states[rel] = stateOf(info)
+
+		return nil
+	})
+
+	return states, err
+}
+
+// copyTree copies src into dst, preserving modification times so that
+// unchanged files can be recognised. It returns the state of both trees.
+func copyTree(src, dst string) (map[string]fileState, map[string]fileState, error) {
+	original, err := scanTree(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for rel := range original {
+		if err := copyEntry(filepath.Join(src, rel), filepath.Join(dst, rel)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	baseline, err := scanTree(dst)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return original, baseline, nil
+}
+
+// copyEntry copies a single file, symlink or directory, without following
+// symlinks
+func copyEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case info.IsDir():
+		return os.MkdirAll(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+
+		os.Remove(dst)
+
+		return os.Symlink(target, dst)
+	case info.Mode().IsRegular():
+		in, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+
+		if err := out.Close(); err != nil {
+			return err
+		}
+
+		return os.Chtimes(dst, info.ModTime(), info.ModTime())
+	default:
+		// Devices, sockets and pipes are not copied
+		return nil
+	}
+}
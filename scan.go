@@ -0,0 +1,229 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edsonmichaque/pluginkit/events"
+	"github.com/edsonmichaque/pluginkit/metrics"
+)
+
+// Severity ranks scan findings
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	default:
+		return "critical"
+	}
+}
+
+// Finding is an issue a scanner found in a plugin
+type Finding struct {
+	Scanner     string
+	Path        string // Relative to the plugin directory, empty for the whole plugin
+	Severity    Severity
+	Description string
+}
+
+// ScanResult is the verdict of a scanner
+type ScanResult struct {
+	Allowed  bool
+	Findings []Finding
+}
+
+// Scanner inspects an extracted plugin before it is activated. Scanners may
+// wrap external tools such as Trivy or ClamAV or apply custom policies.
+type Scanner interface {
+	Scan(ctx context.Context, dir string, info *Info) (*ScanResult, error)
+}
+
+// ScanRejectedError is returned when a scanner blocks an installation
+type ScanRejectedError struct {
+	Plugin   string
+	Findings []Finding
+}
+
+func (e *ScanRejectedError) Error() string {
+	return fmt.Sprintf("plugin %s was rejected by scan: %s", e.Plugin, e.findings())
+}
+
+// UserMessage implements UserMessager
+func (e *ScanRejectedError) UserMessage() Message {
+	return Message{Key: MsgScanRejected, Params: map[string]string{"plugin": e.Plugin, "findings": e.findings()}}
+}
+
+func (e *ScanRejectedError) findings() string {
+	descriptions := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		d := f.Description
+		if f.Path != "" {
+			d = f.Path + ": " + d
+		}
+
+		descriptions = append(descriptions, d)
+	}
+
+	return strings.Join(descriptions, "; ")
+}
+
+// WithScanners runs the given scanners on every plugin after extraction and
+// before activation. Any scanner that does not allow the plugin, or fails,
+// aborts the installation or upgrade.
+func (m *Manager) WithScanners(scanners ...Scanner) *Manager {
+	m.scanners = append(m.scanners, scanners...)
+	return m
+}
+
+// scan runs the configured scanners over an extracted plugin
+func (m *Manager) scan(ctx context.Context, operation, dir string, info *Info) error {
+	var rejected []Finding
+
+	for _, scanner := range m.scanners {
+		result, err := scanner.Scan(ctx, dir, info)
+		if err != nil {
+			m.metrics.Failed(operation, metrics.ReasonScan)
+			return fmt.Errorf("failed to scan plugin %s: %w", info.Name, err)
+		}
+
+		for _, f := range result.Findings {
+			m.logger.V(1).Info("scan finding", "plugin", info.Name, "path", f.Path, "severity", f.Severity.String(), "description", f.Description)
+		}
+
+		if !result.Allowed {
+			rejected = append(rejected, result.Findings...)
+		}
+	}
+
+	if rejected == nil {
+		return nil
+	}
+
+	err := &ScanRejectedError{Plugin: info.Name, Findings: rejected}
+
+	m.metrics.Failed(operation, metrics.ReasonScan)
+	m.events.Publish(events.Event{
+		Type:     events.VerificationFailed,
+		Plugin:   info.Name,
+		Version:  info.Version,
+		Store:    info.Store,
+		Metadata: map[string]string{"reason": "scan"},
+		Err:      err,
+	})
+
+	return err
+}
+
+// BuiltinScanner flags known-bad files and suspicious archive contents:
+// symlinks pointing outside the plugin, setuid or setgid files, device
+// files, and files above a size limit
+type BuiltinScanner struct {
+	KnownBad    map[string]string // Hex SHA-256 digests to the reason they are blocked
+	MaxFileSize int64             // Largest allowed file in bytes, 0 for no limit
+}
+
+var _ Scanner = &BuiltinScanner{}
+
+// Scan implements Scanner. Plugins with findings of high or critical
+// severity are not allowed.
+func (s *BuiltinScanner) Scan(ctx context.Context, dir string, _ *Info) (*ScanResult, error) {
+	result := &ScanResult{Allowed: true}
+
+	add := func(path string, severity Severity, description string) {
+		result.Findings = append(result.Findings, Finding{
+			Scanner:     "builtin",
+			Path:        path,
+			Severity:    severity,
+			Description: description,
+		})
+
+		if severity >= SeverityHigh {
+			result.Allowed = false
+		}
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode()
+
+		switch {
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+
+			if target != root && !strings.HasPrefix(filepath.Clean(target), root+string(filepath.Separator)) {
+				add(rel, SeverityHigh, fmt.Sprintf("symlink points outside the plugin to %s", target))
+			}
+		case mode&(os.ModeDevice|os.ModeCharDevice|os.ModeNamedPipe|os.ModeSocket) != 0:
+			add(rel, SeverityHigh, "special file")
+		case mode.IsRegular():
+			if mode&(os.ModeSetuid|os.ModeSetgid) != 0 {
+				add(rel, SeverityCritical, "setuid or setgid file")
+			}
+
+			if s.MaxFileSize > 0 && info.Size() > s.MaxFileSize {
+				add(rel, SeverityMedium, fmt.Sprintf("file of %d bytes exceeds the %d byte limit", info.Size(), s.MaxFileSize))
+			}
+
+			if len(s.KnownBad) > 0 {
+				sum, err := fileDigest(OSFS{}, path)
+				if err != nil {
+					return err
+				}
+
+				if reason, ok := s.KnownBad[sum]; ok {
+					add(rel, SeverityCritical, "known-bad file: "+reason)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrQueueTimeout is returned when an execution waited in the scheduler
+// queue longer than the configured queue timeout
+var ErrQueueTimeout = errors.New("timed out waiting for an execution slot")
+
+// SchedulerOptions configures the limits of a Scheduler. Zero limits are
+// unlimited.
+type SchedulerOptions struct {
+	GlobalLimit   int            // Maximum concurrent executions across all runtimes
+	RuntimeLimits map[string]int // Maximum concurrent executions per runtime
+	QueueTimeout  time.Duration  // Maximum time an execution may wait for a slot
+}
+
+// ExecutionState is the scheduling state of an execution
+type ExecutionState string
+
+const (
+	ExecutionQueued  ExecutionState = "queued"
+	ExecutionRunning ExecutionState = "running"
+)
+
+// ExecutionStatus describes a queued or running execution
+type ExecutionStatus struct {
+	ID       uint64
+	Plugin   string
+	Runtime  string
+	Priority int
+	State    ExecutionState
+	Enqueued time.Time
+	Started  time.Time
+}
+
+// Scheduler bounds how many plugin executions run at once. Executions over
+// the limits wait in a queue ordered by priority, then arrival.
+type Scheduler struct {
+	opts SchedulerOptions
+
+	mu        sync.Mutex
+	nextID    uint64
+	total     int
+	byRuntime map[string]int
+	queue     []*slot
+	running   map[uint64]*slot
+}
+
+type slot struct {
+	status ExecutionStatus
+	ready  chan struct{}
+}
+
+// NewScheduler creates a scheduler with the given limits
+func NewScheduler(opts SchedulerOptions) *Scheduler {
+	return &Scheduler{
+		opts:      opts,
+		byRuntime: make(map[string]int),
+		running:   make(map[uint64]*slot),
+	}
+}
+
+// Acquire waits for an execution slot for a plugin of the given runtime.
+// The returned function releases the slot and must be called once the
+// execution finishes. A nil scheduler imposes no limits.
+func (s *Scheduler) Acquire(ctx context.Context, runtime, plugin string, priority int) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+
+	s.nextID++
+	sl := &slot{
+		status: ExecutionStatus{
+			ID:       s.nextID,
+			Plugin:   plugin,
+			Runtime:  runtime,
+			Priority: priority,
+			State:    ExecutionQueued,
+			Enqueued: time.Now(),
+		},
+		ready: make(chan struct{}),
+	}
+
+	s.enqueue(sl)
+	s.dispatch()
+	s.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if s.opts.QueueTimeout > 0 {
+		timer := time.NewTimer(s.opts.QueueTimeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case <-sl.ready:
+		return s.releaseFunc(sl), nil
+	case <-ctx.Done():
+		return nil, s.abandon(sl, fmt.Errorf("execution cancelled while queued: %w", ctx.Err()))
+	case <-timeout:
+		return nil, s.abandon(sl, ErrQueueTimeout)
+	}
+}
+
+// Executions returns the queued and running executions, running first
+func (s *Scheduler) Executions() []ExecutionStatus {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]ExecutionStatus, 0, len(s.running)+len(s.queue))
+
+	for _, sl := range s.running {
+		result = append(result, sl.status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	for _, sl := range s.queue {
+		result = append(result, sl.status)
+	}
+
+	return result
+}
+
+// enqueue inserts a slot keeping the queue ordered by priority then arrival
+func (s *Scheduler) enqueue(sl *slot) {
+	i := sort.Search(len(s.queue), func(i int) bool {
+		return s.queue[i].status.Priority < sl.status.Priority
+	})
+
+	s.queue = append(s.queue, nil)
+	copy(s.queue[i+1:], s.queue[i:])
+	s.queue[i] = sl
+}
+
+// dispatch starts every queued execution that fits within the limits, in
+// queue order. An execution blocked by its runtime limit does not hold up
+// executions of other runtimes.
+func (s *Scheduler) dispatch() {
+	for i := 0; i < len(s.queue); {
+		if s.opts.GlobalLimit > 0 && s.total >= s.opts.GlobalLimit {
+			return
+		}
+
+		sl := s.queue[i]
+
+		if limit := s.opts.RuntimeLimits[sl.status.Runtime]; limit > 0 && s.byRuntime[sl.status.Runtime] >= limit {
+			i++
+			continue
+		}
+
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+		s.total++
+		s.byRuntime[sl.status.Runtime]++
+
+		sl.status.State = ExecutionRunning
+		sl.status.Started = time.Now()
+		s.running[sl.status.ID] = sl
+
+		close(sl.ready)
+	}
+}
+
+// abandon removes a slot that stopped waiting. If it was granted in the
+// meantime the slot is released again.
+func (s *Scheduler) abandon(sl *slot, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, queued := range s.queue {
+		if queued == sl {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return err
+		}
+	}
+
+	s.release(sl)
+
+	return err
+}
+
+func (s *Scheduler) releaseFunc(sl *slot) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.release(sl)
+		})
+	}
+}
+
+func (s *Scheduler) release(sl *slot) {
+	if _, ok := s.running[sl.status.ID]; !ok {
+		return
+	}
+
+	delete(s.running, sl.status.ID)
+	s.total--
+	s.byRuntime[sl.status.Runtime]--
+
+	s.dispatch()
+}
+
+// WithScheduler limits concurrent executions started through the Manager
+func (m *Manager) WithScheduler(scheduler *Scheduler) *Manager {
+	m.scheduler = scheduler
+	return m
+}
+
+// Scheduler returns the scheduler of the Manager, if any
+func (m *Manager) Scheduler() *Scheduler {
+	return m.scheduler
+}
@@ -0,0 +1,134 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ConfigSchemaProvider is implemented by executors that describe their
+// configuration with a JSON schema
+type ConfigSchemaProvider interface {
+	ConfigSchema() map[string]interface{}
+}
+
+// FieldError describes a configuration value that does not match its schema
+type FieldError struct {
+	Field   string // JSON pointer to the value, e.g. /security_opts/pids_limit
+	Message string
+}
+
+// ConfigError lists every field of a configuration that failed validation
+type ConfigError struct {
+	Fields []FieldError
+}
+
+func (e *ConfigError) Error() string {
+	problems := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		field := f.Field
+		if field == "" {
+			field = "/"
+		}
+
+		problems = append(problems, field+": "+f.Message)
+	}
+
+	return "invalid configuration: " + strings.Join(problems, "; ")
+}
+
+// ValidateConfig checks a configuration against a JSON schema. It returns
+// the configuration in its JSON form, with numbers as float64, lists as
+// []interface{} and objects as map[string]interface{}, so executors read
+// the same types whether the configuration was decoded from a file or
+// built in Go. Mismatches are reported as *ConfigError.
+func ValidateConfig(schema map[string]interface{}, config map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+
+	rawSchema, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration schema: %w", err)
+	}
+
+	if err := validateJSON(rawSchema, normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// DecodeConfig validates a configuration against a JSON schema and decodes
+// it into out, a pointer to a typed configuration struct whose fields are
+// tagged with the configuration keys. Keys missing from config leave the
+// corresponding fields of out untouched.
+func DecodeConfig(schema map[string]interface{}, config map[string]interface{}, out interface{}) error {
+	normalized, err := ValidateConfig(schema, config)
+	if err != nil {
+		return err
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:     out,
+		TagName:    "mapstructure",
+		ZeroFields: true, // Replace lists and maps rather than merging into them
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create configuration decoder: %w", err)
+	}
+
+	if err := decoder.Decode(normalized); err != nil {
+		return fmt.Errorf("failed to decode configuration: %w", err)
+	}
+
+	return nil
+}
+
+// validateJSON checks a decoded JSON value against an encoded schema
+func validateJSON(schema []byte, value interface{}) error {
+	const schemaURL = "schema.json"
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return fmt.Errorf("invalid configuration schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return fmt.Errorf("invalid configuration schema: %w", err)
+	}
+
+	err = compiled.Validate(value)
+
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &ConfigError{Fields: fieldErrors(validationErr, nil)}
+	}
+
+	return err
+}
+
+// fieldErrors flattens a validation error tree into its leaf causes
+func fieldErrors(err *jsonschema.ValidationError, fields []FieldError) []FieldError {
+	if len(err.Causes) == 0 {
+		return append(fields, FieldError{Field: err.InstanceLocation, Message: err.Message})
+	}
+
+	for _, cause := range err.Causes {
+		fields = fieldErrors(cause, fields)
+	}
+
+	return fields
+}
@@ -0,0 +1,254 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchHit is an installed plugin matching a SearchInstalled query
+type SearchHit struct {
+	Info  Info
+	Score float64 // Higher is a better match
+}
+
+// Weights of the fields a term can be found in
+const (
+	weightName        = 4
+	weightCommand     = 3
+	weightKeyword     = 2
+	weightDescription = 1
+)
+
+// searchIndex is an inverted index over the metadata of installed plugins
+type searchIndex struct {
+	generation uint64 // Cache generation the index was built from
+	plugins    []Info
+	terms      []string             // Sorted, for prefix lookups
+	postings   map[string][]posting // Term to the plugins containing it
+}
+
+type posting struct {
+	plugin int
+	weight float64
+}
+
+// SearchInstalled matches a query against the name, description, keywords
+// and commands of installed plugins, for instant lookups such as command
+// palettes. Every word of the query must match a word of the plugin
+// exactly, as a prefix or, for longer words, with a typo or two. Hits are
+// ranked by how well and where they matched.
+func (m *Manager) SearchInstalled(ctx context.Context, query string) ([]SearchHit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before search: %w", err)
+	}
+
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	index, err := m.installedIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return index.search(words), nil
+}
+
+// installedIndex returns the search index, rebuilding it when the metadata
+// cache saw a change since it was built
+func (m *Manager) installedIndex(ctx context.Context) (*searchIndex, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing refreshes the cache, so its generation now covers plugins
+	generation := m.cache.currentGeneration()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.searchIndex == nil || m.searchIndex.generation != generation {
+		m.searchIndex = buildSearchIndex(plugins, generation)
+	}
+
+	return m.searchIndex, nil
+}
+
+func buildSearchIndex(plugins []Info, generation uint64) *searchIndex {
+	index := &searchIndex{
+		generation: generation,
+		plugins:    plugins,
+		postings:   make(map[string][]posting),
+	}
+
+	for i, info := range plugins {
+		best := make(map[string]float64)
+
+		add := func(text string, weight float64) {
+			for _, term := range tokenize(text) {
+				if weight > best[term] {
+					best[term] = weight
+				}
+			}
+		}
+
+		add(info.Name, weightName)
+		add(info.Description, weightDescription)
+
+		for _, keyword := range info.Keywords {
+			add(keyword, weightKeyword)
+		}
+
+		for _, command := range info.Commands {
+			add(command.Name, weightCommand)
+			add(command.Summary, weightDescription)
+
+			for _, alias := range command.Aliases {
+				add(alias, weightCommand)
+			}
+		}
+
+		for term, weight := range best {
+			index.postings[term] = append(index.postings[term], posting{plugin: i, weight: weight})
+		}
+	}
+
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+
+	sort.Strings(index.terms)
+
+	return index
+}
+
+// search scores every plugin that matches all words
+func (idx *searchIndex) search(words []string) []SearchHit {
+	var scores map[int]float64
+
+	for _, word := range words {
+		matched := idx.match(word)
+		if scores == nil {
+			scores = matched
+			continue
+		}
+
+		for plugin, score := range scores {
+			if extra, ok := matched[plugin]; ok {
+				scores[plugin] = score + extra
+			} else {
+				delete(scores, plugin)
+			}
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for plugin, score := range scores {
+		hits = append(hits, SearchHit{Info: idx.plugins[plugin], Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return hits[i].Info.Name < hits[j].Info.Name
+	})
+
+	return hits
+}
+
+// match returns the best score of word against the terms of each plugin.
+// Exact matches count three times as much as fuzzy ones, prefixes twice.
+func (idx *searchIndex) match(word string) map[int]float64 {
+	scores := make(map[int]float64)
+
+	record := func(term string, quality float64) {
+		for _, p := range idx.postings[term] {
+			if score := quality * p.weight; score > scores[p.plugin] {
+				scores[p.plugin] = score
+			}
+		}
+	}
+
+	for i := sort.SearchStrings(idx.terms, word); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		if idx.terms[i] == word {
+			record(word, 3)
+		} else {
+			record(idx.terms[i], 2)
+		}
+	}
+
+	if maxEdits := allowedEdits(word); maxEdits > 0 {
+		for _, term := range idx.terms {
+			if !strings.HasPrefix(term, word) && withinDistance(word, term, maxEdits) {
+				record(term, 1)
+			}
+		}
+	}
+
+	return scores
+}
+
+// allowedEdits is the number of typos tolerated in a query word
+func allowedEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b
+// is at most limit
+func withinDistance(a, b string, limit int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return false
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+
+		if rowMin > limit {
+			return false
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)] <= limit
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
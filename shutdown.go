@@ -0,0 +1,124 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed is returned when executing through a Manager that was closed
+var ErrClosed = errors.New("manager is closed")
+
+// Close stops the Manager from starting new executions, waits for the ones
+// in flight until ctx is done and then closes every executor registered with
+// WithExecutor that implements Closer. Executors are shared with managers
+// returned by WithProfile, so close those first.
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+
+	m.closed = true
+
+	executors := make(map[string]Executor, len(m.executors))
+	for name, executor := range m.executors {
+		executors[name] = executor
+	}
+	m.mu.Unlock()
+
+	var errs []error
+
+	if err := waitContext(ctx, &m.inflight); err != nil {
+		errs = append(errs, fmt.Errorf("failed to wait for in-flight executions: %w", err))
+	}
+
+	m.logger.V(1).Info("closing executors", "executors", len(executors))
+
+	errs = append(errs, closeExecutors(ctx, executors)...)
+
+	return errors.Join(errs...)
+}
+
+// Close closes every registered executor and runtime that implements Closer
+func (r *Registry) Close(ctx context.Context) error {
+	r.mu.RLock()
+	executors := make(map[string]Executor, len(r.executors))
+	for name, executor := range r.executors {
+		executors[name] = executor
+	}
+
+	var closers []runtimeCloser
+	for name, runtime := range r.runtimes {
+		if closer, ok := runtime.(Closer); ok {
+			closers = append(closers, runtimeCloser{name: name, closer: closer})
+		}
+	}
+	r.mu.RUnlock()
+
+	errs := closeExecutors(ctx, executors)
+
+	for _, rc := range closers {
+		if err := rc.closer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close runtime %s: %w", rc.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type runtimeCloser struct {
+	name   string
+	closer Closer
+}
+
+// beginExecution registers an in-flight execution unless the Manager is
+// closed. The caller must call m.inflight.Done when it finishes.
+func (m *Manager) beginExecution() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	m.inflight.Add(1)
+
+	return nil
+}
+
+func closeExecutors(ctx context.Context, executors map[string]Executor) []error {
+	var errs []error
+
+	for name, executor := range executors {
+		closer, ok := executor.(Closer)
+		if !ok {
+			continue
+		}
+
+		if err := closer.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close executor %s: %w", name, err))
+		}
+	}
+
+	return errs
+}
+
+// waitContext waits for wg until ctx is done
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
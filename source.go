@@ -0,0 +1,152 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Reference identifies a plugin in a specific source, written as
+// [store/]name[@version]
+type Reference struct {
+	Store   string
+	Name    string
+	Version string
+}
+
+func (r Reference) String() string {
+	s := r.Name
+	if r.Store != "" {
+		s = r.Store + "/" + s
+	}
+
+	if r.Version != "" {
+		s += "@" + r.Version
+	}
+
+	return s
+}
+
+// ParseReference splits a plugin reference into its parts. The leading
+// path segment is only treated as a store name when isStore reports it as
+// one, since plugin names may themselves contain slashes (owner/repo).
+func ParseReference(ref string, isStore func(string) bool) Reference {
+	var r Reference
+
+	if i := strings.LastIndex(ref, "@"); i > 0 {
+		ref, r.Version = ref[:i], ref[i+1:]
+	}
+
+	if before, after, ok := strings.Cut(ref, "/"); ok && isStore != nil && isStore(before) {
+		r.Store, ref = before, after
+	}
+
+	r.Name = ref
+
+	return r
+}
+
+// WithRegistry makes every store of the registry available to Search and
+// Install, in addition to the manager's default store
+func (m *Manager) WithRegistry(registry *Registry) *Manager {
+	m.registry = registry
+	return m
+}
+
+// parseReference parses a plugin reference against the configured stores
+func (m *Manager) parseReference(ref string) Reference {
+	return ParseReference(ref, func(name string) bool {
+		if m.registry == nil {
+			return false
+		}
+
+		_, ok := m.registry.GetStore(name)
+
+		return ok
+	})
+}
+
+// storeFor returns the named store, or the default store when name is empty
+func (m *Manager) storeFor(name string) (Store, error) {
+	if name == "" {
+		if m.store == nil {
+			return nil, fmt.Errorf("no default store configured")
+		}
+
+		return m.store, nil
+	}
+
+	if m.registry != nil {
+		if store, ok := m.registry.GetStore(name); ok {
+			return store, nil
+		}
+	}
+
+	return nil, fmt.Errorf("store %s is not registered", name)
+}
+
+// searchSources queries every configured store and merges the results. A
+// plugin offered by several stores is reported once, from the first store
+// that offers it, with all of them recorded in Sources.
+func (m *Manager) searchSources(ctx context.Context, criteria SearchOptions) ([]Info, error) {
+	type source struct {
+		name  string
+		store Store
+	}
+
+	var sources []source
+
+	if m.store != nil {
+		sources = append(sources, source{store: m.store})
+	}
+
+	if m.registry != nil {
+		for _, name := range m.registry.Stores() {
+			store, _ := m.registry.GetStore(name)
+			if m.store != nil && store == m.store {
+				sources[0].name = name
+				continue
+			}
+
+			sources = append(sources, source{name: name, store: store})
+		}
+	}
+
+	var (
+		results []Info
+		index   = make(map[string]int)
+		errs    []error
+	)
+
+	for _, src := range sources {
+		available, err := src.store.Search(ctx, criteria)
+		if err != nil {
+			m.logger.Error(err, "store search failed", "store", src.name)
+			errs = append(errs, err)
+
+			continue
+		}
+
+		for _, info := range available {
+			name := src.name
+			if name == "" {
+				name = info.Store
+			}
+
+			if i, ok := index[info.Name]; ok {
+				results[i].Sources = append(results[i].Sources, name)
+				continue
+			}
+
+			info.Sources = []string{name}
+			index[info.Name] = len(results)
+			results = append(results, info)
+		}
+	}
+
+	if len(errs) > 0 && len(errs) == len(sources) {
+		return nil, errs[0]
+	}
+
+	return results, nil
+}
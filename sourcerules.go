@@ -0,0 +1,92 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SourceRules allows or denies plugins by where they come from, for hosts
+// that do not need a full Policy. Deny lists win over allow lists. A
+// non-empty allow list admits only what it matches. Owners and stores
+// compare case-insensitively; every entry may be a glob pattern.
+type SourceRules struct {
+	AllowOwners []string `mapstructure:"allow_owners"` // Users or organizations publishing plugins
+	DenyOwners  []string `mapstructure:"deny_owners"`
+	AllowStores []string `mapstructure:"allow_stores"` // Configured store names or store types, e.g. github
+	DenyStores  []string `mapstructure:"deny_stores"`
+	AllowNames  []string `mapstructure:"allow_names"` // Plugin names, local or upstream, e.g. acme/*
+	DenyNames   []string `mapstructure:"deny_names"`
+}
+
+// WithSourceRules enforces rules before every install, upgrade and
+// execution, ahead of the policy set with WithPolicy
+func (m *Manager) WithSourceRules(rules SourceRules) *Manager {
+	m.sourceRules = &rules
+	return m
+}
+
+// Evaluate implements Policy, so rules can also be combined with other
+// policies by the host
+func (r *SourceRules) Evaluate(_ context.Context, input PolicyInput) (PolicyDecision, error) {
+	var reasons []string
+
+	stores := []string{strings.ToLower(input.Store)}
+	if input.Source != "" {
+		stores = append(stores, strings.ToLower(input.Source))
+	}
+
+	names := []string{input.Plugin}
+	if input.Upstream != "" {
+		names = append(names, input.Upstream)
+	}
+
+	owner := strings.ToLower(input.Owner)
+
+	if input.Owner != "" && matchAny(lower(r.DenyOwners), owner) {
+		reasons = append(reasons, fmt.Sprintf("owner %s is denied", input.Owner))
+	} else if len(r.AllowOwners) > 0 && (input.Owner == "" || !matchAny(lower(r.AllowOwners), owner)) {
+		reasons = append(reasons, fmt.Sprintf("owner %q is not allowed", input.Owner))
+	}
+
+	if matchAny(lower(r.DenyStores), stores...) {
+		reasons = append(reasons, fmt.Sprintf("store %s is denied", input.Store))
+	} else if len(r.AllowStores) > 0 && !matchAny(lower(r.AllowStores), stores...) {
+		reasons = append(reasons, fmt.Sprintf("store %s is not allowed", input.Store))
+	}
+
+	if matchAny(r.DenyNames, names...) {
+		reasons = append(reasons, fmt.Sprintf("plugin name %s is denied", input.Plugin))
+	} else if len(r.AllowNames) > 0 && !matchAny(r.AllowNames, names...) {
+		reasons = append(reasons, fmt.Sprintf("plugin name %s is not allowed", input.Plugin))
+	}
+
+	return PolicyDecision{Allow: len(reasons) == 0, Reasons: reasons}, nil
+}
+
+// matchAny reports whether any of values matches any of patterns
+func matchAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if pattern == value {
+				return true
+			}
+
+			if matched, _ := filepath.Match(pattern, value); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func lower(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+
+	return lowered
+}
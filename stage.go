@@ -0,0 +1,128 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StageResult reports the outcome of staging a plugin for one platform
+type StageResult struct {
+	Platform Platform
+	Dir      string // Plugin directory inside the staging tree
+	Info     *Info
+	Err      error
+}
+
+// Stage fetches a plugin for each of the given platforms in parallel and
+// writes one plugin tree per platform under destDir/<os>-<arch>/<name>, with
+// the same layout Install produces. It lets provisioning tools pre-stage
+// plugins for machines other than the local one. Results are returned in the
+// order of platforms; the error joins every per-platform failure.
+func (m *Manager) Stage(ctx context.Context, name, version string, platforms []Platform, destDir string) ([]StageResult, error) {
+	ref := m.parseReference(name)
+	if ref.Version != "" && (version == "" || version == "latest") {
+		version = ref.Version
+	}
+
+	store, err := m.storeFor(ref.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(platforms) == 0 {
+		platforms = []Platform{CurrentPlatform()}
+	}
+
+	results := make([]StageResult, len(platforms))
+
+	var wg sync.WaitGroup
+
+	for i, platform := range platforms {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			dir := filepath.Join(destDir, platform.OS+"-"+platform.Arch, ref.Name)
+			info, err := m.stagePlatform(ctx, store, ref.Name, version, platform, dir)
+
+			results[i] = StageResult{Platform: platform, Dir: dir, Info: info, Err: err}
+		}()
+	}
+
+	wg.Wait()
+
+	var errs []error
+
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Platform, result.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// stagePlatform fetches and writes a plugin tree for a single platform
+func (m *Manager) stagePlatform(ctx context.Context, store Store, name, version string, platform Platform, dir string) (*Info, error) {
+	logger := m.logger.WithValues("plugin", name, "version", version, "platform", platform.String(), "dir", dir)
+	logger.V(1).Info("staging plugin")
+
+	if _, err := m.fs.Stat(dir); err == nil {
+		return nil, fmt.Errorf("staging directory %s already exists", dir)
+	}
+
+	info, err := store.Fetch(ContextWithPlatform(m.fetchContext(ctx), platform), name, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plugin: %w", err)
+	}
+
+	if err := m.fs.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	if err := writePluginFiles(m.extractionContext(ctx), m.fs, logger, dir, info); err != nil {
+		m.fs.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to write plugin files: %w", err)
+	}
+
+	if info.Version == "" {
+		info.Version = version
+	}
+
+	info.Content = nil
+	info.Status = StatusEnabled
+	info.Metadata = map[string]string{
+		"staged":   time.Now().Format(time.RFC3339),
+		"platform": platform.String(),
+	}
+
+	if sum, err := fileDigest(m.fs, binaryPath(dir, info)); err == nil {
+		info.Metadata["binary_sha256"] = sum
+	}
+
+	metadataBytes, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		m.fs.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := writeMetadata(m.fs, filepath.Join(dir, "metadata.json"), metadataBytes); err != nil {
+		m.fs.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	if err := m.signMetadata(filepath.Join(dir, "metadata.json"), metadataBytes); err != nil {
+		m.fs.RemoveAll(dir)
+		return nil, err
+	}
+
+	logger.V(1).Info("staged plugin")
+
+	return info, nil
+}
@@ -0,0 +1,174 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// Status is the status of a plugin. Installed plugins are enabled, disabled
+// or quarantined; search results are available or installed.
+type Status string
+
+const (
+	StatusAvailable   Status = "available"   // Offered by a store and not installed
+	StatusInstalled   Status = "installed"   // Offered by a store and already installed
+	StatusEnabled     Status = "enabled"     // Installed and allowed to run
+	StatusDisabled    Status = "disabled"    // Installed and turned off by the user
+	StatusQuarantined Status = "quarantined" // Installed and excluded from execution until cleared
+)
+
+// transitions lists the statuses a plugin may move to from each status.
+// Quarantine is only cleared with Unquarantine, which restores the status
+// the plugin had before.
+var transitions = map[Status][]Status{
+	StatusAvailable:   {StatusInstalled, StatusEnabled, StatusDisabled},
+	StatusInstalled:   {StatusEnabled, StatusDisabled, StatusQuarantined},
+	StatusEnabled:     {StatusDisabled, StatusQuarantined},
+	StatusDisabled:    {StatusEnabled, StatusQuarantined},
+	StatusQuarantined: {StatusEnabled, StatusDisabled},
+}
+
+// Valid reports whether s is a known status
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a plugin with status s may move to status
+// to. Staying in the same status is always allowed.
+func (s Status) CanTransitionTo(to Status) bool {
+	s = s.orDefault()
+	if s == to {
+		return true
+	}
+
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orDefault treats the empty status of metadata written before statuses
+// were recorded as enabled, which is how execution has always treated it
+func (s Status) orDefault() Status {
+	if s == "" {
+		return StatusEnabled
+	}
+
+	return s
+}
+
+// ErrInvalidTransition is returned when a plugin cannot move from its
+// current status to the requested one
+type ErrInvalidTransition struct {
+	Plugin string
+	From   Status
+	To     Status
+}
+
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("plugin %s cannot go from %s to %s", e.Plugin, e.From.orDefault(), e.To)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrInvalidTransition) UserMessage() Message {
+	return Message{Key: MsgInvalidTransition, Params: map[string]string{
+		"plugin": e.Plugin, "from": string(e.From.orDefault()), "to": string(e.To),
+	}}
+}
+
+func validateTransition(name string, from, to Status) error {
+	if !to.Valid() {
+		return fmt.Errorf("invalid status %q for plugin %s", to, name)
+	}
+
+	if !from.CanTransitionTo(to) {
+		return &ErrInvalidTransition{Plugin: name, From: from, To: to}
+	}
+
+	return nil
+}
+
+// ErrNotInstalled is returned when an operation targets a plugin that is not
+// installed
+type ErrNotInstalled struct {
+	Plugin string
+}
+
+func (e *ErrNotInstalled) Error() string {
+	return fmt.Sprintf("plugin %s is not installed", e.Plugin)
+}
+
+// UserMessage implements UserMessager
+func (e *ErrNotInstalled) UserMessage() Message {
+	return Message{Key: MsgNotInstalled, Params: map[string]string{"plugin": e.Plugin}}
+}
+
+// SetStatus enables or disables several plugins. Plugins that already have
+// the status are left unchanged. Every plugin is attempted; the errors of
+// those that could not be updated are joined.
+func (m *Manager) SetStatus(ctx context.Context, names []string, status Status) error {
+	if status != StatusEnabled && status != StatusDisabled {
+		return wrapOp(OpSetStatus, "", "", fmt.Errorf("cannot set status %q, expected %s or %s", status, StatusEnabled, StatusDisabled))
+	}
+
+	var errs []error
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return wrapOp(OpSetStatus, "", "", fmt.Errorf("context cancelled while setting plugin status: %w", err))
+		}
+
+		if err := m.lockedSetStatus(name, status); err != nil {
+			errs = append(errs, wrapOp(OpSetStatus, name, m.storeOf(name), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) lockedSetStatus(name string, status Status) error {
+	defer m.plugins.lock(name)()
+
+	return m.setStatus(name, status)
+}
+
+// setStatus records a new status for a plugin. Setting the status a plugin
+// already has is a no-op. The caller must hold the plugin's lock.
+func (m *Manager) setStatus(name string, status Status) error {
+	if err := m.checkWritable(name); err != nil {
+		return err
+	}
+
+	info, err := readMetadata(m.fs, filepath.Join(m.pluginDir, name, "metadata.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ErrNotInstalled{Plugin: name}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	if info.Status.orDefault() == status {
+		return nil
+	}
+
+	if info.Status == StatusQuarantined {
+		return fmt.Errorf("plugin %s is quarantined, call Unquarantine to clear it", name)
+	}
+
+	if err := validateTransition(name, info.Status, status); err != nil {
+		return err
+	}
+
+	return m.updateMetadata(name, func(info *Info) error {
+		info.Status = status
+		return nil
+	})
+}
@@ -7,6 +7,17 @@ type StoreConfig map[string]interface{}
 
 type SearchOptions map[string]string
 
+// Search criteria understood by stores. Stores ignore the criteria they do
+// not support.
+const (
+	SearchQuery        = "query"         // Free text matched against names and descriptions
+	SearchOwner        = "owner"         // Publishing user or organization
+	SearchLanguage     = "language"      // Language the plugin is written in
+	SearchMinStars     = "min-stars"     // Minimum number of stars
+	SearchUpdatedSince = "updated-since" // Date of the oldest last update, YYYY-MM-DD
+	SearchLimit        = "limit"         // Maximum number of results
+)
+
 type Store interface {
 	Setup(config StoreConfig) error
 	Fetch(ctx context.Context, name string, version string) (*Info, error)
@@ -0,0 +1,54 @@
+package extension
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// maxAttestationSize bounds the size of SBOM and provenance assets
+const maxAttestationSize = 16 << 20
+
+// fetchAttestations downloads the SBOM and provenance assets of a release.
+// Attestations that cannot be downloaded are skipped, which leaves the
+// decision to the manager's provenance policy.
+func (s *GitHubStore) fetchAttestations(ctx context.Context, owner, repo string, release *github.RepositoryRelease) []Attestation {
+	var attestations []Attestation
+
+	for _, asset := range release.Assets {
+		kind, format, ok := ClassifyAttestation(asset.GetName())
+		if !ok {
+			continue
+		}
+
+		if asset.GetSize() > maxAttestationSize {
+			s.log.V(1).Info("skipping oversized attestation", "name", asset.GetName(), "size", asset.GetSize())
+			continue
+		}
+
+		rc, _, err := s.client.Repositories.DownloadReleaseAsset(ctx, owner, repo, asset.GetID(), s.client.Client())
+		if err != nil {
+			s.log.Error(err, "failed to download attestation", "name", asset.GetName())
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(rc, maxAttestationSize))
+		rc.Close()
+		if err != nil {
+			s.log.Error(err, "failed to read attestation", "name", asset.GetName())
+			continue
+		}
+
+		s.log.V(1).Info("downloaded attestation", "name", asset.GetName(), "kind", kind, "format", format)
+
+		attestations = append(attestations, Attestation{
+			Name:    asset.GetName(),
+			Kind:    kind,
+			Format:  format,
+			Content: content,
+		})
+	}
+
+	return attestations
+}
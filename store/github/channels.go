@@ -0,0 +1,53 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// Channels implements ChannelStore. Releases marked as pre-releases are on
+// the beta channel at least, even when their tag carries no pre-release
+// suffix; drafts are left out.
+func (s *GitHubStore) Channels(ctx context.Context, name string) (map[string]Channel, error) {
+	owner, repoName, err := s.parseName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make(map[string]Channel)
+
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		releases, resp, err := s.client.Repositories.ListReleases(ctx, owner, repoName, opts)
+		if err != nil {
+			s.log.Error(err, "failed to list releases", "owner", owner, "repo", repoName)
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+
+		for _, release := range releases {
+			if release.GetDraft() {
+				continue
+			}
+
+			tag := release.GetTagName()
+
+			channel := VersionChannel(tag)
+			if release.GetPrerelease() && channel == ChannelStable {
+				channel = ChannelBeta
+			}
+
+			channels[tag] = channel
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return channels, nil
+}
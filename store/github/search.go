@@ -0,0 +1,199 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// maxSearchPage is the largest page the search API returns
+const maxSearchPage = 100
+
+// searchQuery translates search criteria into a repository search query
+// restricted to the store's topic, with the sort order and the maximum
+// number of results. A limit of 0 keeps the first page.
+func searchQuery(topic string, criteria SearchOptions) (string, *github.SearchOptions, int, error) {
+	terms := []string{fmt.Sprintf("topic:%s", topic), "fork:false"}
+
+	if text := strings.TrimSpace(criteria[SearchQuery]); text != "" {
+		terms = append([]string{text}, terms...)
+	}
+
+	if owner := criteria[SearchOwner]; owner != "" {
+		terms = append(terms, "user:"+owner)
+	}
+
+	if language := criteria[SearchLanguage]; language != "" {
+		terms = append(terms, "language:"+language)
+	}
+
+	if value := criteria[SearchMinStars]; value != "" {
+		stars, err := strconv.Atoi(value)
+		if err != nil || stars < 0 {
+			return "", nil, 0, fmt.Errorf("invalid %s criterion %q", SearchMinStars, value)
+		}
+
+		terms = append(terms, fmt.Sprintf("stars:>=%d", stars))
+	}
+
+	if value := criteria[SearchUpdatedSince]; value != "" {
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return "", nil, 0, fmt.Errorf("invalid %s criterion %q, expected YYYY-MM-DD", SearchUpdatedSince, value)
+		}
+
+		terms = append(terms, "pushed:>="+value)
+	}
+
+	limit := 0
+	if value := criteria[SearchLimit]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return "", nil, 0, fmt.Errorf("invalid %s criterion %q", SearchLimit, value)
+		}
+
+		limit = n
+	}
+
+	opts := &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: maxSearchPage},
+	}
+
+	if limit > 0 && limit < maxSearchPage {
+		opts.PerPage = limit
+	}
+
+	// Orders the API cannot apply are left to the manager, which sorts the
+	// results it receives
+	switch criteria[SearchSort] {
+	case SortStars:
+		opts.Sort, opts.Order = "stars", "desc"
+	case SortUpdated:
+		opts.Sort, opts.Order = "updated", "desc"
+	}
+
+	return strings.Join(terms, " "), opts, limit, nil
+}
+
+// defaultSearchConcurrency bounds the release lookups Search runs at once
+const defaultSearchConcurrency = 8
+
+// resolveReleases turns repositories into search results, looking up their
+// latest releases on a bounded number of workers. Repositories whose
+// release cannot be found are left out without failing the search. In lazy
+// mode no release is looked up and the results carry no version; Fetch
+// resolves it when the plugin is requested.
+func (s *GitHubStore) resolveReleases(ctx context.Context, repos []*github.Repository) []Info {
+	results := make([]*Info, len(repos))
+
+	if s.lazyReleases {
+		for i, repo := range repos {
+			results[i] = s.searchResult(repo, nil)
+		}
+	} else {
+		var wg sync.WaitGroup
+
+		sem := make(chan struct{}, max(s.searchConcurrency, 1))
+
+		for i, repo := range repos {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+
+			if ctx.Err() != nil {
+				break
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				results[i] = s.resolveRelease(ctx, repo)
+			}()
+		}
+
+		wg.Wait()
+	}
+
+	plugins := make([]Info, 0, len(results))
+	for _, info := range results {
+		if info != nil {
+			plugins = append(plugins, *info)
+		}
+	}
+
+	return plugins
+}
+
+// resolveRelease looks up the latest release of a repository, returning
+// nil when it has none or the lookup fails
+func (s *GitHubStore) resolveRelease(ctx context.Context, repo *github.Repository) *Info {
+	s.log.V(1).Info("latest release", "owner", repo.GetOwner().GetLogin(), "repo", repo.GetName())
+
+	release, resp, err := s.client.Repositories.GetLatestRelease(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+
+		s.log.Error(err, "failed to fetch release", "repo", repo.GetFullName(), "status", status)
+
+		return nil
+	}
+
+	return s.searchResult(repo, release)
+}
+
+// searchResult describes a repository found by Search. Without a release
+// the version and runtime are left for Fetch to determine.
+func (s *GitHubStore) searchResult(repo *github.Repository, release *github.RepositoryRelease) *Info {
+	s.log.V(1).Info("processing repository", "name", repo.GetFullName(), "stars", repo.GetStargazersCount(), "created_at", repo.GetCreatedAt().String())
+
+	info := &Info{
+		Name:        repo.GetName(),
+		Description: repo.GetDescription(),
+		Store:       "github",
+		Metadata:    repositoryMetadata(repo),
+		Popularity:  releasePopularity(repo, release),
+	}
+
+	if release == nil {
+		return info
+	}
+
+	// Use Filter function to get valid assets
+	getAssetNames := func() []string {
+		names := make([]string, len(release.Assets))
+		for i, asset := range release.Assets {
+			names[i] = asset.GetName()
+		}
+
+		return names
+	}
+
+	validAssets := Filter(s.prefix, repo.GetName(), release.GetTagName(), getAssetNames)
+
+	// Determine runtime based on valid assets
+	info.Runtime = "exec"
+
+	for _, asset := range validAssets {
+		if AssetRuntime(asset) == "wasm" {
+			info.Runtime = "wasm"
+			break
+		}
+	}
+
+	info.Version = release.GetTagName()
+
+	s.log.V(1).Info("added plugin to results", "name", repo.GetName(), "version", info.Version)
+
+	return info
+}
@@ -4,42 +4,60 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"runtime"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
 )
 
 var _ Store = &GitHubStore{}
 
+// readmeExcerptLength bounds the README text included in plugin metadata
+const readmeExcerptLength = 4096
+
 // GitHubStore implements the Store interface for GitHub-hosted plugins
 type GitHubStore struct {
 	client *github.Client
+	token  string
 	topic  string
 	prefix string
 	log    logr.Logger
+
+	searchConcurrency int  // Release lookups run in parallel by Search
+	lazyReleases      bool // Search skips release lookups, Fetch resolves them
 }
 
 // NewGitHubStore creates a new GitHub plugin store
 func NewGitHubStore(token string, logger logr.Logger) *GitHubStore {
-	var client *github.Client
+	return &GitHubStore{
+		client: newGitHubClient(token, nil),
+		token:  token,
+		log:    logger,
+	}
+}
 
-	if token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		tc := oauth2.NewClient(context.Background(), ts)
-		client = github.NewClient(tc)
-	} else {
-		client = github.NewClient(nil)
+// newGitHubClient creates a GitHub client authenticated with token on top of
+// the given base HTTP client
+func newGitHubClient(token string, base *http.Client) *github.Client {
+	if token == "" {
+		return github.NewClient(base)
 	}
 
-	return &GitHubStore{
-		client: client,
-		log:    logger,
+	ctx := context.Background()
+	if base != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, base)
 	}
+
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+
+	return github.NewClient(oauth2.NewClient(ctx, ts))
 }
 
 // Setup configures the store with specific parameters
@@ -58,34 +76,80 @@ func (s *GitHubStore) Setup(config StoreConfig) error {
 
 	s.prefix = prefix
 
+	// Release lookups Search runs at once, and whether it leaves them to
+	// Fetch so that large catalogs list quickly
+	s.searchConcurrency = defaultSearchConcurrency
+	switch n := config["search_concurrency"].(type) {
+	case int:
+		s.searchConcurrency = n
+	case float64:
+		s.searchConcurrency = int(n)
+	}
+
+	if s.searchConcurrency < 1 {
+		return fmt.Errorf("search_concurrency must be at least 1")
+	}
+
+	s.lazyReleases, _ = config["lazy_releases"].(bool)
+
+	// Client certificates, custom CA bundles and proxy overrides
+	if transportOpts := client.TransportOptionsFromConfig(config); !transportOpts.IsZero() {
+		transport, err := client.NewTransport(transportOpts)
+		if err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+
+		s.client = newGitHubClient(s.token, &http.Client{Transport: transport})
+	}
+
+	// GitHub Enterprise Server, or a local server replaying recorded
+	// responses in tests
+	if baseURL, ok := config["base_url"].(string); ok && baseURL != "" {
+		u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+		if err != nil {
+			return fmt.Errorf("invalid base_url: %w", err)
+		}
+
+		s.client.BaseURL = u
+	}
+
 	return nil
 }
 
-// Fetch retrieves information about a specific plugin
-func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
-	s.log.Info("starting fetch", "name", name, "version", version)
-
+// parseName splits a plugin name into the owner and name of its repository
+func (s *GitHubStore) parseName(name string) (string, string, error) {
 	parts := strings.Split(name, "/")
 	if len(parts) != 2 {
 		s.log.Error(nil, "invalid name format", "name", name)
-		return nil, fmt.Errorf("plugin name must be in format 'owner/name'")
+		return "", "", fmt.Errorf("plugin name must be in format 'owner/name'")
 	}
 
 	pluginName := parts[1]
 	if !strings.HasPrefix(parts[1], s.prefix) {
 		s.log.Error(nil, "invalid plugin name prefix", "expected", s.prefix, "got", pluginName)
-		return nil, fmt.Errorf("plugin name must have second part starting with %s", s.prefix)
+		return "", "", fmt.Errorf("plugin name must have second part starting with %s", s.prefix)
 	}
 
 	// Extract owner and repo name
 	parts = strings.SplitN(strings.TrimPrefix(name, s.prefix), "/", 2)
 	if len(parts) != 2 {
 		s.log.Error(nil, "invalid plugin name format", "name", name)
-		return nil, fmt.Errorf("invalid plugin name format, expected %s<owner>/<repo>", s.prefix)
+		return "", "", fmt.Errorf("invalid plugin name format, expected %s<owner>/<repo>", s.prefix)
 	}
 
-	owner, repoName := parts[0], parts[1]
-	s.log.Info("parsed plugin name", "owner", owner, "repo", repoName)
+	s.log.V(1).Info("parsed plugin name", "owner", parts[0], "repo", parts[1])
+
+	return parts[0], parts[1], nil
+}
+
+// Fetch retrieves information about a specific plugin
+func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	s.log.Info("starting fetch", "name", name, "version", version)
+
+	owner, repoName, err := s.parseName(name)
+	if err != nil {
+		return nil, err
+	}
 
 	repo, _, err := s.client.Repositories.Get(ctx, owner, repoName)
 	if err != nil {
@@ -93,85 +157,84 @@ func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*
 		return nil, fmt.Errorf("failed to fetch repository: %w", err)
 	}
 
-	s.log.Info("successfully fetched repository",
+	s.log.V(1).Info("successfully fetched repository",
 		"owner", owner,
 		"repo", repoName,
 		"description", repo.GetDescription())
 
 	var releaseVersion string
-	var assetNames []string
 	rt := "exec" // default
 
-	s.log.Info("releases url", "url", repo.GetReleasesURL())
+	s.log.V(1).Info("releases url", "url", repo.GetReleasesURL())
 
 	// Check releases first
 	var release *github.RepositoryRelease
 	if version == "" || version == "latest" {
 		release, _, err = s.client.Repositories.GetLatestRelease(ctx, owner, repoName)
-		if err != nil {
+		if err != nil && !isNotFound(err) {
 			s.log.Error(err, "failed to fetch latest release", "owner", owner, "repo", repoName)
 			return nil, fmt.Errorf("failed to fetch latest release: %w", err)
 		}
 	} else {
 		release, _, err = s.client.Repositories.GetReleaseByTag(ctx, owner, repoName, version)
-		if err != nil {
+		if err != nil && !isNotFound(err) {
 			s.log.Error(err, "failed to fetch release by tag", "owner", owner, "repo", repoName, "tag", version)
 			return nil, fmt.Errorf("failed to fetch release by tag: %w", err)
 		}
 	}
 
-	// Helper function to get asset names from a release
-	getAssetNames := func() []string {
-		for _, asset := range release.Assets {
-			assetNames = append(assetNames, asset.GetName())
-		}
-
-		return assetNames
-	}
-
-	var content interface{}
+	var (
+		content          interface{}
+		fallbackFileName string
+		attestations     []Attestation
+	)
 
-	if err == nil && release != nil {
-		s.log.Info("found release", "tag", release.GetTagName(), "assets", len(release.Assets), "created_at", release.GetCreatedAt().String())
+	if release != nil {
+		s.log.V(1).Info("found release", "tag", release.GetTagName(), "assets", len(release.Assets), "created_at", release.GetCreatedAt().String())
 
 		releaseVersion = release.GetTagName()
+		platform := PlatformFromContext(ctx)
+
+		candidates := make([]AssetCandidate, len(release.Assets))
+		for i, asset := range release.Assets {
+			candidates[i] = AssetCandidate{Name: asset.GetName(), Size: int64(asset.GetSize())}
+		}
 
-		match, rtAsset, err := FindAsset(
+		match, rtAsset, err := SelectAsset(
+			s.log,
 			s.prefix,
 			repoName,
 			releaseVersion,
-			runtime.GOOS,
-			runtime.GOARCH,
-			getAssetNames,
+			platform.OS,
+			platform.Arch,
+			AssetPreferenceFromContext(ctx),
+			candidates,
 		)
 		if err != nil {
 			s.log.Error(err, "error finding matching asset")
 			return nil, err
 		}
 
-		s.log.Info("found matching asset", "name", match, "runtime", rtAsset)
+		s.log.V(1).Info("found matching asset", "name", match, "runtime", rtAsset)
 
 		rt = rtAsset
 
 		// Download the matching asset
 		for _, asset := range release.Assets {
 			if asset.GetName() == match {
-				s.log.Info("downloading asset", "name", asset.GetName(), "size", asset.GetSize())
+				s.log.V(1).Info("downloading asset", "name", asset.GetName(), "size", asset.GetSize())
 
 				httpclient := s.client.Client()
 
-				rc, resp, err := s.client.Repositories.DownloadReleaseAsset(ctx, owner, repoName, asset.GetID(), httpclient)
+				rc, redirectURL, err := s.client.Repositories.DownloadReleaseAsset(ctx, owner, repoName, asset.GetID(), httpclient)
 				if err != nil {
 					return nil, fmt.Errorf("failed to download asset: %w", err)
 				}
 
-				s.log.Info("resp", "resp", resp)
-
-				//defer rc.Close()
-
-				s.log.Info("rc", "rc", rc)
+				s.log.V(1).Info("asset download started", "redirect", redirectURL)
 
 				content, err = io.ReadAll(rc)
+				rc.Close()
 				if err != nil {
 					return nil, fmt.Errorf("failed to read asset content: %w", err)
 				}
@@ -179,41 +242,111 @@ func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*
 				break
 			}
 		}
+
+		attestations = s.fetchAttestations(ctx, owner, repoName, release)
 	} else {
-		s.log.Info("no release found", "err", err)
-		// Fallback to checking root directory if no release found
-		rc, resp, err := s.client.Repositories.DownloadContents(ctx, owner, repoName, fmt.Sprintf("%s-%s", s.prefix, repoName), nil)
-		if err == nil && resp.StatusCode == 200 {
-			releaseVersion = "main"
-			// Download the content
-			defer rc.Close()
-
-			content, err = io.ReadAll(rc)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read content: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("no release or root executable found")
+		s.log.V(1).Info("no release found, falling back to tags", "owner", owner, "repo", repoName)
+
+		artifact, err := s.fetchFromTag(ctx, owner, repoName, version)
+		if err != nil {
+			return nil, fmt.Errorf("no release found and tag fallback failed: %w", err)
 		}
+
+		releaseVersion = artifact.tag
+		content = artifact.content
+		rt = artifact.runtime
+		fallbackFileName = artifact.fileName
 	}
 
 	s.log.Info("successfully fetched plugin", "name", repo.GetName(), "version", releaseVersion, "runtime", rt)
 
+	var deprecation *Deprecation
+
+	metadata := repositoryMetadata(repo)
+	if release != nil {
+		metadata["release_notes"] = release.GetBody()
+		deprecation = ParseDeprecation(release.GetBody())
+	}
+
+	popularity := releasePopularity(repo, release)
+
+	if readme := s.readmeExcerpt(ctx, owner, repoName); readme != "" {
+		metadata["readme"] = readme
+	}
+
 	return &Info{
-		Name:        repo.GetName(),
-		Version:     releaseVersion,
-		Description: repo.GetDescription(),
-		Store:       "github",
-		Runtime:     rt,
-		Content:     content,
-		Metadata: map[string]string{
-			"owner":      repo.GetOwner().GetLogin(),
-			"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
-			"repository": repo.GetHTMLURL(),
-		},
+		Name:         repo.GetName(),
+		Version:      releaseVersion,
+		Description:  repo.GetDescription(),
+		Store:        "github",
+		FileName:     fallbackFileName,
+		Runtime:      rt,
+		Content:      content,
+		Metadata:     metadata,
+		Attestations: attestations,
+		Deprecation:  deprecation,
+		Popularity:   popularity,
 	}, nil
 }
 
+// repositoryMetadata collects the detail page fields available on a
+// repository without extra API calls
+func repositoryMetadata(repo *github.Repository) map[string]string {
+	metadata := map[string]string{
+		"owner":      repo.GetOwner().GetLogin(),
+		"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
+		"repository": repo.GetHTMLURL(),
+	}
+
+	if homepage := repo.GetHomepage(); homepage != "" {
+		metadata["homepage"] = homepage
+	}
+
+	if license := repo.GetLicense(); license != nil {
+		metadata["license"] = license.GetSPDXID()
+	}
+
+	return metadata
+}
+
+// releasePopularity reports the stars of a repository and the downloads of
+// the assets of a release, which may be nil
+func releasePopularity(repo *github.Repository, release *github.RepositoryRelease) *Popularity {
+	popularity := &Popularity{Stars: repo.GetStargazersCount()}
+
+	if release != nil {
+		popularity.LastRelease = release.GetPublishedAt().Time
+
+		for _, asset := range release.Assets {
+			popularity.Downloads += int64(asset.GetDownloadCount())
+		}
+	}
+
+	return popularity
+}
+
+// readmeExcerpt returns the beginning of the repository README. Failures are
+// not fatal since the README is purely informational.
+func (s *GitHubStore) readmeExcerpt(ctx context.Context, owner, repo string) string {
+	readme, _, err := s.client.Repositories.GetReadme(ctx, owner, repo, nil)
+	if err != nil {
+		s.log.V(1).Info("failed to fetch readme", "owner", owner, "repo", repo, "error", err.Error())
+		return ""
+	}
+
+	text, err := readme.GetContent()
+	if err != nil {
+		s.log.V(1).Info("failed to decode readme", "owner", owner, "repo", repo, "error", err.Error())
+		return ""
+	}
+
+	if len(text) > readmeExcerptLength {
+		text = strings.ToValidUTF8(text[:readmeExcerptLength], "") + "..."
+	}
+
+	return text
+}
+
 // Search finds plugins matching the given criteria
 func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Info, error) {
 	s.log.Info("starting search with criteria", "criteria", criteria)
@@ -223,70 +356,41 @@ func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Inf
 		return nil, fmt.Errorf("store not properly initialized: topic is empty")
 	}
 
-	query := fmt.Sprintf("topic:%s fork:false", s.topic)
-	s.log.Info("executing search query", "query", query)
-
-	result, _, err := s.client.Search.Repositories(ctx, query, &github.SearchOptions{
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
-	})
+	query, opts, limit, err := searchQuery(s.topic, criteria)
 	if err != nil {
-		s.log.Error(err, "search failed")
-		return nil, fmt.Errorf("failed to search repositories: %w", err)
+		return nil, err
 	}
 
-	s.log.Info("found repositories matching search criteria", "count", len(result.Repositories))
+	s.log.V(1).Info("executing search query", "query", query, "sort", opts.Sort)
 
-	var plugins []Info
+	var repos []*github.Repository
 
-	for _, repo := range result.Repositories {
-		s.log.Info("processing repository", "name", repo.GetFullName(), "stars", repo.GetStargazersCount(), "created_at", repo.GetCreatedAt().String())
-
-		s.log.Info("latest release", "owner", repo.GetOwner().GetLogin(), "repo", repo.GetName())
-
-		release, resp, err := s.client.Repositories.GetLatestRelease(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	for {
+		result, resp, err := s.client.Search.Repositories(ctx, query, opts)
 		if err != nil {
-			s.log.Error(err, "failed to fetch release", "repo", repo.GetFullName(), "status", resp.StatusCode)
-			continue
+			s.log.Error(err, "search failed")
+			return nil, fmt.Errorf("failed to search repositories: %w", err)
 		}
 
-		// Use Filter function to get valid assets
-		getAssetNames := func() []string {
-			names := make([]string, len(release.Assets))
-			for i, asset := range release.Assets {
-				names[i] = asset.GetName()
-			}
+		repos = append(repos, result.Repositories...)
 
-			return names
+		if limit == 0 || len(repos) >= limit || resp.NextPage == 0 {
+			break
 		}
 
-		validAssets := Filter(s.prefix, repo.GetName(), release.GetTagName(), getAssetNames)
+		opts.Page = resp.NextPage
+	}
 
-		// Determine runtime based on valid assets
-		runtime := "exec"
+	if limit > 0 && len(repos) > limit {
+		repos = repos[:limit]
+	}
 
-		for _, asset := range validAssets {
-			if strings.HasSuffix(asset, ".wasm") {
-				runtime = "wasm"
-				break
-			}
-		}
+	s.log.Info("found repositories matching search criteria", "count", len(repos))
+
+	plugins := s.resolveReleases(ctx, repos)
 
-		plugins = append(plugins, Info{
-			Name:        repo.GetName(),
-			Version:     release.GetTagName(),
-			Description: repo.GetDescription(),
-			Store:       "github",
-			Runtime:     runtime,
-			Metadata: map[string]string{
-				"owner":      repo.GetOwner().GetLogin(),
-				"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
-				"repository": repo.GetHTMLURL(),
-			},
-		})
-
-		s.log.Info("added plugin to results", "name", repo.GetName(), "version", release.GetTagName())
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("search cancelled: %w", err)
 	}
 
 	s.log.Info("search complete", "found", len(plugins), "criteria", criteria)
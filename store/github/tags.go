@@ -0,0 +1,229 @@
+package extension
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceManifestFile is the file, at the root of a repository, that tells
+// the store where to find the plugin inside a tagged source tarball
+const SourceManifestFile = "plugin.yaml"
+
+// sourceManifest describes how to locate a plugin in a source tree. Bin is
+// used for every platform unless a more specific entry is listed; both may
+// use the {{os}} and {{arch}} placeholders.
+type sourceManifest struct {
+	Bin       string           `yaml:"bin"`
+	Runtime   string           `yaml:"runtime"`
+	Platforms []sourcePlatform `yaml:"platforms"`
+}
+
+type sourcePlatform struct {
+	OS   string `yaml:"os"`
+	Arch string `yaml:"arch"`
+	Bin  string `yaml:"bin"`
+}
+
+// sourceArtifact is a plugin located inside a tagged source tarball
+type sourceArtifact struct {
+	tag      string
+	fileName string
+	runtime  string
+	content  []byte
+}
+
+// maxSourceTarballSize bounds the size of source tarballs read into memory
+const maxSourceTarballSize = 256 << 20
+
+// fetchFromTag downloads the source tarball of a tag and extracts the plugin
+// it declares in its plugin.yaml. Used for repositories that publish tags
+// but no releases. Plugins that must be compiled first are not supported
+// here; the manifest has to point at a file committed to the repository.
+func (s *GitHubStore) fetchFromTag(ctx context.Context, owner, repo, version string) (*sourceArtifact, error) {
+	tag, err := s.resolveTag(ctx, owner, repo, version)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := s.log.WithValues("owner", owner, "repo", repo, "tag", tag)
+
+	link, _, err := s.client.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: tag}, 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source tarball link: %w", err)
+	}
+
+	logger.V(1).Info("downloading source tarball", "url", link.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download source tarball: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download source tarball: unexpected status %d", resp.StatusCode)
+	}
+
+	files, err := readTarball(io.LimitReader(resp.Body, maxSourceTarballSize))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := files[SourceManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("tag %s has no %s describing the plugin", tag, SourceManifestFile)
+	}
+
+	var manifest sourceManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", SourceManifestFile, err)
+	}
+
+	platform := PlatformFromContext(ctx)
+
+	bin := manifest.Bin
+	for _, p := range manifest.Platforms {
+		if p.OS == platform.OS && p.Arch == platform.Arch {
+			bin = p.Bin
+			break
+		}
+	}
+
+	if bin == "" {
+		return nil, fmt.Errorf("%s does not declare a plugin for %s", SourceManifestFile, platform)
+	}
+
+	bin = strings.NewReplacer("{{os}}", platform.OS, "{{arch}}", platform.Arch).Replace(bin)
+
+	content, ok := files[path.Clean(bin)]
+	if !ok {
+		return nil, fmt.Errorf("plugin file %s declared in %s not found in tag %s", bin, SourceManifestFile, tag)
+	}
+
+	rt := manifest.Runtime
+	if rt == "" {
+		rt = "exec"
+		if strings.HasSuffix(bin, ".wasm") {
+			rt = "wasm"
+		}
+	}
+
+	logger.V(1).Info("located plugin in source tarball", "bin", bin, "runtime", rt)
+
+	return &sourceArtifact{
+		tag:      tag,
+		fileName: path.Base(bin),
+		runtime:  rt,
+		content:  content,
+	}, nil
+}
+
+// resolveTag returns version if it names an existing tag, or the highest
+// version tag when version is empty or "latest"
+func (s *GitHubStore) resolveTag(ctx context.Context, owner, repo, version string) (string, error) {
+	var tags []string
+
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		page, resp, err := s.client.Repositories.ListTags(ctx, owner, repo, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list tags: %w", err)
+		}
+
+		for _, tag := range page {
+			tags = append(tags, tag.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	if len(tags) == 0 {
+		return "", fmt.Errorf("repository %s/%s has no tags", owner, repo)
+	}
+
+	if version != "" && version != "latest" {
+		for _, tag := range tags {
+			if tag == version {
+				return tag, nil
+			}
+		}
+
+		return "", fmt.Errorf("tag %s not found", version)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return CompareVersions(tags[i], tags[j]) > 0
+	})
+
+	return tags[0], nil
+}
+
+// readTarball reads the regular files of a gzipped source tarball, keyed by
+// their path with the top-level directory GitHub adds stripped
+func readTarball(r io.Reader) (map[string][]byte, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source tarball: %w", err)
+	}
+	defer gr.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source tarball: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		_, name, ok := strings.Cut(path.Clean(header.Name), "/")
+		if !ok {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from source tarball: %w", name, err)
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// isNotFound reports whether err is a 404 response from the GitHub API
+func isNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
+}
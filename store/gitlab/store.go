@@ -4,19 +4,25 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"runtime"
+	"net/http"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
 )
 
 var _ Store = &GitHubStore{}
 
+// readmeExcerptLength bounds the README text included in plugin metadata
+const readmeExcerptLength = 4096
+
 // GitHubStore implements the Store interface for GitHub-hosted plugins
 type GitHubStore struct {
 	client *github.Client
+	token  string
 	topic  string
 	prefix string
 	log    logr.Logger
@@ -24,22 +30,30 @@ type GitHubStore struct {
 
 // NewGitHubStore creates a new GitHub plugin store
 func NewGitHubStore(token string, logger logr.Logger) *GitHubStore {
-	var client *github.Client
+	return &GitHubStore{
+		client: newGitHubClient(token, nil),
+		token:  token,
+		log:    logger,
+	}
+}
 
-	if token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		tc := oauth2.NewClient(context.Background(), ts)
-		client = github.NewClient(tc)
-	} else {
-		client = github.NewClient(nil)
+// newGitHubClient creates a GitHub client authenticated with token on top of
+// the given base HTTP client
+func newGitHubClient(token string, base *http.Client) *github.Client {
+	if token == "" {
+		return github.NewClient(base)
 	}
 
-	return &GitHubStore{
-		client: client,
-		log:    logger,
+	ctx := context.Background()
+	if base != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, base)
 	}
+
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+
+	return github.NewClient(oauth2.NewClient(ctx, ts))
 }
 
 // Setup configures the store with specific parameters
@@ -58,6 +72,16 @@ func (s *GitHubStore) Setup(config StoreConfig) error {
 
 	s.prefix = prefix
 
+	// Client certificates, custom CA bundles and proxy overrides
+	if transportOpts := client.TransportOptionsFromConfig(config); !transportOpts.IsZero() {
+		transport, err := client.NewTransport(transportOpts)
+		if err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+
+		s.client = newGitHubClient(s.token, &http.Client{Transport: transport})
+	}
+
 	return nil
 }
 
@@ -85,7 +109,7 @@ func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*
 	}
 
 	owner, repoName := parts[0], parts[1]
-	s.log.Info("parsed plugin name", "owner", owner, "repo", repoName)
+	s.log.V(1).Info("parsed plugin name", "owner", owner, "repo", repoName)
 
 	repo, _, err := s.client.Repositories.Get(ctx, owner, repoName)
 	if err != nil {
@@ -93,85 +117,84 @@ func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*
 		return nil, fmt.Errorf("failed to fetch repository: %w", err)
 	}
 
-	s.log.Info("successfully fetched repository",
+	s.log.V(1).Info("successfully fetched repository",
 		"owner", owner,
 		"repo", repoName,
 		"description", repo.GetDescription())
 
 	var releaseVersion string
-	var assetNames []string
 	rt := "exec" // default
 
-	s.log.Info("releases url", "url", repo.GetReleasesURL())
+	s.log.V(1).Info("releases url", "url", repo.GetReleasesURL())
 
 	// Check releases first
 	var release *github.RepositoryRelease
 	if version == "" || version == "latest" {
 		release, _, err = s.client.Repositories.GetLatestRelease(ctx, owner, repoName)
-		if err != nil {
+		if err != nil && !isNotFound(err) {
 			s.log.Error(err, "failed to fetch latest release", "owner", owner, "repo", repoName)
 			return nil, fmt.Errorf("failed to fetch latest release: %w", err)
 		}
 	} else {
 		release, _, err = s.client.Repositories.GetReleaseByTag(ctx, owner, repoName, version)
-		if err != nil {
+		if err != nil && !isNotFound(err) {
 			s.log.Error(err, "failed to fetch release by tag", "owner", owner, "repo", repoName, "tag", version)
 			return nil, fmt.Errorf("failed to fetch release by tag: %w", err)
 		}
 	}
 
-	// Helper function to get asset names from a release
-	getAssetNames := func() []string {
-		for _, asset := range release.Assets {
-			assetNames = append(assetNames, asset.GetName())
-		}
-
-		return assetNames
-	}
-
-	var content interface{}
+	var (
+		content          interface{}
+		fallbackFileName string
+		attestations     []Attestation
+	)
 
-	if err == nil && release != nil {
-		s.log.Info("found release", "tag", release.GetTagName(), "assets", len(release.Assets), "created_at", release.GetCreatedAt().String())
+	if release != nil {
+		s.log.V(1).Info("found release", "tag", release.GetTagName(), "assets", len(release.Assets), "created_at", release.GetCreatedAt().String())
 
 		releaseVersion = release.GetTagName()
+		platform := PlatformFromContext(ctx)
+
+		candidates := make([]AssetCandidate, len(release.Assets))
+		for i, asset := range release.Assets {
+			candidates[i] = AssetCandidate{Name: asset.GetName(), Size: int64(asset.GetSize())}
+		}
 
-		match, rtAsset, err := FindAsset(
+		match, rtAsset, err := SelectAsset(
+			s.log,
 			s.prefix,
 			repoName,
 			releaseVersion,
-			runtime.GOOS,
-			runtime.GOARCH,
-			getAssetNames,
+			platform.OS,
+			platform.Arch,
+			AssetPreferenceFromContext(ctx),
+			candidates,
 		)
 		if err != nil {
 			s.log.Error(err, "error finding matching asset")
 			return nil, err
 		}
 
-		s.log.Info("found matching asset", "name", match, "runtime", rtAsset)
+		s.log.V(1).Info("found matching asset", "name", match, "runtime", rtAsset)
 
 		rt = rtAsset
 
 		// Download the matching asset
 		for _, asset := range release.Assets {
 			if asset.GetName() == match {
-				s.log.Info("downloading asset", "name", asset.GetName(), "size", asset.GetSize())
+				s.log.V(1).Info("downloading asset", "name", asset.GetName(), "size", asset.GetSize())
 
 				httpclient := s.client.Client()
 
-				rc, resp, err := s.client.Repositories.DownloadReleaseAsset(ctx, owner, repoName, asset.GetID(), httpclient)
+				rc, redirectURL, err := s.client.Repositories.DownloadReleaseAsset(ctx, owner, repoName, asset.GetID(), httpclient)
 				if err != nil {
 					return nil, fmt.Errorf("failed to download asset: %w", err)
 				}
 
-				s.log.Info("resp", "resp", resp)
-
-				//defer rc.Close()
-
-				s.log.Info("rc", "rc", rc)
+				s.log.V(1).Info("asset download started", "redirect", redirectURL)
 
 				content, err = io.ReadAll(rc)
+				rc.Close()
 				if err != nil {
 					return nil, fmt.Errorf("failed to read asset content: %w", err)
 				}
@@ -179,41 +202,111 @@ func (s *GitHubStore) Fetch(ctx context.Context, name string, version string) (*
 				break
 			}
 		}
+
+		attestations = s.fetchAttestations(ctx, owner, repoName, release)
 	} else {
-		s.log.Info("no release found", "err", err)
-		// Fallback to checking root directory if no release found
-		rc, resp, err := s.client.Repositories.DownloadContents(ctx, owner, repoName, fmt.Sprintf("%s-%s", s.prefix, repoName), nil)
-		if err == nil && resp.StatusCode == 200 {
-			releaseVersion = "main"
-			// Download the content
-			defer rc.Close()
-
-			content, err = io.ReadAll(rc)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read content: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("no release or root executable found")
+		s.log.V(1).Info("no release found, falling back to tags", "owner", owner, "repo", repoName)
+
+		artifact, err := s.fetchFromTag(ctx, owner, repoName, version)
+		if err != nil {
+			return nil, fmt.Errorf("no release found and tag fallback failed: %w", err)
 		}
+
+		releaseVersion = artifact.tag
+		content = artifact.content
+		rt = artifact.runtime
+		fallbackFileName = artifact.fileName
 	}
 
 	s.log.Info("successfully fetched plugin", "name", repo.GetName(), "version", releaseVersion, "runtime", rt)
 
+	var deprecation *Deprecation
+
+	metadata := repositoryMetadata(repo)
+	if release != nil {
+		metadata["release_notes"] = release.GetBody()
+		deprecation = ParseDeprecation(release.GetBody())
+	}
+
+	popularity := releasePopularity(repo, release)
+
+	if readme := s.readmeExcerpt(ctx, owner, repoName); readme != "" {
+		metadata["readme"] = readme
+	}
+
 	return &Info{
-		Name:        repo.GetName(),
-		Version:     releaseVersion,
-		Description: repo.GetDescription(),
-		Store:       "github",
-		Runtime:     rt,
-		Content:     content,
-		Metadata: map[string]string{
-			"owner":      repo.GetOwner().GetLogin(),
-			"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
-			"repository": repo.GetHTMLURL(),
-		},
+		Name:         repo.GetName(),
+		Version:      releaseVersion,
+		Description:  repo.GetDescription(),
+		Store:        "github",
+		FileName:     fallbackFileName,
+		Runtime:      rt,
+		Content:      content,
+		Metadata:     metadata,
+		Attestations: attestations,
+		Deprecation:  deprecation,
+		Popularity:   popularity,
 	}, nil
 }
 
+// repositoryMetadata collects the detail page fields available on a
+// repository without extra API calls
+func repositoryMetadata(repo *github.Repository) map[string]string {
+	metadata := map[string]string{
+		"owner":      repo.GetOwner().GetLogin(),
+		"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
+		"repository": repo.GetHTMLURL(),
+	}
+
+	if homepage := repo.GetHomepage(); homepage != "" {
+		metadata["homepage"] = homepage
+	}
+
+	if license := repo.GetLicense(); license != nil {
+		metadata["license"] = license.GetSPDXID()
+	}
+
+	return metadata
+}
+
+// releasePopularity reports the stars of a repository and the downloads of
+// the assets of a release, which may be nil
+func releasePopularity(repo *github.Repository, release *github.RepositoryRelease) *Popularity {
+	popularity := &Popularity{Stars: repo.GetStargazersCount()}
+
+	if release != nil {
+		popularity.LastRelease = release.GetPublishedAt().Time
+
+		for _, asset := range release.Assets {
+			popularity.Downloads += int64(asset.GetDownloadCount())
+		}
+	}
+
+	return popularity
+}
+
+// readmeExcerpt returns the beginning of the repository README. Failures are
+// not fatal since the README is purely informational.
+func (s *GitHubStore) readmeExcerpt(ctx context.Context, owner, repo string) string {
+	readme, _, err := s.client.Repositories.GetReadme(ctx, owner, repo, nil)
+	if err != nil {
+		s.log.V(1).Info("failed to fetch readme", "owner", owner, "repo", repo, "error", err.Error())
+		return ""
+	}
+
+	text, err := readme.GetContent()
+	if err != nil {
+		s.log.V(1).Info("failed to decode readme", "owner", owner, "repo", repo, "error", err.Error())
+		return ""
+	}
+
+	if len(text) > readmeExcerptLength {
+		text = strings.ToValidUTF8(text[:readmeExcerptLength], "") + "..."
+	}
+
+	return text
+}
+
 // Search finds plugins matching the given criteria
 func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Info, error) {
 	s.log.Info("starting search with criteria", "criteria", criteria)
@@ -224,7 +317,7 @@ func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Inf
 	}
 
 	query := fmt.Sprintf("topic:%s fork:false", s.topic)
-	s.log.Info("executing search query", "query", query)
+	s.log.V(1).Info("executing search query", "query", query)
 
 	result, _, err := s.client.Search.Repositories(ctx, query, &github.SearchOptions{
 		ListOptions: github.ListOptions{
@@ -241,9 +334,9 @@ func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Inf
 	var plugins []Info
 
 	for _, repo := range result.Repositories {
-		s.log.Info("processing repository", "name", repo.GetFullName(), "stars", repo.GetStargazersCount(), "created_at", repo.GetCreatedAt().String())
+		s.log.V(1).Info("processing repository", "name", repo.GetFullName(), "stars", repo.GetStargazersCount(), "created_at", repo.GetCreatedAt().String())
 
-		s.log.Info("latest release", "owner", repo.GetOwner().GetLogin(), "repo", repo.GetName())
+		s.log.V(1).Info("latest release", "owner", repo.GetOwner().GetLogin(), "repo", repo.GetName())
 
 		release, resp, err := s.client.Repositories.GetLatestRelease(ctx, repo.GetOwner().GetLogin(), repo.GetName())
 		if err != nil {
@@ -267,7 +360,7 @@ func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Inf
 		runtime := "exec"
 
 		for _, asset := range validAssets {
-			if strings.HasSuffix(asset, ".wasm") {
+			if AssetRuntime(asset) == "wasm" {
 				runtime = "wasm"
 				break
 			}
@@ -279,14 +372,11 @@ func (s *GitHubStore) Search(ctx context.Context, criteria SearchOptions) ([]Inf
 			Description: repo.GetDescription(),
 			Store:       "github",
 			Runtime:     runtime,
-			Metadata: map[string]string{
-				"owner":      repo.GetOwner().GetLogin(),
-				"stars":      fmt.Sprintf("%d", repo.GetStargazersCount()),
-				"repository": repo.GetHTMLURL(),
-			},
+			Metadata:    repositoryMetadata(repo),
+			Popularity:  releasePopularity(repo, release),
 		})
 
-		s.log.Info("added plugin to results", "name", repo.GetName(), "version", release.GetTagName())
+		s.log.V(1).Info("added plugin to results", "name", repo.GetName(), "version", release.GetTagName())
 	}
 
 	s.log.Info("search complete", "found", len(plugins), "criteria", criteria)
@@ -0,0 +1,244 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+var _ Store = &GoBuildStore{}
+
+// GoBuildStore implements the Store interface by building Go plugins from
+// source. Plugin names are Go package import paths of main packages, such
+// as github.com/acme/dashboard-foo/cmd/foo, and versions are module
+// versions. Built binaries are cached per package, version, platform and
+// toolchain so that repeated installs do not rebuild.
+type GoBuildStore struct {
+	goPath       string
+	cacheDir     string
+	minGoVersion string
+	cgo          bool
+	log          logr.Logger
+}
+
+// NewGoBuildStore creates a new store that builds plugins with the local Go
+// toolchain
+func NewGoBuildStore(logger logr.Logger) *GoBuildStore {
+	return &GoBuildStore{
+		goPath: "go",
+		log:    logger,
+	}
+}
+
+// Setup configures the store with specific parameters
+func (s *GoBuildStore) Setup(config StoreConfig) error {
+	if goPath, ok := config["go_path"].(string); ok && goPath != "" {
+		s.goPath = goPath
+	}
+
+	if cacheDir, ok := config["cache_dir"].(string); ok && cacheDir != "" {
+		s.cacheDir = cacheDir
+	} else {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("cache_dir is required: %w", err)
+		}
+
+		s.cacheDir = filepath.Join(userCache, "pluginkit", "gobuild")
+	}
+
+	if minGoVersion, ok := config["min_go_version"].(string); ok {
+		s.minGoVersion = minGoVersion
+	}
+
+	if cgo, ok := config["cgo"].(bool); ok {
+		s.cgo = cgo
+	}
+
+	return nil
+}
+
+// Fetch builds a plugin for the target platform of ctx and returns the
+// binary as its content
+func (s *GoBuildStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	if version == "" {
+		version = "latest"
+	}
+
+	platform := PlatformFromContext(ctx)
+	logger := s.log.WithValues("package", name, "version", version, "platform", platform.String())
+
+	goVersion, err := s.toolchain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exact versions can be served from the cache without touching the network
+	if version != "latest" {
+		if content, ok := s.cached(name, version, platform, goVersion); ok {
+			logger.V(1).Info("using cached build")
+			return s.info(name, version, goVersion, platform, content), nil
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "plugin-gobuild-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if _, err := s.run(ctx, workDir, nil, "mod", "init", "pluginkit.local/build"); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.run(ctx, workDir, nil, "get", name+"@"+version); err != nil {
+		return nil, err
+	}
+
+	out, err := s.run(ctx, workDir, nil, "list", "-f", "{{.Name}} {{.Module.Version}}", name)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName, resolved, _ := strings.Cut(strings.TrimSpace(out), " ")
+	if pkgName != "main" {
+		return nil, fmt.Errorf("package %s is not a main package", name)
+	}
+
+	logger = logger.WithValues("resolved", resolved)
+
+	if content, ok := s.cached(name, resolved, platform, goVersion); ok {
+		logger.V(1).Info("using cached build")
+		return s.info(name, resolved, goVersion, platform, content), nil
+	}
+
+	cgo := "0"
+	if s.cgo {
+		cgo = "1"
+	}
+
+	binary := filepath.Join(workDir, "plugin")
+	env := []string{"GOOS=" + platform.OS, "GOARCH=" + platform.Arch, "CGO_ENABLED=" + cgo}
+
+	logger.Info("building plugin from source", "toolchain", goVersion)
+
+	if _, err := s.run(ctx, workDir, env, "build", "-trimpath", "-o", binary, name); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built plugin: %w", err)
+	}
+
+	if err := s.store(name, resolved, platform, goVersion, content); err != nil {
+		logger.V(1).Info("failed to cache build", "error", err.Error())
+	}
+
+	return s.info(name, resolved, goVersion, platform, content), nil
+}
+
+// Search is not supported since Go modules cannot be enumerated; it always
+// returns no results
+func (s *GoBuildStore) Search(_ context.Context, _ SearchOptions) ([]Info, error) {
+	return nil, nil
+}
+
+// toolchain locates the Go toolchain and returns its version
+func (s *GoBuildStore) toolchain(ctx context.Context) (string, error) {
+	if _, err := exec.LookPath(s.goPath); err != nil {
+		return "", fmt.Errorf("go toolchain not found: %w", err)
+	}
+
+	out, err := s.run(ctx, "", nil, "env", "GOVERSION")
+	if err != nil {
+		return "", err
+	}
+
+	goVersion := strings.TrimSpace(out)
+
+	if s.minGoVersion != "" && CompareVersions(strings.TrimPrefix(goVersion, "go"), strings.TrimPrefix(s.minGoVersion, "go")) < 0 {
+		return "", fmt.Errorf("go toolchain %s is older than the required %s", goVersion, s.minGoVersion)
+	}
+
+	return goVersion, nil
+}
+
+// run executes a go command and returns its standard output
+func (s *GoBuildStore) run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, s.goPath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	s.log.V(2).Info("running go command", "args", args)
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("go %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// cachePath returns where the build of a package version is cached
+func (s *GoBuildStore) cachePath(name, version string, platform Platform, goVersion string) string {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(name+"@"+version)))
+
+	return filepath.Join(s.cacheDir, key[:16], version, platform.OS+"-"+platform.Arch, goVersion, path.Base(name))
+}
+
+func (s *GoBuildStore) cached(name, version string, platform Platform, goVersion string) ([]byte, bool) {
+	content, err := os.ReadFile(s.cachePath(name, version, platform, goVersion))
+	if err != nil {
+		return nil, false
+	}
+
+	return content, true
+}
+
+func (s *GoBuildStore) store(name, version string, platform Platform, goVersion string, content []byte) error {
+	target := s.cachePath(name, version, platform, goVersion)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, content, 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, target)
+}
+
+func (s *GoBuildStore) info(name, version, goVersion string, platform Platform, content []byte) *Info {
+	fileName := path.Base(name)
+	if platform.OS == "windows" {
+		fileName += ".exe"
+	}
+
+	return &Info{
+		Name:     path.Base(name),
+		FileName: fileName,
+		Version:  version,
+		Store:    "gobuild",
+		Runtime:  "exec",
+		Content:  content,
+		Metadata: map[string]string{
+			"package":   name,
+			"toolchain": goVersion,
+			"sha256":    fmt.Sprintf("%x", sha256.Sum256(content)),
+		},
+	}
+}
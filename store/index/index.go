@@ -0,0 +1,201 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var indexNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Index is a Git repository of plugin manifests
+type Index struct {
+	Name string
+	URL  string
+	Path string
+}
+
+// IndexManager maintains local clones of plugin indexes
+type IndexManager struct {
+	dir     string
+	gitPath string
+}
+
+// NewIndexManager creates a manager keeping index clones under dir
+func NewIndexManager(dir string) *IndexManager {
+	return &IndexManager{
+		dir:     dir,
+		gitPath: "git",
+	}
+}
+
+// Add clones a new index
+func (m *IndexManager) Add(ctx context.Context, name, url string) (*Index, error) {
+	if !indexNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid index name %q", name)
+	}
+
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("index %s already exists", name)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	if err := m.git(ctx, "", "clone", "--depth=1", "--", url, path); err != nil {
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("failed to clone index %s: %w", name, err)
+	}
+
+	return &Index{Name: name, URL: url, Path: path}, nil
+}
+
+// Update pulls the latest manifests for the named index, or for every index
+// when name is empty
+func (m *IndexManager) Update(ctx context.Context, name string) error {
+	indexes, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	found := false
+
+	for _, index := range indexes {
+		if name != "" && index.Name != name {
+			continue
+		}
+
+		found = true
+
+		if err := m.git(ctx, index.Path, "pull", "--ff-only", "--quiet"); err != nil {
+			return fmt.Errorf("failed to update index %s: %w", index.Name, err)
+		}
+	}
+
+	if name != "" && !found {
+		return fmt.Errorf("index %s not found", name)
+	}
+
+	return nil
+}
+
+// Remove deletes the local clone of an index
+func (m *IndexManager) Remove(name string) error {
+	if !indexNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid index name %q", name)
+	}
+
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("index %s not found", name)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove index %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// List returns the locally available indexes sorted by name
+func (m *IndexManager) List() ([]Index, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read index directory: %w", err)
+	}
+
+	var indexes []Index
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(m.dir, entry.Name())
+
+		out, err := exec.Command(m.gitPath, "-C", path, "remote", "get-url", "origin").Output()
+		if err != nil {
+			continue
+		}
+
+		indexes = append(indexes, Index{
+			Name: entry.Name(),
+			URL:  strings.TrimSpace(string(out)),
+			Path: path,
+		})
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+
+	return indexes, nil
+}
+
+// Manifests loads every valid manifest of an index. Invalid manifests are
+// reported in the returned error map keyed by file name.
+func (i Index) Manifests() ([]*Manifest, map[string]error) {
+	files, _ := filepath.Glob(filepath.Join(i.Path, "plugins", "*.yaml"))
+
+	var (
+		manifests []*Manifest
+		invalid   map[string]error
+	)
+
+	for _, file := range files {
+		m, err := LoadManifest(file)
+		if err != nil {
+			if invalid == nil {
+				invalid = make(map[string]error)
+			}
+
+			invalid[filepath.Base(file)] = err
+
+			continue
+		}
+
+		if want := strings.TrimSuffix(filepath.Base(file), ".yaml"); m.Metadata.Name != want {
+			if invalid == nil {
+				invalid = make(map[string]error)
+			}
+
+			invalid[filepath.Base(file)] = fmt.Errorf("metadata.name %q does not match file name", m.Metadata.Name)
+
+			continue
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	return manifests, invalid
+}
+
+// Manifest loads a single plugin manifest from the index
+func (i Index) Manifest(name string) (*Manifest, error) {
+	if !manifestNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid plugin name %q", name)
+	}
+
+	return LoadManifest(filepath.Join(i.Path, "plugins", name+".yaml"))
+}
+
+func (m *IndexManager) git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, m.gitPath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
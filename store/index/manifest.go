@@ -0,0 +1,175 @@
+package extension
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	manifestAPIVersion = "pluginkit/v1alpha1"
+	manifestKind       = "Plugin"
+)
+
+var manifestNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Manifest describes a plugin in an index, following the layout of krew
+// index manifests
+type Manifest struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   ManifestMetadata `yaml:"metadata"`
+	Spec       ManifestSpec     `yaml:"spec"`
+}
+
+// ManifestMetadata identifies the plugin
+type ManifestMetadata struct {
+	Name string `yaml:"name"`
+}
+
+// ManifestSpec holds the plugin details and its per-platform artifacts
+type ManifestSpec struct {
+	Version          string                 `yaml:"version"`
+	ShortDescription string                 `yaml:"shortDescription"`
+	Description      string                 `yaml:"description,omitempty"`
+	Homepage         string                 `yaml:"homepage,omitempty"`
+	Keywords         []string               `yaml:"keywords,omitempty"`
+	Commands         []ManifestCommand      `yaml:"commands,omitempty"` // Subcommands the plugin contributes to the host CLI
+	Runtime          string                 `yaml:"runtime,omitempty"`  // Defaults to exec
+	Permissions      *Permissions           `yaml:"permissions,omitempty"`
+	Requirements     *Requirements          `yaml:"requirements,omitempty"`
+	ConfigSchema     map[string]interface{} `yaml:"configSchema,omitempty"` // JSON schema of the plugin's user configuration
+	Deprecated       string                 `yaml:"deprecated,omitempty"`   // Deprecation notice, marks the plugin deprecated when set
+	Yanked           string                 `yaml:"yanked,omitempty"`       // Reason the version was withdrawn, marks it yanked when set
+	Replacement      string                 `yaml:"replacement,omitempty"`  // Plugin to use instead
+	EndOfLife        string                 `yaml:"endOfLife,omitempty"`    // Date support ends, YYYY-MM-DD
+	Platforms        []Platform             `yaml:"platforms"`
+}
+
+// ManifestCommand declares a subcommand of the plugin
+type ManifestCommand struct {
+	Name    string                 `yaml:"name"`
+	Summary string                 `yaml:"summary,omitempty"`
+	Usage   string                 `yaml:"usage,omitempty"`
+	Aliases []string               `yaml:"aliases,omitempty"`
+	Args    map[string]interface{} `yaml:"args,omitempty"` // JSON schema of the arguments and flags
+}
+
+// Platform is an artifact for one OS/architecture combination
+type Platform struct {
+	OS     string `yaml:"os"`
+	Arch   string `yaml:"arch"`
+	URI    string `yaml:"uri"`
+	SHA256 string `yaml:"sha256"`
+	Bin    string `yaml:"bin"` // File name of the plugin executable
+}
+
+// LoadManifest reads and validates a manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// Validate checks that the manifest is complete and well-formed
+func (m *Manifest) Validate() error {
+	var problems []string
+
+	if m.APIVersion != manifestAPIVersion {
+		problems = append(problems, fmt.Sprintf("apiVersion must be %s", manifestAPIVersion))
+	}
+
+	if m.Kind != manifestKind {
+		problems = append(problems, fmt.Sprintf("kind must be %s", manifestKind))
+	}
+
+	if !manifestNameRegexp.MatchString(m.Metadata.Name) {
+		problems = append(problems, "metadata.name must be lowercase alphanumeric with dashes")
+	}
+
+	if m.Spec.Version == "" {
+		problems = append(problems, "spec.version is required")
+	}
+
+	if m.Spec.ShortDescription == "" {
+		problems = append(problems, "spec.shortDescription is required")
+	}
+
+	if m.Spec.EndOfLife != "" {
+		if _, err := time.Parse("2006-01-02", m.Spec.EndOfLife); err != nil {
+			problems = append(problems, "spec.endOfLife must be a YYYY-MM-DD date")
+		}
+	}
+
+	for i, c := range m.Spec.Commands {
+		if c.Name == "" {
+			problems = append(problems, fmt.Sprintf("spec.commands[%d]: name is required", i))
+		}
+	}
+
+	if len(m.Spec.Platforms) == 0 {
+		problems = append(problems, "spec.platforms must not be empty")
+	}
+
+	seen := make(map[string]bool)
+
+	for i, p := range m.Spec.Platforms {
+		key := p.OS + "/" + p.Arch
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("spec.platforms[%d]: duplicate platform %s", i, key))
+		}
+
+		seen[key] = true
+
+		if p.OS == "" || p.Arch == "" {
+			problems = append(problems, fmt.Sprintf("spec.platforms[%d]: os and arch are required", i))
+		}
+
+		if !strings.HasPrefix(p.URI, "https://") {
+			problems = append(problems, fmt.Sprintf("spec.platforms[%d]: uri must use https", i))
+		}
+
+		if digest, err := hex.DecodeString(p.SHA256); err != nil || len(digest) != sha256.Size {
+			problems = append(problems, fmt.Sprintf("spec.platforms[%d]: sha256 must be a hex digest", i))
+		}
+
+		if p.Bin == "" || strings.ContainsAny(p.Bin, `/\`) {
+			problems = append(problems, fmt.Sprintf("spec.platforms[%d]: bin must be a file name", i))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Platform returns the artifact for the given OS and architecture
+func (m *Manifest) Platform(goos, arch string) (*Platform, bool) {
+	for i := range m.Spec.Platforms {
+		if m.Spec.Platforms[i].OS == goos && m.Spec.Platforms[i].Arch == arch {
+			return &m.Spec.Platforms[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,336 @@
+package extension
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
+)
+
+var (
+	_ StoreV2         = &IndexStore{}
+	_ MetadataFetcher = &IndexStore{}
+)
+
+const defaultIndexName = "default"
+
+// IndexStore implements the Store interface on top of Git-hosted manifest
+// indexes, in the style of the kubectl krew index
+type IndexStore struct {
+	indexes    *IndexManager
+	defaultIdx string
+	httpClient *http.Client
+	log        logr.Logger
+}
+
+// NewIndexStore creates a new index-backed plugin store
+func NewIndexStore(logger logr.Logger) *IndexStore {
+	return &IndexStore{
+		defaultIdx: defaultIndexName,
+		httpClient: &http.Client{},
+		log:        logger,
+	}
+}
+
+// Indexes returns the manager used to add, update and remove indexes
+func (s *IndexStore) Indexes() *IndexManager {
+	return s.indexes
+}
+
+// Setup configures the store with specific parameters
+func (s *IndexStore) Setup(config StoreConfig) error {
+	dir, ok := config["index_dir"].(string)
+	if !ok || dir == "" {
+		return fmt.Errorf("index_dir is required")
+	}
+
+	s.indexes = NewIndexManager(dir)
+
+	if name, ok := config["default_index"].(string); ok && name != "" {
+		s.defaultIdx = name
+	}
+
+	if gitPath, ok := config["git_path"].(string); ok && gitPath != "" {
+		s.indexes.gitPath = gitPath
+	}
+
+	// Client certificates, custom CA bundles and proxy overrides for
+	// artifact downloads
+	if transportOpts := client.TransportOptionsFromConfig(config); !transportOpts.IsZero() {
+		transport, err := client.NewTransport(transportOpts)
+		if err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+
+		s.httpClient = &http.Client{Transport: transport}
+	}
+
+	return nil
+}
+
+// Fetch retrieves a plugin by name. Names may be qualified with the index
+// they come from as <index>/<plugin>; unqualified names are looked up in the
+// default index.
+func (s *IndexStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	target := PlatformFromContext(ctx)
+
+	info, platform, err := s.resolve(name, version, target.OS, target.Arch)
+	if err != nil {
+		return nil, err
+	}
+
+	if platform == nil {
+		return nil, fmt.Errorf("plugin %s does not support %s/%s", info.Name, target.OS, target.Arch)
+	}
+
+	s.log.V(1).Info("downloading plugin artifact", "plugin", info.Name, "index", info.Metadata["index"], "uri", platform.URI)
+
+	content, err := s.download(ctx, platform.URI, platform.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Content = content
+
+	return info, nil
+}
+
+// FetchMetadata describes a plugin from its manifest without downloading it
+func (s *IndexStore) FetchMetadata(ctx context.Context, name string, version string) (*Info, error) {
+	target := PlatformFromContext(ctx)
+
+	info, _, err := s.resolve(name, version, target.OS, target.Arch)
+	return info, err
+}
+
+// Describe implements StoreV2
+func (s *IndexStore) Describe(ctx context.Context, name string, version string) (*Info, error) {
+	return s.FetchMetadata(ctx, name, version)
+}
+
+// Versions implements StoreV2. An index holds a single manifest per plugin,
+// so only its current version is available.
+func (s *IndexStore) Versions(ctx context.Context, name string) ([]string, error) {
+	info, err := s.FetchMetadata(ctx, name, "latest")
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{info.Version}, nil
+}
+
+// Resolve implements StoreV2
+func (s *IndexStore) Resolve(ctx context.Context, name string, constraint string) (string, error) {
+	versions, err := s.Versions(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return ResolveVersion(versions, constraint)
+}
+
+// resolve loads the manifest for a plugin reference and returns its
+// metadata together with the artifact for the target platform, if any
+func (s *IndexStore) resolve(name, version, goos, arch string) (*Info, *Platform, error) {
+	if s.indexes == nil {
+		return nil, nil, fmt.Errorf("store not properly initialized: index_dir is empty")
+	}
+
+	indexName, pluginName := s.defaultIdx, name
+	if before, after, ok := strings.Cut(name, "/"); ok {
+		indexName, pluginName = before, after
+	}
+
+	index, err := s.index(indexName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest, err := index.Manifest(pluginName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("plugin %s not found in index %s: %w", pluginName, indexName, err)
+	}
+
+	if version != "" && version != "latest" && version != manifest.Spec.Version {
+		return nil, nil, fmt.Errorf("index %s only provides %s version %s", indexName, pluginName, manifest.Spec.Version)
+	}
+
+	info := manifestInfo(manifest, indexName)
+
+	platform, ok := manifest.Platform(goos, arch)
+	if !ok {
+		return info, nil, nil
+	}
+
+	info.FileName = platform.Bin
+	info.Metadata["uri"] = platform.URI
+	info.Metadata["sha256"] = platform.SHA256
+
+	return info, platform, nil
+}
+
+// manifestInfo converts a manifest into plugin metadata
+func manifestInfo(m *Manifest, indexName string) *Info {
+	rt := m.Spec.Runtime
+	if rt == "" {
+		rt = "exec"
+	}
+
+	requirements := m.Spec.Requirements
+	if requirements == nil {
+		requirements = &Requirements{}
+	}
+
+	if len(requirements.Platforms) == 0 {
+		for _, p := range m.Spec.Platforms {
+			requirements.Platforms = append(requirements.Platforms, p.OS+"/"+p.Arch)
+		}
+	}
+
+	var configSchema json.RawMessage
+	if m.Spec.ConfigSchema != nil {
+		// The schema was parsed from YAML, so it always encodes
+		configSchema, _ = json.Marshal(m.Spec.ConfigSchema)
+	}
+
+	commands := make([]Command, 0, len(m.Spec.Commands))
+	for _, c := range m.Spec.Commands {
+		command := Command{Name: c.Name, Summary: c.Summary, Usage: c.Usage, Aliases: c.Aliases}
+		if c.Args != nil {
+			// The schema was parsed from YAML, so it always encodes
+			command.Args, _ = json.Marshal(c.Args)
+		}
+
+		commands = append(commands, command)
+	}
+
+	var deprecation *Deprecation
+	if m.Spec.Deprecated != "" || m.Spec.Yanked != "" {
+		deprecation = &Deprecation{
+			Deprecated:  m.Spec.Deprecated != "",
+			Yanked:      m.Spec.Yanked != "",
+			Message:     m.Spec.Deprecated,
+			Replacement: m.Spec.Replacement,
+			EndOfLife:   m.Spec.EndOfLife,
+		}
+
+		if m.Spec.Yanked != "" {
+			deprecation.Message = m.Spec.Yanked
+		}
+	}
+
+	return &Info{
+		Name:         m.Metadata.Name,
+		Version:      m.Spec.Version,
+		Description:  m.Spec.ShortDescription,
+		Store:        "index",
+		Runtime:      rt,
+		Permissions:  m.Spec.Permissions,
+		Requirements: requirements,
+		ConfigSchema: configSchema,
+		Deprecation:  deprecation,
+		Keywords:     m.Spec.Keywords,
+		Commands:     commands,
+		Metadata: map[string]string{
+			"index":    indexName,
+			"homepage": m.Spec.Homepage,
+		},
+	}
+}
+
+// Search lists the plugins of every index. The "query" criterion matches
+// plugin names and descriptions, and "index" restricts results to one index.
+func (s *IndexStore) Search(ctx context.Context, criteria SearchOptions) ([]Info, error) {
+	if s.indexes == nil {
+		return nil, fmt.Errorf("store not properly initialized: index_dir is empty")
+	}
+
+	indexes, err := s.indexes.List()
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.ToLower(criteria["query"])
+
+	var plugins []Info
+
+	for _, index := range indexes {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("search cancelled: %w", err)
+		}
+
+		if want := criteria["index"]; want != "" && want != index.Name {
+			continue
+		}
+
+		manifests, invalid := index.Manifests()
+		for file, err := range invalid {
+			s.log.Error(err, "skipping invalid manifest", "index", index.Name, "file", file)
+		}
+
+		for _, m := range manifests {
+			if query != "" &&
+				!strings.Contains(strings.ToLower(m.Metadata.Name), query) &&
+				!strings.Contains(strings.ToLower(m.Spec.ShortDescription), query) {
+				continue
+			}
+
+			plugins = append(plugins, *manifestInfo(m, index.Name))
+		}
+	}
+
+	return plugins, nil
+}
+
+func (s *IndexStore) index(name string) (*Index, error) {
+	indexes, err := s.indexes.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		if indexes[i].Name == name {
+			return &indexes[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("index %s not found", name)
+}
+
+// download fetches an artifact and verifies its SHA-256 digest
+func (s *IndexStore) download(ctx context.Context, uri, digest string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download artifact: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, digest) {
+		return nil, fmt.Errorf("artifact checksum mismatch: expected %s, got %s", digest, got)
+	}
+
+	return data, nil
+}
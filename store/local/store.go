@@ -0,0 +1,212 @@
+package extension
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+var _ StoreV2 = &LocalStore{}
+
+// LocalStore implements the Store interface on top of a directory tree laid
+// out as <dir>/<name>/<version>/<os>-<arch>/, as produced by the mirror
+// package. Each platform directory holds a metadata.json and the asset it
+// describes.
+type LocalStore struct {
+	dir string
+	log logr.Logger
+}
+
+// NewLocalStore creates a new directory-backed plugin store
+func NewLocalStore(logger logr.Logger) *LocalStore {
+	return &LocalStore{
+		log: logger,
+	}
+}
+
+// Setup configures the store with specific parameters
+func (s *LocalStore) Setup(config StoreConfig) error {
+	dir, ok := config["dir"].(string)
+	if !ok || dir == "" {
+		return fmt.Errorf("dir is required")
+	}
+
+	s.dir = dir
+
+	return nil
+}
+
+// Fetch retrieves a plugin asset for the target platform of ctx
+func (s *LocalStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	if s.dir == "" {
+		return nil, fmt.Errorf("store not properly initialized: dir is empty")
+	}
+
+	if version == "" || version == "latest" {
+		latest, err := s.latest(name)
+		if err != nil {
+			return nil, err
+		}
+
+		version = latest
+	}
+
+	platform := PlatformFromContext(ctx)
+	dir := filepath.Join(s.dir, name, version, platform.OS+"-"+platform.Arch)
+
+	info, err := readInfo(dir)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s@%s is not available for %s: %w", name, version, platform, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, info.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin asset: %w", err)
+	}
+
+	if expected := info.Metadata["sha256"]; expected != "" {
+		if actual := fmt.Sprintf("%x", sha256.Sum256(content)); actual != expected {
+			return nil, fmt.Errorf("checksum mismatch for %s@%s: expected %s, got %s", name, version, expected, actual)
+		}
+	}
+
+	s.log.V(1).Info("fetched plugin from local store", "name", name, "version", version, "platform", platform.String())
+
+	info.Store = "local"
+	info.Content = content
+
+	return info, nil
+}
+
+// Search lists the latest version of every plugin in the store whose name
+// contains the optional "name" criterion
+func (s *LocalStore) Search(ctx context.Context, criteria SearchOptions) ([]Info, error) {
+	if s.dir == "" {
+		return nil, fmt.Errorf("store not properly initialized: dir is empty")
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store directory: %w", err)
+	}
+
+	platform := PlatformFromContext(ctx)
+
+	var plugins []Info
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if query := criteria["name"]; query != "" && !strings.Contains(entry.Name(), query) {
+			continue
+		}
+
+		version, err := s.latest(entry.Name())
+		if err != nil {
+			s.log.V(1).Info("skipping plugin", "name", entry.Name(), "error", err.Error())
+			continue
+		}
+
+		info, err := readInfo(filepath.Join(s.dir, entry.Name(), version, platform.OS+"-"+platform.Arch))
+		if err != nil {
+			s.log.V(1).Info("skipping plugin without asset for platform", "name", entry.Name(), "platform", platform.String())
+			continue
+		}
+
+		info.Store = "local"
+		plugins = append(plugins, *info)
+	}
+
+	return plugins, nil
+}
+
+// Versions implements StoreV2. Versions are returned newest first.
+func (s *LocalStore) Versions(_ context.Context, name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s not found: %w", name, err)
+	}
+
+	var versions []string
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) > 0
+	})
+
+	return versions, nil
+}
+
+// Resolve implements StoreV2
+func (s *LocalStore) Resolve(ctx context.Context, name string, constraint string) (string, error) {
+	versions, err := s.Versions(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return ResolveVersion(versions, constraint)
+}
+
+// Describe implements StoreV2
+func (s *LocalStore) Describe(ctx context.Context, name string, version string) (*Info, error) {
+	if version == "" || version == "latest" {
+		latest, err := s.latest(name)
+		if err != nil {
+			return nil, err
+		}
+
+		version = latest
+	}
+
+	platform := PlatformFromContext(ctx)
+
+	info, err := readInfo(filepath.Join(s.dir, name, version, platform.OS+"-"+platform.Arch))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s@%s is not available for %s: %w", name, version, platform, err)
+	}
+
+	info.Store = "local"
+
+	return info, nil
+}
+
+func (s *LocalStore) latest(name string) (string, error) {
+	versions, err := s.Versions(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions found for plugin %s", name)
+	}
+
+	return versions[0], nil
+}
+
+func readInfo(dir string) (*Info, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	return &info, nil
+}
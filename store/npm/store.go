@@ -0,0 +1,306 @@
+package extension
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
+)
+
+var _ Store = &NPMStore{}
+
+const defaultNPMRegistry = "https://registry.npmjs.org"
+
+// NPMStore implements the Store interface for plugins published as npm
+// packages. The package tarball is installed as-is and its bin script is
+// recorded as the entrypoint of the node runtime.
+type NPMStore struct {
+	client   *client.Client
+	registry string
+	keyword  string
+	log      logr.Logger
+}
+
+// npmPackument is the subset of the registry package document in use
+type npmPackument struct {
+	Name     string                       `json:"name"`
+	DistTags map[string]string            `json:"dist-tags"`
+	Versions map[string]npmPackageVersion `json:"versions"`
+}
+
+type npmPackageVersion struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Description string          `json:"description"`
+	Homepage    string          `json:"homepage"`
+	License     string          `json:"license"`
+	Keywords    []string        `json:"keywords"`
+	Bin         json.RawMessage `json:"bin"`
+	Dist        struct {
+		Tarball   string `json:"tarball"`
+		Shasum    string `json:"shasum"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
+}
+
+type npmSearchResult struct {
+	Objects []struct {
+		Package struct {
+			Name        string   `json:"name"`
+			Version     string   `json:"version"`
+			Description string   `json:"description"`
+			Keywords    []string `json:"keywords"`
+		} `json:"package"`
+	} `json:"objects"`
+}
+
+// NewNPMStore creates a new npm registry plugin store
+func NewNPMStore(logger logr.Logger) *NPMStore {
+	return &NPMStore{
+		client:   client.New("", ""),
+		registry: defaultNPMRegistry,
+		log:      logger,
+	}
+}
+
+// Setup configures the store with specific parameters
+func (s *NPMStore) Setup(config StoreConfig) error {
+	if registry, ok := config["registry"].(string); ok && registry != "" {
+		s.registry = strings.TrimSuffix(registry, "/")
+	}
+
+	if keyword, ok := config["keyword"].(string); ok {
+		s.keyword = keyword
+	}
+
+	if token, ok := config["token"].(string); ok && token != "" {
+		s.client.AuthToken = "Bearer " + token
+	}
+
+	// Client certificates, custom CA bundles and proxy overrides
+	if transportOpts := client.TransportOptionsFromConfig(config); !transportOpts.IsZero() {
+		if _, err := s.client.WithTLS(transportOpts.TLS); err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+
+		if _, err := s.client.WithProxy(transportOpts.Proxy); err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Fetch downloads a package version. Versions may be exact, a dist-tag such
+// as "latest" or "next", or a range such as ^1.2.
+func (s *NPMStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	logger := s.log.WithValues("package", name, "version", version)
+
+	doc, err := client.GetJSON[npmPackument](ctx, s.client, s.registry+"/"+escapePackage(name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch package %s: %w", name, err)
+	}
+
+	resolved, err := resolveNPMVersion(doc, version)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := doc.Versions[resolved]
+
+	entrypoint, err := binEntrypoint(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info("downloading package tarball", "resolved", resolved, "tarball", pkg.Dist.Tarball)
+
+	resp, err := s.client.Get(ctx, pkg.Dist.Tarball, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download package tarball: %w", err)
+	}
+
+	if err := verifyTarball(resp.Body, pkg.Dist.Integrity, pkg.Dist.Shasum); err != nil {
+		return nil, fmt.Errorf("package %s@%s: %w", name, resolved, err)
+	}
+
+	// Tarballs unpack under a "package" directory
+	entrypoint = path.Join("package", entrypoint)
+
+	return &Info{
+		Name:        path.Base(name),
+		FileName:    entrypoint,
+		Entrypoint:  entrypoint,
+		Version:     resolved,
+		Description: pkg.Description,
+		Store:       "npm",
+		Runtime:     "node",
+		Content:     resp.Body,
+		Keywords:    pkg.Keywords,
+		Commands:    binCommands(pkg),
+		Metadata: map[string]string{
+			"package":  name,
+			"tarball":  pkg.Dist.Tarball,
+			"homepage": pkg.Homepage,
+			"license":  pkg.License,
+		},
+	}, nil
+}
+
+// Search finds packages with the configured keyword whose name or
+// description matches the optional "name" criterion
+func (s *NPMStore) Search(ctx context.Context, criteria SearchOptions) ([]Info, error) {
+	text := criteria["name"]
+	if s.keyword != "" {
+		text = strings.TrimSpace(text + " keywords:" + s.keyword)
+	}
+
+	if text == "" {
+		return nil, fmt.Errorf("store not properly initialized: keyword is empty")
+	}
+
+	result, err := client.GetJSON[npmSearchResult](ctx, s.client, s.registry+"/-/v1/search", &client.RequestOptions{
+		QueryParams: map[string]string{"text": text, "size": "100"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search packages: %w", err)
+	}
+
+	plugins := make([]Info, 0, len(result.Objects))
+
+	for _, object := range result.Objects {
+		plugins = append(plugins, Info{
+			Name:        path.Base(object.Package.Name),
+			Version:     object.Package.Version,
+			Description: object.Package.Description,
+			Store:       "npm",
+			Runtime:     "node",
+			Keywords:    object.Package.Keywords,
+			Metadata:    map[string]string{"package": object.Package.Name},
+		})
+	}
+
+	s.log.V(1).Info("search complete", "found", len(plugins), "text", text)
+
+	return plugins, nil
+}
+
+// resolveNPMVersion maps a requested version, dist-tag or range to a
+// published version
+func resolveNPMVersion(doc npmPackument, version string) (string, error) {
+	if version == "" {
+		version = "latest"
+	}
+
+	if tagged, ok := doc.DistTags[version]; ok {
+		version = tagged
+	}
+
+	if _, ok := doc.Versions[version]; ok {
+		return version, nil
+	}
+
+	if !IsVersionConstraint(version) {
+		return "", fmt.Errorf("version %s of package %s not found", version, doc.Name)
+	}
+
+	versions := make([]string, 0, len(doc.Versions))
+	for v := range doc.Versions {
+		versions = append(versions, v)
+	}
+
+	return ResolveVersion(versions, version)
+}
+
+// binEntrypoint returns the script declared in the package's bin field,
+// preferring the command named after the package
+func binEntrypoint(pkg npmPackageVersion) (string, error) {
+	var single string
+	if err := json.Unmarshal(pkg.Bin, &single); err == nil && single != "" {
+		return path.Clean(single), nil
+	}
+
+	var commands map[string]string
+	if err := json.Unmarshal(pkg.Bin, &commands); err != nil || len(commands) == 0 {
+		return "", fmt.Errorf("package %s does not declare a bin entrypoint", pkg.Name)
+	}
+
+	if script, ok := commands[path.Base(pkg.Name)]; ok {
+		return path.Clean(script), nil
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return path.Clean(commands[names[0]]), nil
+}
+
+// binCommands returns the commands declared in the package's bin field. A
+// single script is installed under the package name.
+func binCommands(pkg npmPackageVersion) []Command {
+	var single string
+	if err := json.Unmarshal(pkg.Bin, &single); err == nil && single != "" {
+		return []Command{{Name: path.Base(pkg.Name), Summary: pkg.Description}}
+	}
+
+	var commands map[string]string
+	if err := json.Unmarshal(pkg.Bin, &commands); err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	declared := make([]Command, len(names))
+	for i, name := range names {
+		declared[i] = Command{Name: name}
+	}
+
+	return declared
+}
+
+// verifyTarball checks a tarball against the registry's integrity string,
+// falling back to the legacy SHA-1 shasum
+func verifyTarball(data []byte, integrity, shasum string) error {
+	if algo, digest, ok := strings.Cut(integrity, "-"); ok && algo == "sha512" {
+		sum := sha512.Sum512(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != digest {
+			return fmt.Errorf("tarball integrity check failed")
+		}
+
+		return nil
+	}
+
+	if shasum != "" {
+		if fmt.Sprintf("%x", sha1.Sum(data)) != shasum {
+			return fmt.Errorf("tarball shasum check failed")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("registry provided no checksum for the tarball")
+}
+
+// escapePackage encodes a possibly scoped package name for use in a URL
+func escapePackage(name string) string {
+	return url.PathEscape(name)
+}
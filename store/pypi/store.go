@@ -0,0 +1,242 @@
+package extension
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	client "github.com/edsonmichaque/pluginkit/httpclient"
+)
+
+var _ Store = &PyPIStore{}
+
+const defaultPyPIIndex = "https://pypi.org"
+
+// PyPIStore implements the Store interface for plugins published as Python
+// packages. Pure-Python wheels are installed as-is and the package's
+// console script is recorded as the entrypoint of the python runtime.
+type PyPIStore struct {
+	client *client.Client
+	index  string
+	log    logr.Logger
+}
+
+// pypiRelease is the subset of the PyPI JSON API response in use
+type pypiRelease struct {
+	Info struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Summary  string `json:"summary"`
+		HomePage string `json:"home_page"`
+		License  string `json:"license"`
+	} `json:"info"`
+	Releases map[string][]pypiFile `json:"releases"`
+	URLs     []pypiFile            `json:"urls"`
+}
+
+type pypiFile struct {
+	Filename    string            `json:"filename"`
+	PackageType string            `json:"packagetype"`
+	URL         string            `json:"url"`
+	Digests     map[string]string `json:"digests"`
+	Yanked      bool              `json:"yanked"`
+}
+
+// NewPyPIStore creates a new PyPI plugin store
+func NewPyPIStore(logger logr.Logger) *PyPIStore {
+	return &PyPIStore{
+		client: client.New("", ""),
+		index:  defaultPyPIIndex,
+		log:    logger,
+	}
+}
+
+// Setup configures the store with specific parameters
+func (s *PyPIStore) Setup(config StoreConfig) error {
+	if index, ok := config["index"].(string); ok && index != "" {
+		s.index = strings.TrimSuffix(index, "/")
+	}
+
+	if token, ok := config["token"].(string); ok && token != "" {
+		s.client.AuthToken = "Bearer " + token
+	}
+
+	// Client certificates, custom CA bundles and proxy overrides
+	if transportOpts := client.TransportOptionsFromConfig(config); !transportOpts.IsZero() {
+		if _, err := s.client.WithTLS(transportOpts.TLS); err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+
+		if _, err := s.client.WithProxy(transportOpts.Proxy); err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Fetch downloads the pure-Python wheel of a package version. Versions may
+// be exact, "latest" or a range such as >=1.2,<2.
+func (s *PyPIStore) Fetch(ctx context.Context, name string, version string) (*Info, error) {
+	logger := s.log.WithValues("package", name, "version", version)
+
+	release, err := client.GetJSON[pypiRelease](ctx, s.client, s.index+"/pypi/"+name+"/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch package %s: %w", name, err)
+	}
+
+	resolved := release.Info.Version
+	files := release.URLs
+
+	if version != "" && version != "latest" {
+		versions := make([]string, 0, len(release.Releases))
+		for v := range release.Releases {
+			versions = append(versions, v)
+		}
+
+		if resolved, err = ResolveVersion(versions, version); err != nil {
+			return nil, fmt.Errorf("failed to resolve package %s: %w", name, err)
+		}
+
+		files = release.Releases[resolved]
+	}
+
+	wheel, ok := pureWheel(files)
+	if !ok {
+		return nil, fmt.Errorf("package %s@%s has no pure-Python wheel", name, resolved)
+	}
+
+	logger.V(1).Info("downloading wheel", "resolved", resolved, "file", wheel.Filename)
+
+	resp, err := s.client.Get(ctx, wheel.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download wheel: %w", err)
+	}
+
+	if expected := wheel.Digests["sha256"]; expected == "" || fmt.Sprintf("%x", sha256.Sum256(resp.Body)) != expected {
+		return nil, fmt.Errorf("checksum verification failed for %s", wheel.Filename)
+	}
+
+	entrypoint, err := consoleScript(resp.Body, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Name:        name,
+		Entrypoint:  entrypoint,
+		Version:     resolved,
+		Description: release.Info.Summary,
+		Store:       "pypi",
+		Runtime:     "python",
+		Content:     resp.Body,
+		Metadata: map[string]string{
+			"package":  name,
+			"wheel":    wheel.Filename,
+			"homepage": release.Info.HomePage,
+			"license":  release.Info.License,
+		},
+	}, nil
+}
+
+// Search is not supported since PyPI has no search API; it always returns
+// no results
+func (s *PyPIStore) Search(_ context.Context, _ SearchOptions) ([]Info, error) {
+	return nil, nil
+}
+
+// pureWheel picks a wheel that runs on any platform
+func pureWheel(files []pypiFile) (pypiFile, bool) {
+	for _, f := range files {
+		if f.PackageType == "bdist_wheel" && !f.Yanked && strings.HasSuffix(f.Filename, "-none-any.whl") {
+			return f, true
+		}
+	}
+
+	return pypiFile{}, false
+}
+
+// consoleScript reads the console_scripts entry points of a wheel and
+// returns the module:function reference of the script named after the
+// package, or of the first script declared
+func consoleScript(wheel []byte, name string) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(wheel), int64(len(wheel)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open wheel: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if path.Base(f.Name) != "entry_points.txt" || !strings.HasSuffix(path.Dir(f.Name), ".dist-info") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to read entry points: %w", err)
+		}
+		defer rc.Close()
+
+		scripts, err := parseConsoleScripts(rc)
+		if err != nil {
+			return "", err
+		}
+
+		if len(scripts) == 0 {
+			break
+		}
+
+		for _, script := range scripts {
+			if script[0] == name {
+				return script[1], nil
+			}
+		}
+
+		return scripts[0][1], nil
+	}
+
+	return "", fmt.Errorf("package %s does not declare a console script", name)
+}
+
+// parseConsoleScripts returns the name and reference of every entry in the
+// [console_scripts] section of an entry_points.txt file, in order
+func parseConsoleScripts(r io.Reader) ([][2]string, error) {
+	var (
+		scripts [][2]string
+		section string
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+
+		if section != "console_scripts" {
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok {
+			scripts = append(scripts, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse entry points: %w", err)
+	}
+
+	return scripts, nil
+}
@@ -0,0 +1,470 @@
+// Package storetest provides a scriptable in-memory Store for testing
+// applications that embed the plugin manager without reaching GitHub,
+// GitLab or any other registry.
+//
+//	store := storetest.New().
+//		Add(extension.Info{Name: "hello", Version: "1.0.0", Content: []byte("#!/bin/sh\necho hello\n")}).
+//		Add(extension.Info{Name: "hello", Version: "1.1.0-beta.1", Content: []byte("#!/bin/sh\necho beta\n")})
+//	store.FailNext(storetest.OpFetch, errors.New("connection reset"))
+//
+//	manager := extension.NewManager(dir, store, logger).WithFS(extension.NewMemFS())
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+var (
+	_ extension.StoreV2      = &Fake{}
+	_ extension.ChannelStore = &Fake{}
+)
+
+// ErrNotFound is returned for plugins and versions the store does not have
+var ErrNotFound = errors.New("not found")
+
+// Op names a store operation
+type Op string
+
+const (
+	OpSetup    Op = "setup"
+	OpFetch    Op = "fetch"
+	OpSearch   Op = "search"
+	OpVersions Op = "versions"
+	OpResolve  Op = "resolve"
+	OpDescribe Op = "describe"
+	OpChannels Op = "channels"
+)
+
+// Call records an operation made on the store
+type Call struct {
+	Op      Op
+	Name    string // Plugin name, empty for Setup and Search
+	Version string // Requested version or constraint, if any
+}
+
+// Fake is an in-memory Store serving canned releases. Its zero value is not
+// usable; call New. It is safe for concurrent use.
+type Fake struct {
+	mu       sync.Mutex
+	releases map[string][]extension.Info // Keyed by plugin name, in insertion order
+	channels map[string]extension.Channel
+	latency  map[Op]time.Duration
+	failures map[Op][]error // Errors returned by the next calls, consumed in order
+	always   map[Op]error   // Error returned by every call
+	calls    []Call
+	config   extension.StoreConfig
+}
+
+// New creates an empty fake store
+func New() *Fake {
+	return &Fake{
+		releases: make(map[string][]extension.Info),
+		channels: make(map[string]extension.Channel),
+		latency:  make(map[Op]time.Duration),
+		failures: make(map[Op][]error),
+		always:   make(map[Op]error),
+	}
+}
+
+// Add adds a release. Fetch returns info as given, with Store defaulting to
+// "fake" and Runtime to "native"; Content should hold the plugin binary or
+// archive. Adding a version again replaces it.
+func (f *Fake) Add(info extension.Info) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if info.Store == "" {
+		info.Store = "fake"
+	}
+
+	if info.Runtime == "" {
+		info.Runtime = "native"
+	}
+
+	if info.FileName == "" {
+		info.FileName = info.Name
+	}
+
+	releases := f.releases[info.Name]
+	for i, r := range releases {
+		if r.Version == info.Version {
+			releases[i] = info
+			return f
+		}
+	}
+
+	f.releases[info.Name] = append(releases, info)
+
+	return f
+}
+
+// Remove withdraws a release, as when a publisher deletes it
+func (f *Fake) Remove(name, version string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.releases[name] = slices.DeleteFunc(f.releases[name], func(info extension.Info) bool {
+		return info.Version == version
+	})
+
+	return f
+}
+
+// SetChannel publishes a version on a channel other than the one its
+// version string implies, see extension.VersionChannel
+func (f *Fake) SetChannel(name, version string, channel extension.Channel) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.channels[name+"@"+version] = channel
+
+	return f
+}
+
+// WithLatency delays every call of op by d, or every call of the store when
+// op is empty. Delayed calls return early when their context is done.
+func (f *Fake) WithLatency(op Op, d time.Duration) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.latency[op] = d
+
+	return f
+}
+
+// FailNext makes the next call of op return err. Repeated calls queue
+// errors for the following calls.
+func (f *Fake) FailNext(op Op, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[op] = append(f.failures[op], err)
+
+	return f
+}
+
+// Fail makes every call of op return err until Fail is called again with
+// a nil error
+func (f *Fake) Fail(op Op, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.always, op)
+	} else {
+		f.always[op] = err
+	}
+
+	return f
+}
+
+// Calls returns the operations made on the store, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Config returns the configuration passed to Setup
+func (f *Fake) Config() extension.StoreConfig {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return maps.Clone(f.config)
+}
+
+// Setup records the configuration
+func (f *Fake) Setup(config extension.StoreConfig) error {
+	if err := f.begin(context.Background(), Call{Op: OpSetup}); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config = maps.Clone(config)
+
+	return nil
+}
+
+// Fetch returns a release with its content. An empty version or "latest"
+// selects the highest stable version, falling back to the highest
+// pre-release.
+func (f *Fake) Fetch(ctx context.Context, name string, version string) (*extension.Info, error) {
+	if err := f.begin(ctx, Call{Op: OpFetch, Name: name, Version: version}); err != nil {
+		return nil, err
+	}
+
+	return f.release(name, version, true)
+}
+
+// Describe returns a release without its content
+func (f *Fake) Describe(ctx context.Context, name string, version string) (*extension.Info, error) {
+	if err := f.begin(ctx, Call{Op: OpDescribe, Name: name, Version: version}); err != nil {
+		return nil, err
+	}
+
+	return f.release(name, version, false)
+}
+
+// Search returns the latest release of every plugin whose name, description
+// or keywords contain the query, without content. SearchOwner matches the
+// prefix of names of the form owner/repo and SearchLimit bounds the
+// results.
+func (f *Fake) Search(ctx context.Context, criteria extension.SearchOptions) ([]extension.Info, error) {
+	if err := f.begin(ctx, Call{Op: OpSearch}); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	names := make([]string, 0, len(f.releases))
+	for name, releases := range f.releases {
+		if len(releases) > 0 {
+			names = append(names, name)
+		}
+	}
+	f.mu.Unlock()
+
+	sort.Strings(names)
+
+	query := strings.ToLower(criteria[extension.SearchQuery])
+	owner := criteria[extension.SearchOwner]
+
+	limit := -1
+	if value, ok := criteria[extension.SearchLimit]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %q", extension.SearchLimit, value)
+		}
+
+		limit = n
+	}
+
+	var results []extension.Info
+
+	for _, name := range names {
+		if limit >= 0 && len(results) == limit {
+			break
+		}
+
+		info, err := f.release(name, "latest", false)
+		if err != nil {
+			continue
+		}
+
+		if owner != "" && !strings.HasPrefix(name, owner+"/") {
+			continue
+		}
+
+		if query != "" && !matches(info, query) {
+			continue
+		}
+
+		results = append(results, *info)
+	}
+
+	return results, nil
+}
+
+// Versions lists the versions of a plugin from the highest
+func (f *Fake) Versions(ctx context.Context, name string) ([]string, error) {
+	if err := f.begin(ctx, Call{Op: OpVersions, Name: name}); err != nil {
+		return nil, err
+	}
+
+	versions := f.versions(name)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("plugin %s: %w", name, ErrNotFound)
+	}
+
+	return versions, nil
+}
+
+// Resolve returns the highest version satisfying constraint
+func (f *Fake) Resolve(ctx context.Context, name string, constraint string) (string, error) {
+	if err := f.begin(ctx, Call{Op: OpResolve, Name: name, Version: constraint}); err != nil {
+		return "", err
+	}
+
+	for _, v := range f.versions(name) {
+		ok, err := extension.MatchVersion(v, constraint)
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("plugin %s has no version matching %s: %w", name, constraint, ErrNotFound)
+}
+
+// Channels returns the channel of every version of a plugin
+func (f *Fake) Channels(ctx context.Context, name string) (map[string]extension.Channel, error) {
+	if err := f.begin(ctx, Call{Op: OpChannels, Name: name}); err != nil {
+		return nil, err
+	}
+
+	versions := f.versions(name)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("plugin %s: %w", name, ErrNotFound)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	channels := make(map[string]extension.Channel, len(versions))
+	for _, v := range versions {
+		channels[v] = f.channelOf(name, v)
+	}
+
+	return channels, nil
+}
+
+// begin records a call, waits out its latency and returns the error
+// scripted for it
+func (f *Fake) begin(ctx context.Context, call Call) error {
+	f.mu.Lock()
+
+	f.calls = append(f.calls, call)
+
+	delay := f.latency[call.Op]
+	if delay == 0 {
+		delay = f.latency[""]
+	}
+
+	var err error
+	if queued := f.failures[call.Op]; len(queued) > 0 {
+		err = queued[0]
+		f.failures[call.Op] = queued[1:]
+	} else {
+		err = f.always[call.Op]
+	}
+
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return err
+}
+
+// release returns a copy of a release. An empty version or "latest"
+// selects the highest stable version.
+func (f *Fake) release(name, version string, content bool) (*extension.Info, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	releases := f.releases[name]
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("plugin %s: %w", name, ErrNotFound)
+	}
+
+	var found *extension.Info
+
+	for i := range releases {
+		r := &releases[i]
+
+		switch {
+		case version == "" || version == "latest":
+			if found == nil || better(f.channelOf(name, r.Version), r.Version, f.channelOf(name, found.Version), found.Version) {
+				found = r
+			}
+		case extension.CompareVersions(r.Version, version) == 0:
+			found = r
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("plugin %s version %s: %w", name, version, ErrNotFound)
+	}
+
+	info := *found
+	info.Metadata = maps.Clone(found.Metadata)
+
+	if b, ok := found.Content.([]byte); ok {
+		info.Content = append([]byte(nil), b...)
+	}
+
+	if !content {
+		info.Content = nil
+	}
+
+	return &info, nil
+}
+
+// versions returns the versions of a plugin from the highest
+func (f *Fake) versions(name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions := make([]string, 0, len(f.releases[name]))
+	for _, r := range f.releases[name] {
+		versions = append(versions, r.Version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return extension.CompareVersions(versions[i], versions[j]) > 0
+	})
+
+	return versions
+}
+
+func (f *Fake) channelOf(name, version string) extension.Channel {
+	if channel, ok := f.channels[name+"@"+version]; ok {
+		return channel
+	}
+
+	return extension.VersionChannel(version)
+}
+
+// better reports whether version a should be preferred as latest over b:
+// stable versions win over pre-releases, then higher versions win
+func better(aChannel extension.Channel, a string, bChannel extension.Channel, b string) bool {
+	aStable, bStable := aChannel == extension.ChannelStable, bChannel == extension.ChannelStable
+	if aStable != bStable {
+		return aStable
+	}
+
+	return extension.CompareVersions(a, b) > 0
+}
+
+func matches(info *extension.Info, query string) bool {
+	if strings.Contains(strings.ToLower(info.Name), query) ||
+		strings.Contains(strings.ToLower(info.Description), query) {
+		return true
+	}
+
+	for _, keyword := range info.Keywords {
+		if strings.Contains(strings.ToLower(keyword), query) {
+			return true
+		}
+	}
+
+	return false
+}
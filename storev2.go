@@ -0,0 +1,96 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+)
+
+// StoreV2 extends Store with the operations the Manager needs to plan
+// installs and upgrades without downloading plugin binaries
+type StoreV2 interface {
+	Store
+
+	// Versions lists the versions available for a plugin
+	Versions(ctx context.Context, name string) ([]string, error)
+
+	// Resolve returns the highest version of a plugin that satisfies the
+	// given constraint (see MatchVersion)
+	Resolve(ctx context.Context, name string, constraint string) (string, error)
+
+	// Describe returns the metadata of a plugin version without its content
+	Describe(ctx context.Context, name string, version string) (*Info, error)
+}
+
+// AdaptStore returns s as a StoreV2. Stores that only implement Store are
+// wrapped: Describe uses FetchMetadata when the store is a MetadataFetcher
+// and otherwise falls back to Fetch, discarding the content, while Versions
+// only reports the latest version.
+func AdaptStore(s Store) StoreV2 {
+	if v2, ok := s.(StoreV2); ok {
+		return v2
+	}
+
+	return &storeAdapter{Store: s}
+}
+
+// describesWithoutContent reports whether Describe on the adapted store is
+// cheap, i.e. does not download the plugin
+func describesWithoutContent(s Store) bool {
+	switch s.(type) {
+	case StoreV2, MetadataFetcher:
+		return true
+	default:
+		return false
+	}
+}
+
+// storeAdapter implements StoreV2 on top of a Store
+type storeAdapter struct {
+	Store
+}
+
+func (a *storeAdapter) Versions(ctx context.Context, name string) ([]string, error) {
+	info, err := a.Describe(ctx, name, "latest")
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Version == "" {
+		return nil, fmt.Errorf("store did not report a version for plugin %s", name)
+	}
+
+	return []string{info.Version}, nil
+}
+
+func (a *storeAdapter) Resolve(ctx context.Context, name string, constraint string) (string, error) {
+	versions, err := a.Versions(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := ResolveVersion(versions, constraint)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve plugin %s: %w", name, err)
+	}
+
+	return version, nil
+}
+
+func (a *storeAdapter) Describe(ctx context.Context, name string, version string) (*Info, error) {
+	if fetcher, ok := a.Store.(MetadataFetcher); ok {
+		return fetcher.FetchMetadata(ctx, name, version)
+	}
+
+	info, err := a.Store.Fetch(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if closer, ok := info.Content.(interface{ Close() error }); ok {
+		closer.Close()
+	}
+
+	info.Content = nil
+
+	return info, nil
+}
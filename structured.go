@@ -0,0 +1,56 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	// ResultTrailer marks the start of a structured result on stdout. Everything
+	// after the marker line is parsed as a JSON object and removed from Stdout.
+	ResultTrailer = "::pluginkit-result::"
+
+	// ResultFDEnvVar names the environment variable holding the file
+	// descriptor a plugin may write its JSON result to, for runtimes that
+	// support passing extra descriptors
+	ResultFDEnvVar = "PLUGINKIT_RESULT_FD"
+)
+
+// ExtractStructured moves a trailer-delimited JSON result from the captured
+// stdout into result.Structured. Output without a trailer, or with a trailer
+// that is not followed by a JSON object, is left untouched.
+func ExtractStructured(result *ExecuteResult) *ExecuteResult {
+	if result == nil || result.Structured != nil {
+		return result
+	}
+
+	stdout, ok := result.Stdout.([]byte)
+	if !ok {
+		return result
+	}
+
+	i := bytes.LastIndex(stdout, []byte(ResultTrailer))
+	if i < 0 || (i > 0 && stdout[i-1] != '\n') {
+		return result
+	}
+
+	structured, err := parseStructured(stdout[i+len(ResultTrailer):])
+	if err != nil {
+		return result
+	}
+
+	result.Stdout = stdout[:i]
+	result.Structured = structured
+
+	return result
+}
+
+// parseStructured decodes a JSON object emitted by a plugin
+func parseStructured(data []byte) (map[string]any, error) {
+	var structured map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &structured); err != nil {
+		return nil, err
+	}
+
+	return structured, nil
+}
@@ -0,0 +1,196 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the conventional name of a project plugin file
+const ProjectFileName = ".extensions.yaml"
+
+// ProjectFile declares the plugins a project requires
+type ProjectFile struct {
+	Plugins []ProjectPlugin `yaml:"plugins"`
+	Prune   bool            `yaml:"prune,omitempty"` // Remove installed user plugins that are not declared
+}
+
+// ProjectPlugin is a plugin requirement in a project file
+type ProjectPlugin struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"` // Exact version, the latest one resolved when empty
+	Source  string `yaml:"source,omitempty"`  // Store to install from
+}
+
+// SyncAction is what Sync did for one plugin
+type SyncAction string
+
+const (
+	SyncInstalled SyncAction = "installed"
+	SyncUpgraded  SyncAction = "upgraded"
+	SyncRemoved   SyncAction = "removed"
+	SyncUnchanged SyncAction = "unchanged"
+	SyncFailed    SyncAction = "failed"
+)
+
+// SyncResult reports the outcome for one plugin
+type SyncResult struct {
+	Name    string     `json:"name"`
+	Action  SyncAction `json:"action"`
+	Version string     `json:"version,omitempty"`
+	Err     error      `json:"-"`
+}
+
+// LoadProjectFile reads and validates a project plugin file
+func LoadProjectFile(path string) (*ProjectFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var project ProjectFile
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for i, p := range project.Plugins {
+		if p.Name == "" {
+			return nil, fmt.Errorf("plugins[%d]: name is required", i)
+		}
+
+		if seen[p.Name] {
+			return nil, fmt.Errorf("plugins[%d]: %s is declared more than once", i, p.Name)
+		}
+
+		seen[p.Name] = true
+	}
+
+	return &project, nil
+}
+
+// Sync installs, upgrades and, when the file enables pruning, removes
+// plugins until the installed set matches the project file. Plugins declared
+// without a version are upgraded when a newer version resolves; plugins in
+// the system directory are never pruned. Every plugin is attempted; the
+// returned error reports the first failure.
+func (m *Manager) Sync(ctx context.Context, manifestPath string) ([]SyncResult, error) {
+	results, err := m.sync(ctx, manifestPath)
+
+	return results, wrapOp(OpSync, "", "", err)
+}
+
+func (m *Manager) sync(ctx context.Context, manifestPath string) ([]SyncResult, error) {
+	project, err := LoadProjectFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]Info, len(installed))
+	for _, info := range installed {
+		current[info.Name] = info
+	}
+
+	var (
+		results  []SyncResult
+		firstErr error
+	)
+
+	record := func(result SyncResult) {
+		if result.Err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to sync plugin %s: %w", result.Name, result.Err)
+		}
+
+		results = append(results, result)
+	}
+
+	declared := make(map[string]bool, len(project.Plugins))
+
+	for _, p := range project.Plugins {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("sync cancelled: %w", err)
+		}
+
+		ref := Reference{Store: p.Source, Name: p.Name}
+		declared[p.Name] = true
+
+		info, ok := current[p.Name]
+
+		switch {
+		case !ok:
+			version := p.Version
+			if version == "" {
+				version = "latest"
+			}
+
+			err := m.Install(ctx, ref.String(), InstallOptions{Version: version})
+			if installed, readErr := m.readInfo(p.Name); err == nil && readErr == nil {
+				version = installed.Version
+			}
+
+			record(syncResult(p.Name, SyncInstalled, version, err))
+		case p.Version == "":
+			record(m.syncLatest(ctx, info))
+		case info.Version != p.Version:
+			// The project pins the version, older or not
+			err := m.UpgradeWithOptions(ctx, p.Name, UpgradeOptions{Version: p.Version, AllowDowngrade: true})
+			record(syncResult(p.Name, SyncUpgraded, p.Version, err))
+		default:
+			record(SyncResult{Name: p.Name, Action: SyncUnchanged, Version: info.Version})
+		}
+	}
+
+	if project.Prune {
+		for name, info := range current {
+			// System plugins are not the project's to remove
+			if declared[name] || info.Layer == LayerSystem {
+				continue
+			}
+
+			err := m.Uninstall(ctx, name)
+			record(syncResult(name, SyncRemoved, "", err))
+		}
+	}
+
+	return results, firstErr
+}
+
+// syncLatest upgrades an installed plugin the project requires at no
+// particular version when a newer one than installed resolves. Pinned
+// plugins stay at their version.
+func (m *Manager) syncLatest(ctx context.Context, info Info) SyncResult {
+	if IsPinned(&info) {
+		return SyncResult{Name: info.Name, Action: SyncUnchanged, Version: info.Version}
+	}
+
+	latest, err := m.latestVersion(ctx, info)
+	if err != nil {
+		return syncResult(info.Name, SyncUpgraded, "", err)
+	}
+
+	if CompareVersions(latest, info.Version) <= 0 {
+		return SyncResult{Name: info.Name, Action: SyncUnchanged, Version: info.Version}
+	}
+
+	// Upgrade to latest rather than the resolved version so that the plugin
+	// keeps following its channel
+	err = m.Upgrade(ctx, info.Name, "latest")
+
+	return syncResult(info.Name, SyncUpgraded, latest, err)
+}
+
+func syncResult(name string, action SyncAction, version string, err error) SyncResult {
+	if err != nil {
+		action = SyncFailed
+	}
+
+	return SyncResult{Name: name, Action: action, Version: version, Err: err}
+}
@@ -0,0 +1,83 @@
+package extension_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestSyncFollowsLatestAndPrunesUserPlugins(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	store := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.0.0", Content: content}).
+		Add(extension.Info{Name: "stale", Version: "1.0.0", Content: content}).
+		Add(extension.Info{Name: "system", Version: "1.0.0", Content: content})
+
+	fsys := extension.NewMemFS()
+
+	system := extension.NewManager("/system", store, logr.Discard()).WithFS(fsys)
+	if err := system.Install(ctx, "system", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() in the system directory error = %v", err)
+	}
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(fsys).
+		WithSystemDir("/system")
+
+	manifest := filepath.Join(t.TempDir(), extension.ProjectFileName)
+	if err := os.WriteFile(manifest, []byte("prune: true\nplugins:\n  - name: hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Install(ctx, "stale", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	sync := func() map[string]extension.SyncResult {
+		t.Helper()
+
+		results, err := manager.Sync(ctx, manifest)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+
+		byName := make(map[string]extension.SyncResult, len(results))
+		for _, result := range results {
+			byName[result.Name] = result
+		}
+
+		return byName
+	}
+
+	results := sync()
+
+	if got := results["hello"]; got.Action != extension.SyncInstalled || got.Version != "1.0.0" {
+		t.Errorf("first sync of hello = %+v, want installed 1.0.0", got)
+	}
+
+	if got := results["stale"]; got.Action != extension.SyncRemoved {
+		t.Errorf("first sync of stale = %+v, want removed", got)
+	}
+
+	if _, ok := results["system"]; ok {
+		t.Errorf("sync touched the system plugin: %+v", results["system"])
+	}
+
+	if got := sync()["hello"]; got.Action != extension.SyncUnchanged || got.Version != "1.0.0" {
+		t.Errorf("second sync of hello = %+v, want unchanged 1.0.0", got)
+	}
+
+	store.Add(extension.Info{Name: "hello", Version: "1.1.0", Content: content})
+
+	if got := sync()["hello"]; got.Action != extension.SyncUpgraded || got.Version != "1.1.0" {
+		t.Errorf("sync after a release of hello = %+v, want upgraded to 1.1.0", got)
+	}
+}
@@ -0,0 +1,309 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edsonmichaque/pluginkit/events"
+)
+
+// Count is the usage of one plugin version since the last report
+type Count struct {
+	Plugin     string `json:"plugin"`
+	Version    string `json:"version,omitempty"`
+	Installs   int    `json:"installs,omitempty"`
+	Upgrades   int    `json:"upgrades,omitempty"`
+	Executions int    `json:"executions,omitempty"`
+	Failures   int    `json:"failures,omitempty"` // Executions that failed or exited non-zero
+}
+
+// Report is what is sent to the endpoint. It holds counts only: no
+// arguments, environment, paths, output, errors or host details. The
+// installation ID is random, is not derived from the machine or user, and
+// is replaced whenever telemetry is enabled again.
+type Report struct {
+	InstallationID string    `json:"installation_id"`
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+	Plugins        []Count   `json:"plugins"`
+}
+
+// state is the content of the telemetry file
+type state struct {
+	Enabled        bool              `json:"enabled"`
+	InstallationID string            `json:"installation_id,omitempty"`
+	Since          time.Time         `json:"since,omitempty"`
+	Counts         map[string]*Count `json:"counts,omitempty"` // Keyed by plugin@version
+}
+
+// Recorder counts plugin installs and executions in a local file and can
+// report them to a catalog operator's endpoint. Telemetry is disabled until
+// Enable is called, and the choice is persisted, so a Recorder that was
+// never enabled records and sends nothing.
+type Recorder struct {
+	path     string
+	endpoint string
+	client   *http.Client
+
+	mu    sync.Mutex
+	state state
+}
+
+// Open loads the telemetry state stored at path. A missing file means
+// telemetry was never enabled.
+func Open(path string) (*Recorder, error) {
+	r := &Recorder{path: path, client: http.DefaultClient}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+
+		return nil, fmt.Errorf("failed to read telemetry state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.state); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry state: %w", err)
+	}
+
+	return r, nil
+}
+
+// WithEndpoint sets the URL reports are POSTed to. Without an endpoint
+// counts are only kept locally.
+func (r *Recorder) WithEndpoint(endpoint string) *Recorder {
+	r.endpoint = endpoint
+	return r
+}
+
+// WithHTTPClient sets the client used to send reports
+func (r *Recorder) WithHTTPClient(client *http.Client) *Recorder {
+	r.client = client
+	return r
+}
+
+// Enable opts in to telemetry and starts a new installation ID
+func (r *Recorder) Enable() error {
+	id, err := newInstallationID()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = state{Enabled: true, InstallationID: id, Since: time.Now().UTC()}
+
+	return r.save()
+}
+
+// Disable opts out of telemetry and discards every count and the
+// installation ID
+func (r *Recorder) Disable() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = state{}
+
+	return r.save()
+}
+
+// Enabled reports whether telemetry was opted in to
+func (r *Recorder) Enabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state.Enabled
+}
+
+// Attach records the install, upgrade and execution events published on
+// bus. The returned function detaches the recorder.
+func (r *Recorder) Attach(bus *events.Bus) func() {
+	return bus.Subscribe(r.handle, events.PluginInstalled, events.PluginUpgraded, events.ExecutionFinished)
+}
+
+func (r *Recorder) handle(e events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.state.Enabled {
+		return
+	}
+
+	count := r.count(e.Plugin, e.Version)
+
+	switch e.Type {
+	case events.PluginInstalled:
+		count.Installs++
+	case events.PluginUpgraded:
+		count.Upgrades++
+	case events.ExecutionFinished:
+		count.Executions++
+		if e.Err != nil || e.Metadata["exit_code"] != "0" {
+			count.Failures++
+		}
+	}
+
+	// Counts are best effort; a failed save is retried with the next event
+	r.save()
+}
+
+func (r *Recorder) count(plugin, version string) *Count {
+	if r.state.Counts == nil {
+		r.state.Counts = make(map[string]*Count)
+	}
+
+	key := plugin + "@" + version
+
+	count, ok := r.state.Counts[key]
+	if !ok {
+		count = &Count{Plugin: plugin, Version: version}
+		r.state.Counts[key] = count
+	}
+
+	return count
+}
+
+// Snapshot returns the counts recorded since the last report, or nil when
+// telemetry is disabled
+func (r *Recorder) Snapshot() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshot()
+}
+
+func (r *Recorder) snapshot() *Report {
+	if !r.state.Enabled {
+		return nil
+	}
+
+	report := &Report{
+		InstallationID: r.state.InstallationID,
+		Since:          r.state.Since,
+		Until:          time.Now().UTC(),
+		Plugins:        make([]Count, 0, len(r.state.Counts)),
+	}
+
+	for _, count := range r.state.Counts {
+		report.Plugins = append(report.Plugins, *count)
+	}
+
+	sort.Slice(report.Plugins, func(i, j int) bool {
+		if report.Plugins[i].Plugin != report.Plugins[j].Plugin {
+			return report.Plugins[i].Plugin < report.Plugins[j].Plugin
+		}
+
+		return report.Plugins[i].Version < report.Plugins[j].Version
+	})
+
+	return report
+}
+
+// Send reports the counts recorded since the last report to the endpoint
+// and resets them once the endpoint accepted them. It does nothing when
+// telemetry is disabled, no endpoint is set or nothing was recorded.
+func (r *Recorder) Send(ctx context.Context) error {
+	if r.endpoint == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	report := r.snapshot()
+	r.mu.Unlock()
+
+	if report == nil || len(report.Plugins) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint rejected report: %s", resp.Status)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Keep what was recorded while the report was in flight
+	if r.state.InstallationID == report.InstallationID {
+		for _, sent := range report.Plugins {
+			count := r.count(sent.Plugin, sent.Version)
+			count.Installs -= sent.Installs
+			count.Upgrades -= sent.Upgrades
+			count.Executions -= sent.Executions
+			count.Failures -= sent.Failures
+
+			if *count == (Count{Plugin: sent.Plugin, Version: sent.Version}) {
+				delete(r.state.Counts, sent.Plugin+"@"+sent.Version)
+			}
+		}
+
+		r.state.Since = report.Until
+	}
+
+	return r.save()
+}
+
+// save writes the state atomically. The caller must hold r.mu.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create telemetry directory: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write telemetry state: %w", err)
+	}
+
+	if err := os.Rename(tmp, r.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save telemetry state: %w", err)
+	}
+
+	return nil
+}
+
+func newInstallationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate installation ID: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,130 @@
+package extension
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TrustLevel is how much the host trusts a plugin, derived from who signed
+// it and where it comes from
+type TrustLevel string
+
+const (
+	TrustVerified  TrustLevel = "verified-publisher"
+	TrustCommunity TrustLevel = "community"
+	TrustLocalDev  TrustLevel = "local-dev"
+)
+
+// TrustRule assigns a trust level to the plugins it matches. Every
+// non-empty list must match; entries may be glob patterns.
+type TrustRule struct {
+	Level      TrustLevel `mapstructure:"level"`
+	Identities []string   `mapstructure:"identities"` // Signature identities, e.g. https://github.com/acme/*
+	Owners     []string   `mapstructure:"owners"`
+	Stores     []string   `mapstructure:"stores"` // Configured store names or store types
+}
+
+// TrustPolicy is what a trust level implies for the plugins it applies to
+type TrustPolicy struct {
+	// Runtime the plugins are installed with and must run with, such as
+	// docker to always run them in containers. Empty keeps the runtime the
+	// plugin declares.
+	Runtime string `mapstructure:"runtime"`
+
+	// Permissions are the most the plugins are granted at execution, on top
+	// of what they declare. Nil leaves their declared permissions as is.
+	Permissions *Permissions `mapstructure:"permissions"`
+}
+
+// TrustConfig classifies plugins into trust levels and maps the levels to
+// runtime and security policies
+type TrustConfig struct {
+	Rules    []TrustRule                `mapstructure:"rules"`   // Evaluated in order, the first match wins
+	Default  TrustLevel                 `mapstructure:"default"` // Level when no rule matches, community when empty
+	Policies map[TrustLevel]TrustPolicy `mapstructure:"policies"`
+}
+
+// WithTrust classifies plugins at install and upgrade time and enforces the
+// policy of their trust level when they are installed and run. The level is
+// recorded in the plugin's metadata and passed to policies in
+// PolicyInput.Trust.
+func (m *Manager) WithTrust(config TrustConfig) *Manager {
+	m.trust = &config
+	return m
+}
+
+// Classify returns the trust level of the plugin described by input
+func (c *TrustConfig) Classify(input PolicyInput) TrustLevel {
+	stores := []string{strings.ToLower(input.Store)}
+	if input.Source != "" {
+		stores = append(stores, strings.ToLower(input.Source))
+	}
+
+	for _, rule := range c.Rules {
+		if len(rule.Identities) > 0 && !matchAny(rule.Identities, input.SignatureIdentities...) {
+			continue
+		}
+
+		if len(rule.Owners) > 0 && (input.Owner == "" || !matchAny(lower(rule.Owners), strings.ToLower(input.Owner))) {
+			continue
+		}
+
+		if len(rule.Stores) > 0 && !matchAny(lower(rule.Stores), stores...) {
+			continue
+		}
+
+		return rule.Level
+	}
+
+	if c.Default != "" {
+		return c.Default
+	}
+
+	return TrustCommunity
+}
+
+// classifyTrust sets the trust level of input and returns its policy
+func (m *Manager) classifyTrust(input *PolicyInput) TrustPolicy {
+	if m.trust == nil {
+		return TrustPolicy{}
+	}
+
+	if input.Trust == "" {
+		input.Trust = m.trust.Classify(*input)
+	}
+
+	return m.trust.Policies[input.Trust]
+}
+
+// applyTrust classifies a plugin about to be installed or upgraded and
+// moves it to the runtime its trust level requires. A runtime explicitly
+// requested by the caller must agree with it.
+func (m *Manager) applyTrust(input *PolicyInput, info *Info, requested string) error {
+	policy := m.classifyTrust(input)
+	if policy.Runtime == "" {
+		return nil
+	}
+
+	if requested != "" && requested != policy.Runtime {
+		return fmt.Errorf("%s plugins must use runtime %s, not %s", input.Trust, policy.Runtime, requested)
+	}
+
+	info.Runtime = policy.Runtime
+	input.Runtime = policy.Runtime
+
+	return nil
+}
+
+// enforceTrust checks that an installed plugin runs with the runtime of its
+// trust level and narrows the permissions it is granted
+func (m *Manager) enforceTrust(input *PolicyInput, opts *ExecuteOptions) error {
+	policy := m.classifyTrust(input)
+
+	if policy.Runtime != "" && input.Runtime != policy.Runtime {
+		return fmt.Errorf("plugin %s is %s and must run with runtime %s, not %s", input.Plugin, input.Trust, policy.Runtime, input.Runtime)
+	}
+
+	opts.Permissions = opts.Permissions.Restrict(policy.Permissions)
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultUpgradeConcurrency bounds the plugins UpgradeAll upgrades at once
+const defaultUpgradeConcurrency = 4
+
+// UpgradeOutcome is what UpgradeAll did with a plugin
+type UpgradeOutcome string
+
+const (
+	UpgradeUpgraded         UpgradeOutcome = "upgraded"
+	UpgradeUpToDate         UpgradeOutcome = "up-to-date"
+	UpgradeSkippedPinned    UpgradeOutcome = "skipped-pinned"
+	UpgradeSkippedSystem    UpgradeOutcome = "skipped-system"    // Read-only system plugin
+	UpgradeSkippedCancelled UpgradeOutcome = "skipped-cancelled" // Not attempted before ctx was cancelled
+	UpgradeFailed           UpgradeOutcome = "failed"
+)
+
+// UpgradeAllOptions controls UpgradeAll
+type UpgradeAllOptions struct {
+	Concurrency int // Plugins upgraded at once, defaults to 4
+}
+
+// UpgradeResult reports the outcome of UpgradeAll for one plugin
+type UpgradeResult struct {
+	Name    string         `json:"name"`
+	Outcome UpgradeOutcome `json:"outcome"`
+	From    string         `json:"from"`
+	To      string         `json:"to,omitempty"`
+	Reason  string         `json:"reason,omitempty"` // Why the upgrade failed or was cancelled
+	Err     error          `json:"-"`
+}
+
+// UpgradeReport lists the outcome of UpgradeAll for every installed plugin,
+// in the order List returns them
+type UpgradeReport struct {
+	Results []UpgradeResult `json:"results"`
+}
+
+// Count returns the number of plugins with the given outcome
+func (r *UpgradeReport) Count(outcome UpgradeOutcome) int {
+	n := 0
+
+	for _, result := range r.Results {
+		if result.Outcome == outcome {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Err joins the errors of the plugins that failed to upgrade
+func (r *UpgradeReport) Err() error {
+	var errs []error
+
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UpgradeAll checks every installed plugin that is not pinned or in the
+// system directory for a newer version, on the channel it follows if any,
+// and upgrades it. Plugins are handled concurrently and independently: a
+// failure is recorded in the report and does not stop the others. The error
+// is only set when the plugins cannot be listed or ctx is cancelled, in
+// which case the plugins not attempted yet are reported as
+// UpgradeSkippedCancelled.
+func (m *Manager) UpgradeAll(ctx context.Context, opts UpgradeAllOptions) (*UpgradeReport, error) {
+	plugins, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultUpgradeConcurrency
+	}
+
+	report := &UpgradeReport{Results: make([]UpgradeResult, len(plugins))}
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, concurrency)
+
+	for i, info := range plugins {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if err := ctx.Err(); err != nil {
+			for j := i; j < len(plugins); j++ {
+				report.Results[j] = UpgradeResult{
+					Name:    plugins[j].Name,
+					Outcome: UpgradeSkippedCancelled,
+					From:    plugins[j].Version,
+					Reason:  err.Error(),
+					Err:     err,
+				}
+			}
+
+			break
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			report.Results[i] = m.upgradeOne(ctx, info)
+		}()
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("upgrade cancelled: %w", err)
+	}
+
+	return report, nil
+}
+
+// upgradeOne checks a plugin for a newer version and upgrades it
+func (m *Manager) upgradeOne(ctx context.Context, info Info) UpgradeResult {
+	result := UpgradeResult{Name: info.Name, From: info.Version}
+
+	if IsPinned(&info) {
+		result.Outcome = UpgradeSkippedPinned
+		return result
+	}
+
+	if info.Layer == LayerSystem {
+		result.Outcome = UpgradeSkippedSystem
+		return result
+	}
+
+	status := m.checkUpdate(ctx, info)
+	if status.Err != nil {
+		return failedUpgrade(result, status.Err)
+	}
+
+	if !status.UpdateAvailable {
+		result.Outcome = UpgradeUpToDate
+		return result
+	}
+
+	// Upgrade to latest rather than status.Latest so that the plugin keeps
+	// following its channel
+	if err := m.Upgrade(ctx, info.Name, "latest"); err != nil {
+		if errors.Is(err, ErrUpToDate) {
+			result.Outcome = UpgradeUpToDate
+			return result
+		}
+
+		return failedUpgrade(result, err)
+	}
+
+	result.Outcome = UpgradeUpgraded
+	result.To = status.Latest
+
+	if upgraded, err := m.readInfo(info.Name); err == nil {
+		result.To = upgraded.Version
+	}
+
+	return result
+}
+
+func failedUpgrade(result UpgradeResult, err error) UpgradeResult {
+	result.Outcome = UpgradeFailed
+	result.Err = err
+	result.Reason = err.Error()
+
+	return result
+}
+
+// Pin keeps a plugin at its installed version: UpgradeAll skips it. Explicit
+// upgrades of the plugin are still applied.
+func (m *Manager) Pin(ctx context.Context, name string) error {
+	return m.setPinned(ctx, name, true)
+}
+
+// Unpin lets UpgradeAll upgrade a pinned plugin again
+func (m *Manager) Unpin(ctx context.Context, name string) error {
+	return m.setPinned(ctx, name, false)
+}
+
+// IsPinned reports whether the plugin is pinned at its installed version
+func IsPinned(info *Info) bool {
+	return info.Metadata["pinned"] != ""
+}
+
+func (m *Manager) setPinned(ctx context.Context, name string, pinned bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before pinning plugin: %w", err)
+	}
+
+	defer m.plugins.lock(name)()
+
+	return m.updateMetadata(name, func(info *Info) error {
+		if pinned {
+			info.Metadata["pinned"] = time.Now().Format(time.RFC3339)
+		} else {
+			delete(info.Metadata, "pinned")
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package extension_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	extension "github.com/edsonmichaque/pluginkit"
+	"github.com/edsonmichaque/pluginkit/store/storetest"
+)
+
+func TestUpgradeAllSkipsSystemAndCancelledPlugins(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("#!/bin/sh\necho hello\n")
+
+	store := storetest.New().
+		Add(extension.Info{Name: "hello", Version: "1.0.0", Content: content}).
+		Add(extension.Info{Name: "world", Version: "1.0.0", Content: content}).
+		Add(extension.Info{Name: "system", Version: "1.0.0", Content: content})
+
+	fsys := extension.NewMemFS()
+
+	system := extension.NewManager("/system", store, logr.Discard()).WithFS(fsys)
+	if err := system.Install(ctx, "system", extension.InstallOptions{}); err != nil {
+		t.Fatalf("Install() in the system directory error = %v", err)
+	}
+
+	manager := extension.NewManager("/plugins", store, logr.Discard()).
+		WithFS(fsys).
+		WithSystemDir("/system")
+
+	for _, name := range []string{"hello", "world"} {
+		if err := manager.Install(ctx, name, extension.InstallOptions{}); err != nil {
+			t.Fatalf("Install(%s) error = %v", name, err)
+		}
+	}
+
+	store.Add(extension.Info{Name: "hello", Version: "1.1.0", Content: content}).
+		Add(extension.Info{Name: "system", Version: "1.1.0", Content: content})
+
+	t.Run("system plugins", func(t *testing.T) {
+		report, err := manager.UpgradeAll(ctx, extension.UpgradeAllOptions{})
+		if err != nil {
+			t.Fatalf("UpgradeAll() error = %v", err)
+		}
+
+		want := map[string]extension.UpgradeOutcome{
+			"hello":  extension.UpgradeUpgraded,
+			"world":  extension.UpgradeUpToDate,
+			"system": extension.UpgradeSkippedSystem,
+		}
+
+		for _, result := range report.Results {
+			if result.Outcome != want[result.Name] {
+				t.Errorf("%s: outcome %s, want %s", result.Name, result.Outcome, want[result.Name])
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		store.WithLatency("", time.Minute)
+
+		cancelled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		// One plugin at a time: the first one checked against the store
+		// waits until ctx expires and the ones after it are not attempted
+		report, err := manager.UpgradeAll(cancelled, extension.UpgradeAllOptions{Concurrency: 1})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("UpgradeAll() error = %v, want context.DeadlineExceeded", err)
+		}
+
+		for _, result := range report.Results {
+			if result.Name == "" || result.Outcome == "" {
+				t.Errorf("result %+v was left empty", result)
+			}
+		}
+
+		if last := report.Results[len(report.Results)-1]; last.Outcome != extension.UpgradeSkippedCancelled || !errors.Is(last.Err, context.DeadlineExceeded) {
+			t.Errorf("last result %+v, want %s", last, extension.UpgradeSkippedCancelled)
+		}
+	})
+}
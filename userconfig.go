@@ -0,0 +1,134 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configDir holds the user configuration of every plugin. It lives outside
+// the plugin directories so configuration survives upgrades.
+const configDir = ".config"
+
+// ConfigValidationError is returned when a configuration does not match
+// the schema declared by the plugin
+type ConfigValidationError struct {
+	Plugin string
+	Err    error
+}
+
+func (e *ConfigValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration for plugin %s: %v", e.Plugin, e.Err)
+}
+
+// UserMessage implements UserMessager
+func (e *ConfigValidationError) UserMessage() Message {
+	return Message{Key: MsgInvalidConfig, Params: map[string]string{"plugin": e.Plugin, "error": fmt.Sprint(e.Err)}}
+}
+
+func (e *ConfigValidationError) Unwrap() error {
+	return e.Err
+}
+
+// GetConfig returns the user configuration of an installed plugin, or an
+// empty configuration when none was set
+func (m *Manager) GetConfig(ctx context.Context, name string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before reading plugin configuration: %w", err)
+	}
+
+	defer m.plugins.rlock(name)()
+
+	if _, err := m.cache.read(filepath.Join(m.pluginDir, name, "metadata.json")); err != nil {
+		return nil, fmt.Errorf("plugin %s is not installed: %w", name, err)
+	}
+
+	data, err := m.fs.ReadFile(m.configPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]interface{}{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read plugin configuration: %w", err)
+	}
+
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// SetConfig replaces the user configuration of an installed plugin. The
+// configuration is validated against the plugin's config schema, if any.
+func (m *Manager) SetConfig(ctx context.Context, name string, config map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context cancelled before saving plugin configuration: %w", err)
+	}
+
+	defer m.plugins.lock(name)()
+
+	info, err := m.cache.read(filepath.Join(m.pluginDir, name, "metadata.json"))
+	if err != nil {
+		return fmt.Errorf("plugin %s is not installed: %w", name, err)
+	}
+
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin configuration: %w", err)
+	}
+
+	if err := validateConfig(info, data); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(m.pluginDir, configDir)
+	if err := m.fs.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create configuration directory: %w", err)
+	}
+
+	if err := writeFileSync(m.fs, m.configPath(name), data); err != nil {
+		return fmt.Errorf("failed to save plugin configuration: %w", err)
+	}
+
+	return syncDir(m.fs, dir)
+}
+
+// configPath returns where the user configuration of a plugin is stored
+func (m *Manager) configPath(name string) string {
+	return filepath.Join(m.pluginDir, configDir, name+".json")
+}
+
+// validateConfig checks an encoded configuration against the JSON schema
+// declared in the plugin's manifest
+func validateConfig(info *Info, data []byte) error {
+	if len(info.ConfigSchema) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to parse plugin configuration: %w", err)
+	}
+
+	err := validateJSON(info.ConfigSchema, value)
+
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		return &ConfigValidationError{Plugin: info.Name, Err: configErr}
+	}
+
+	if err != nil {
+		return fmt.Errorf("plugin %s declares an invalid config schema: %w", info.Name, err)
+	}
+
+	return nil
+}
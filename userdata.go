@@ -0,0 +1,92 @@
+package extension
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DataDirName is the directory of a plugin's persistent state, such as
+	// caches, inside its plugin directory
+	DataDirName = "data"
+
+	// ConfigDirName is the directory of the settings a plugin keeps for its
+	// user inside its plugin directory
+	ConfigDirName = "config"
+)
+
+// preservedPaths are carried over from the installed version by Upgrade
+// instead of being replaced with the new version's files
+var preservedPaths = []string{DataDirName, ConfigDirName, historyFile}
+
+// DataDir returns the directory where a plugin keeps state that survives
+// upgrades. Hosts building a hostctx.Context pass it as DataDir.
+func (m *Manager) DataDir(name string) string {
+	return filepath.Join(m.pluginDir, name, DataDirName)
+}
+
+// ConfigDir returns the directory where a plugin keeps user settings that
+// survive upgrades. Hosts building a hostctx.Context pass it as ConfigDir.
+func (m *Manager) ConfigDir(name string) string {
+	return filepath.Join(m.pluginDir, name, ConfigDirName)
+}
+
+// isUserDataPath reports whether a path relative to a plugin directory is
+// inside the plugin's data or config directory
+func isUserDataPath(rel string) bool {
+	return strings.HasPrefix(rel, DataDirName+"/") || strings.HasPrefix(rel, ConfigDirName+"/")
+}
+
+// prepareUserDirs creates the plugin's data and config directories and
+// grants the plugin access to them when its filesystem access is restricted
+func (m *Manager) prepareUserDirs(name string, opts *ExecuteOptions) error {
+	for _, dir := range []string{m.DataDir(name), m.ConfigDir(name)} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create plugin directory %s: %w", dir, err)
+		}
+
+		if opts.Permissions != nil && !opts.Permissions.AllowsPath(dir) {
+			opts.Permissions = opts.Permissions.withPath(dir)
+		}
+	}
+
+	return nil
+}
+
+// carryOverUserData moves the preserved paths of the installed version in
+// from into the upgraded tree in to, replacing any the new version ships.
+// It returns a function moving them back, for when the upgrade is rolled
+// back.
+func carryOverUserData(fsys FS, from, to string) (func(), error) {
+	var moved []string
+
+	restore := func() {
+		for _, rel := range moved {
+			fsys.Rename(filepath.Join(to, rel), filepath.Join(from, rel))
+		}
+	}
+
+	for _, rel := range preservedPaths {
+		src := filepath.Join(from, rel)
+		if _, err := fsys.Stat(src); err != nil {
+			continue
+		}
+
+		dst := filepath.Join(to, rel)
+		if err := fsys.RemoveAll(dst); err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to make room for %s: %w", rel, err)
+		}
+
+		if err := fsys.Rename(src, dst); err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to carry over %s: %w", rel, err)
+		}
+
+		moved = append(moved, rel)
+	}
+
+	return restore, nil
+}
@@ -0,0 +1,111 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyReport compares an installed plugin with what was recorded when it
+// was installed and with what its store publishes
+type VerifyReport struct {
+	Plugin   string   `json:"plugin"`
+	Version  string   `json:"version"`
+	Modified []string `json:"modified,omitempty"` // Files whose content or mode changed, relative to the plugin directory
+	Missing  []string `json:"missing,omitempty"`  // Recorded files that no longer exist
+	Extra    []string `json:"extra,omitempty"`    // Files that were not installed by the Manager
+
+	// ArtifactDigest is the SHA-256 of the artifact downloaded at install
+	// time and PublishedDigest the one the store publishes for the version
+	// now. PublishedDigest is empty when the store publishes none or cannot
+	// describe the plugin without downloading it.
+	ArtifactDigest  string `json:"artifactDigest,omitempty"`
+	PublishedDigest string `json:"publishedDigest,omitempty"`
+
+	Unrecorded bool `json:"unrecorded,omitempty"` // Installed before file digests were recorded
+}
+
+// OK reports whether the plugin matches its records and its store
+func (r *VerifyReport) OK() bool {
+	return len(r.Modified) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0 && !r.Unrecorded &&
+		(r.PublishedDigest == "" || strings.EqualFold(r.PublishedDigest, r.ArtifactDigest))
+}
+
+// Verify compares an installed plugin's files with the bill of files
+// recorded at install or upgrade time, and compares the
+// digest of the installed artifact with the checksum its store publishes.
+// It only reports; hosts that want failing plugins quarantined pass the
+// outcome to ReportVerificationFailure.
+func (m *Manager) Verify(ctx context.Context, name string) (*VerifyReport, error) {
+	report, err := m.verify(ctx, name)
+	if err != nil {
+		return nil, wrapOp(OpVerify, name, m.storeOf(name), err)
+	}
+
+	return report, nil
+}
+
+func (m *Manager) verify(ctx context.Context, name string) (*VerifyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before verification: %w", err)
+	}
+
+	info, err := m.readInfo(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ErrNotInstalled{Plugin: name}
+		}
+
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Plugin:         name,
+		Version:        info.Version,
+		ArtifactDigest: info.Metadata["artifact_sha256"],
+		Unrecorded:     info.Files == nil,
+	}
+
+	release := m.plugins.rlock(name)
+	current, err := recordFiles(m.fs, filepath.Join(m.pluginDir, name))
+	release()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash plugin files: %w", err)
+	}
+
+	if info.Files != nil {
+		diff := DiffFiles(info.Files, current)
+		report.Modified, report.Missing, report.Extra = diff.Changed, diff.Removed, diff.Added
+	}
+
+	if report.PublishedDigest, err = m.publishedDigest(ctx, info); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// publishedDigest asks the plugin's store for the checksum of the installed
+// version, when it can answer without downloading it
+func (m *Manager) publishedDigest(ctx context.Context, info *Info) (string, error) {
+	store, err := m.storeFor(info.Metadata["source"])
+	if err != nil || !describesWithoutContent(store) {
+		return "", nil
+	}
+
+	upstream := info.Name
+	if info.Metadata["upstream"] != "" {
+		upstream = info.Metadata["upstream"]
+	}
+
+	published, err := AdaptStore(store).Describe(ctx, upstream, info.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe installed version: %w", err)
+	}
+
+	return published.Metadata["sha256"], nil
+}
@@ -0,0 +1,225 @@
+package extension
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two version strings such as v1.2.3 or
+// 1.4.0-rc.1. It returns -1, 0 or 1. Missing components count as zero and a
+// pre-release sorts before the corresponding release. Pre-releases compare
+// by their dot-separated identifiers as in Semantic Versioning, so rc.10
+// sorts after rc.2.
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := 0; i < len(aCore) || i < len(bCore); i++ {
+		var x, y int
+		if i < len(aCore) {
+			x = aCore[i]
+		}
+
+		if i < len(bCore) {
+			y = bCore[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return comparePrerelease(aPre, bPre)
+	}
+}
+
+// comparePrerelease compares two pre-release tags identifier by identifier.
+// Numeric identifiers compare numerically and sort before alphanumeric ones;
+// a tag that is a prefix of the other sorts first.
+func comparePrerelease(a, b string) int {
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, xErr := strconv.Atoi(aIDs[i])
+		y, yErr := strconv.Atoi(bIDs[i])
+
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				return cmp.Compare(x, y)
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		case aIDs[i] != bIDs[i]:
+			return strings.Compare(aIDs[i], bIDs[i])
+		}
+	}
+
+	return cmp.Compare(len(aIDs), len(bIDs))
+}
+
+// splitVersion parses the numeric components and pre-release tag of a version
+func splitVersion(v string) ([]int, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+
+	// Build metadata does not take part in precedence
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, _ := strings.Cut(v, "-")
+
+	var parts []int
+
+	for _, p := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+
+		parts = append(parts, n)
+	}
+
+	return parts, pre
+}
+
+// IsVersionConstraint reports whether s is a version range such as
+// ">=1.2, <2" or "^1.4" rather than a single version
+func IsVersionConstraint(s string) bool {
+	return strings.ContainsAny(s, "<>=!~^*,")
+}
+
+// MatchVersion reports whether version satisfies constraint. A constraint is
+// a comma-separated list of clauses, all of which must hold. Each clause is
+// an operator (=, !=, >, >=, <, <=, ~ or ^) followed by a version, or a bare
+// version for an exact match. "~1.2.3" allows patch updates and "^1.2.3"
+// allows updates that do not change the left-most non-zero component. An
+// empty constraint, "*" or "latest" matches every version.
+func MatchVersion(version, constraint string) (bool, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == "*" || constraint == "latest" {
+		return true, nil
+	}
+
+	for _, clause := range strings.Split(constraint, ",") {
+		ok, err := matchClause(version, strings.TrimSpace(clause))
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// ResolveVersion returns the highest of versions that satisfies constraint.
+// Releases are preferred; a pre-release is only selected when no release
+// satisfies the constraint.
+func ResolveVersion(versions []string, constraint string) (string, error) {
+	for _, allowPre := range []bool{false, true} {
+		var best string
+
+		for _, v := range versions {
+			if _, pre := splitVersion(v); pre != "" && !allowPre {
+				continue
+			}
+
+			ok, err := MatchVersion(v, constraint)
+			if err != nil {
+				return "", err
+			}
+
+			if ok && (best == "" || CompareVersions(v, best) > 0) {
+				best = v
+			}
+		}
+
+		if best != "" {
+			return best, nil
+		}
+	}
+
+	return "", fmt.Errorf("no version satisfies %q", constraint)
+}
+
+func matchClause(version, clause string) (bool, error) {
+	if clause == "" {
+		return false, fmt.Errorf("empty version constraint clause")
+	}
+
+	var op string
+
+	for _, candidate := range []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(clause, candidate) {
+			op = candidate
+			break
+		}
+	}
+
+	target := strings.TrimSpace(strings.TrimPrefix(clause, op))
+
+	core, _ := splitVersion(target)
+	if len(core) == 0 {
+		return false, fmt.Errorf("invalid version in constraint %q", clause)
+	}
+
+	cmp := CompareVersions(version, target)
+
+	switch op {
+	case "", "=", "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "~":
+		// Allow changes below the minor component, or below the major one
+		// when only a major version is given
+		bound := 1
+		if len(core) == 1 {
+			bound = 0
+		}
+
+		return cmp >= 0 && CompareVersions(version, upperBound(core, bound)) < 0, nil
+	default: // ^
+		bound := 0
+		for bound < len(core)-1 && core[bound] == 0 {
+			bound++
+		}
+
+		return cmp >= 0 && CompareVersions(version, upperBound(core, bound)) < 0, nil
+	}
+}
+
+// upperBound increments the component at index i and drops the rest,
+// returning the first version outside the range
+func upperBound(core []int, i int) string {
+	parts := make([]string, i+1)
+	for j := 0; j < i; j++ {
+		parts[j] = strconv.Itoa(core[j])
+	}
+
+	parts[i] = strconv.Itoa(core[i] + 1)
+
+	// Sort before any pre-release of the bound itself
+	return strings.Join(parts, ".") + "-0"
+}
@@ -0,0 +1,111 @@
+package extension_test
+
+import (
+	"testing"
+
+	extension "github.com/edsonmichaque/pluginkit"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.2.3+build.5", "1.2.3", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc.1", 1},
+		{"1.0.0-rc.10", "1.0.0-rc.2", 1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-0", "1.0.0-alpha", -1},
+	}
+
+	for _, tt := range tests {
+		if got := extension.CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "*", true},
+		{"1.2.3", "latest", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "=1.2.3", false},
+		{"1.2.4", "!=1.2.3", true},
+		{"1.5.0", ">=1.2, <2", true},
+		{"2.0.0", ">=1.2, <2", false},
+		{"2.0.0-rc.1", "<2", true},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.2.2", "~1.2.3", false},
+		{"1.9.0", "~1", true},
+		{"2.0.0", "~1", false},
+		{"1.9.9", "^1.2.3", true},
+		{"2.0.0", "^1.2.3", false},
+		{"2.0.0-rc.1", "^1.2.3", false},
+		{"0.2.9", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"0.0.3", "^0.0.3", true},
+		{"0.0.4", "^0.0.3", false},
+	}
+
+	for _, tt := range tests {
+		got, err := extension.MatchVersion(tt.version, tt.constraint)
+		if err != nil {
+			t.Errorf("MatchVersion(%q, %q) error = %v", tt.version, tt.constraint, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("MatchVersion(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+
+	for _, constraint := range []string{">=", "^x", "1.2.3,"} {
+		if _, err := extension.MatchVersion("1.2.3", constraint); err == nil {
+			t.Errorf("MatchVersion(1.2.3, %q) succeeded, want an error", constraint)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	versions := []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0-rc.2", "2.0.0-rc.10", "0.9.0"}
+
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"", "1.10.0"},
+		{"latest", "1.10.0"},
+		{"^1.0.0", "1.10.0"},
+		{"~1.2.0", "1.2.0"},
+		{"<1", "0.9.0"},
+		{">=2.0.0-0", "2.0.0-rc.10"},
+		{"1.0.0", "1.0.0"},
+	}
+
+	for _, tt := range tests {
+		got, err := extension.ResolveVersion(versions, tt.constraint)
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveVersion(%q) = %q, %v, want %q", tt.constraint, got, err, tt.want)
+		}
+	}
+
+	if got, err := extension.ResolveVersion(versions, ">=3"); err == nil {
+		t.Errorf("ResolveVersion(>=3) = %q, want an error", got)
+	}
+}
@@ -0,0 +1,50 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Volume is a named volume or cache a plugin declares in its manifest.
+// Container executors create it on first use and mount it at Path on every
+// run, so the plugin keeps state between runs without writing into the
+// host project.
+type Volume struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`            // Absolute mount point inside the container
+	Cache bool   `json:"cache,omitempty"` // Contents can be regenerated and may be discarded
+}
+
+// VolumePurger is implemented by executors that can remove the volumes
+// they created for a plugin
+type VolumePurger interface {
+	// RemoveVolumes removes the plugin's volumes. Volumes that were never
+	// created are ignored.
+	RemoveVolumes(ctx context.Context, pluginName string, volumes []Volume) error
+}
+
+var (
+	volumeNamePattern  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	invalidVolumeChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// Validate checks that the volume has a usable name and mount point
+func (v Volume) Validate() error {
+	if !volumeNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("invalid volume name %q", v.Name)
+	}
+
+	if !path.IsAbs(v.Path) {
+		return fmt.Errorf("volume %s must be mounted at an absolute path, got %q", v.Name, v.Path)
+	}
+
+	return nil
+}
+
+// VolumeName returns the name the runtime knows a plugin's volume by, which
+// is the same on every run
+func VolumeName(pluginName string, volume Volume) string {
+	return "pluginkit-" + invalidVolumeChars.ReplaceAllString(pluginName, "_") + "-" + volume.Name
+}